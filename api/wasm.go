@@ -375,6 +375,10 @@ type Function interface {
 	// another Function if you want to invoke the same function concurrently.
 	// On the other hand, sequential invocations of Call is allowed.
 	//
+	// The caller doesn't own the returned results: the implementation may
+	// reuse the slice, so they may be overwritten by the next invocation of
+	// Call on the same Function. Copy them to retain them across calls.
+	//
 	// To safely encode/decode params/results expressed as uint64, users are encouraged to
 	// use api.EncodeXXX or DecodeXXX functions. See the docs on api.ValueType.
 	//
@@ -135,8 +135,50 @@ type Config interface {
 	// as the value of os.Getwd. For example, it would be an error to mount `C:\`
 	// as the guest path "", while the current directory is inside `D:\`.
 	WithOSWorkdir() Config
+
+	// WithTTY marks the file descriptor as a terminal, so that functions like
+	// golang.org/x/term.IsTerminal return true for it. None are by default.
+	//
+	// Here's an example that reports stdout as a terminal:
+	//
+	//	err = gojs.Run(ctx, r, compiled, gojs.NewConfig(moduleConfig).
+	//			WithTTY(1))
+	//
+	// Note: This doesn't change how the file descriptor is read or written.
+	WithTTY(fd int32) Config
+
+	// WithStdinMode sets how reading stdin behaves when no data is available.
+	// Defaults to StdinModeDefault, which reads stdin as is.
+	//
+	// Here's an example that makes interactive programs poll stdin without
+	// blocking:
+	//
+	//	err = gojs.Run(ctx, r, compiled, gojs.NewConfig(moduleConfig).
+	//			WithStdinMode(gojs.StdinModeNonblocking))
+	//
+	// Note: Whether data is available is only known for stdin backed by a
+	// file such as os.Stdin or a pipe. Other io.Reader values are always
+	// considered ready, so they are read as is in every mode.
+	WithStdinMode(mode StdinMode) Config
 }
 
+// StdinMode is how reading stdin behaves when no data is available.
+type StdinMode = internalconfig.StdinMode
+
+const (
+	// StdinModeDefault reads stdin as is, so whether it blocks depends on the
+	// underlying reader.
+	StdinModeDefault = internalconfig.StdinModeDefault
+	// StdinModeBlocking waits until data is available, even if stdin is in
+	// the non-blocking mode.
+	StdinModeBlocking = internalconfig.StdinModeBlocking
+	// StdinModeNonblocking fails with EAGAIN instead of waiting.
+	StdinModeNonblocking = internalconfig.StdinModeNonblocking
+	// StdinModeEOF reads nothing instead of waiting, which the program sees
+	// as the end of the file.
+	StdinModeEOF = internalconfig.StdinModeEOF
+)
+
 // NewConfig returns a Config that can be used for configuring module instantiation.
 func NewConfig(moduleConfig wazero.ModuleConfig) Config {
 	return &cfg{moduleConfig: moduleConfig, internal: internalconfig.NewConfig()}
@@ -158,6 +200,26 @@ func (c *cfg) WithOSWorkdir() Config {
 	return ret
 }
 
+// WithTTY implements Config.WithTTY
+func (c *cfg) WithTTY(fd int32) Config {
+	ret := c.clone()
+	// Copy the map, as it is shared with c after clone.
+	ttys := make(map[int32]struct{}, len(c.internal.TTYs)+1)
+	for k := range c.internal.TTYs {
+		ttys[k] = struct{}{}
+	}
+	ttys[fd] = struct{}{}
+	ret.internal.TTYs = ttys
+	return ret
+}
+
+// WithStdinMode implements Config.WithStdinMode
+func (c *cfg) WithStdinMode(mode StdinMode) Config {
+	ret := c.clone()
+	ret.internal.StdinMode = mode
+	return ret
+}
+
 // Run instantiates a new module and calls "run" with the given config.
 //
 // # Parameters
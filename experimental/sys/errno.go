@@ -38,6 +38,7 @@ const (
 	ENOTEMPTY
 	ENOTSOCK
 	ENOTSUP
+	EOVERFLOW
 	EPERM
 	EROFS
 
@@ -85,6 +86,8 @@ func (e Errno) Error() string {
 		return "not a socket"
 	case ENOTSUP:
 		return "not supported (may be the same value as [EOPNOTSUPP])"
+	case EOVERFLOW:
+		return "value too large to be stored in data type"
 	case EPERM:
 		return "operation not permitted"
 	case EROFS:
@@ -247,6 +247,8 @@ type File interface {
 	//
 	//   - This is like syscall.Ftruncate and `ftruncate` in POSIX. See
 	//     https://pubs.opengroup.org/onlinepubs/9699919799/functions/ftruncate.html
+	//   - When the file grows, the extended part must read back as zeros, even
+	//     if it held data before the file was shrunk.
 	//   - Windows does not error when calling Truncate on a closed file.
 	Truncate(size int64) Errno
 
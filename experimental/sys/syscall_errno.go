@@ -46,6 +46,8 @@ func syscallToErrno(err error) (Errno, bool) {
 		return ENOTSOCK, true
 	case syscall.ENOTSUP:
 		return ENOTSUP, true
+	case syscall.EOVERFLOW:
+		return EOVERFLOW, true
 	case syscall.EPERM:
 		return EPERM, true
 	case syscall.EROFS:
@@ -94,6 +96,8 @@ func (e Errno) Unwrap() error {
 		return syscall.ENOTSOCK
 	case ENOTSUP:
 		return syscall.ENOTSUP
+	case EOVERFLOW:
+		return syscall.EOVERFLOW
 	case EPERM:
 		return syscall.EPERM
 	case EROFS:
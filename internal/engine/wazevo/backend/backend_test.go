@@ -77,6 +77,25 @@ L1 (SSA Block: blk0):
 	orr w0, wzr, #0x1
 	ldr x30, [sp], #0x10
 	ret
+`,
+		},
+		{
+			name: "i64_consts", m: testcases.I64Constants.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	movn x2, #0x0, LSL 0
+	movn x1, #0x8000, LSL 48
+	movz x0, #0x8000, LSL 48
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	movn x2, #0x0, LSL 0
+	movn x1, #0x8000, LSL 48
+	movz x0, #0x8000, LSL 48
+	ldr x30, [sp], #0x10
+	ret
 `,
 		},
 		{
@@ -162,6 +181,37 @@ L1 (SSA Block: blk0):
 	mov x0, x2
 	ldr x30, [sp], #0x10
 	ret
+`,
+		},
+		{
+			// Neither the jump nor the return is emitted after the return right before the end of the function.
+			name: "tail_returns", m: testcases.TailReturns.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	mov x3?, x3
+	cbnz w2?, L2
+L3 (SSA Block: blk2):
+L4 (SSA Block: blk3):
+	mov x0, x3?
+	ret
+L2 (SSA Block: blk1):
+	mov x0, x2?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	cbnz w2, #0x10 (L2)
+L3 (SSA Block: blk2):
+L4 (SSA Block: blk3):
+	mov x0, x3
+	ldr x30, [sp], #0x10
+	ret
+L2 (SSA Block: blk1):
+	mov x0, x2
+	ldr x30, [sp], #0x10
+	ret
 `,
 		},
 		{
@@ -229,7 +279,7 @@ L1 (SSA Block: blk0):
 L3 (SSA Block: blk1):
 	ret
 L2 (SSA Block: blk2):
-	movz x27, #0x2, LSL 0
+	movz x27, #0x702, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
 `,
@@ -242,7 +292,7 @@ L3 (SSA Block: blk1):
 	ldr x30, [sp], #0x10
 	ret
 L2 (SSA Block: blk2):
-	movz x27, #0x2, LSL 0
+	movz x27, #0x702, LSL 0
 	str w27, [x0]
 	exit_sequence w0
 `,
@@ -357,6 +407,37 @@ L2 (SSA Block: blk1):
 L4 (SSA Block: blk3):
 	ldr x30, [sp], #0x10
 	ret
+`,
+		},
+		{
+			name: "if_without_else_params", m: testcases.IfWithoutElseParams.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	cbnz w2?, L2
+L3 (SSA Block: blk2):
+	mov x5?, x2?
+	b L4
+L2 (SSA Block: blk1):
+	add w4?, w2?, #0x1
+	mov x5?, x4?
+L4 (SSA Block: blk3):
+	mov x0, x5?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	cbnz w2, #0x8 (L2)
+L3 (SSA Block: blk2):
+	b #0xc (L4)
+L2 (SSA Block: blk1):
+	add w8, w2, #0x1
+	mov x2, x8
+L4 (SSA Block: blk3):
+	mov x0, x2
+	ldr x30, [sp], #0x10
+	ret
 `,
 		},
 		{
@@ -1397,6 +1478,52 @@ L1 (SSA Block: blk0):
 	sxth w6, w8
 	ldr x30, [sp], #0x10
 	ret
+`,
+		},
+		{
+			name: "integer_extension_chains",
+			m:    testcases.IntegerExtensionChains.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	mov x3?, x3
+	sxtb w4?, w2?
+	sxtb w5?, w2?
+	sxtb x6?, w2?
+	sxth w7?, w2?
+	uxtw x8?, w7?
+	sxtw x9?, w2?
+	uxtw x10?, w2?
+	sxtw x11?, w10?
+	sxtb x12?, w2?
+	sxtb x13?, w3?
+	mov x7, x13?
+	mov x6, x12?
+	mov x5, x11?
+	mov x4, x9?
+	mov x3, x8?
+	mov x2, x6?
+	mov x1, x5?
+	mov x0, x4?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	mov x9, x2
+	mov x8, x3
+	sxtb w0, w9
+	sxtb w1, w9
+	sxtb x2, w9
+	sxth w10, w9
+	uxtw x3, w10
+	sxtw x4, w9
+	uxtw x10, w9
+	sxtw x5, w10
+	sxtb x6, w9
+	sxtb x7, w8
+	ldr x30, [sp], #0x10
+	ret
 `,
 		},
 		{
@@ -1420,17 +1547,17 @@ L1 (SSA Block: blk0):
 	cset x10?, ls
 	fcmp s2?, s3?
 	cset x11?, ge
-	fcmp s4?, s5?
+	fcmp d4?, d5?
 	cset x12?, eq
-	fcmp s4?, s5?
+	fcmp d4?, d5?
 	cset x13?, ne
-	fcmp s4?, s5?
+	fcmp d4?, d5?
 	cset x14?, mi
-	fcmp s4?, s5?
+	fcmp d4?, d5?
 	cset x15?, gt
-	fcmp s4?, s5?
+	fcmp d4?, d5?
 	cset x16?, ls
-	fcmp s4?, s5?
+	fcmp d4?, d5?
 	cset x17?, ge
 	str w17?, [#ret_space, #0x18]
 	str w16?, [#ret_space, #0x10]
@@ -1461,17 +1588,17 @@ L1 (SSA Block: blk0):
 	cset x4, ls
 	fcmp s0, s1
 	cset x5, ge
-	fcmp s2, s3
+	fcmp d2, d3
 	cset x6, eq
-	fcmp s2, s3
+	fcmp d2, d3
 	cset x7, ne
-	fcmp s2, s3
+	fcmp d2, d3
 	cset x11, mi
-	fcmp s2, s3
+	fcmp d2, d3
 	cset x10, gt
-	fcmp s2, s3
+	fcmp d2, d3
 	cset x9, ls
-	fcmp s2, s3
+	fcmp d2, d3
 	cset x8, ge
 	str w8, [sp, #0x28]
 	str w9, [sp, #0x20]
@@ -1878,624 +2005,1859 @@ L1 (SSA Block: blk0):
 `,
 		},
 		{
-			name: "memory_load_basic", m: testcases.MemoryLoadBasic.Module,
+			name: "imported_global", m: testcases.ImportedGlobal.Module,
 			afterLoweringARM64: `
 L1 (SSA Block: blk0):
 	mov x0?, x0
 	mov x1?, x1
 	mov x2?, x2
-	uxtw x4?, w2?
-	ldr w5?, [x1?, #0x8]
-	add x6?, x4?, #0x4
-	subs xzr, x5?, x6?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x0?]
-	exit_sequence w0?
+	ldr x3?, [x1?, #0x10]
+	ldr w4?, [x3?, #0x8]
+	ldr w5?, [x3?, #0x8]
+	add w6?, w5?, w2?
+	str w6?, [x3?, #0x8]
+	ldr w7?, [x3?, #0x8]
+	str x1?, [x0?, #0x8]
 	ldr x8?, [x1?]
-	add x11?, x8?, x4?
-	ldr w10?, [x11?]
-	mov x0, x10?
+	ldr x9?, [x1?, #0x8]
+	mov x0, x0?
+	mov x1, x9?
+	bl w8?
+	mov x10?, x0
+	ldr x11?, [x1?, #0x18]
+	ldr x12?, [x11?, #0x8]
+	add x14?, x12?, #0x1
+	str x14?, [x11?, #0x8]
+	ldr x15?, [x11?, #0x8]
+	mov x3, x15?
+	mov x2, x10?
+	mov x1, x7?
+	mov x0, x4?
 	ret
 `,
 			afterFinalizeARM64: `
 L1 (SSA Block: blk0):
 	str x30, [sp, #-0x10]!
-	uxtw x8, w2
-	ldr w10, [x1, #0x8]
-	add x9, x8, #0x4
-	subs xzr, x10, x9
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x0]
-	exit_sequence w0
-	ldr x9, [x1]
-	add x8, x9, x8
-	ldr w0, [x8]
+	sub sp, sp, #0x10
+	mov x10, x1
+	ldr x9, [x10, #0x10]
+	ldr w8, [x9, #0x8]
+	ldr w11, [x9, #0x8]
+	add w11, w11, w2
+	str w11, [x9, #0x8]
+	ldr w9, [x9, #0x8]
+	str x10, [x0, #0x8]
+	ldr x11, [x10]
+	ldr x1, [x10, #0x8]
+	str x10, [sp]
+	str w8, [sp, #0x8]
+	str w9, [sp, #0xc]
+	bl w11
+	ldr w9, [sp, #0xc]
+	ldr w8, [sp, #0x8]
+	ldr x10, [sp]
+	ldr x10, [x10, #0x18]
+	ldr x11, [x10, #0x8]
+	add x11, x11, #0x1
+	str x11, [x10, #0x8]
+	ldr x3, [x10, #0x8]
+	mov x2, x0
+	mov x1, x9
+	mov x0, x8
+	add sp, sp, #0x10
 	ldr x30, [sp], #0x10
 	ret
 `,
 		},
 		{
-			name: "memory_loads", m: testcases.MemoryLoads.Module,
+			name: "memory_stores", m: testcases.MemoryStores.Module,
 			afterLoweringARM64: `
 L1 (SSA Block: blk0):
 	mov x0?, x0
 	mov x1?, x1
 	mov x2?, x2
-	uxtw x4?, w2?
-	ldr w5?, [x1?, #0x8]
-	add x6?, x4?, #0x4
-	subs xzr, x5?, x6?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	mov x3?, x3
+	mov x4?, x4
+	mov q5?.8b, q0.8b
+	mov q6?.8b, q1.8b
+	uxtw x8?, w2?
+	ldr w9?, [x1?, #0x8]
+	add x10?, x8?, #0x4
+	subs xzr, x9?, x10?
+	b.hs #0x24
+	str x10?, [x0?, #0x450]
+	movz x27, #0x403, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	ldr x8?, [x1?]
-	add x200?, x8?, x4?
-	ldr w10?, [x200?]
-	uxtw x12?, w2?
-	add x13?, x12?, #0x8
-	subs xzr, x5?, x13?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	ldr x12?, [x1?]
+	add x101?, x12?, x8?
+	str w3?, [x101?]
+	uxtw x15?, w2?
+	add x16?, x15?, #0x10
+	subs xzr, x9?, x16?
+	b.hs #0x24
+	str x16?, [x0?, #0x450]
+	movz x27, #0x803, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x199?, x8?, x12?
-	ldr x16?, [x199?]
-	uxtw x18?, w2?
-	add x19?, x18?, #0x4
-	subs xzr, x5?, x19?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	add x100?, x12?, x15?
+	str x4?, [x100?, #0x8]
+	uxtw x20?, w2?
+	add x21?, x20?, #0x14
+	subs xzr, x9?, x21?
+	b.hs #0x24
+	str x21?, [x0?, #0x450]
+	movz x27, #0x403, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x198?, x8?, x18?
-	ldr s22?, [x198?]
-	uxtw x24?, w2?
-	add x25?, x24?, #0x8
-	subs xzr, x5?, x25?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	add x99?, x12?, x20?
+	str s5?, [x99?, #0x10]
+	uxtw x25?, w2?
+	add x26?, x25?, #0x20
+	subs xzr, x9?, x26?
+	b.hs #0x24
+	str x26?, [x0?, #0x450]
+	movz x27, #0x803, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x197?, x8?, x24?
-	ldr d28?, [x197?]
+	add x98?, x12?, x25?
+	str d6?, [x98?, #0x18]
 	uxtw x30?, w2?
-	add x31?, x30?, #0x13
-	subs xzr, x5?, x31?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x0?]
-	exit_sequence w0?
-	add x196?, x8?, x30?
-	ldr w34?, [x196?, #0xf]
-	uxtw x36?, w2?
-	add x37?, x36?, #0x17
-	subs xzr, x5?, x37?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x0?]
-	exit_sequence w0?
-	add x195?, x8?, x36?
-	ldr x40?, [x195?, #0xf]
-	uxtw x42?, w2?
-	add x43?, x42?, #0x13
-	subs xzr, x5?, x43?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x0?]
-	exit_sequence w0?
-	add x194?, x8?, x42?
-	ldr s46?, [x194?, #0xf]
-	uxtw x48?, w2?
-	add x49?, x48?, #0x17
-	subs xzr, x5?, x49?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x0?]
-	exit_sequence w0?
-	add x193?, x8?, x48?
-	ldr d52?, [x193?, #0xf]
-	uxtw x54?, w2?
-	add x55?, x54?, #0x1
-	subs xzr, x5?, x55?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x0?]
-	exit_sequence w0?
-	add x192?, x8?, x54?
-	ldrsb w58?, [x192?]
-	uxtw x60?, w2?
-	add x61?, x60?, #0x10
-	subs xzr, x5?, x61?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x0?]
-	exit_sequence w0?
-	add x191?, x8?, x60?
-	ldrsb w64?, [x191?, #0xf]
-	uxtw x66?, w2?
-	add x67?, x66?, #0x1
-	subs xzr, x5?, x67?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x0?]
-	exit_sequence w0?
-	add x190?, x8?, x66?
-	ldrb w70?, [x190?]
-	uxtw x72?, w2?
-	add x73?, x72?, #0x10
-	subs xzr, x5?, x73?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	add x31?, x30?, #0x21
+	subs xzr, x9?, x31?
+	b.hs #0x24
+	str x31?, [x0?, #0x450]
+	movz x27, #0x103, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x189?, x8?, x72?
-	ldrb w76?, [x189?, #0xf]
-	uxtw x78?, w2?
-	add x79?, x78?, #0x2
-	subs xzr, x5?, x79?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	add x97?, x12?, x30?
+	strb w3?, [x97?, #0x20]
+	uxtw x35?, w2?
+	add x36?, x35?, #0x24
+	subs xzr, x9?, x36?
+	b.hs #0x24
+	str x36?, [x0?, #0x450]
+	movz x27, #0x203, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x188?, x8?, x78?
-	ldrsh w82?, [x188?]
-	uxtw x84?, w2?
-	add x85?, x84?, #0x11
-	subs xzr, x5?, x85?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	add x96?, x12?, x35?
+	strh w3?, [x96?, #0x22]
+	uxtw x40?, w2?
+	add x41?, x40?, #0x29
+	subs xzr, x9?, x41?
+	b.hs #0x24
+	str x41?, [x0?, #0x450]
+	movz x27, #0x103, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x187?, x8?, x84?
-	ldrsh w88?, [x187?, #0xf]
-	uxtw x90?, w2?
-	add x91?, x90?, #0x2
-	subs xzr, x5?, x91?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	add x95?, x12?, x40?
+	strb w4?, [x95?, #0x28]
+	uxtw x45?, w2?
+	add x46?, x45?, #0x2c
+	subs xzr, x9?, x46?
+	b.hs #0x24
+	str x46?, [x0?, #0x450]
+	movz x27, #0x203, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x186?, x8?, x90?
-	ldrh w94?, [x186?]
-	uxtw x96?, w2?
-	add x97?, x96?, #0x11
-	subs xzr, x5?, x97?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	add x94?, x12?, x45?
+	strh w4?, [x94?, #0x2a]
+	uxtw x50?, w2?
+	add x51?, x50?, #0x30
+	subs xzr, x9?, x51?
+	b.hs #0x24
+	str x51?, [x0?, #0x450]
+	movz x27, #0x403, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x185?, x8?, x96?
-	ldrh w100?, [x185?, #0xf]
-	uxtw x102?, w2?
-	add x103?, x102?, #0x1
-	subs xzr, x5?, x103?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	add x93?, x12?, x50?
+	str w4?, [x93?, #0x2c]
+	uxtw x55?, w2?
+	add x56?, x55?, #0x30
+	subs xzr, x9?, x56?
+	b.hs #0x28
+	str x56?, [x0?, #0x450]
+	movz x27, #0x403, LSL 0
+	movk x27, #0x80, LSL 16
 	str w27, [x0?]
 	exit_sequence w0?
-	add x184?, x8?, x102?
-	ldrsb w106?, [x184?]
-	uxtw x108?, w2?
-	add x109?, x108?, #0x10
-	subs xzr, x5?, x109?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	add x92?, x12?, x55?
+	ldr w59?, [x92?]
+	add x91?, x12?, #0x8
+	ldr x62?, [x91?, w2?, UXTW]
+	add x90?, x12?, #0x10
+	ldr s65?, [x90?, w2?, UXTW]
+	add x89?, x12?, #0x18
+	ldr d68?, [x89?, w2?, UXTW]
+	add x88?, x12?, #0x20
+	ldrb w71?, [x88?, w2?, UXTW]
+	add x87?, x12?, #0x22
+	ldrh w74?, [x87?, w2?, UXTW]
+	add x86?, x12?, #0x28
+	ldrb w77?, [x86?, w2?, UXTW]
+	add x85?, x12?, #0x2a
+	ldrh w80?, [x85?, w2?, UXTW]
+	add x84?, x12?, #0x2c
+	ldr w83?, [x84?, w2?, UXTW]
+	mov x6, x83?
+	mov x5, x80?
+	mov x4, x77?
+	mov x3, x74?
+	mov x2, x71?
+	mov q1.8b, q68?.8b
+	mov q0.8b, q65?.8b
+	mov x1, x62?
+	mov x0, x59?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	uxtw x10, w2
+	ldr w9, [x1, #0x8]
+	add x8, x10, #0x4
+	subs xzr, x9, x8
+	b.hs #0x24
+	str x8, [x0, #0x450]
+	movz x27, #0x403, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	ldr x8, [x1]
+	add x10, x8, x10
+	str w3, [x10]
+	uxtw x11, w2
+	add x10, x11, #0x10
+	subs xzr, x9, x10
+	b.hs #0x24
+	str x10, [x0, #0x450]
+	movz x27, #0x803, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	add x10, x8, x11
+	str x4, [x10, #0x8]
+	uxtw x11, w2
+	add x10, x11, #0x14
+	subs xzr, x9, x10
+	b.hs #0x24
+	str x10, [x0, #0x450]
+	movz x27, #0x403, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	add x10, x8, x11
+	str s0, [x10, #0x10]
+	uxtw x11, w2
+	add x10, x11, #0x20
+	subs xzr, x9, x10
+	b.hs #0x24
+	str x10, [x0, #0x450]
+	movz x27, #0x803, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	add x10, x8, x11
+	str d1, [x10, #0x18]
+	uxtw x11, w2
+	add x10, x11, #0x21
+	subs xzr, x9, x10
+	b.hs #0x24
+	str x10, [x0, #0x450]
+	movz x27, #0x103, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	add x10, x8, x11
+	strb w3, [x10, #0x20]
+	uxtw x11, w2
+	add x10, x11, #0x24
+	subs xzr, x9, x10
+	b.hs #0x24
+	str x10, [x0, #0x450]
+	movz x27, #0x203, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	add x10, x8, x11
+	strh w3, [x10, #0x22]
+	uxtw x11, w2
+	add x10, x11, #0x29
+	subs xzr, x9, x10
+	b.hs #0x24
+	str x10, [x0, #0x450]
+	movz x27, #0x103, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	add x10, x8, x11
+	strb w4, [x10, #0x28]
+	uxtw x11, w2
+	add x10, x11, #0x2c
+	subs xzr, x9, x10
+	b.hs #0x24
+	str x10, [x0, #0x450]
+	movz x27, #0x203, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	add x10, x8, x11
+	strh w4, [x10, #0x2a]
+	uxtw x11, w2
+	add x10, x11, #0x30
+	subs xzr, x9, x10
+	b.hs #0x24
+	str x10, [x0, #0x450]
+	movz x27, #0x403, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	add x10, x8, x11
+	str w4, [x10, #0x2c]
+	uxtw x11, w2
+	add x10, x11, #0x30
+	subs xzr, x9, x10
+	b.hs #0x28
+	str x10, [x0, #0x450]
+	movz x27, #0x403, LSL 0
+	movk x27, #0x80, LSL 16
+	str w27, [x0]
+	exit_sequence w0
+	add x9, x8, x11
+	ldr w0, [x9]
+	add x9, x8, #0x8
+	ldr x1, [x9, w2, UXTW]
+	add x9, x8, #0x10
+	ldr s0, [x9, w2, UXTW]
+	add x9, x8, #0x18
+	ldr d1, [x9, w2, UXTW]
+	add x9, x8, #0x20
+	ldrb w9, [x9, w2, UXTW]
+	add x10, x8, #0x22
+	ldrh w3, [x10, w2, UXTW]
+	add x10, x8, #0x28
+	ldrb w4, [x10, w2, UXTW]
+	add x10, x8, #0x2a
+	ldrh w5, [x10, w2, UXTW]
+	add x8, x8, #0x2c
+	ldr w6, [x8, w2, UXTW]
+	mov x2, x9
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "const_address_memory_accesses", m: testcases.ConstAddressMemoryAccesses.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x0?, x0
+	mov x1?, x1
+	mov x2?, x2
+	ldr w4?, [x1?, #0x8]
+	movz x20?, #0x404, LSL 0
+	subs xzr, x4?, #0x404
+	b.hs #0x24
+	str x20?, [x0?, #0x450]
+	movz x27, #0x403, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x183?, x8?, x108?
-	ldrsb w112?, [x183?, #0xf]
-	uxtw x114?, w2?
-	add x115?, x114?, #0x1
-	subs xzr, x5?, x115?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	ldr x6?, [x1?]
+	ldr w7?, [x6?, #0x400]
+	add w8?, w7?, w2?
+	str w8?, [x6?, #0x400]
+	movz x19?, #0x410, LSL 0
+	subs xzr, x4?, #0x410
+	b.hs #0x24
+	str x19?, [x0?, #0x450]
+	movz x27, #0x803, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x182?, x8?, x114?
-	ldrb w118?, [x182?]
-	uxtw x120?, w2?
-	add x121?, x120?, #0x10
-	subs xzr, x5?, x121?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	ldr x11?, [x6?, #0x408]
+	add x13?, x11?, #0x1
+	str x13?, [x6?, #0x408]
+	movz x18?, #0x410, LSL 0
+	subs xzr, x4?, #0x410
+	b.hs #0x28
+	str x18?, [x0?, #0x450]
+	movz x27, #0x803, LSL 0
+	movk x27, #0x80, LSL 16
 	str w27, [x0?]
 	exit_sequence w0?
-	add x181?, x8?, x120?
-	ldrb w124?, [x181?, #0xf]
-	uxtw x126?, w2?
-	add x127?, x126?, #0x2
-	subs xzr, x5?, x127?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	ldr w16?, [x6?, #0x400]
+	ldr x17?, [x6?, #0x408]
+	mov x1, x17?
+	mov x0, x16?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	ldr w8, [x1, #0x8]
+	movz x9, #0x404, LSL 0
+	subs xzr, x8, #0x404
+	b.hs #0x24
+	str x9, [x0, #0x450]
+	movz x27, #0x403, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	ldr x9, [x1]
+	ldr w10, [x9, #0x400]
+	add w10, w10, w2
+	str w10, [x9, #0x400]
+	movz x10, #0x410, LSL 0
+	subs xzr, x8, #0x410
+	b.hs #0x24
+	str x10, [x0, #0x450]
+	movz x27, #0x803, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	ldr x10, [x9, #0x408]
+	add x10, x10, #0x1
+	str x10, [x9, #0x408]
+	movz x10, #0x410, LSL 0
+	subs xzr, x8, #0x410
+	b.hs #0x28
+	str x10, [x0, #0x450]
+	movz x27, #0x803, LSL 0
+	movk x27, #0x80, LSL 16
+	str w27, [x0]
+	exit_sequence w0
+	ldr w0, [x9, #0x400]
+	ldr x1, [x9, #0x408]
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			// The conditional branch carrying the argument is inverted to fall through, and the argument follows it.
+			name: "memory_same_base_loads_br_if", m: testcases.MemorySameBaseLoadsBrIf.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x0?, x0
+	mov x1?, x1
+	mov x2?, x2
+	mov x3?, x3
+	uxtw x5?, w2?
+	ldr w6?, [x1?, #0x8]
+	add x7?, x5?, #0x8
+	subs xzr, x6?, x7?
+	b.hs #0x24
+	str x7?, [x0?, #0x450]
+	movz x27, #0x803, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x180?, x8?, x126?
-	ldrsh w130?, [x180?]
-	uxtw x132?, w2?
-	add x133?, x132?, #0x11
-	subs xzr, x5?, x133?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	ldr x9?, [x1?]
+	add x19?, x9?, x5?
+	ldr x11?, [x19?]
+	cbz w3?, (L2)
+L3 (SSA Block: blk3):
+	mov x12?, x11?
+L4 (SSA Block: blk1):
+	mov x0, x12?
+	ret
+L2 (SSA Block: blk2):
+	uxtw x14?, w2?
+	movz x21?, #0x8, LSL 0
+	movk x21?, #0x1, LSL 16
+	add x15?, x14?, x21?
+	subs xzr, x6?, x15?
+	b.hs #0x24
+	str x15?, [x0?, #0x450]
+	movz x27, #0x803, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x179?, x8?, x132?
-	ldrsh w136?, [x179?, #0xf]
-	uxtw x138?, w2?
-	add x139?, x138?, #0x2
-	subs xzr, x5?, x139?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	add x20?, x9?, #0x10000
+	ldr x18?, [x20?, x14?]
+	mov x12?, x18?
+	b L4
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	uxtw x10, w2
+	ldr w8, [x1, #0x8]
+	add x9, x10, #0x8
+	subs xzr, x8, x9
+	b.hs #0x24
+	str x9, [x0, #0x450]
+	movz x27, #0x803, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	ldr x9, [x1]
+	add x10, x9, x10
+	ldr x10, [x10]
+	cbz w3, #0x10 L2
+L3 (SSA Block: blk3):
+L4 (SSA Block: blk1):
+	mov x0, x10
+	ldr x30, [sp], #0x10
+	ret
+L2 (SSA Block: blk2):
+	uxtw x10, w2
+	movz x11, #0x8, LSL 0
+	movk x11, #0x1, LSL 16
+	add x11, x10, x11
+	subs xzr, x8, x11
+	b.hs #0x24
+	str x11, [x0, #0x450]
+	movz x27, #0x803, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	add x8, x9, #0x10000
+	ldr x8, [x8, x10]
+	mov x10, x8
+	b #-0x50 (L4)
+`,
+		},
+		{
+			name: "integer_division", m: testcases.IntegerDivision.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x0?, x0
+	mov x2?, x2
+	mov x3?, x3
+	mov x4?, x4
+	mov x5?, x5
+	subs wzr, w3?, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x178?, x8?, x138?
-	ldrh w142?, [x178?]
-	uxtw x144?, w2?
-	add x145?, x144?, #0x11
-	subs xzr, x5?, x145?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	movn w34?, #0x0, LSL 0
+	subs wzr, w3?, w34?
+	mov x35?, xzr
+	csel w11?, w2?, w35?, eq
+	movz w33?, #0x8000, LSL 16
+	subs wzr, w11?, w33?
+	b.ne #0x20
+	movz x27, #0x9, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x177?, x8?, x144?
-	ldrh w148?, [x177?, #0xf]
-	uxtw x150?, w2?
-	add x151?, x150?, #0x4
-	subs xzr, x5?, x151?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	sdiv w14?, w2?, w3?
+	subs wzr, w3?, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x176?, x8?, x150?
-	ldrs w154?, [x176?]
-	uxtw x156?, w2?
-	add x157?, x156?, #0x13
-	subs xzr, x5?, x157?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	udiv w17?, w2?, w3?
+	subs xzr, x5?, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x175?, x8?, x156?
-	ldrs w160?, [x175?, #0xf]
-	uxtw x162?, w2?
-	add x163?, x162?, #0x4
-	subs xzr, x5?, x163?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	movn x31?, #0x0, LSL 0
+	subs xzr, x5?, x31?
+	mov x32?, xzr
+	csel x23?, x4?, x32?, eq
+	movz x30?, #0x8000, LSL 48
+	subs xzr, x23?, x30?
+	b.ne #0x20
+	movz x27, #0x9, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x174?, x8?, x162?
-	ldr w166?, [x174?]
-	uxtw x168?, w2?
-	add x169?, x168?, #0x13
-	subs xzr, x5?, x169?
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
+	sdiv x26?, x4?, x5?
+	subs xzr, x5?, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
 	str w27, [x0?]
 	exit_sequence w0?
-	add x173?, x8?, x168?
-	ldr w172?, [x173?, #0xf]
-	str x172?, [#ret_space, #0x78]
-	str x166?, [#ret_space, #0x70]
-	str x160?, [#ret_space, #0x68]
-	str x154?, [#ret_space, #0x60]
-	str x148?, [#ret_space, #0x58]
-	str x142?, [#ret_space, #0x50]
-	str x136?, [#ret_space, #0x48]
-	str x130?, [#ret_space, #0x40]
-	str x124?, [#ret_space, #0x38]
-	str x118?, [#ret_space, #0x30]
-	str x112?, [#ret_space, #0x28]
-	str x106?, [#ret_space, #0x20]
-	str w100?, [#ret_space, #0x18]
-	str w94?, [#ret_space, #0x10]
-	str w88?, [#ret_space, #0x8]
-	str w82?, [#ret_space, #0x0]
-	mov x7, x76?
-	mov x6, x70?
-	mov x5, x64?
-	mov x4, x58?
-	mov q3.8b, q52?.8b
-	mov q2.8b, q46?.8b
-	mov x3, x40?
-	mov x2, x34?
-	mov q1.8b, q28?.8b
-	mov q0.8b, q22?.8b
-	mov x1, x16?
-	mov x0, x10?
+	udiv x29?, x4?, x5?
+	mov x3, x29?
+	mov x2, x26?
+	mov x1, x17?
+	mov x0, x14?
 	ret
 `,
-
 			afterFinalizeARM64: `
 L1 (SSA Block: blk0):
 	str x30, [sp, #-0x10]!
-	str x18, [sp, #-0x10]!
-	str x19, [sp, #-0x10]!
-	str x20, [sp, #-0x10]!
-	str x21, [sp, #-0x10]!
-	str x22, [sp, #-0x10]!
-	str x23, [sp, #-0x10]!
+	subs wzr, w3, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	movn w8, #0x0, LSL 0
+	subs wzr, w3, w8
+	mov x8, xzr
+	csel w9, w2, w8, eq
+	movz w8, #0x8000, LSL 16
+	subs wzr, w9, w8
+	b.ne #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	sdiv w8, w2, w3
+	subs wzr, w3, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	udiv w1, w2, w3
+	subs xzr, x5, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	movn x9, #0x0, LSL 0
+	subs xzr, x5, x9
+	mov x9, xzr
+	csel x10, x4, x9, eq
+	movz x9, #0x8000, LSL 48
+	subs xzr, x10, x9
+	b.ne #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	sdiv x2, x4, x5
+	subs xzr, x5, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	udiv x3, x4, x5
+	mov x0, x8
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "integer_remainder", m: testcases.IntegerRemainder.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x0?, x0
+	mov x2?, x2
+	mov x3?, x3
+	mov x4?, x4
+	mov x5?, x5
+	subs wzr, w3?, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	sdiv w21?, w2?, w3?
+	msub w8?, w21?, w3?, w2?
+	subs wzr, w3?, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	udiv w20?, w2?, w3?
+	msub w11?, w20?, w3?, w2?
+	subs xzr, x5?, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	sdiv x19?, x4?, x5?
+	msub x14?, x19?, x5?, x4?
+	subs xzr, x5?, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	udiv x18?, x4?, x5?
+	msub x17?, x18?, x5?, x4?
+	mov x3, x17?
+	mov x2, x14?
+	mov x1, x11?
+	mov x0, x8?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	mov x8, x0
+	subs wzr, w3, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	sdiv w9, w2, w3
+	msub w0, w9, w3, w2
+	subs wzr, w3, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	udiv w9, w2, w3
+	msub w1, w9, w3, w2
+	subs xzr, x5, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	sdiv x9, x4, x5
+	msub x2, x9, x5, x4
+	subs xzr, x5, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	udiv x8, x4, x5
+	msub x3, x8, x5, x4
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "integer_bitwise", m: testcases.IntegerBitwise.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	mov x3?, x3
+	mov x4?, x4
+	mov x5?, x5
+	and w6?, w2?, w3?
+	orr w7?, w2?, w3?
+	eor w8?, w2?, w3?
+	and x9?, x4?, x5?
+	orr x10?, x4?, x5?
+	eor x11?, x4?, x5?
+	mov x5, x11?
+	mov x4, x10?
+	mov x3, x9?
+	mov x2, x8?
+	mov x1, x7?
+	mov x0, x6?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	and w0, w2, w3
+	orr w1, w2, w3
+	eor w2, w2, w3
+	and x3, x4, x5
+	orr x8, x4, x5
+	eor x5, x4, x5
+	mov x4, x8
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "integer_rotate", m: testcases.IntegerRotate.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	mov x3?, x3
+	mov x4?, x4
+	mov x5?, x5
+	and w15?, w3?, #0x1f
+	lsl w16?, w2?, w15?
+	sub w17?, wzr, w15?
+	and w18?, w17?, #0x1f
+	lsr w19?, w2?, w18?
+	orr w6?, w16?, w19?
+	ror w7?, w2?, w3?
+	and x10?, x5?, #0x3f
+	lsl x11?, x4?, x10?
+	sub x12?, xzr, x10?
+	and x13?, x12?, #0x3f
+	lsr x14?, x4?, x13?
+	orr x8?, x11?, x14?
+	ror x9?, x4?, x5?
+	mov x3, x9?
+	mov x2, x8?
+	mov x1, x7?
+	mov x0, x6?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	and w9, w3, #0x1f
+	lsl w8, w2, w9
+	sub w9, wzr, w9
+	and w9, w9, #0x1f
+	lsr w9, w2, w9
+	orr w0, w8, w9
+	ror w1, w2, w3
+	and x9, x5, #0x3f
+	lsl x8, x4, x9
+	sub x9, xzr, x9
+	and x9, x9, #0x3f
+	lsr x9, x4, x9
+	orr x2, x8, x9
+	ror x3, x4, x5
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "integer_bit_counts", m: testcases.IntegerBitCounts.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	mov x3?, x3
+	clz w4?, w2?
+	rbit w17?, w2?
+	clz w5?, w17?
+	fmov s14?, w2?
+	cnt q15?.8b, q14?.8b
+	addv b16?, q15?.8b
+	umov w6?, q16?.s[0]
+	clz x7?, x3?
+	rbit x13?, x3?
+	clz x8?, x13?
+	fmov d10?, x3?
+	cnt q11?.8b, q10?.8b
+	addv b12?, q11?.8b
+	umov w9?, q12?.s[0]
+	mov x5, x9?
+	mov x4, x8?
+	mov x3, x7?
+	mov x2, x6?
+	mov x1, x5?
+	mov x0, x4?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	mov x8, x3
+	clz w0, w2
+	rbit w9, w2
+	clz w1, w9
+	fmov s8, w2
+	cnt q8.8b, q8.8b
+	addv b8, q8.8b
+	umov w2, q8.s[0]
+	clz x3, x8
+	rbit x9, x8
+	clz x4, x9
+	fmov d8, x8
+	cnt q8.8b, q8.8b
+	addv b8, q8.8b
+	umov w5, q8.s[0]
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "integer_eqz", m: testcases.IntegerEqz.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	mov x3?, x3
+	subs wzr, w2?, #0x0
+	cset x5?, eq
+	subs xzr, x3?, #0x0
+	cset x7?, eq
+	mov x1, x7?
+	mov x0, x5?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	subs wzr, w2, #0x0
+	cset x0, eq
+	subs xzr, x3, #0x0
+	cset x1, eq
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "table_const_indices", m: testcases.TableConstIndices.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x0?, x0
+	mov x1?, x1
+	mov x2?, x2
+	mov x3?, x3
+	ldr x5?, [x1?]
+	mov x40?, xzr
+	uxtw x6?, w40?
+	ldr x7?, [x5?]
+	lsl x9?, x6?, 0x3
+	add x39?, x7?, x9?
+	str x3?, [x39?]
+	ldr x12?, [x1?]
+	orr w38?, wzr, #0x3
+	uxtw x13?, w38?
+	ldr x14?, [x12?]
+	lsl x16?, x13?, 0x3
+	add x37?, x14?, x16?
+	str x3?, [x37?]
+	cbnz w2?, L2
+L3 (SSA Block: blk2):
+	ldr x19?, [x1?]
+	orr w42?, wzr, #0x3
+	uxtw x20?, w42?
+	ldr x21?, [x19?]
+	lsl x23?, x20?, 0x3
+	add x41?, x21?, x23?
+	ldr x25?, [x41?]
+	mov x36?, x25?
+	b L4
+L2 (SSA Block: blk1):
+	ldr x27?, [x1?]
+	ldr x28?, [x27?, #0x8]
+	orr w44?, wzr, #0x4
+	uxtw x29?, w44?
+	subs xzr, x28?, x29?
+	b.hi #0x20
+	movz x27, #0x7, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr x31?, [x27?]
+	lsl x33?, x29?, 0x3
+	add x43?, x31?, x33?
+	ldr x35?, [x43?]
+	mov x36?, x35?
+L4 (SSA Block: blk3):
+	mov x0, x36?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	ldr x8, [x1]
+	mov x9, xzr
+	uxtw x9, w9
+	ldr x8, [x8]
+	lsl x9, x9, 0x3
+	add x8, x8, x9
+	str x3, [x8]
+	ldr x8, [x1]
+	orr w9, wzr, #0x3
+	uxtw x9, w9
+	ldr x8, [x8]
+	lsl x9, x9, 0x3
+	add x8, x8, x9
+	str x3, [x8]
+	cbnz w2, #0x24 (L2)
+L3 (SSA Block: blk2):
+	ldr x8, [x1]
+	orr w9, wzr, #0x3
+	uxtw x9, w9
+	ldr x8, [x8]
+	lsl x9, x9, 0x3
+	add x8, x8, x9
+	ldr x8, [x8]
+	b #0x48 (L4)
+L2 (SSA Block: blk1):
+	ldr x10, [x1]
+	ldr x9, [x10, #0x8]
+	orr w8, wzr, #0x4
+	uxtw x8, w8
+	subs xzr, x9, x8
+	b.hi #0x20
+	movz x27, #0x7, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	ldr x9, [x10]
+	lsl x8, x8, 0x3
+	add x8, x9, x8
+	ldr x8, [x8]
+L4 (SSA Block: blk3):
+	mov x0, x8
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "i32_wrap_i64", m: testcases.I32WrapI64.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	uxtw x3?, w2?
+	add x4?, x2?, x2?
+	uxtw x5?, w4?
+	mov x1, x5?
+	mov x0, x3?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	uxtw x0, w2
+	add x8, x2, x2
+	uxtw x1, w8
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "float_unary_ops", m: testcases.FloatUnaryOps.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov q2?.8b, q0.8b
+	mov q3?.8b, q1.8b
+	fneg s4?, s2?
+	fabs s5?, s2?
+	fsqrt s6?, s2?
+	fneg d7?, d3?
+	fabs d8?, d3?
+	fsqrt d9?, d3?
+	mov q5.8b, q9?.8b
+	mov q4.8b, q8?.8b
+	mov q3.8b, q7?.8b
+	mov q2.8b, q6?.8b
+	mov q1.8b, q5?.8b
+	mov q0.8b, q4?.8b
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	mov q9.8b, q0.8b
+	mov q8.8b, q1.8b
+	fneg s0, s9
+	fabs s1, s9
+	fsqrt s2, s9
+	fneg d3, d8
+	fabs d4, d8
+	fsqrt d5, d8
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "float_rounding", m: testcases.FloatRounding.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov q2?.8b, q0.8b
+	mov q3?.8b, q1.8b
+	frintp s4?, s2?
+	frintm s5?, s2?
+	frintz s6?, s2?
+	frintn s7?, s2?
+	frintp d8?, d3?
+	frintm d9?, d3?
+	frintz d10?, d3?
+	frintn d11?, d3?
+	mov q7.8b, q11?.8b
+	mov q6.8b, q10?.8b
+	mov q5.8b, q9?.8b
+	mov q4.8b, q8?.8b
+	mov q3.8b, q7?.8b
+	mov q2.8b, q6?.8b
+	mov q1.8b, q5?.8b
+	mov q0.8b, q4?.8b
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	mov q9.8b, q0.8b
+	mov q8.8b, q1.8b
+	frintp s0, s9
+	frintm s1, s9
+	frintz s2, s9
+	frintn s3, s9
+	frintp d4, d8
+	frintm d5, d8
+	frintz d6, d8
+	frintn d7, d8
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "float_copysign", m: testcases.FloatCopysign.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov q2?.8b, q0.8b
+	mov q3?.8b, q1.8b
+	mov q4?.8b, q2.8b
+	mov q5?.8b, q3.8b
+	umov w13?, q2?.s[0]
+	umov w14?, q3?.s[0]
+	and w15?, w13?, #0x7fffffff
+	and w16?, w14?, #0x80000000
+	orr w17?, w15?, w16?
+	fmov s6?, w17?
+	umov x8?, q4?.d[0]
+	umov x9?, q5?.d[0]
+	and x10?, x8?, #0x7fffffffffffffff
+	and x11?, x9?, #0x8000000000000000
+	orr x12?, x10?, x11?
+	fmov d7?, x12?
+	mov q1.8b, q7?.8b
+	mov q0.8b, q6?.8b
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	umov w8, q0.s[0]
+	umov w9, q1.s[0]
+	and w8, w8, #0x7fffffff
+	and w9, w9, #0x80000000
+	orr w8, w8, w9
+	fmov s0, w8
+	umov x8, q2.d[0]
+	umov x9, q3.d[0]
+	and x8, x8, #0x7fffffffffffffff
+	and x9, x9, #0x8000000000000000
+	orr x8, x8, x9
+	fmov d1, x8
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "block_params_br", m: testcases.BlockParamsBr.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	mov x3?, x3
+	sub w5?, w2?, w3?
+	cbz w2?, (L2)
+L3 (SSA Block: blk3):
+	mov x6?, x5?
+L4 (SSA Block: blk1):
+	movz w10?, #0xa, LSL 0
+	add w7?, w10?, w6?
+	mov x0, x7?
+	ret
+L2 (SSA Block: blk2):
+	add w9?, w5?, #0x32
+	mov x6?, x9?
+	b L4
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	sub w8, w2, w3
+	cbz w2, #0x18 L2
+L3 (SSA Block: blk3):
+	mov x9, x8
+L4 (SSA Block: blk1):
+	movz w8, #0xa, LSL 0
+	add w0, w8, w9
+	ldr x30, [sp], #0x10
+	ret
+L2 (SSA Block: blk2):
+	add w9, w8, #0x32
+	b #-0x14 (L4)
+`,
+		},
+		{
+			name: "float_promote_demote", m: testcases.FloatPromoteDemote.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov q2?.8b, q0.8b
+	mov q3?.8b, q1.8b
+	fcvt d4?, s2?
+	fcvt s5?, d3?
+	fcvt d6?, s2?
+	fcvt s7?, d6?
+	mov q2.8b, q7?.8b
+	mov q1.8b, q5?.8b
+	mov q0.8b, q4?.8b
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	mov q8.8b, q0.8b
+	fcvt d0, s8
+	fcvt s1, d1
+	fcvt d8, s8
+	fcvt s2, d8
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "float_to_i32_s", m: testcases.FloatToI32S.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x0?, x0
+	mov q2?.8b, q0.8b
+	mov q3?.8b, q1.8b
+	fcmp s2?, s2?
+	b.eq #0x20
+	movz x27, #0xa, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr s19?, #8; b 8; data.f32 -2147483648.000000
+	fcmp s2?, s19?
+	b.ge #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr s18?, #8; b 8; data.f32 2147483648.000000
+	fcmp s2?, s18?
+	b.mi #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	fcvtzs w9?, s2?
+	fcmp d3?, d3?
+	b.eq #0x20
+	movz x27, #0xa, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr d17?, #8; b 16; data.f64 -2147483649.000000
+	fcmp d3?, d17?
+	b.gt #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr d16?, #8; b 16; data.f64 2147483648.000000
+	fcmp d3?, d16?
+	b.mi #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	fcvtzs w15?, d3?
+	mov x1, x15?
+	mov x0, x9?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	mov x8, x0
+	fcmp s0, s0
+	b.eq #0x20
+	movz x27, #0xa, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	ldr s8, #8; b 8; data.f32 -2147483648.000000
+	fcmp s0, s8
+	b.ge #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	ldr s8, #8; b 8; data.f32 2147483648.000000
+	fcmp s0, s8
+	b.mi #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	fcvtzs w0, s0
+	fcmp d1, d1
+	b.eq #0x20
+	movz x27, #0xa, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	ldr d8, #8; b 16; data.f64 -2147483649.000000
+	fcmp d1, d8
+	b.gt #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	ldr d8, #8; b 16; data.f64 2147483648.000000
+	fcmp d1, d8
+	b.mi #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	fcvtzs w1, d1
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "float_to_i64_u", m: testcases.FloatToI64U.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x0?, x0
+	mov q2?.8b, q0.8b
+	mov q3?.8b, q1.8b
+	fcmp s2?, s2?
+	b.eq #0x20
+	movz x27, #0xa, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr s19?, #8; b 8; data.f32 -1.000000
+	fcmp s2?, s19?
+	b.gt #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr s18?, #8; b 8; data.f32 18446744073709551616.000000
+	fcmp s2?, s18?
+	b.mi #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	fcvtzu x9?, s2?
+	fcmp d3?, d3?
+	b.eq #0x20
+	movz x27, #0xa, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr d17?, #8; b 16; data.f64 -1.000000
+	fcmp d3?, d17?
+	b.gt #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr d16?, #8; b 16; data.f64 18446744073709551616.000000
+	fcmp d3?, d16?
+	b.mi #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	fcvtzu x15?, d3?
+	mov x1, x15?
+	mov x0, x9?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	mov x8, x0
+	fcmp s0, s0
+	b.eq #0x20
+	movz x27, #0xa, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	ldr s8, #8; b 8; data.f32 -1.000000
+	fcmp s0, s8
+	b.gt #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	ldr s8, #8; b 8; data.f32 18446744073709551616.000000
+	fcmp s0, s8
+	b.mi #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	fcvtzu x0, s0
+	fcmp d1, d1
+	b.eq #0x20
+	movz x27, #0xa, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	ldr d8, #8; b 16; data.f64 -1.000000
+	fcmp d1, d8
+	b.gt #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	ldr d8, #8; b 16; data.f64 18446744073709551616.000000
+	fcmp d1, d8
+	b.mi #0x20
+	movz x27, #0x9, LSL 0
+	str w27, [x8]
+	exit_sequence w8
+	fcvtzu x1, d1
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "float_to_int_sat", m: testcases.FloatToIntSat.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov q2?.8b, q0.8b
+	mov q3?.8b, q1.8b
+	fcvtzs w4?, s2?
+	fcvtzu w5?, s2?
+	fcvtzs w6?, d3?
+	fcvtzu w7?, d3?
+	fcvtzs x8?, s2?
+	fcvtzu x9?, s2?
+	fcvtzs x10?, d3?
+	fcvtzu x11?, d3?
+	mov x7, x11?
+	mov x6, x10?
+	mov x5, x9?
+	mov x4, x8?
+	mov x3, x7?
+	mov x2, x6?
+	mov x1, x5?
+	mov x0, x4?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	fcvtzs w0, s0
+	fcvtzu w1, s0
+	fcvtzs w2, d1
+	fcvtzu w3, d1
+	fcvtzs x4, s0
+	fcvtzu x5, s0
+	fcvtzs x6, d1
+	fcvtzu x7, d1
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "if_then_empty", m: testcases.IfThenEmpty.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	mov x3?, x3
+	cbnz w2?, L2
+L3 (SSA Block: blk2):
+	add w5?, w3?, #0x1
+	mov x6?, x5?
+	b L4
+L2 (SSA Block: blk1):
+	mov x6?, x3?
+L4 (SSA Block: blk3):
+	cbnz w2?, L5
+L6 (SSA Block: blk5):
+	add w8?, w6?, #0x32
+	mov x9?, x8?
+	b L7
+L5 (SSA Block: blk4):
+	mov x9?, x6?
+L7 (SSA Block: blk6):
+	mov x0, x9?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	cbnz w2, #0xc (L2)
+L3 (SSA Block: blk2):
+	add w8, w3, #0x1
+	b #0x8 (L4)
+L2 (SSA Block: blk1):
+	mov x8, x3
+L4 (SSA Block: blk3):
+	cbnz w2, #0xc (L5)
+L6 (SSA Block: blk5):
+	add w8, w8, #0x32
+	b #0x4 (L7)
+L5 (SSA Block: blk4):
+L7 (SSA Block: blk6):
+	mov x0, x8
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "int_to_float", m: testcases.IntToFloat.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	mov x3?, x3
+	scvtf s4?, w2?
+	ucvtf s5?, w2?
+	scvtf s6?, x3?
+	ucvtf s7?, x3?
+	scvtf d8?, w2?
+	ucvtf d9?, w2?
+	scvtf d10?, x3?
+	ucvtf d11?, x3?
+	mov q7.8b, q11?.8b
+	mov q6.8b, q10?.8b
+	mov q5.8b, q9?.8b
+	mov q4.8b, q8?.8b
+	mov q3.8b, q7?.8b
+	mov q2.8b, q6?.8b
+	mov q1.8b, q5?.8b
+	mov q0.8b, q4?.8b
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	scvtf s0, w2
+	ucvtf s1, w2
+	scvtf s2, x3
+	ucvtf s3, x3
+	scvtf d4, w2
+	ucvtf d5, w2
+	scvtf d6, x3
+	ucvtf d7, x3
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "nested_loops_br_to_block", m: testcases.NestedLoopsBrToBlock.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x0?, x0
+	mov x2?, x2
+	mov x3?, x2?
+L2 (SSA Block: blk1):
+	mov x4?, x3?
+L3 (SSA Block: blk4):
+	mov x5?, x4?
+L4 (SSA Block: blk6):
+	add w7?, w5?, #0x1
+	orr w22?, wzr, #0x3
+	subs wzr, w22?, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	orr w20?, wzr, #0x3
+	udiv w21?, w7?, w20?
+	msub w11?, w21?, w20?, w7?
+	cbz w11?, (L5)
+L6 (SSA Block: blk11):
+	mov x5?, x7?
+	b L4
+L5 (SSA Block: blk8):
+	orr w25?, wzr, #0x7
+	subs wzr, w25?, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	orr w23?, wzr, #0x7
+	udiv w24?, w7?, w23?
+	msub w15?, w24?, w23?, w7?
+	cbz w15?, (L7)
+L8 (SSA Block: blk12):
+	mov x4?, x7?
+	b L3
+L7 (SSA Block: blk9):
+	movz w26?, #0xa, LSL 0
+	madd w17?, w7?, w26?, wzr
+L9 (SSA Block: blk3):
+	subs wzr, w7?, #0x1e
+	b.hs L10
+L11 (SSA Block: blk13):
+	mov x3?, x7?
+	b L2
+L10 (SSA Block: blk10):
+L12 (SSA Block: blk2):
+	mov x1, x17?
+	mov x0, x7?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+L2 (SSA Block: blk1):
+	mov x8, x2
+L3 (SSA Block: blk4):
+L4 (SSA Block: blk6):
+	add w8, w8, #0x1
+	orr w9, wzr, #0x3
+	subs wzr, w9, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	orr w10, wzr, #0x3
+	udiv w9, w8, w10
+	msub w9, w9, w10, w8
+	cbz w9, #0x8 L5
+L6 (SSA Block: blk11):
+	b #-0x3c (L4)
+L5 (SSA Block: blk8):
+	orr w9, wzr, #0x7
+	subs wzr, w9, #0x0
+	b.ne #0x20
+	movz x27, #0x8, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	orr w10, wzr, #0x7
+	udiv w9, w8, w10
+	msub w9, w9, w10, w8
+	cbz w9, #0x8 L7
+L8 (SSA Block: blk12):
+	b #-0x78 (L3)
+L7 (SSA Block: blk9):
+	movz w9, #0xa, LSL 0
+	madd w1, w8, w9, wzr
+L9 (SSA Block: blk3):
+	subs wzr, w8, #0x1e
+	b.hs #0xc, (L10)
+L11 (SSA Block: blk13):
+	mov x2, x8
+	b #-0x94 (L2)
+L10 (SSA Block: blk10):
+L12 (SSA Block: blk2):
+	mov x0, x8
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "globals_mutated_by_call", m: testcases.GlobalsMutatedByCall.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x0?, x0
+	mov x1?, x1
+	mov x2?, x2
+	ldr x3?, [x1?]
+	ldr x4?, [x3?, #0x8]
+	str x1?, [x0?, #0x8]
+	mov x0, x0?
+	mov x1, x1?
+	mov x2, x2?
+	bl f1
+	ldr x5?, [x3?, #0x8]
+	ldr x6?, [x1?, #0x8]
+	ldr d7?, [x6?, #0x8]
+	mov q0.8b, q7?.8b
+	mov x1, x5?
+	mov x0, x4?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	sub sp, sp, #0x20
+	mov x9, x1
+	ldr x10, [x9]
+	ldr x8, [x10, #0x8]
+	str x9, [x0, #0x8]
+	mov x1, x9
+	str x9, [sp]
+	str x10, [sp, #0x8]
+	str x8, [sp, #0x10]
+	bl f1
+	ldr x8, [sp, #0x10]
+	ldr x10, [sp, #0x8]
+	ldr x9, [sp]
+	ldr x1, [x10, #0x8]
+	ldr x9, [x9, #0x8]
+	ldr d0, [x9, #0x8]
+	mov x0, x8
+	add sp, sp, #0x20
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "float_const_bit_patterns", m: testcases.FloatConstBitPatterns.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov q2?.8b, q0.8b
+	ldr d11?, #8; b 16; data.f64 1.500000
+	fmul d8?, d2?, d11?
+	ldr d10?, #8; b 16; data.f64 1.500000
+	fadd d9?, d8?, d10?
+	mov q5.8b, q9?.8b
+	ldr d4, #8; b 16; data.f64 -0.000000
+	ldr d3, #8; b 16; data.f64 0.000000
+	ldr s2, #8; b 8; data.f32 NaN
+	ldr s1, #8; b 8; data.f32 NaN
+	ldr s0, #8; b 8; data.f32 NaN
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	ldr d8, #8; b 16; data.f64 1.500000
+	fmul d9, d0, d8
+	ldr d8, #8; b 16; data.f64 1.500000
+	fadd d5, d9, d8
+	ldr d4, #8; b 16; data.f64 -0.000000
+	ldr d3, #8; b 16; data.f64 0.000000
+	ldr s2, #8; b 8; data.f32 NaN
+	ldr s1, #8; b 8; data.f32 NaN
+	ldr s0, #8; b 8; data.f32 NaN
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "memory_load_basic", m: testcases.MemoryLoadBasic.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x0?, x0
+	mov x1?, x1
+	mov x2?, x2
+	uxtw x4?, w2?
+	ldr w5?, [x1?, #0x8]
+	add x6?, x4?, #0x4
+	subs xzr, x5?, x6?
+	b.hs #0x24
+	str x6?, [x0?, #0x450]
+	movz x27, #0x403, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr x8?, [x1?]
+	add x11?, x8?, x4?
+	ldr w10?, [x11?]
+	mov x0, x10?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	uxtw x8, w2
+	ldr w10, [x1, #0x8]
+	add x9, x8, #0x4
+	subs xzr, x10, x9
+	b.hs #0x24
+	str x9, [x0, #0x450]
+	movz x27, #0x403, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	ldr x9, [x1]
+	add x8, x9, x8
+	ldr w0, [x8]
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "memory_loads", m: testcases.MemoryLoads.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x0?, x0
+	mov x1?, x1
+	mov x2?, x2
+	uxtw x4?, w2?
+	ldr w5?, [x1?, #0x8]
+	add x6?, x4?, #0x17
+	subs xzr, x5?, x6?
+	b.hs #0x28
+	str x6?, [x0?, #0x450]
+	movz x27, #0x803, LSL 0
+	movk x27, #0x80, LSL 16
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr x8?, [x1?]
+	add x106?, x8?, x4?
+	ldr w10?, [x106?]
+	ldr x13?, [x8?, w2?, UXTW]
+	ldr s16?, [x8?, w2?, UXTW]
+	ldr d19?, [x8?, w2?, UXTW]
+	add x105?, x8?, #0xf
+	ldr w22?, [x105?, w2?, UXTW]
+	add x104?, x8?, #0xf
+	ldr x25?, [x104?, w2?, UXTW]
+	add x103?, x8?, #0xf
+	ldr s28?, [x103?, w2?, UXTW]
+	add x102?, x8?, #0xf
+	ldr d31?, [x102?, w2?, UXTW]
+	ldrsb w34?, [x8?, w2?, UXTW]
+	add x101?, x8?, #0xf
+	ldrsb w37?, [x101?, w2?, UXTW]
+	ldrb w40?, [x8?, w2?, UXTW]
+	add x100?, x8?, #0xf
+	ldrb w43?, [x100?, w2?, UXTW]
+	ldrsh w46?, [x8?, w2?, UXTW]
+	add x99?, x8?, #0xf
+	ldrsh w49?, [x99?, w2?, UXTW]
+	ldrh w52?, [x8?, w2?, UXTW]
+	add x98?, x8?, #0xf
+	ldrh w55?, [x98?, w2?, UXTW]
+	ldrsb w58?, [x8?, w2?, UXTW]
+	add x97?, x8?, #0xf
+	ldrsb w61?, [x97?, w2?, UXTW]
+	ldrb w64?, [x8?, w2?, UXTW]
+	add x96?, x8?, #0xf
+	ldrb w67?, [x96?, w2?, UXTW]
+	ldrsh w70?, [x8?, w2?, UXTW]
+	add x95?, x8?, #0xf
+	ldrsh w73?, [x95?, w2?, UXTW]
+	ldrh w76?, [x8?, w2?, UXTW]
+	add x94?, x8?, #0xf
+	ldrh w79?, [x94?, w2?, UXTW]
+	ldrs w82?, [x8?, w2?, UXTW]
+	add x93?, x8?, #0xf
+	ldrs w85?, [x93?, w2?, UXTW]
+	ldr w88?, [x8?, w2?, UXTW]
+	add x92?, x8?, #0xf
+	ldr w91?, [x92?, w2?, UXTW]
+	str x91?, [#ret_space, #0x78]
+	str x88?, [#ret_space, #0x70]
+	str x85?, [#ret_space, #0x68]
+	str x82?, [#ret_space, #0x60]
+	str x79?, [#ret_space, #0x58]
+	str x76?, [#ret_space, #0x50]
+	str x73?, [#ret_space, #0x48]
+	str x70?, [#ret_space, #0x40]
+	str x67?, [#ret_space, #0x38]
+	str x64?, [#ret_space, #0x30]
+	str x61?, [#ret_space, #0x28]
+	str x58?, [#ret_space, #0x20]
+	str w55?, [#ret_space, #0x18]
+	str w52?, [#ret_space, #0x10]
+	str w49?, [#ret_space, #0x8]
+	str w46?, [#ret_space, #0x0]
+	mov x7, x43?
+	mov x6, x40?
+	mov x5, x37?
+	mov x4, x34?
+	mov q3.8b, q31?.8b
+	mov q2.8b, q28?.8b
+	mov x3, x25?
+	mov x2, x22?
+	mov q1.8b, q19?.8b
+	mov q0.8b, q16?.8b
+	mov x1, x13?
+	mov x0, x10?
+	ret
+`,
+
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	str x18, [sp, #-0x10]!
+	str x19, [sp, #-0x10]!
+	str x20, [sp, #-0x10]!
+	str x21, [sp, #-0x10]!
+	str x22, [sp, #-0x10]!
+	str x23, [sp, #-0x10]!
 	str x24, [sp, #-0x10]!
-	str x25, [sp, #-0x10]!
-	str x26, [sp, #-0x10]!
-	str x28, [sp, #-0x10]!
-	mov x8, x0
-	uxtw x11, w2
-	ldr w9, [x1, #0x8]
-	add x10, x11, #0x4
-	subs xzr, x9, x10
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	ldr x10, [x1]
-	add x11, x10, x11
-	ldr w0, [x11]
-	uxtw x12, w2
-	add x11, x12, #0x8
-	subs xzr, x9, x11
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x11, x10, x12
-	ldr x1, [x11]
-	uxtw x12, w2
-	add x11, x12, #0x4
-	subs xzr, x9, x11
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x11, x10, x12
-	ldr s0, [x11]
-	uxtw x12, w2
-	add x11, x12, #0x8
-	subs xzr, x9, x11
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x11, x10, x12
-	ldr d1, [x11]
-	uxtw x12, w2
-	add x11, x12, #0x13
-	subs xzr, x9, x11
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x11, x10, x12
-	ldr w11, [x11, #0xf]
-	uxtw x13, w2
-	add x12, x13, #0x17
-	subs xzr, x9, x12
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x12, x10, x13
-	ldr x3, [x12, #0xf]
-	uxtw x13, w2
-	add x12, x13, #0x13
-	subs xzr, x9, x12
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x12, x10, x13
-	ldr s2, [x12, #0xf]
-	uxtw x13, w2
-	add x12, x13, #0x17
-	subs xzr, x9, x12
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x12, x10, x13
-	ldr d3, [x12, #0xf]
-	uxtw x13, w2
-	add x12, x13, #0x1
-	subs xzr, x9, x12
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x12, x10, x13
-	ldrsb w4, [x12]
-	uxtw x13, w2
-	add x12, x13, #0x10
-	subs xzr, x9, x12
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x12, x10, x13
-	ldrsb w5, [x12, #0xf]
-	uxtw x13, w2
-	add x12, x13, #0x1
-	subs xzr, x9, x12
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x12, x10, x13
-	ldrb w6, [x12]
-	uxtw x13, w2
-	add x12, x13, #0x10
-	subs xzr, x9, x12
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x12, x10, x13
-	ldrb w7, [x12, #0xf]
-	uxtw x13, w2
-	add x12, x13, #0x2
-	subs xzr, x9, x12
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x12, x10, x13
-	ldrsh w12, [x12]
-	uxtw x14, w2
-	add x13, x14, #0x11
-	subs xzr, x9, x13
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x13, x10, x14
-	ldrsh w13, [x13, #0xf]
-	uxtw x15, w2
-	add x14, x15, #0x2
-	subs xzr, x9, x14
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x14, x10, x15
-	ldrh w14, [x14]
-	uxtw x16, w2
-	add x15, x16, #0x11
-	subs xzr, x9, x15
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x15, x10, x16
-	ldrh w15, [x15, #0xf]
-	uxtw x17, w2
-	add x16, x17, #0x1
-	subs xzr, x9, x16
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x16, x10, x17
-	ldrsb w16, [x16]
-	uxtw x18, w2
-	add x17, x18, #0x10
-	subs xzr, x9, x17
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x17, x10, x18
-	ldrsb w17, [x17, #0xf]
-	uxtw x19, w2
-	add x18, x19, #0x1
-	subs xzr, x9, x18
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x18, x10, x19
-	ldrb w18, [x18]
-	uxtw x20, w2
-	add x19, x20, #0x10
-	subs xzr, x9, x19
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x19, x10, x20
-	ldrb w19, [x19, #0xf]
-	uxtw x21, w2
-	add x20, x21, #0x2
-	subs xzr, x9, x20
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x20, x10, x21
-	ldrsh w20, [x20]
-	uxtw x22, w2
-	add x21, x22, #0x11
-	subs xzr, x9, x21
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x21, x10, x22
-	ldrsh w21, [x21, #0xf]
-	uxtw x23, w2
-	add x22, x23, #0x2
-	subs xzr, x9, x22
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x22, x10, x23
-	ldrh w22, [x22]
-	uxtw x24, w2
-	add x23, x24, #0x11
-	subs xzr, x9, x23
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x23, x10, x24
-	ldrh w23, [x23, #0xf]
-	uxtw x25, w2
-	add x24, x25, #0x4
-	subs xzr, x9, x24
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x24, x10, x25
-	ldrs w24, [x24]
-	uxtw x26, w2
-	add x25, x26, #0x13
-	subs xzr, x9, x25
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x25, x10, x26
-	ldrs w25, [x25, #0xf]
-	uxtw x28, w2
-	add x26, x28, #0x4
-	subs xzr, x9, x26
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x26, x10, x28
-	ldr w26, [x26]
-	uxtw x29, w2
-	add x28, x29, #0x13
-	subs xzr, x9, x28
-	b.hs #0x20
-	movz x27, #0x3, LSL 0
-	str w27, [x8]
-	exit_sequence w8
-	add x8, x10, x29
-	ldr w8, [x8, #0xf]
-	str x8, [sp, #0x128]
-	str x26, [sp, #0x120]
-	str x25, [sp, #0x118]
-	str x24, [sp, #0x110]
-	str x23, [sp, #0x108]
-	str x22, [sp, #0x100]
-	str x21, [sp, #0xf8]
-	str x20, [sp, #0xf0]
-	str x19, [sp, #0xe8]
-	str x18, [sp, #0xe0]
-	str x17, [sp, #0xd8]
-	str x16, [sp, #0xd0]
-	str w15, [sp, #0xc8]
-	str w14, [sp, #0xc0]
-	str w13, [sp, #0xb8]
-	str w12, [sp, #0xb0]
-	mov x2, x11
-	ldr x28, [sp], #0x10
-	ldr x26, [sp], #0x10
-	ldr x25, [sp], #0x10
+	uxtw x8, w2
+	ldr w10, [x1, #0x8]
+	add x9, x8, #0x17
+	subs xzr, x10, x9
+	b.hs #0x28
+	str x9, [x0, #0x450]
+	movz x27, #0x803, LSL 0
+	movk x27, #0x80, LSL 16
+	str w27, [x0]
+	exit_sequence w0
+	ldr x9, [x1]
+	add x8, x9, x8
+	ldr w0, [x8]
+	ldr x1, [x9, w2, UXTW]
+	ldr s0, [x9, w2, UXTW]
+	ldr d1, [x9, w2, UXTW]
+	add x8, x9, #0xf
+	ldr w8, [x8, w2, UXTW]
+	add x10, x9, #0xf
+	ldr x3, [x10, w2, UXTW]
+	add x10, x9, #0xf
+	ldr s2, [x10, w2, UXTW]
+	add x10, x9, #0xf
+	ldr d3, [x10, w2, UXTW]
+	ldrsb w4, [x9, w2, UXTW]
+	add x10, x9, #0xf
+	ldrsb w5, [x10, w2, UXTW]
+	ldrb w6, [x9, w2, UXTW]
+	add x10, x9, #0xf
+	ldrb w7, [x10, w2, UXTW]
+	ldrsh w10, [x9, w2, UXTW]
+	add x11, x9, #0xf
+	ldrsh w12, [x11, w2, UXTW]
+	ldrh w11, [x9, w2, UXTW]
+	add x13, x9, #0xf
+	ldrh w14, [x13, w2, UXTW]
+	ldrsb w13, [x9, w2, UXTW]
+	add x15, x9, #0xf
+	ldrsb w16, [x15, w2, UXTW]
+	ldrb w15, [x9, w2, UXTW]
+	add x17, x9, #0xf
+	ldrb w18, [x17, w2, UXTW]
+	ldrsh w17, [x9, w2, UXTW]
+	add x19, x9, #0xf
+	ldrsh w20, [x19, w2, UXTW]
+	ldrh w19, [x9, w2, UXTW]
+	add x21, x9, #0xf
+	ldrh w22, [x21, w2, UXTW]
+	ldrs w21, [x9, w2, UXTW]
+	add x23, x9, #0xf
+	ldrs w24, [x23, w2, UXTW]
+	ldr w23, [x9, w2, UXTW]
+	add x9, x9, #0xf
+	ldr w9, [x9, w2, UXTW]
+	str x9, [sp, #0xf8]
+	str x23, [sp, #0xf0]
+	str x24, [sp, #0xe8]
+	str x21, [sp, #0xe0]
+	str x22, [sp, #0xd8]
+	str x19, [sp, #0xd0]
+	str x20, [sp, #0xc8]
+	str x17, [sp, #0xc0]
+	str x18, [sp, #0xb8]
+	str x15, [sp, #0xb0]
+	str x16, [sp, #0xa8]
+	str x13, [sp, #0xa0]
+	str w14, [sp, #0x98]
+	str w11, [sp, #0x90]
+	str w12, [sp, #0x88]
+	str w10, [sp, #0x80]
+	mov x2, x8
 	ldr x24, [sp], #0x10
 	ldr x23, [sp], #0x10
 	ldr x22, [sp], #0x10
@@ -2505,6 +3867,195 @@ L1 (SSA Block: blk0):
 	ldr x18, [sp], #0x10
 	ldr x30, [sp], #0x10
 	ret
+`,
+		},
+		{
+			name: "float mul add", m: testcases.FloatMulAdd.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov q2?.8b, q0.8b
+	mov q3?.8b, q1.8b
+	mov q4?.8b, q2.8b
+	mov q5?.8b, q3.8b
+	mov q6?.8b, q4.8b
+	mov q7?.8b, q5.8b
+	fmul s8?, s2?, s3?
+	fadd s9?, s8?, s4?
+	fmul d10?, d5?, d6?
+	fadd d11?, d10?, d7?
+	mov q1.8b, q11?.8b
+	mov q0.8b, q9?.8b
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	fmul s8, s0, s1
+	fadd s0, s8, s2
+	fmul d8, d3, d4
+	fadd d1, d8, d5
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "selects", m: testcases.Selects.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	mov x3?, x3
+	mov x4?, x4
+	mov x5?, x5
+	mov x6?, x6
+	mov q7?.8b, q0.8b
+	mov q8?.8b, q1.8b
+	mov q9?.8b, q2.8b
+	mov q10?.8b, q3.8b
+	subs wzr, w2?, #0x0
+	csel w11?, w3?, w4?, ne
+	subs wzr, w2?, #0x0
+	csel x12?, x5?, x6?, ne
+	subs wzr, w2?, #0x0
+	fcsel s13?, s7?, s8?, ne
+	subs wzr, w2?, #0x0
+	fcsel d16?, d9?, d10?, ne
+	mov q1.8b, q16?.8b
+	mov q0.8b, q13?.8b
+	mov x1, x12?
+	mov x0, x11?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	subs wzr, w2, #0x0
+	csel w0, w3, w4, ne
+	subs wzr, w2, #0x0
+	csel x1, x5, x6, ne
+	subs wzr, w2, #0x0
+	fcsel s0, s0, s1, ne
+	subs wzr, w2, #0x0
+	fcsel d1, d2, d3, ne
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "table_get_set", m: testcases.TableGetSet.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x0?, x0
+	mov x1?, x1
+	mov x2?, x2
+	mov x3?, x3
+	mov x4?, x4
+	mov x5?, x5
+	ldr x6?, [x1?]
+	ldr x7?, [x6?, #0x8]
+	uxtw x8?, w2?
+	subs xzr, x7?, x8?
+	b.hi #0x20
+	movz x27, #0x7, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr x10?, [x6?]
+	lsl x12?, x8?, 0x3
+	add x43?, x10?, x12?
+	str x4?, [x43?]
+	ldr x14?, [x1?, #0x8]
+	ldr x15?, [x14?, #0x8]
+	uxtw x16?, w2?
+	subs xzr, x15?, x16?
+	b.hi #0x20
+	movz x27, #0x7, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr x18?, [x14?]
+	lsl x20?, x16?, 0x3
+	add x42?, x18?, x20?
+	str x5?, [x42?]
+	ldr x22?, [x1?]
+	ldr x23?, [x22?, #0x8]
+	uxtw x24?, w3?
+	subs xzr, x23?, x24?
+	b.hi #0x20
+	movz x27, #0x7, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr x26?, [x22?]
+	lsl x28?, x24?, 0x3
+	add x41?, x26?, x28?
+	ldr x30?, [x41?]
+	ldr x31?, [x1?, #0x8]
+	ldr x32?, [x31?, #0x8]
+	uxtw x33?, w3?
+	subs xzr, x32?, x33?
+	b.hi #0x20
+	movz x27, #0x7, LSL 0
+	str w27, [x0?]
+	exit_sequence w0?
+	ldr x35?, [x31?]
+	lsl x37?, x33?, 0x3
+	add x40?, x35?, x37?
+	ldr x39?, [x40?]
+	mov x1, x39?
+	mov x0, x30?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	ldr x10, [x1]
+	ldr x9, [x10, #0x8]
+	uxtw x8, w2
+	subs xzr, x9, x8
+	b.hi #0x20
+	movz x27, #0x7, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	ldr x9, [x10]
+	lsl x8, x8, 0x3
+	add x8, x9, x8
+	str x4, [x8]
+	ldr x10, [x1, #0x8]
+	ldr x9, [x10, #0x8]
+	uxtw x8, w2
+	subs xzr, x9, x8
+	b.hi #0x20
+	movz x27, #0x7, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	ldr x9, [x10]
+	lsl x8, x8, 0x3
+	add x8, x9, x8
+	str x5, [x8]
+	ldr x10, [x1]
+	ldr x9, [x10, #0x8]
+	uxtw x8, w3
+	subs xzr, x9, x8
+	b.hi #0x20
+	movz x27, #0x7, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	ldr x9, [x10]
+	lsl x8, x8, 0x3
+	add x8, x9, x8
+	ldr x8, [x8]
+	ldr x11, [x1, #0x8]
+	ldr x10, [x11, #0x8]
+	uxtw x9, w3
+	subs xzr, x10, x9
+	b.hi #0x20
+	movz x27, #0x7, LSL 0
+	str w27, [x0]
+	exit_sequence w0
+	ldr x10, [x11]
+	lsl x9, x9, 0x3
+	add x9, x10, x9
+	ldr x1, [x9]
+	mov x0, x8
+	ldr x30, [sp], #0x10
+	ret
 `,
 		},
 	} {
@@ -2580,3 +4131,45 @@ L1 (SSA Block: blk0):
 		})
 	}
 }
+
+func TestCompiler_reused(t *testing.T) {
+	// The compilers are reused across the functions of a module, so none of the state of the previous function
+	// must leak into the next one. The functions of the test cases not referring to the other entities of their
+	// modules are merged into a module.
+	m := &wasm.Module{}
+	for _, tc := range []testcases.TestCase{
+		testcases.ManyMiddleValues, testcases.BrIfSwitch, testcases.IntegerBitwise, testcases.MultiPredecessorLocalRef,
+		testcases.Selects, testcases.IntegerComparisons, testcases.FloatComparisons, testcases.IntegerShift,
+		testcases.FloatRounding, testcases.IntegerExtensions,
+	} {
+		for i, typeIndex := range tc.Module.FunctionSection {
+			m.FunctionSection = append(m.FunctionSection, typeIndex+wasm.Index(len(m.TypeSection)))
+			m.CodeSection = append(m.CodeSection, tc.Module.CodeSection[i])
+		}
+		m.TypeSection = append(m.TypeSection, tc.Module.TypeSection...)
+	}
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+
+	compile := func(fc *frontend.Compiler, ssab ssa.Builder, be backend.Compiler, index wasm.Index) []byte {
+		code := &m.CodeSection[index]
+		fc.Init(index, &m.TypeSection[m.FunctionSection[index]], code.LocalTypes, code.Body)
+		be.Init(false)
+		err := fc.LowerToSSA()
+		require.NoError(t, err)
+		ssab.RunPasses()
+		ssab.LayoutBlocks()
+		body, _, _, err := be.Compile()
+		require.NoError(t, err)
+		return append([]byte(nil), body...)
+	}
+
+	ssab := ssa.NewBuilder()
+	fc := frontend.NewFrontendCompiler(m, ssab, &offset)
+	be := backend.NewCompiler(newMachine(), ssab)
+	for i := range m.CodeSection {
+		freshSSAB := ssa.NewBuilder()
+		freshFC := frontend.NewFrontendCompiler(m, freshSSAB, &offset)
+		exp := compile(freshFC, freshSSAB, backend.NewCompiler(newMachine(), freshSSAB), wasm.Index(i))
+		require.Equal(t, exp, compile(fc, ssab, be, wasm.Index(i)), i)
+	}
+}
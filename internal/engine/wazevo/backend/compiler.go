@@ -259,8 +259,11 @@ func (c *compiler) AllocateVRegWithSSAType(regType regalloc.RegType, typ ssa.Typ
 
 // Init implements Compiler.Init.
 func (c *compiler) Init(needGoEntryPreamble bool) {
-	for i := regalloc.VRegID(0); i < c.nextVRegID; i++ {
+	// ssaValueToVRegs is indexed by ssa.ValueID, whose count differs from the number of VRegs.
+	for i := range c.ssaValueToVRegs {
 		c.ssaValueToVRegs[i] = regalloc.VRegInvalid
+	}
+	for i := regalloc.VRegID(0); i < c.nextVRegID; i++ {
 		delete(c.ssaTypeOfVRegID, i)
 	}
 	c.currentGID = 0
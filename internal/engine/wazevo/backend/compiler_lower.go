@@ -9,7 +9,7 @@ import (
 func (c *compiler) Lower() {
 	c.assignVirtualRegisters()
 	c.mach.InitializeABI(c.ssaBuilder.Signature())
-	c.mach.StartLoweringFunction(c.ssaBuilder.Blocks())
+	c.mach.StartLoweringFunction(c.ssaBuilder.BlockIDMax())
 	c.lowerBlocks()
 	c.mach.EndLoweringFunction()
 }
@@ -60,7 +60,7 @@ func (c *compiler) lowerBlock(blk ssa.BasicBlock) {
 	// Now start lowering the non-branching instructions.
 	for ; cur != nil; cur = cur.Prev() {
 		c.setCurrentGroupID(cur.GroupID())
-		if _, ok := c.alreadyLowered[cur]; ok {
+		if c.alreadyLowered[cur] {
 			continue
 		}
 
@@ -0,0 +1,23 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend/regalloc"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestCompiler_Init(t *testing.T) {
+	c := newCompiler(mockMachine{reset: func() {}}, ssa.NewBuilder())
+	// The previous function allocated more VRegs than its SSA values.
+	c.ssaValueToVRegs = []regalloc.VReg{1, 2}
+	for i := 0; i < 5; i++ {
+		c.AllocateVRegWithSSAType(regalloc.RegTypeInt, ssa.TypeI32)
+	}
+
+	c.Init(false)
+	require.Equal(t, []regalloc.VReg{regalloc.VRegInvalid, regalloc.VRegInvalid}, c.ssaValueToVRegs)
+	require.Equal(t, 0, len(c.ssaTypeOfVRegID))
+	require.Equal(t, regalloc.VRegID(0), c.nextVRegID)
+}
@@ -23,7 +23,7 @@ var (
 	executionContextPtrReg = x0VReg
 	// callee-saved regs so that they can be used in the prologue and epilogue.
 	savedExecutionContextPtr = x18VReg
-	paramResultSlicePtr      = v19VReg
+	paramResultSlicePtr      = x19VReg
 	// goAllocatedStackPtr is not used in the epilogue.
 	goAllocatedStackPtr = x26VReg
 )
@@ -48,6 +48,10 @@ func (a *abiImpl) constructGoEntryPreamble() (root *instruction) {
 	cur = a.loadOrStoreAtExecutionContext(tmpRegVReg, wazevoapi.ExecutionContextOffsets.OriginalStackPointer, true, cur)
 	cur = a.loadOrStoreAtExecutionContext(lrVReg, wazevoapi.ExecutionContextOffsets.GoReturnAddress, true, cur)
 
+	if m.fpcr() != 0 {
+		cur = m.setFPCR(cur, m.allocateInstr)
+	}
+
 	// Next, adjust the Go-allocated stack pointer to reserve the arg/result spaces.
 	// 		sub x28, x28, #stackSlotSize
 	if stackSlotSize := a.alignedStackSlotSize(); stackSlotSize > 0 {
@@ -145,6 +149,10 @@ func (a *abiImpl) constructGoEntryPreamble() (root *instruction) {
 			cur = linkInstr(cur, storeTmp)
 		}
 	}
+	if m.fpcr() != 0 {
+		cur = m.clearFPCR(cur, m.allocateInstr)
+	}
+
 	// Finally, restore the FP, SP and LR, and return to the Go code.
 	// 		ldr fp, [savedExecutionContextPtr, #OriginalFramePointer]
 	// 		ldr tmp, [savedExecutionContextPtr, #OriginalStackPointer]
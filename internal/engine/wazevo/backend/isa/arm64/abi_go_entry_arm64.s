@@ -8,12 +8,12 @@ TEXT ·entrypoint(SB), NOSPLIT|NOFRAME, $0-40
 	MOVD executable+0(FP), R27
 	MOVD executionContextPtr+8(FP), R0
 	MOVD moduleContextPtr+16(FP), R1
-	MOVD paramResultSlicePtr+24(FP), R19
+	MOVD paramResultPtr+24(FP), R19
 	MOVD goAllocatedStackSlicePtr+32(FP), R26
 	JMP  (R27)
 
 TEXT ·afterStackGrowEntrypoint(SB), NOSPLIT|NOFRAME, $0-24
-	MOVD goCallReturnAddress+0(FP), R20
+	MOVD executable+0(FP), R20
 	MOVD executionContextPtr+8(FP), R0
 	MOVD stackPointer+16(FP), R19
 
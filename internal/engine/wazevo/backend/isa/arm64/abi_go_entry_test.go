@@ -2,8 +2,10 @@ package arm64
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
@@ -49,11 +51,11 @@ func TestAbiImpl_constructGoEntryPreamble(t *testing.T) {
 	str x27, [x18, #0x18]
 	str x30, [x18, #0x20]
 	mov sp, x26
-	ldr s0, [d19]
-	ldr s1, [d19, #0x8]
-	ldr s2, [d19, #0x10]
-	ldr s3, [d19, #0x18]
-	ldr d4, [d19, #0x20]
+	ldr s0, [x19]
+	ldr s1, [x19, #0x8]
+	ldr s2, [x19, #0x10]
+	ldr s3, [x19, #0x18]
+	ldr d4, [x19, #0x20]
 	bl #0x18
 	ldr x29, [x18, #0x10]
 	ldr x27, [x18, #0x18]
@@ -77,11 +79,11 @@ func TestAbiImpl_constructGoEntryPreamble(t *testing.T) {
 	str x27, [x18, #0x18]
 	str x30, [x18, #0x20]
 	mov sp, x26
-	ldr w2, [d19]
-	ldr w3, [d19, #0x8]
-	ldr w4, [d19, #0x10]
-	ldr x5, [d19, #0x18]
-	ldr w6, [d19, #0x20]
+	ldr w2, [x19]
+	ldr w3, [x19, #0x8]
+	ldr w4, [x19, #0x10]
+	ldr x5, [x19, #0x18]
+	ldr w6, [x19, #0x20]
 	bl #0x18
 	ldr x29, [x18, #0x10]
 	ldr x27, [x18, #0x18]
@@ -105,16 +107,16 @@ func TestAbiImpl_constructGoEntryPreamble(t *testing.T) {
 	str x27, [x18, #0x18]
 	str x30, [x18, #0x20]
 	mov sp, x26
-	ldr w2, [d19]
-	ldr d0, [d19, #0x8]
-	ldr w3, [d19, #0x10]
-	ldr s1, [d19, #0x18]
-	ldr x4, [d19, #0x20]
-	ldr w5, [d19, #0x28]
-	ldr x6, [d19, #0x30]
-	ldr d2, [d19, #0x38]
-	ldr w7, [d19, #0x40]
-	ldr s3, [d19, #0x48]
+	ldr w2, [x19]
+	ldr d0, [x19, #0x8]
+	ldr w3, [x19, #0x10]
+	ldr s1, [x19, #0x18]
+	ldr x4, [x19, #0x20]
+	ldr w5, [x19, #0x28]
+	ldr x6, [x19, #0x30]
+	ldr d2, [x19, #0x38]
+	ldr w7, [x19, #0x40]
+	ldr s3, [x19, #0x48]
 	bl #0x18
 	ldr x29, [x18, #0x10]
 	ldr x27, [x18, #0x18]
@@ -139,19 +141,19 @@ func TestAbiImpl_constructGoEntryPreamble(t *testing.T) {
 	str x27, [x18, #0x18]
 	str x30, [x18, #0x20]
 	mov sp, x26
-	ldr w2, [d19]
-	ldr d0, [d19, #0x8]
-	ldr w3, [d19, #0x10]
-	ldr s1, [d19, #0x18]
-	ldr x4, [d19, #0x20]
+	ldr w2, [x19]
+	ldr d0, [x19, #0x8]
+	ldr w3, [x19, #0x10]
+	ldr s1, [x19, #0x18]
+	ldr x4, [x19, #0x20]
 	bl #0x34
-	str s0, [d19], #0x8
-	str d1, [d19], #0x8
-	str w0, [d19], #0x8
-	str s2, [d19], #0x8
-	str x1, [d19], #0x8
-	str w2, [d19], #0x8
-	str d3, [d19], #0x8
+	str s0, [x19], #0x8
+	str d1, [x19], #0x8
+	str w0, [x19], #0x8
+	str s2, [x19], #0x8
+	str x1, [x19], #0x8
+	str w2, [x19], #0x8
+	str d3, [x19], #0x8
 	ldr x29, [x18, #0x10]
 	ldr x27, [x18, #0x18]
 	mov sp, x27
@@ -176,32 +178,32 @@ func TestAbiImpl_constructGoEntryPreamble(t *testing.T) {
 	sub x26, x26, #0x30
 	mov sp, x26
 	bl #0x80
-	str s0, [d19], #0x8
-	str d1, [d19], #0x8
-	str w0, [d19], #0x8
-	str s2, [d19], #0x8
-	str x1, [d19], #0x8
-	str w2, [d19], #0x8
-	str w3, [d19], #0x8
-	str x4, [d19], #0x8
-	str w5, [d19], #0x8
-	str x6, [d19], #0x8
-	str s3, [d19], #0x8
-	str d4, [d19], #0x8
-	str w7, [d19], #0x8
-	str s5, [d19], #0x8
+	str s0, [x19], #0x8
+	str d1, [x19], #0x8
+	str w0, [x19], #0x8
+	str s2, [x19], #0x8
+	str x1, [x19], #0x8
+	str w2, [x19], #0x8
+	str w3, [x19], #0x8
+	str x4, [x19], #0x8
+	str w5, [x19], #0x8
+	str x6, [x19], #0x8
+	str s3, [x19], #0x8
+	str d4, [x19], #0x8
+	str w7, [x19], #0x8
+	str s5, [x19], #0x8
 	ldr x27, [sp]
-	str x27, [d19], #0x8
+	str x27, [x19], #0x8
 	ldr w27, [sp, #0x8]
-	str w27, [d19], #0x8
+	str w27, [x19], #0x8
 	ldr w27, [sp, #0x10]
-	str w27, [d19], #0x8
+	str w27, [x19], #0x8
 	ldr x27, [sp, #0x18]
-	str x27, [d19], #0x8
+	str x27, [x19], #0x8
 	ldr w27, [sp, #0x20]
-	str w27, [d19], #0x8
+	str w27, [x19], #0x8
 	ldr x27, [sp, #0x28]
-	str x27, [d19], #0x8
+	str x27, [x19], #0x8
 	ldr x29, [x18, #0x10]
 	ldr x27, [x18, #0x18]
 	mov sp, x27
@@ -219,3 +221,70 @@ func TestAbiImpl_constructGoEntryPreamble(t *testing.T) {
 		})
 	}
 }
+
+func TestAbiImpl_constructGoEntryPreamble_flushDenormalsToZero(t *testing.T) {
+	_, _, m := newSetupWithMockContext()
+	m.EnableFlushDenormalsToZero()
+	abi := m.getOrCreateABIImpl(&ssa.Signature{})
+	m.rootInstr = abi.constructGoEntryPreamble()
+	require.Equal(t, `
+	mov x18, x0
+	str x29, [x18, #0x10]
+	mov x27, sp
+	str x27, [x18, #0x18]
+	str x30, [x18, #0x20]
+	movz x27, #0x100, LSL 16
+	msr fpcr, x27
+	mov sp, x26
+	bl #0x1c
+	msr fpcr, xzr
+	ldr x29, [x18, #0x10]
+	ldr x27, [x18, #0x18]
+	mov sp, x27
+	ldr x30, [x18, #0x20]
+	ret
+`, m.Format())
+}
+
+func TestAbiImpl_constructGoEntryPreamble_roundingMode(t *testing.T) {
+	for _, tc := range []struct {
+		mode backend.RoundingMode
+		ftz  bool
+		exp  string
+	}{
+		{mode: backend.RoundingModeTowardZero, exp: "movz x27, #0xc0, LSL 16"},
+		{mode: backend.RoundingModeTowardPositive, exp: "movz x27, #0x40, LSL 16"},
+		{mode: backend.RoundingModeTowardNegative, ftz: true, exp: "movz x27, #0x180, LSL 16"},
+	} {
+		_, _, m := newSetupWithMockContext()
+		m.SetRoundingMode(tc.mode)
+		if tc.ftz {
+			m.EnableFlushDenormalsToZero()
+		}
+		abi := m.getOrCreateABIImpl(&ssa.Signature{})
+		m.rootInstr = abi.constructGoEntryPreamble()
+		require.Equal(t, `
+	mov x18, x0
+	str x29, [x18, #0x10]
+	mov x27, sp
+	str x27, [x18, #0x18]
+	str x30, [x18, #0x20]
+	`+tc.exp+`
+	msr fpcr, x27
+	mov sp, x26
+	bl #0x1c
+	msr fpcr, xzr
+	ldr x29, [x18, #0x10]
+	ldr x27, [x18, #0x18]
+	mov sp, x27
+	ldr x30, [x18, #0x20]
+	ret
+`, m.Format())
+	}
+
+	// The default rounding mode doesn't touch FPCR at all.
+	_, _, m := newSetupWithMockContext()
+	m.SetRoundingMode(backend.RoundingModeNearest)
+	m.rootInstr = m.getOrCreateABIImpl(&ssa.Signature{}).constructGoEntryPreamble()
+	require.False(t, strings.Contains(m.Format(), "fpcr"))
+}
@@ -195,6 +195,11 @@ func condFlagFromSSAIntegerCmpCond(c ssa.IntegerCmpCond) condFlag {
 }
 
 // condFlagFromSSAFloatCmpCond returns the condition flag for the given ssa.FloatCmpCond.
+//
+// All the conditions are "ordered" as required by Wasm: FCMP sets NZCV to 0b0011 when either operand is NaN,
+// so eq, mi, ls, gt and ge evaluate to false while ne evaluates to true. Notably, lt and le must not be used
+// here since they evaluate to true on unordered operands. Also, the inversions of these flags (used for Brz)
+// are the exact logical negations, so they are safe as well.
 func condFlagFromSSAFloatCmpCond(c ssa.FloatCmpCond) condFlag {
 	switch c {
 	case ssa.FloatCmpCondEqual:
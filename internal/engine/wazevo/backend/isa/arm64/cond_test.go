@@ -0,0 +1,86 @@
+package arm64
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// condFlagHolds returns true if the given condFlag holds for the given NZCV flags.
+// https://developer.arm.com/documentation/den0024/a/The-A64-instruction-set/Data-processing-instructions/Conditional-instructions
+func condFlagHolds(c condFlag, nzcv byte) bool {
+	n, z, cf, v := nzcv&0b1000 != 0, nzcv&0b0100 != 0, nzcv&0b0010 != 0, nzcv&0b0001 != 0
+	switch c {
+	case eq:
+		return z
+	case ne:
+		return !z
+	case hs:
+		return cf
+	case lo:
+		return !cf
+	case mi:
+		return n
+	case pl:
+		return !n
+	case vs:
+		return v
+	case vc:
+		return !v
+	case hi:
+		return cf && !z
+	case ls:
+		return !cf || z
+	case ge:
+		return n == v
+	case lt:
+		return n != v
+	case gt:
+		return !z && n == v
+	case le:
+		return z || n != v
+	case al:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestCondFlagFromSSAFloatCmpCond(t *testing.T) {
+	// NZCV flags set by FCMP for each ordering of the operands.
+	const (
+		lessThan  byte = 0b1000
+		equal     byte = 0b0110
+		greater   byte = 0b0010
+		unordered byte = 0b0011 // Either operand is NaN.
+	)
+
+	for _, tc := range []struct {
+		c                                   ssa.FloatCmpCond
+		lessThan, equal, greater, unordered bool
+	}{
+		{c: ssa.FloatCmpCondEqual, equal: true},
+		{c: ssa.FloatCmpCondNotEqual, lessThan: true, greater: true, unordered: true},
+		{c: ssa.FloatCmpCondLessThan, lessThan: true},
+		{c: ssa.FloatCmpCondLessThanOrEqual, lessThan: true, equal: true},
+		{c: ssa.FloatCmpCondGreaterThan, greater: true},
+		{c: ssa.FloatCmpCondGreaterThanOrEqual, equal: true, greater: true},
+	} {
+		tc := tc
+		t.Run(tc.c.String(), func(t *testing.T) {
+			flag := condFlagFromSSAFloatCmpCond(tc.c)
+			require.Equal(t, tc.lessThan, condFlagHolds(flag, lessThan))
+			require.Equal(t, tc.equal, condFlagHolds(flag, equal))
+			require.Equal(t, tc.greater, condFlagHolds(flag, greater))
+			require.Equal(t, tc.unordered, condFlagHolds(flag, unordered))
+
+			// The inverted flag is used for Brz, so it must be the exact negation, including the unordered case.
+			inverted := flag.invert()
+			require.Equal(t, !tc.lessThan, condFlagHolds(inverted, lessThan))
+			require.Equal(t, !tc.equal, condFlagHolds(inverted, equal))
+			require.Equal(t, !tc.greater, condFlagHolds(inverted, greater))
+			require.Equal(t, !tc.unordered, condFlagHolds(inverted, unordered))
+		})
+	}
+}
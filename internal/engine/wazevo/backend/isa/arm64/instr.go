@@ -51,22 +51,36 @@ var defKinds = [numInstructionKinds]defKind{
 	aluRRImmShift:   defKindRD,
 	aluRRRExtend:    defKindRD,
 	movZ:            defKindRD,
+	movK:            defKindRD,
 	movN:            defKindRD,
 	mov32:           defKindRD,
 	mov64:           defKindRD,
 	fpuMov64:        defKindRD,
 	fpuMov128:       defKindRD,
+	fpuRR:           defKindRD,
+	fpuToInt:        defKindRD,
+	intToFpu:        defKindRD,
 	fpuRRR:          defKindRD,
+	movToFpu:        defKindRD,
+	movFromVec:      defKindRD,
+	bitRR:           defKindRD,
+	vecMisc:         defKindRD,
+	vecLanes:        defKindRD,
 	nop0:            defKindNone,
 	call:            defKindCall,
 	callInd:         defKindCall,
 	ret:             defKindNone,
+	store8:          defKindNone,
+	store16:         defKindNone,
 	store32:         defKindNone,
 	store64:         defKindNone,
 	exitSequence:    defKindNone,
 	condBr:          defKindNone,
 	br:              defKindNone,
 	cSet:            defKindRD,
+	cSel:            defKindRD,
+	fpuCSel32:       defKindRD,
+	fpuCSel64:       defKindRD,
 	extend:          defKindRD,
 	fpuCmp:          defKindNone,
 	uLoad8:          defKindRD,
@@ -84,6 +98,7 @@ var defKinds = [numInstructionKinds]defKind{
 	fpuStore32:      defKindNone,
 	fpuStore64:      defKindNone,
 	fpuStore128:     defKindNone,
+	movToFPCR:       defKindNone,
 	udf:             defKindNone,
 }
 
@@ -119,6 +134,8 @@ type useKind byte
 const (
 	useKindNone useKind = iota + 1
 	useKindRN
+	// useKindRD is for the instructions which keep the part of rd as is, e.g. movk.
+	useKindRD
 	useKindRNRM
 	useKindRNRMRA
 	useKindRet
@@ -131,6 +148,7 @@ const (
 
 var useKinds = [numInstructionKinds]useKind{
 	udf:             useKindNone,
+	movToFPCR:       useKindRN,
 	aluRRR:          useKindRNRM,
 	aluRRRR:         useKindRNRMRA,
 	aluRRImm12:      useKindRN,
@@ -139,22 +157,36 @@ var useKinds = [numInstructionKinds]useKind{
 	aluRRImmShift:   useKindRN,
 	aluRRRExtend:    useKindRNRM,
 	movZ:            useKindNone,
+	movK:            useKindRD,
 	movN:            useKindNone,
 	mov32:           useKindRN,
 	mov64:           useKindRN,
 	fpuMov64:        useKindRN,
 	fpuMov128:       useKindRN,
+	fpuRR:           useKindRN,
+	fpuToInt:        useKindRN,
+	intToFpu:        useKindRN,
 	fpuRRR:          useKindRNRM,
+	movToFpu:        useKindRN,
+	movFromVec:      useKindRN,
+	bitRR:           useKindRN,
+	vecMisc:         useKindRN,
+	vecLanes:        useKindRN,
 	nop0:            useKindNone,
 	call:            useKindCall,
 	callInd:         useKindCallInd,
 	ret:             useKindRet,
+	store8:          useKindRNAMode,
+	store16:         useKindRNAMode,
 	store32:         useKindRNAMode,
 	store64:         useKindRNAMode,
 	exitSequence:    useKindRN,
 	condBr:          useKindCond,
 	br:              useKindNone,
 	cSet:            useKindNone,
+	cSel:            useKindRNRM,
+	fpuCSel32:       useKindRNRM,
+	fpuCSel64:       useKindRNRM,
 	extend:          useKindRN,
 	fpuCmp:          useKindRNRM,
 	uLoad8:          useKindAMode,
@@ -183,6 +215,10 @@ func (i *instruction) uses(regs []regalloc.VReg) []regalloc.VReg {
 		if rn := i.rn.reg(); rn.Valid() {
 			regs = append(regs, rn)
 		}
+	case useKindRD:
+		if rd := i.rd.reg(); rd.Valid() {
+			regs = append(regs, rd)
+		}
 	case useKindRNRM:
 		if rn := i.rn.reg(); rn.Valid() {
 			regs = append(regs, rn)
@@ -240,6 +276,10 @@ func (i *instruction) assignUses(regs []regalloc.VReg) {
 		if rn := i.rn.reg(); rn.Valid() {
 			i.rn = i.rn.assignReg(regs[0])
 		}
+	case useKindRD:
+		if rd := i.rd.reg(); rd.Valid() {
+			i.rd = i.rd.assignReg(regs[0])
+		}
 	case useKindRNRM:
 		if rn := i.rn.reg(); rn.Valid() {
 			i.rn = i.rn.assignReg(regs[0])
@@ -442,6 +482,28 @@ func (i *instruction) asCSst(rd regalloc.VReg, c condFlag) {
 	i.u1 = uint64(c)
 }
 
+// asCSel sets up a conditional select: rd = c ? rn : rm.
+func (i *instruction) asCSel(rd, rn, rm operand, c condFlag, _64bit bool) {
+	i.kind = cSel
+	i.rd, i.rn, i.rm = rd, rn, rm
+	i.u1 = uint64(c)
+	if _64bit {
+		i.u3 = 1
+	}
+}
+
+// asFpuCSel sets up a floating point conditional select: rd = c ? rn : rm.
+// This copies the bits as-is, so NaN payloads are never canonicalized.
+func (i *instruction) asFpuCSel(rd, rn, rm operand, c condFlag, _64bit bool) {
+	if _64bit {
+		i.kind = fpuCSel64
+	} else {
+		i.kind = fpuCSel32
+	}
+	i.rd, i.rn, i.rm = rd, rn, rm
+	i.u1 = uint64(c)
+}
+
 func (i *instruction) asBr(target label) {
 	if target == returnLabel {
 		panic("BUG: call site should special case for returnLabel")
@@ -579,6 +641,89 @@ func (i *instruction) asALUBitmaskImm(aluOp aluOp, rn, rd regalloc.VReg, imm uin
 	}
 }
 
+func (i *instruction) asFpuRR(op fpuUniOp, rd, rn operand, dst64bit bool) {
+	i.kind = fpuRR
+	i.u1 = uint64(op)
+	i.rd, i.rn = rd, rn
+	if dst64bit {
+		i.u3 = 1
+	}
+}
+
+func (i *instruction) asMovToFpu(rd, rn operand, _64bit bool) {
+	i.kind = movToFpu
+	i.rd, i.rn = rd, rn
+	if _64bit {
+		i.u3 = 1
+	}
+}
+
+// asFpuToInt converts the floating-point rn to the integer rd by truncating towards zero, which saturates NaN and
+// the out-of-range values rather than trapping.
+func (i *instruction) asFpuToInt(rd, rn operand, signed, src64bit, dst64bit bool) {
+	i.kind = fpuToInt
+	i.rd, i.rn = rd, rn
+	if signed {
+		i.u1 = 1
+	}
+	if src64bit {
+		i.u2 = 1
+	}
+	if dst64bit {
+		i.u3 = 1
+	}
+}
+
+// asIntToFpu converts the integer rn to the floating-point rd, which rounds by FPCR if it is not exact.
+func (i *instruction) asIntToFpu(rd, rn operand, signed, src64bit, dst64bit bool) {
+	i.kind = intToFpu
+	i.rd, i.rn = rd, rn
+	if signed {
+		i.u1 = 1
+	}
+	if src64bit {
+		i.u2 = 1
+	}
+	if dst64bit {
+		i.u3 = 1
+	}
+}
+
+// asMovFromVec moves the index-th 32-bit or 64-bit (depending on _64bit) element of the vector register rn to the GPR rd.
+func (i *instruction) asMovFromVec(rd, rn operand, index byte, _64bit bool) {
+	i.kind = movFromVec
+	i.rd, i.rn = rd, rn
+	i.u1 = uint64(index)
+	if _64bit {
+		i.u3 = 1
+	}
+}
+
+// asBitRR initializes this instruction as the 32-bit or 64-bit (depending on _64bit) bit operation `op` of rn into rd.
+func (i *instruction) asBitRR(op bitOp, rd, rn operand, _64bit bool) {
+	i.kind = bitRR
+	i.u1 = uint64(op)
+	i.rd, i.rn = rd, rn
+	if _64bit {
+		i.u3 = 1
+	}
+}
+
+// asVecMisc initializes this instruction as the vector operation `op` of the 8B arrangement of rn into rd.
+func (i *instruction) asVecMisc(op vecOp, rd, rn operand) {
+	i.kind = vecMisc
+	i.u1 = uint64(op)
+	i.rd, i.rn = rd, rn
+}
+
+// asVecLanes initializes this instruction as the operation `op` across the lanes of the 8B arrangement of rn, whose
+// result is the byte scalar rd.
+func (i *instruction) asVecLanes(op vecOp, rd, rn operand) {
+	i.kind = vecLanes
+	i.u1 = uint64(op)
+	i.rd, i.rn = rd, rn
+}
+
 func (i *instruction) asFpuRRR(op fpuBinOp, rd, rn, rm operand, dst64bit bool) {
 	i.kind = fpuRRR
 	i.u1 = uint64(op)
@@ -689,7 +834,9 @@ func (i *instruction) String() (str string) {
 			e,
 		)
 	case bitRR:
-		panic("TODO")
+		size := is64SizeBitToSize(i.u3)
+		str = fmt.Sprintf("%s %s, %s", bitOp(i.u1).String(),
+			formatVRegSized(i.rd.nr(), size), formatVRegSized(i.rn.nr(), size))
 	case uLoad8:
 		str = fmt.Sprintf("ldrb %s, %s", formatVRegSized(i.rd.nr(), 32), i.amode.format(32))
 	case sLoad8:
@@ -705,9 +852,9 @@ func (i *instruction) String() (str string) {
 	case uLoad64:
 		str = fmt.Sprintf("ldr %s, %s", formatVRegSized(i.rd.nr(), 64), i.amode.format(64))
 	case store8:
-		panic("TODO")
+		str = fmt.Sprintf("strb %s, %s", formatVRegSized(i.rn.nr(), 32), i.amode.format(8))
 	case store16:
-		panic("TODO")
+		str = fmt.Sprintf("strh %s, %s", formatVRegSized(i.rn.nr(), 32), i.amode.format(16))
 	case store32:
 		str = fmt.Sprintf("str %s, %s", formatVRegSized(i.rn.nr(), 32), i.amode.format(32))
 	case store64:
@@ -753,7 +900,13 @@ func (i *instruction) String() (str string) {
 		}
 		str = fmt.Sprintf("%sxt%s %s, %s", signedStr, fromStr, formatVRegSized(i.rd.nr(), toBits), formatVRegSized(i.rn.nr(), 32))
 	case cSel:
-		panic("TODO")
+		size := is64SizeBitToSize(i.u3)
+		str = fmt.Sprintf("csel %s, %s, %s, %s",
+			formatVRegSized(i.rd.nr(), size),
+			formatVRegSized(i.rn.nr(), size),
+			formatVRegSized(i.rm.nr(), size),
+			condFlag(i.u1),
+		)
 	case cSet:
 		str = fmt.Sprintf("cset %s, %s", formatVRegSized(i.rd.nr(), 64), condFlag(i.u1))
 	case cCmpImm:
@@ -765,7 +918,16 @@ func (i *instruction) String() (str string) {
 	case fpuMovFromVec:
 		panic("TODO")
 	case fpuRR:
-		panic("TODO")
+		dstSize := is64SizeBitToSize(i.u3)
+		srcSize := dstSize
+		switch fpuUniOp(i.u1) {
+		case fpuUniOpCvt32To64:
+			srcSize = 32
+		case fpuUniOpCvt64To32:
+			srcSize = 64
+		}
+		str = fmt.Sprintf("%s %s, %s", fpuUniOp(i.u1).String(),
+			formatVRegSized(i.rd.nr(), dstSize), formatVRegSized(i.rn.nr(), srcSize))
 	case fpuRRR:
 		size := is64SizeBitToSize(i.u3)
 		str = fmt.Sprintf("%s %s, %s, %s", fpuBinOp(i.u1).String(),
@@ -775,7 +937,7 @@ func (i *instruction) String() (str string) {
 	case fpuRRRR:
 		panic("TODO")
 	case fpuCmp:
-		size := is64SizeBitToSize(i.u3)
+		size := is64SizeBitToSize(i.u1)
 		str = fmt.Sprintf("fcmp %s, %s",
 			formatVRegSized(i.rn.nr(), size), formatVRegSized(i.rm.nr(), size))
 	case fpuLoad32:
@@ -797,21 +959,50 @@ func (i *instruction) String() (str string) {
 	case loadFpuConst128:
 		panic("TODO")
 	case fpuToInt:
-		panic("TODO")
+		var op string
+		if i.u1 == 1 {
+			op = "fcvtzs"
+		} else {
+			op = "fcvtzu"
+		}
+		str = fmt.Sprintf("%s %s, %s", op,
+			formatVRegSized(i.rd.nr(), is64SizeBitToSize(i.u3)), formatVRegSized(i.rn.nr(), is64SizeBitToSize(i.u2)))
 	case intToFpu:
-		panic("TODO")
+		var op string
+		if i.u1 == 1 {
+			op = "scvtf"
+		} else {
+			op = "ucvtf"
+		}
+		str = fmt.Sprintf("%s %s, %s", op,
+			formatVRegSized(i.rd.nr(), is64SizeBitToSize(i.u3)), formatVRegSized(i.rn.nr(), is64SizeBitToSize(i.u2)))
 	case fpuCSel32:
-		panic("TODO")
+		str = fmt.Sprintf("fcsel %s, %s, %s, %s",
+			formatVRegSized(i.rd.nr(), 32),
+			formatVRegSized(i.rn.nr(), 32),
+			formatVRegSized(i.rm.nr(), 32),
+			condFlag(i.u1),
+		)
 	case fpuCSel64:
-		panic("TODO")
+		str = fmt.Sprintf("fcsel %s, %s, %s, %s",
+			formatVRegSized(i.rd.nr(), 64),
+			formatVRegSized(i.rn.nr(), 64),
+			formatVRegSized(i.rm.nr(), 64),
+			condFlag(i.u1),
+		)
 	case fpuRound:
 		panic("TODO")
 	case movToFpu:
-		panic("TODO")
+		size := is64SizeBitToSize(i.u3)
+		str = fmt.Sprintf("fmov %s, %s", formatVRegSized(i.rd.nr(), size), formatVRegSized(i.rn.nr(), size))
 	case movToVec:
 		panic("TODO")
 	case movFromVec:
-		panic("TODO")
+		size, arr := is64SizeBitToSize(i.u3), "s"
+		if size == 64 {
+			arr = "d"
+		}
+		str = fmt.Sprintf("umov %s, %s.%s[%d]", formatVRegSized(i.rd.nr(), size), formatVRegSized(i.rn.nr(), 128), arr, i.u1)
 	case movFromVecSigned:
 		panic("TODO")
 	case vecDup:
@@ -827,9 +1018,11 @@ func (i *instruction) String() (str string) {
 	case vecRRR:
 		panic("TODO")
 	case vecMisc:
-		panic("TODO")
+		str = fmt.Sprintf("%s %s.8b, %s.8b", vecOp(i.u1).String(),
+			formatVRegSized(i.rd.nr(), 128), formatVRegSized(i.rn.nr(), 128))
 	case vecLanes:
-		panic("TODO")
+		str = fmt.Sprintf("%s %s, %s.8b", vecOp(i.u1).String(),
+			formatVRegSized(i.rd.nr(), 8), formatVRegSized(i.rn.nr(), 128))
 	case vecTbl:
 		panic("TODO")
 	case vecTbl2:
@@ -838,6 +1031,8 @@ func (i *instruction) String() (str string) {
 		panic("TODO")
 	case movFromNZCV:
 		panic("TODO")
+	case movToFPCR:
+		str = fmt.Sprintf("msr fpcr, %s", formatVRegSized(i.rn.nr(), 64))
 	case call:
 		if i.u2 > 0 {
 			str = fmt.Sprintf("bl #%#x", i.u2)
@@ -1055,6 +1250,8 @@ const (
 	movToNZCV
 	// movFromNZCV represents a move from the NZCV flags.
 	movFromNZCV
+	// movToFPCR represents a move to the FPCR (floating-point control register).
+	movToFPCR
 	// call represents a machine call instruction.
 	call
 	// callInd represents a machine indirect-call instruction.
@@ -1091,6 +1288,11 @@ const (
 	numInstructionKinds
 )
 
+func (i *instruction) asMovToFPCR(rn regalloc.VReg) {
+	i.kind = movToFPCR
+	i.rn = operandNR(rn)
+}
+
 func (i *instruction) asUDF() {
 	i.kind = udf
 }
@@ -1127,12 +1329,12 @@ func (a aluOp) String() string {
 		return "sMulH"
 	case aluOpUMulH:
 		return "uMulH"
-	case aluOpSDiv64:
-		return "sDiv64"
-	case aluOpUDiv64:
-		return "uDiv64"
+	case aluOpSDiv:
+		return "sdiv"
+	case aluOpUDiv:
+		return "udiv"
 	case aluOpRotR:
-		return "rotR"
+		return "ror"
 	case aluOpLsr:
 		return "lsr"
 	case aluOpAsr:
@@ -1168,10 +1370,10 @@ const (
 	aluOpSMulH
 	// Unsigned multiply, high-word result.
 	aluOpUMulH
-	// 64-bit Signed divide.
-	aluOpSDiv64
-	// 64-bit Unsigned divide.
-	aluOpUDiv64
+	// 32/64-bit Signed divide.
+	aluOpSDiv
+	// 32/64-bit Unsigned divide.
+	aluOpUDiv
 	// 32/64-bit Rotate right.
 	aluOpRotR
 	// 32/64-bit Logical shift right.
@@ -1217,6 +1419,88 @@ func (f fpuBinOp) String() string {
 	panic(int(f))
 }
 
+// fpuUniOp represents a unary floating point operation.
+type fpuUniOp byte
+
+const (
+	fpuUniOpNeg fpuUniOp = iota
+	fpuUniOpAbs
+	fpuUniOpSqrt
+	fpuUniOpRoundPlus
+	fpuUniOpRoundMinus
+	fpuUniOpRoundZero
+	fpuUniOpRoundNearest
+	// fpuUniOpCvt32To64 and fpuUniOpCvt64To32 convert between the single and double precisions, whose destination
+	// size is the one of the instruction.
+	fpuUniOpCvt32To64
+	fpuUniOpCvt64To32
+)
+
+// String implements the fmt.Stringer.
+func (f fpuUniOp) String() string {
+	switch f {
+	case fpuUniOpNeg:
+		return "fneg"
+	case fpuUniOpAbs:
+		return "fabs"
+	case fpuUniOpSqrt:
+		return "fsqrt"
+	case fpuUniOpRoundPlus:
+		return "frintp"
+	case fpuUniOpRoundMinus:
+		return "frintm"
+	case fpuUniOpRoundZero:
+		return "frintz"
+	case fpuUniOpRoundNearest:
+		return "frintn"
+	case fpuUniOpCvt32To64, fpuUniOpCvt64To32:
+		return "fcvt"
+	}
+	panic(int(f))
+}
+
+// bitOp represents a bit operation of a single register.
+type bitOp byte
+
+const (
+	// bitOpRbit reverses the bits.
+	bitOpRbit bitOp = iota
+	// bitOpClz counts the leading zero bits.
+	bitOpClz
+)
+
+// String implements the fmt.Stringer.
+func (b bitOp) String() string {
+	switch b {
+	case bitOpRbit:
+		return "rbit"
+	case bitOpClz:
+		return "clz"
+	}
+	panic(int(b))
+}
+
+// vecOp represents a vector operation. Only the 8B arrangement is supported so far.
+type vecOp byte
+
+const (
+	// vecOpCnt counts the one bits of each byte. This is vecMisc.
+	vecOpCnt vecOp = iota
+	// vecOpAddv adds all the bytes. This is vecLanes.
+	vecOpAddv
+)
+
+// String implements the fmt.Stringer.
+func (v vecOp) String() string {
+	switch v {
+	case vecOpCnt:
+		return "cnt"
+	case vecOpAddv:
+		return "addv"
+	}
+	panic(int(v))
+}
+
 // extMode represents the mode of a register operand extension.
 // For example, aluRRRExtend instructions need this info to determine the extensions.
 type extMode byte
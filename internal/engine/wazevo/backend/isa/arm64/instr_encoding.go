@@ -155,6 +155,13 @@ func (i *instruction) encode(c backend.Compiler) {
 			imm12, shift,
 			i.u3 == 1,
 		))
+	case fpuRR:
+		c.Emit4Bytes(encodeFpuRR(
+			fpuUniOp(i.u1),
+			regNumberInEncoding[i.rd.realReg()],
+			regNumberInEncoding[i.rn.realReg()],
+			i.u3 == 1,
+		))
 	case fpuRRR:
 		c.Emit4Bytes(encodeFpuRRR(
 			fpuBinOp(i.u1),
@@ -163,6 +170,33 @@ func (i *instruction) encode(c backend.Compiler) {
 			regNumberInEncoding[i.rm.realReg()],
 			i.u3 == 1,
 		))
+	case fpuToInt:
+		c.Emit4Bytes(encodeFpuToInt(
+			regNumberInEncoding[i.rd.realReg()],
+			regNumberInEncoding[i.rn.realReg()],
+			i.u1 == 1, i.u2 == 1, i.u3 == 1,
+		))
+	case intToFpu:
+		c.Emit4Bytes(encodeIntToFpu(
+			regNumberInEncoding[i.rd.realReg()],
+			regNumberInEncoding[i.rn.realReg()],
+			i.u1 == 1, i.u2 == 1, i.u3 == 1,
+		))
+	case movToFpu:
+		c.Emit4Bytes(encodeMovToFpu(regNumberInEncoding[i.rd.realReg()], regNumberInEncoding[i.rn.realReg()], i.u3 == 1))
+	case bitRR:
+		c.Emit4Bytes(encodeBitRR(
+			bitOp(i.u1),
+			regNumberInEncoding[i.rd.realReg()],
+			regNumberInEncoding[i.rn.realReg()],
+			i.u3 == 1,
+		))
+	case vecMisc:
+		c.Emit4Bytes(encodeVecMisc(vecOp(i.u1), regNumberInEncoding[i.rd.realReg()], regNumberInEncoding[i.rn.realReg()]))
+	case vecLanes:
+		c.Emit4Bytes(encodeVecLanes(vecOp(i.u1), regNumberInEncoding[i.rd.realReg()], regNumberInEncoding[i.rn.realReg()]))
+	case movFromVec:
+		c.Emit4Bytes(encodeMovFromVec(regNumberInEncoding[i.rd.realReg()], regNumberInEncoding[i.rn.realReg()], byte(i.u1), i.u3 == 1))
 	case fpuMov64, fpuMov128:
 		// https://developer.arm.com/documentation/ddi0596/2021-12/SIMD-FP-Instructions/MOV--vector---Move-vector--an-alias-of-ORR--vector--register--
 		rd := regNumberInEncoding[i.rd.realReg()]
@@ -178,6 +212,18 @@ func (i *instruction) encode(c backend.Compiler) {
 		// https://developer.arm.com/documentation/ddi0602/2022-06/Base-Instructions/CSET--Conditional-Set--an-alias-of-CSINC-
 		// Note that we set 64bit version here.
 		c.Emit4Bytes(0b1001101010011111<<16 | uint32(cf.invert())<<12 | 0b111111<<5 | rd)
+	case cSel:
+		// https://developer.arm.com/documentation/ddi0596/2020-12/Base-Instructions/CSEL--Conditional-Select-
+		rd, rn, rm := regNumberInEncoding[i.rd.realReg()], regNumberInEncoding[i.rn.realReg()], regNumberInEncoding[i.rm.realReg()]
+		c.Emit4Bytes(uint32(i.u3)<<31 | 0b11010100<<21 | rm<<16 | uint32(i.u1)<<12 | rn<<5 | rd)
+	case fpuCSel32, fpuCSel64:
+		// https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/FCSEL--Floating-point-Conditional-Select--scalar--
+		rd, rn, rm := regNumberInEncoding[i.rd.realReg()], regNumberInEncoding[i.rn.realReg()], regNumberInEncoding[i.rm.realReg()]
+		var ftype uint32
+		if kind == fpuCSel64 {
+			ftype = 0b01 // double precision.
+		}
+		c.Emit4Bytes(0b11110<<24 | ftype<<22 | 1<<21 | rm<<16 | uint32(i.u1)<<12 | 0b11<<10 | rn<<5 | rd)
 	case extend:
 		c.Emit4Bytes(encodeExtend(i.u3 == 1, byte(i.u1), byte(i.u2), regNumberInEncoding[i.rd.realReg()], regNumberInEncoding[i.rn.realReg()]))
 	case fpuCmp:
@@ -188,6 +234,11 @@ func (i *instruction) encode(c backend.Compiler) {
 			ftype = 0b01 // double precision.
 		}
 		c.Emit4Bytes(0b1111<<25 | ftype<<22 | 1<<21 | rm<<16 | 0b1<<13 | rn<<5)
+	case movToFPCR:
+		// MSR FPCR, <Xt>
+		// https://developer.arm.com/documentation/ddi0596/2020-12/Base-Instructions/MSR--register---Move-general-purpose-register-to-System-Register-?lang=en
+		rt := regNumberInEncoding[i.rn.realReg()]
+		c.Emit4Bytes(0b1101010100_0_1_1_011_0100_0100_000<<5 | rt)
 	case udf:
 		// https://developer.arm.com/documentation/ddi0596/2020-12/Base-Instructions/UDF--Permanently-Undefined-?lang=en
 		c.Emit4Bytes(0)
@@ -510,6 +561,145 @@ func encodeLoadOrStoreSIMM9(_22to32, _1011 uint32, rn, rt uint32, imm9 int64) ui
 	return _22to32<<22 | (uint32(imm9)&0b111111111)<<12 | _1011<<10 | rn<<5 | rt
 }
 
+// encodeFpuRR encodes as "Floating-point data-processing (1 source)" in
+// https://developer.arm.com/documentation/ddi0596/2020-12/Index-by-Encoding/Data-Processing----Scalar-Floating-Point-and-Advanced-SIMD?lang=en
+func encodeFpuRR(op fpuUniOp, rd, rn uint32, _64bit bool) (ret uint32) {
+	var opcode uint32
+	switch op {
+	case fpuUniOpAbs:
+		opcode = 0b000001
+	case fpuUniOpNeg:
+		opcode = 0b000010
+	case fpuUniOpSqrt:
+		opcode = 0b000011
+	case fpuUniOpRoundNearest:
+		opcode = 0b001000
+	case fpuUniOpRoundPlus:
+		opcode = 0b001001
+	case fpuUniOpRoundMinus:
+		opcode = 0b001010
+	case fpuUniOpRoundZero:
+		opcode = 0b001011
+	case fpuUniOpCvt32To64:
+		// FCVT whose opc (the lowest two bits) is the destination type, and ptype is the source type.
+		opcode = 0b000101
+	case fpuUniOpCvt64To32:
+		opcode = 0b000100
+	default:
+		panic("BUG")
+	}
+	var ptype uint32
+	if (_64bit && op != fpuUniOpCvt32To64) || op == fpuUniOpCvt64To32 {
+		ptype = 0b01
+	}
+	return 0b11110<<24 | ptype<<22 | 0b1<<21 | opcode<<15 | 0b1<<14 | rn<<5 | rd
+}
+
+// encodeFpuToInt encodes as "FCVTZS (scalar, integer)" or "FCVTZU (scalar, integer)" in
+// https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/FCVTZS--scalar--integer---Floating-point-Convert-to-Signed-integer--rounding-toward-Zero--scalar--?lang=en
+// https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/FCVTZU--scalar--integer---Floating-point-Convert-to-Unsigned-integer--rounding-toward-Zero--scalar--?lang=en
+func encodeFpuToInt(rd, rn uint32, signed, src64bit, dst64bit bool) uint32 {
+	var sf, ftype, opcode uint32
+	if dst64bit {
+		sf = 0b1
+	}
+	if src64bit {
+		ftype = 0b01
+	}
+	if !signed {
+		opcode = 0b001
+	}
+	return sf<<31 | 0b11110<<24 | ftype<<22 | 0b1<<21 | 0b11<<19 | opcode<<16 | rn<<5 | rd
+}
+
+// encodeIntToFpu encodes as "SCVTF (scalar, integer)" or "UCVTF (scalar, integer)" in
+// https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/SCVTF--scalar--integer---Signed-integer-Convert-to-Floating-point--scalar--?lang=en
+// https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/UCVTF--scalar--integer---Unsigned-integer-Convert-to-Floating-point--scalar--?lang=en
+func encodeIntToFpu(rd, rn uint32, signed, src64bit, dst64bit bool) uint32 {
+	var sf, ftype uint32
+	if src64bit {
+		sf = 0b1
+	}
+	if dst64bit {
+		ftype = 0b01
+	}
+	opcode := uint32(0b010)
+	if !signed {
+		opcode = 0b011
+	}
+	return sf<<31 | 0b11110<<24 | ftype<<22 | 0b1<<21 | opcode<<16 | rn<<5 | rd
+}
+
+// encodeMovToFpu encodes as "FMOV (general)" from the GPR rn to the FP register rd in
+// https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/FMOV--general---Floating-point-Move-to-or-from-general-purpose-register-without-conversion-?lang=en
+func encodeMovToFpu(rd, rn uint32, _64bit bool) uint32 {
+	var sf, ftype uint32
+	if _64bit {
+		sf, ftype = 0b1, 0b01
+	}
+	return sf<<31 | 0b11110<<24 | ftype<<22 | 0b1<<21 | 0b111<<16 | rn<<5 | rd
+}
+
+// encodeMovFromVec encodes as "UMOV" of the index-th 32-bit or 64-bit element in
+// https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/UMOV--Unsigned-Move-vector-element-to-general-purpose-register-?lang=en
+func encodeMovFromVec(rd, rn uint32, index byte, _64bit bool) uint32 {
+	var q, imm5 uint32
+	if _64bit {
+		q, imm5 = 0b1, uint32(index)<<4|0b1000
+	} else {
+		imm5 = uint32(index)<<3 | 0b100
+	}
+	return q<<30 | 0b001110000<<21 | imm5<<16 | 0b001111<<10 | rn<<5 | rd
+}
+
+// encodeBitRR encodes as "Data-processing (1 source)" in
+// https://developer.arm.com/documentation/ddi0596/2020-12/Index-by-Encoding/Data-Processing----Register?lang=en
+func encodeBitRR(op bitOp, rd, rn uint32, _64bit bool) uint32 {
+	var sf, opcode uint32
+	if _64bit {
+		sf = 0b1
+	}
+	switch op {
+	case bitOpRbit:
+		// https://developer.arm.com/documentation/ddi0596/2020-12/Base-Instructions/RBIT--Reverse-Bits-?lang=en
+		opcode = 0b000000
+	case bitOpClz:
+		// https://developer.arm.com/documentation/ddi0596/2020-12/Base-Instructions/CLZ--Count-Leading-Zeros-?lang=en
+		opcode = 0b000100
+	default:
+		panic("BUG")
+	}
+	return sf<<31 | 0b1011010110<<21 | opcode<<10 | rn<<5 | rd
+}
+
+// encodeVecMisc encodes as the 8B arrangement of "Advanced SIMD two-register miscellaneous" in
+// https://developer.arm.com/documentation/ddi0596/2020-12/Index-by-Encoding/Data-Processing----Scalar-Floating-Point-and-Advanced-SIMD?lang=en
+func encodeVecMisc(op vecOp, rd, rn uint32) uint32 {
+	var opcode uint32
+	switch op {
+	case vecOpCnt:
+		// https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/CNT--Population-Count-per-byte-?lang=en
+		opcode = 0b00101
+	default:
+		panic("BUG")
+	}
+	return 0b01110<<24 | 0b1<<21 | opcode<<12 | 0b10<<10 | rn<<5 | rd
+}
+
+// encodeVecLanes encodes as the 8B arrangement of "Advanced SIMD across lanes" in
+// https://developer.arm.com/documentation/ddi0596/2020-12/Index-by-Encoding/Data-Processing----Scalar-Floating-Point-and-Advanced-SIMD?lang=en
+func encodeVecLanes(op vecOp, rd, rn uint32) uint32 {
+	var opcode uint32
+	switch op {
+	case vecOpAddv:
+		// https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/ADDV--Add-across-Vector-?lang=en
+		opcode = 0b11011
+	default:
+		panic("BUG")
+	}
+	return 0b01110<<24 | 0b11<<20 | opcode<<12 | 0b10<<10 | rn<<5 | rd
+}
+
 // encodeFpuRRR encodes as single or double precision (depending on `_64bit`) of Floating-point data-processing (2 source) in
 // https://developer.arm.com/documentation/ddi0596/2020-12/Index-by-Encoding/Data-Processing----Scalar-Floating-Point-and-Advanced-SIMD?lang=en
 func encodeFpuRRR(op fpuBinOp, rd, rn, rm uint32, _64bit bool) (ret uint32) {
@@ -595,16 +785,31 @@ func encodeAluRRR(op aluOp, rd, rn, rm uint32, _64bit, isRnSp bool) uint32 {
 		}
 		// "Shifted register" with shift = 0
 		_31to21 = 0b01101011_000
-	case aluOpLsl, aluOpAsr, aluOpLsr:
+	case aluOpAnd:
+		// "Logical (shifted register)" with shift = 0
+		_31to21 = 0b00001010_000
+	case aluOpOrr:
+		// "Logical (shifted register)" with shift = 0
+		_31to21 = 0b00101010_000
+	case aluOpEor:
+		// "Logical (shifted register)" with shift = 0
+		_31to21 = 0b01001010_000
+	case aluOpLsl, aluOpAsr, aluOpLsr, aluOpRotR, aluOpSDiv, aluOpUDiv:
 		// "Data-processing (2 source)".
 		_31to21 = 0b00011010_110
 		switch op {
+		case aluOpUDiv:
+			_15to10 = 0b000010
+		case aluOpSDiv:
+			_15to10 = 0b000011
 		case aluOpLsl:
 			_15to10 = 0b001000
 		case aluOpLsr:
 			_15to10 = 0b001001
 		case aluOpAsr:
 			_15to10 = 0b001010
+		case aluOpRotR:
+			_15to10 = 0b001011
 		}
 	default:
 		panic(op.String())
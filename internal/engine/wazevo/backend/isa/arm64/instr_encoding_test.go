@@ -5,6 +5,7 @@ import (
 	"math"
 	"testing"
 
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend/regalloc"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
@@ -20,12 +21,30 @@ func TestInstruction_encode(t *testing.T) {
 		{want: "60033fd6", setup: func(i *instruction) {
 			i.asCallIndirect(tmpRegVReg, nil)
 		}},
+		{want: "08441bd5", setup: func(i *instruction) {
+			i.asMovToFPCR(x8VReg)
+		}},
+		{want: "1f441bd5", setup: func(i *instruction) {
+			i.asMovToFPCR(xzrVReg)
+		}},
 		{want: "fb633bcb", setup: func(i *instruction) {
 			i.asALU(aluOpSub, operandNR(tmpRegVReg), operandNR(spVReg), operandNR(tmpRegVReg), true)
 		}},
 		{want: "fb633b8b", setup: func(i *instruction) {
 			i.asALU(aluOpAdd, operandNR(tmpRegVReg), operandNR(spVReg), operandNR(tmpRegVReg), true)
 		}},
+		{want: "8210861a", setup: func(i *instruction) {
+			i.asCSel(operandNR(x2VReg), operandNR(x4VReg), operandNR(x6VReg), ne, false)
+		}},
+		{want: "8210869a", setup: func(i *instruction) {
+			i.asCSel(operandNR(x2VReg), operandNR(x4VReg), operandNR(x6VReg), ne, true)
+		}},
+		{want: "821c261e", setup: func(i *instruction) {
+			i.asFpuCSel(operandNR(v2VReg), operandNR(v4VReg), operandNR(v6VReg), ne, false)
+		}},
+		{want: "821c661e", setup: func(i *instruction) {
+			i.asFpuCSel(operandNR(v2VReg), operandNR(v4VReg), operandNR(v6VReg), ne, true)
+		}},
 		{want: "30000010", setup: func(i *instruction) { i.asAdr(v16VReg, 4) }},
 		{want: "50050030", setup: func(i *instruction) { i.asAdr(v16VReg, 169) }},
 		{want: "5000001c020000140000803f", setup: func(i *instruction) {
@@ -207,6 +226,120 @@ func TestInstruction_encode(t *testing.T) {
 			i.asCondBr(registerAsRegNotZeroCond(x1VReg), dummyLabel, true)
 			i.condBrOffsetResolve(0x80)
 		}},
+		{want: "8300271e", setup: func(i *instruction) {
+			i.asMovToFpu(operandNR(v3VReg), operandNR(x4VReg), false)
+		}},
+		{want: "8300679e", setup: func(i *instruction) {
+			i.asMovToFpu(operandNR(v3VReg), operandNR(x4VReg), true)
+		}},
+		{want: "833c040e", setup: func(i *instruction) {
+			i.asMovFromVec(operandNR(x3VReg), operandNR(v4VReg), 0, false)
+		}},
+		{want: "833c084e", setup: func(i *instruction) {
+			i.asMovFromVec(operandNR(x3VReg), operandNR(v4VReg), 0, true)
+		}},
+		{want: "833c0c0e", setup: func(i *instruction) {
+			i.asMovFromVec(operandNR(x3VReg), operandNR(v4VReg), 1, false)
+		}},
+		{want: "833c184e", setup: func(i *instruction) {
+			i.asMovFromVec(operandNR(x3VReg), operandNR(v4VReg), 1, true)
+		}},
+		{want: "8300c05a", setup: func(i *instruction) {
+			i.asBitRR(bitOpRbit, operandNR(x3VReg), operandNR(x4VReg), false)
+		}},
+		{want: "8300c0da", setup: func(i *instruction) {
+			i.asBitRR(bitOpRbit, operandNR(x3VReg), operandNR(x4VReg), true)
+		}},
+		{want: "8310c05a", setup: func(i *instruction) {
+			i.asBitRR(bitOpClz, operandNR(x3VReg), operandNR(x4VReg), false)
+		}},
+		{want: "8310c0da", setup: func(i *instruction) {
+			i.asBitRR(bitOpClz, operandNR(x3VReg), operandNR(x4VReg), true)
+		}},
+		{want: "8358200e", setup: func(i *instruction) {
+			i.asVecMisc(vecOpCnt, operandNR(v3VReg), operandNR(v4VReg))
+		}},
+		{want: "83b8310e", setup: func(i *instruction) {
+			i.asVecLanes(vecOpAddv, operandNR(v3VReg), operandNR(v4VReg))
+		}},
+		{want: "8340211e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpNeg, operandNR(v3VReg), operandNR(v4VReg), false)
+		}},
+		{want: "8340611e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpNeg, operandNR(v3VReg), operandNR(v4VReg), true)
+		}},
+		{want: "83c0201e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpAbs, operandNR(v3VReg), operandNR(v4VReg), false)
+		}},
+		{want: "83c0601e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpAbs, operandNR(v3VReg), operandNR(v4VReg), true)
+		}},
+		{want: "83c0211e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpSqrt, operandNR(v3VReg), operandNR(v4VReg), false)
+		}},
+		{want: "83c0611e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpSqrt, operandNR(v3VReg), operandNR(v4VReg), true)
+		}},
+		{want: "83c0241e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpRoundPlus, operandNR(v3VReg), operandNR(v4VReg), false)
+		}},
+		{want: "83c0641e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpRoundPlus, operandNR(v3VReg), operandNR(v4VReg), true)
+		}},
+		{want: "8340251e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpRoundMinus, operandNR(v3VReg), operandNR(v4VReg), false)
+		}},
+		{want: "8340651e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpRoundMinus, operandNR(v3VReg), operandNR(v4VReg), true)
+		}},
+		{want: "83c0251e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpRoundZero, operandNR(v3VReg), operandNR(v4VReg), false)
+		}},
+		{want: "83c0651e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpRoundZero, operandNR(v3VReg), operandNR(v4VReg), true)
+		}},
+		{want: "8340241e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpRoundNearest, operandNR(v3VReg), operandNR(v4VReg), false)
+		}},
+		{want: "8340641e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpRoundNearest, operandNR(v3VReg), operandNR(v4VReg), true)
+		}},
+		{want: "83c0221e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpCvt32To64, operandNR(v3VReg), operandNR(v4VReg), true)
+		}},
+		{want: "8340621e", setup: func(i *instruction) {
+			i.asFpuRR(fpuUniOpCvt64To32, operandNR(v3VReg), operandNR(v4VReg), false)
+		}},
+		{want: "8300381e", setup: func(i *instruction) {
+			i.asFpuToInt(operandNR(x3VReg), operandNR(v4VReg), true, false, false)
+		}},
+		{want: "8300789e", setup: func(i *instruction) {
+			i.asFpuToInt(operandNR(x3VReg), operandNR(v4VReg), true, true, true)
+		}},
+		{want: "8300391e", setup: func(i *instruction) {
+			i.asFpuToInt(operandNR(x3VReg), operandNR(v4VReg), false, false, false)
+		}},
+		{want: "8300399e", setup: func(i *instruction) {
+			i.asFpuToInt(operandNR(x3VReg), operandNR(v4VReg), false, false, true)
+		}},
+		{want: "8300791e", setup: func(i *instruction) {
+			i.asFpuToInt(operandNR(x3VReg), operandNR(v4VReg), false, true, false)
+		}},
+		{want: "8300221e", setup: func(i *instruction) {
+			i.asIntToFpu(operandNR(v3VReg), operandNR(x4VReg), true, false, false)
+		}},
+		{want: "8300629e", setup: func(i *instruction) {
+			i.asIntToFpu(operandNR(v3VReg), operandNR(x4VReg), true, true, true)
+		}},
+		{want: "8300231e", setup: func(i *instruction) {
+			i.asIntToFpu(operandNR(v3VReg), operandNR(x4VReg), false, false, false)
+		}},
+		{want: "8300239e", setup: func(i *instruction) {
+			i.asIntToFpu(operandNR(v3VReg), operandNR(x4VReg), false, true, false)
+		}},
+		{want: "8300631e", setup: func(i *instruction) {
+			i.asIntToFpu(operandNR(v3VReg), operandNR(x4VReg), false, false, true)
+		}},
 		{want: "8328321e", setup: func(i *instruction) {
 			i.asFpuRRR(fpuBinOpAdd, operandNR(v3VReg), operandNR(v4VReg), operandNR(v18VReg), false)
 		}},
@@ -303,12 +436,60 @@ func TestInstruction_encode(t *testing.T) {
 		{want: "4024d49a", setup: func(i *instruction) {
 			i.asALU(aluOpLsr, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), true)
 		}},
+		{want: "400cd41a", setup: func(i *instruction) {
+			i.asALU(aluOpSDiv, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), false)
+		}},
+		{want: "400cd49a", setup: func(i *instruction) {
+			i.asALU(aluOpSDiv, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), true)
+		}},
+		{want: "4008d41a", setup: func(i *instruction) {
+			i.asALU(aluOpUDiv, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), false)
+		}},
+		{want: "4008d49a", setup: func(i *instruction) {
+			i.asALU(aluOpUDiv, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), true)
+		}},
 		{want: "4028d41a", setup: func(i *instruction) {
 			i.asALU(aluOpAsr, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), false)
 		}},
 		{want: "4028d49a", setup: func(i *instruction) {
 			i.asALU(aluOpAsr, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), true)
 		}},
+		{want: "402cd41a", setup: func(i *instruction) {
+			i.asALU(aluOpRotR, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), false)
+		}},
+		{want: "402cd49a", setup: func(i *instruction) {
+			i.asALU(aluOpRotR, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), true)
+		}},
+		{want: "4000140a", setup: func(i *instruction) {
+			i.asALU(aluOpAnd, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), false)
+		}},
+		{want: "4000148a", setup: func(i *instruction) {
+			i.asALU(aluOpAnd, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), true)
+		}},
+		{want: "4000144a", setup: func(i *instruction) {
+			i.asALU(aluOpEor, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), false)
+		}},
+		{want: "400014ca", setup: func(i *instruction) {
+			i.asALU(aluOpEor, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), true)
+		}},
+		{want: "4000142a", setup: func(i *instruction) {
+			i.asALU(aluOpOrr, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), false)
+		}},
+		{want: "400014aa", setup: func(i *instruction) {
+			i.asALU(aluOpOrr, operandNR(x0VReg), operandNR(x2VReg), operandNR(x20VReg), true)
+		}},
+		{want: "e003144b", setup: func(i *instruction) {
+			i.asALU(aluOpSub, operandNR(x0VReg), operandNR(xzrVReg), operandNR(x20VReg), false)
+		}},
+		{want: "e00314cb", setup: func(i *instruction) {
+			i.asALU(aluOpSub, operandNR(x0VReg), operandNR(xzrVReg), operandNR(x20VReg), true)
+		}},
+		{want: "40100012", setup: func(i *instruction) {
+			i.asALUBitmaskImm(aluOpAnd, x2VReg, x0VReg, 31, false)
+		}},
+		{want: "40144092", setup: func(i *instruction) {
+			i.asALUBitmaskImm(aluOpAnd, x2VReg, x0VReg, 63, true)
+		}},
 		{want: "407c0113", setup: func(i *instruction) {
 			i.asALUShift(aluOpAsr, operandNR(x0VReg), operandNR(x2VReg), operandShiftImm(1), false)
 		}},
@@ -763,10 +944,19 @@ func Test_encodeExitSequence(t *testing.T) {
 }
 
 func Test_lowerExitWithCodeEncodingSize(t *testing.T) {
-	compiler, _, m := newSetupWithMockContext()
-	m.lowerExitWithCode(x10VReg, wazevoapi.ExitCodeGrowStack)
-	m.FlushPendingInstructions()
-	require.NotNil(t, m.perBlockHead)
-	m.encode(m.perBlockHead)
-	require.Equal(t, exitWithCodeEncodingSize, len(compiler.Buf()))
+	for _, ftz := range []bool{false, true} {
+		for _, mode := range []backend.RoundingMode{backend.RoundingModeNearest, backend.RoundingModeTowardZero} {
+			// The latter needs movk for the upper 16 bits of the code.
+			for _, code := range []wazevoapi.ExitCode{wazevoapi.ExitCodeGrowStack, wazevoapi.ExitCodeUnreachableWithOffset(0x12345)} {
+				compiler, _, m := newSetupWithMockContext()
+				m.flushDenormalsToZero = ftz
+				m.SetRoundingMode(mode)
+				m.lowerExitWithCode(x10VReg, code)
+				m.FlushPendingInstructions()
+				require.NotNil(t, m.perBlockHead)
+				m.encode(m.perBlockHead)
+				require.Equal(t, m.exitWithCodeEncodingSize(code), int64(len(compiler.Buf())))
+			}
+		}
+	}
 }
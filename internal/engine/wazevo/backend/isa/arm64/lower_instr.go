@@ -126,13 +126,25 @@ func (m *machine) LowerInstr(instr *ssa.Instruction) {
 		m.lowerSubOrAdd(instr, op == ssa.OpcodeIadd)
 	case ssa.OpcodeFadd, ssa.OpcodeFsub, ssa.OpcodeFmul, ssa.OpcodeFdiv, ssa.OpcodeFmax, ssa.OpcodeFmin:
 		m.lowerFpuBinOp(instr)
+	case ssa.OpcodeFcopysign:
+		m.lowerFcopysign(instr)
+	case ssa.OpcodeFcvtToSint, ssa.OpcodeFcvtToUint, ssa.OpcodeFcvtToSintSat, ssa.OpcodeFcvtToUintSat:
+		m.lowerFpuToInt(instr, op == ssa.OpcodeFcvtToSint || op == ssa.OpcodeFcvtToSintSat)
+	case ssa.OpcodeFcvtFromSint, ssa.OpcodeFcvtFromUint:
+		m.lowerIntToFpu(instr, op == ssa.OpcodeFcvtFromSint)
+	case ssa.OpcodeFneg, ssa.OpcodeFabs, ssa.OpcodeSqrt, ssa.OpcodeCeil, ssa.OpcodeFloor, ssa.OpcodeTrunc, ssa.OpcodeNearest,
+		ssa.OpcodeFpromote, ssa.OpcodeFdemote:
+		m.lowerFpuUniOp(instr)
 	case ssa.OpcodeIconst, ssa.OpcodeF32const, ssa.OpcodeF64const: // Constant instructions are inlined.
 	case ssa.OpcodeExitWithCode:
 		execCtx, code := instr.ExitWithCodeData()
 		m.lowerExitWithCode(m.compiler.VRegOf(execCtx), code)
 	case ssa.OpcodeExitIfNotZeroWithCode:
 		execCtx, c, code := instr.ExitIfNotZeroWithCodeData()
-		m.lowerExitIfNotZeroWithCode(m.compiler.VRegOf(execCtx), c, code)
+		m.lowerExitIfNotZeroWithCode(m.compiler.VRegOf(execCtx), c, instr.ExitValue(), code)
+	case ssa.OpcodeExitWithDynamicCode:
+		execCtx, code, v := instr.ExitWithDynamicCodeData()
+		m.lowerExitWithDynamicCode(m.compiler.VRegOf(execCtx), code, v)
 	case ssa.OpcodeStore, ssa.OpcodeIstore8, ssa.OpcodeIstore16, ssa.OpcodeIstore32:
 		m.lowerStore(instr)
 	case ssa.OpcodeLoad:
@@ -157,9 +169,19 @@ func (m *machine) LowerInstr(instr *ssa.Instruction) {
 		} else {
 			m.lowerShifts(instr, extModeZeroExtend32, aluOpAsr)
 		}
+	case ssa.OpcodeRotl, ssa.OpcodeRotr:
+		m.lowerRotate(instr, op == ssa.OpcodeRotl)
+	case ssa.OpcodeClz, ssa.OpcodeCtz:
+		m.lowerClzOrCtz(instr, op == ssa.OpcodeCtz)
+	case ssa.OpcodePopcnt:
+		m.lowerPopcnt(instr)
 	case ssa.OpcodeSExtend, ssa.OpcodeUExtend:
 		from, to, signed := instr.ExtendData()
 		m.lowerExtend(instr.Arg(), instr.Return(), from, to, signed)
+	case ssa.OpcodeIreduce:
+		// The lower 32 bits are zero-extended rather than moved, since the moves between the same registers are
+		// removed after the register allocation, which would leave the upper 32 bits as is.
+		m.lowerExtend(instr.Arg(), instr.Return(), 32, 64, false)
 	case ssa.OpcodeFcmp:
 		x, y, c := instr.FcmpData()
 		m.lowerFcmp(x, y, instr.Return(), c)
@@ -167,12 +189,28 @@ func (m *machine) LowerInstr(instr *ssa.Instruction) {
 		x, y := instr.BinaryData()
 		result := instr.Return()
 		m.lowerImul(x, y, result)
+	case ssa.OpcodeBand, ssa.OpcodeBor, ssa.OpcodeBxor:
+		m.lowerBitwiseAluOp(instr)
+	case ssa.OpcodeSdiv, ssa.OpcodeUdiv:
+		x, y := instr.BinaryData()
+		m.lowerIDiv(x, y, instr.Return(), op == ssa.OpcodeSdiv)
+	case ssa.OpcodeSrem, ssa.OpcodeUrem:
+		x, y := instr.BinaryData()
+		m.lowerIRem(x, y, instr.Return(), op == ssa.OpcodeSrem)
+	case ssa.OpcodeSelect:
+		c, x, y := instr.SelectData()
+		m.lowerSelect(c, x, y, instr.Return())
 	default:
 		panic("TODO: lowering " + instr.Opcode().String())
 	}
 	m.FlushPendingInstructions()
 }
 
+// lowerFpuBinOp lowers the given floating point binary operation into a single instruction.
+//
+// Note that Wasm requires each floating point arithmetic to be rounded separately, so we must never contract
+// the combination of multiplication and addition/subtraction into fused multiply-add instructions (fmadd, fmsub, etc.),
+// even though that would save an instruction.
 func (m *machine) lowerFpuBinOp(si *ssa.Instruction) {
 	instr := m.allocateInstr()
 	var op fpuBinOp
@@ -199,6 +237,157 @@ func (m *machine) lowerFpuBinOp(si *ssa.Instruction) {
 	m.insert(instr)
 }
 
+// lowerFpuUniOp lowers the unary floating point operations. fneg and fabs only flip or clear the sign bit regardless
+// of NaN and FPCR as Wasm requires, unlike the sign manipulations by arithmetic, e.g. the subtraction from zero.
+func (m *machine) lowerFpuUniOp(si *ssa.Instruction) {
+	var op fpuUniOp
+	switch si.Opcode() {
+	case ssa.OpcodeFneg:
+		op = fpuUniOpNeg
+	case ssa.OpcodeFabs:
+		op = fpuUniOpAbs
+	case ssa.OpcodeSqrt:
+		op = fpuUniOpSqrt
+	case ssa.OpcodeCeil:
+		op = fpuUniOpRoundPlus
+	case ssa.OpcodeFloor:
+		op = fpuUniOpRoundMinus
+	case ssa.OpcodeTrunc:
+		op = fpuUniOpRoundZero
+	case ssa.OpcodeNearest:
+		// frintn rounds to the nearest with ties to even regardless of FPCR, as Wasm's nearest requires.
+		op = fpuUniOpRoundNearest
+	case ssa.OpcodeFpromote:
+		op = fpuUniOpCvt32To64
+	case ssa.OpcodeFdemote:
+		// fcvt rounds by FPCR which is to the nearest with ties to even unless configured otherwise, and quiets
+		// the signaling NaN with the payload kept as far as it fits.
+		op = fpuUniOpCvt64To32
+	}
+	x := si.Arg()
+	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+	result := si.Return()
+	rd := operandNR(m.compiler.VRegOf(result))
+	instr := m.allocateInstr()
+	instr.asFpuRR(op, rd, rn, result.Type().Bits() == 64)
+	m.insert(instr)
+}
+
+// lowerFpuToInt lowers the float-to-int conversion into a single fcvtzs or fcvtzu, which saturate the out-of-range
+// values and convert NaN to zero as the saturating conversions require. For the trapping ones, NaN and the
+// out-of-range values are checked by the preceding OpcodeExitIfNotZeroWithCode.
+func (m *machine) lowerFpuToInt(si *ssa.Instruction, signed bool) {
+	x := si.Arg()
+	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+	result := si.Return()
+	rd := operandNR(m.compiler.VRegOf(result))
+	cvt := m.allocateInstr()
+	cvt.asFpuToInt(rd, rn, signed, x.Type().Bits() == 64, result.Type().Bits() == 64)
+	m.insert(cvt)
+}
+
+// lowerIntToFpu lowers the int-to-float conversion into a single scvtf or ucvtf, which rounds by FPCR, i.e. to the
+// nearest with ties to even unless configured otherwise. Unlike amd64, arm64 converts the unsigned 64-bit integer
+// directly, so no fixup is needed for the ones above the maximum signed value.
+func (m *machine) lowerIntToFpu(si *ssa.Instruction, signed bool) {
+	x := si.Arg()
+	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+	result := si.Return()
+	rd := operandNR(m.compiler.VRegOf(result))
+	cvt := m.allocateInstr()
+	cvt.asIntToFpu(rd, rn, signed, x.Type().Bits() == 64, result.Type().Bits() == 64)
+	m.insert(cvt)
+}
+
+// lowerFcopysign lowers the copysign by assembling the bits in GPRs, since arm64 doesn't have the scalar copysign.
+// The sign bit is taken from y as-is even when y is NaN, as Wasm requires.
+func (m *machine) lowerFcopysign(si *ssa.Instruction) {
+	x, y := si.BinaryData()
+	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+	rm := m.getOperand_NR(m.compiler.ValueDefinition(y), extModeNone)
+	rd := operandNR(m.compiler.VRegOf(si.Return()))
+	_64bit := x.Type().Bits() == 64
+
+	signBit := uint64(1) << 31
+	if _64bit {
+		signBit = 1 << 63
+	}
+
+	xBits, yBits := m.compiler.AllocateVReg(regalloc.RegTypeInt), m.compiler.AllocateVReg(regalloc.RegTypeInt)
+	movX := m.allocateInstr()
+	movX.asMovFromVec(operandNR(xBits), rn, 0, _64bit)
+	m.insert(movX)
+	movY := m.allocateInstr()
+	movY.asMovFromVec(operandNR(yBits), rm, 0, _64bit)
+	m.insert(movY)
+
+	magnitude, sign := m.compiler.AllocateVReg(regalloc.RegTypeInt), m.compiler.AllocateVReg(regalloc.RegTypeInt)
+	andX := m.allocateInstr()
+	andX.asALUBitmaskImm(aluOpAnd, xBits, magnitude, signBit-1, _64bit)
+	m.insert(andX)
+	andY := m.allocateInstr()
+	andY.asALUBitmaskImm(aluOpAnd, yBits, sign, signBit, _64bit)
+	m.insert(andY)
+
+	bits := m.compiler.AllocateVReg(regalloc.RegTypeInt)
+	orr := m.allocateInstr()
+	orr.asALU(aluOpOrr, operandNR(bits), operandNR(magnitude), operandNR(sign), _64bit)
+	m.insert(orr)
+
+	mov := m.allocateInstr()
+	mov.asMovToFpu(rd, operandNR(bits), _64bit)
+	m.insert(mov)
+}
+
+// lowerClzOrCtz lowers clz into a single clz, and ctz into rbit followed by clz since arm64 doesn't have ctz. Both
+// result in the bit width for zero as Wasm requires.
+func (m *machine) lowerClzOrCtz(si *ssa.Instruction, ctz bool) {
+	x := si.Arg()
+	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+	rd := operandNR(m.compiler.VRegOf(si.Return()))
+	_64bit := x.Type().Bits() == 64
+
+	if ctz {
+		reversed := m.compiler.AllocateVReg(regalloc.RegTypeInt)
+		rbit := m.allocateInstr()
+		rbit.asBitRR(bitOpRbit, operandNR(reversed), rn, _64bit)
+		m.insert(rbit)
+		rn = operandNR(reversed)
+	}
+
+	clz := m.allocateInstr()
+	clz.asBitRR(bitOpClz, rd, rn, _64bit)
+	m.insert(clz)
+}
+
+// lowerPopcnt lowers popcnt via a vector register since arm64 doesn't have the scalar popcnt: cnt counts the one bits
+// of each byte, and addv adds them up. fmov and addv zero the rest of the vector register, so the count is read by
+// the 32-bit umov of the lowest element for both 32-bit and 64-bit.
+func (m *machine) lowerPopcnt(si *ssa.Instruction) {
+	x := si.Arg()
+	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+	rd := operandNR(m.compiler.VRegOf(si.Return()))
+
+	bits := m.compiler.AllocateVReg(regalloc.RegTypeFloat)
+	mov := m.allocateInstr()
+	mov.asMovToFpu(operandNR(bits), rn, x.Type().Bits() == 64)
+	m.insert(mov)
+
+	counts := m.compiler.AllocateVReg(regalloc.RegTypeFloat)
+	cnt := m.allocateInstr()
+	cnt.asVecMisc(vecOpCnt, operandNR(counts), operandNR(bits))
+	m.insert(cnt)
+
+	sum := m.compiler.AllocateVReg(regalloc.RegTypeFloat)
+	addv := m.allocateInstr()
+	addv.asVecLanes(vecOpAddv, operandNR(sum), operandNR(counts))
+	m.insert(addv)
+
+	umov := m.allocateInstr()
+	umov.asMovFromVec(rd, operandNR(sum), 0, false)
+	m.insert(umov)
+}
+
 func (m *machine) lowerSubOrAdd(si *ssa.Instruction, add bool) {
 	x, y := si.BinaryData()
 	if !x.Type().IsInt() {
@@ -282,6 +471,63 @@ func (m *machine) lowerShifts(si *ssa.Instruction, ext extMode, aluOp aluOp) {
 	m.insert(alu)
 }
 
+// lowerRotate lowers OpcodeRotl and OpcodeRotr. The rotate right is a single ror (RORV) which takes the amount modulo
+// the bit width, but arm64 has no rotate left instruction, so the rotate left is lowered into the pair of shifts:
+//
+//	and rl, amount, #(bits-1)
+//	lsl hi, x, rl
+//	sub neg, xzr, rl
+//	and rr, neg, #(bits-1)
+//	lsr lo, x, rr
+//	orr rd, hi, lo
+//
+// Both shift amounts are masked so that the rotate by zero, or by a multiple of the bit width, is the identity
+// instead of shifting by the full width on the right hand side.
+func (m *machine) lowerRotate(si *ssa.Instruction, left bool) {
+	x, amount := si.BinaryData()
+	_64 := x.Type().Bits() == 64
+	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+	rm := m.getOperand_NR(m.compiler.ValueDefinition(amount), extModeNone)
+	rd := operandNR(m.compiler.VRegOf(si.Return()))
+
+	if !left {
+		ror := m.allocateInstr()
+		ror.asALU(aluOpRotR, rd, rn, rm, _64)
+		m.insert(ror)
+		return
+	}
+
+	mask := uint64(x.Type().Bits() - 1)
+	rl := m.compiler.AllocateVReg(regalloc.RegTypeInt)
+	andL := m.allocateInstr()
+	andL.asALUBitmaskImm(aluOpAnd, rm.nr(), rl, mask, _64)
+	m.insert(andL)
+
+	hi := m.compiler.AllocateVReg(regalloc.RegTypeInt)
+	lsl := m.allocateInstr()
+	lsl.asALU(aluOpLsl, operandNR(hi), rn, operandNR(rl), _64)
+	m.insert(lsl)
+
+	negated := m.compiler.AllocateVReg(regalloc.RegTypeInt)
+	neg := m.allocateInstr()
+	neg.asALU(aluOpSub, operandNR(negated), operandNR(xzrVReg), operandNR(rl), _64)
+	m.insert(neg)
+
+	rr := m.compiler.AllocateVReg(regalloc.RegTypeInt)
+	andR := m.allocateInstr()
+	andR.asALUBitmaskImm(aluOpAnd, negated, rr, mask, _64)
+	m.insert(andR)
+
+	lo := m.compiler.AllocateVReg(regalloc.RegTypeInt)
+	lsr := m.allocateInstr()
+	lsr.asALU(aluOpLsr, operandNR(lo), rn, operandNR(rr), _64)
+	m.insert(lsr)
+
+	orr := m.allocateInstr()
+	orr.asALU(aluOpOrr, rd, operandNR(hi), operandNR(lo), _64)
+	m.insert(orr)
+}
+
 func (m *machine) lowerExtend(arg, ret ssa.Value, from, to byte, signed bool) {
 	rd := m.compiler.VRegOf(ret)
 	rn := m.getOperand_NR(m.compiler.ValueDefinition(arg), extModeNone)
@@ -303,6 +549,55 @@ func (m *machine) lowerFcmp(x, y, result ssa.Value, c ssa.FloatCmpCond) {
 	m.insert(cset)
 }
 
+// lowerSelect lowers OpcodeSelect into csel or fcsel, which pick one of the registers bitwise.
+// Notably, floats must never go through an arithmetic instruction here as that could canonicalize NaN.
+func (m *machine) lowerSelect(c, x, y, result ssa.Value) {
+	cvalDef := m.compiler.ValueDefinition(c)
+
+	var cc condFlag
+	switch {
+	case m.compiler.MatchInstr(cvalDef, ssa.OpcodeIcmp): // This case, we can use the ALU flag set by SUBS instruction.
+		cvalInstr := cvalDef.Instr
+		cx, cy, ic := cvalInstr.IcmpData()
+		cc = condFlagFromSSAIntegerCmpCond(ic)
+		extMod := extModeOf(cx.Type(), ic.Signed())
+		rn := m.getOperand_NR(m.compiler.ValueDefinition(cx), extMod)
+		rm := m.getOperand_Imm12_ER_SR_NR(m.compiler.ValueDefinition(cy), extMod)
+		alu := m.allocateInstr()
+		alu.asALU(aluOpSubS, operandNR(xzrVReg), rn, rm, cx.Type().Bits() == 64)
+		m.insert(alu)
+		m.compiler.MarkLowered(cvalInstr)
+	case m.compiler.MatchInstr(cvalDef, ssa.OpcodeFcmp): // This case we can use the Fpu flag directly.
+		cvalInstr := cvalDef.Instr
+		cx, cy, fc := cvalInstr.FcmpData()
+		cc = condFlagFromSSAFloatCmpCond(fc)
+		rn := m.getOperand_NR(m.compiler.ValueDefinition(cx), extModeNone)
+		rm := m.getOperand_NR(m.compiler.ValueDefinition(cy), extModeNone)
+		cmp := m.allocateInstr()
+		cmp.asFpuCmp(rn, rm, cx.Type().Bits() == 64)
+		m.insert(cmp)
+		m.compiler.MarkLowered(cvalInstr)
+	default:
+		// subs wzr, c, #0
+		rn := m.getOperand_NR(cvalDef, extModeNone)
+		alu := m.allocateInstr()
+		alu.asALU(aluOpSubS, operandNR(xzrVReg), rn, operandImm12(0, 0), c.Type().Bits() == 64)
+		m.insert(alu)
+		cc = ne
+	}
+
+	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+	rm := m.getOperand_NR(m.compiler.ValueDefinition(y), extModeNone)
+	rd := operandNR(m.compiler.VRegOf(result))
+	sel := m.allocateInstr()
+	if x.Type().IsInt() {
+		sel.asCSel(rd, rn, rm, cc, x.Type().Bits() == 64)
+	} else {
+		sel.asFpuCSel(rd, rn, rm, cc, x.Type().Bits() == 64)
+	}
+	m.insert(sel)
+}
+
 func (m *machine) lowerImul(x, y, result ssa.Value) {
 	rd := m.compiler.VRegOf(result)
 	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
@@ -315,12 +610,83 @@ func (m *machine) lowerImul(x, y, result ssa.Value) {
 	m.insert(mul)
 }
 
-const exitWithCodeEncodingSize = exitSequenceSize + 8
+// lowerBitwiseAluOp lowers the integer bitwise and, or and xor into a single instruction.
+func (m *machine) lowerBitwiseAluOp(si *ssa.Instruction) {
+	x, y := si.BinaryData()
+	rd := m.compiler.VRegOf(si.Return())
+	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+	rm := m.getOperand_NR(m.compiler.ValueDefinition(y), extModeNone)
+
+	var op aluOp
+	switch si.Opcode() {
+	case ssa.OpcodeBand:
+		op = aluOpAnd
+	case ssa.OpcodeBor:
+		op = aluOpOrr
+	case ssa.OpcodeBxor:
+		op = aluOpEor
+	}
+	alu := m.allocateInstr()
+	alu.asALU(op, operandNR(rd), rn, rm, x.Type().Bits() == 64)
+	m.insert(alu)
+}
+
+// lowerIDiv lowers the integer division. The divisor is checked against zero, and the overflow is checked
+// by the preceding OpcodeExitIfNotZeroWithCode, so this is a single sdiv or udiv.
+func (m *machine) lowerIDiv(x, y, result ssa.Value, signed bool) {
+	rd := m.compiler.VRegOf(result)
+	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+	rm := m.getOperand_NR(m.compiler.ValueDefinition(y), extModeNone)
+
+	op := aluOpUDiv
+	if signed {
+		op = aluOpSDiv
+	}
+	div := m.allocateInstr()
+	div.asALU(op, operandNR(rd), rn, rm, x.Type().Bits() == 64)
+	m.insert(div)
+}
+
+// lowerIRem lowers the integer remainder as `x - (x / y) * y` since arm64 doesn't have the remainder instruction.
+// The divisor is checked against zero by the preceding OpcodeExitIfNotZeroWithCode. The signed remainder of the
+// minimum value divided by -1 is zero as Wasm requires, since sdiv results in the minimum value in that case.
+func (m *machine) lowerIRem(x, y, result ssa.Value, signed bool) {
+	rd := m.compiler.VRegOf(result)
+	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+	rm := m.getOperand_NR(m.compiler.ValueDefinition(y), extModeNone)
+	_64bit := x.Type().Bits() == 64
+
+	op := aluOpUDiv
+	if signed {
+		op = aluOpSDiv
+	}
+	quotient := m.compiler.AllocateVReg(regalloc.RegTypeInt)
+	div := m.allocateInstr()
+	div.asALU(op, operandNR(quotient), rn, rm, _64bit)
+	m.insert(div)
+
+	// rd = rn - quotient * rm
+	msub := m.allocateInstr()
+	msub.asALURRRR(aluOpMSub, operandNR(rd), operandNR(quotient), rm, rn, _64bit)
+	m.insert(msub)
+}
+
+// exitWithCodeEncodingSize returns the size of the instructions emitted by lowerExitWithCode for the code.
+func (m *machine) exitWithCodeEncodingSize(code wazevoapi.ExitCode) int64 {
+	size := int64(exitSequenceSize + 8)
+	if code>>16 != 0 {
+		size += 4 // movk for the upper 16 bits of the code.
+	}
+	if m.fpcr() != 0 {
+		size += 4 // msr fpcr, xzr
+	}
+	return size
+}
 
 // lowerExitWithCode lowers the lowerExitWithCode takes a context pointer as argument.
 func (m *machine) lowerExitWithCode(execCtxVReg regalloc.VReg, code wazevoapi.ExitCode) {
 	loadExitCodeConst := m.allocateInstr()
-	loadExitCodeConst.asMOVZ(tmpRegVReg, uint64(code), 0, true)
+	loadExitCodeConst.asMOVZ(tmpRegVReg, uint64(code)&0xffff, 0, true)
 
 	setExitCode := m.allocateInstr()
 	setExitCode.asStore(operandNR(tmpRegVReg),
@@ -333,49 +699,119 @@ func (m *machine) lowerExitWithCode(execCtxVReg regalloc.VReg, code wazevoapi.Ex
 	exitSeq.asExitSequence(execCtxVReg)
 
 	m.insert(loadExitCodeConst)
+	if upper := uint64(code) >> 16; upper != 0 {
+		// The upper bits hold the operand of the exit, e.g. the offset of the unreachable instruction.
+		m.insertMOVK(tmpRegVReg, upper, 1, true)
+	}
 	m.insert(setExitCode)
+	if m.fpcr() != 0 {
+		// Go code must run with the default FPCR.
+		restoreFPCR := m.allocateInstr()
+		restoreFPCR.asMovToFPCR(xzrVReg)
+		m.insert(restoreFPCR)
+	}
 	m.insert(exitSeq)
 }
 
-func (m *machine) lowerExitIfNotZeroWithCode(execCtxVReg regalloc.VReg, cond ssa.Value, code wazevoapi.ExitCode) {
+// lowerExitWithDynamicCode lowers OpcodeExitWithDynamicCode. This is the same as lowerExitWithCode except that
+// the exit code is stored from the register, and the exit value is stored as well.
+func (m *machine) lowerExitWithDynamicCode(execCtxVReg regalloc.VReg, code, exitValue ssa.Value) {
+	v := m.getOperand_NR(m.compiler.ValueDefinition(exitValue), extModeNone)
+	storeExitValue := m.allocateInstr()
+	storeExitValue.asStore(v,
+		addressMode{
+			kind: addressModeKindRegUnsignedImm12,
+			rn:   execCtxVReg, imm: wazevoapi.ExecutionContextOffsets.ExitValue.I64(),
+		}, 64)
+	m.insert(storeExitValue)
+
+	c := m.getOperand_NR(m.compiler.ValueDefinition(code), extModeNone)
+	setExitCode := m.allocateInstr()
+	setExitCode.asStore(c,
+		addressMode{
+			kind: addressModeKindRegUnsignedImm12,
+			rn:   execCtxVReg, imm: wazevoapi.ExecutionContextOffsets.ExitCodeOffset.I64(),
+		}, 32)
+	m.insert(setExitCode)
+
+	if m.fpcr() != 0 {
+		// Go code must run with the default FPCR.
+		restoreFPCR := m.allocateInstr()
+		restoreFPCR.asMovToFPCR(xzrVReg)
+		m.insert(restoreFPCR)
+	}
+	exitSeq := m.allocateInstr()
+	exitSeq.asExitSequence(execCtxVReg)
+	m.insert(exitSeq)
+}
+
+// lowerExitIfNotZeroWithCode lowers OpcodeExitIfNotZeroWithCode. If exitValue is valid, it is stored into
+// the execution context before exiting. See ssa.Instruction ExitValue.
+func (m *machine) lowerExitIfNotZeroWithCode(execCtxVReg regalloc.VReg, cond, exitValue ssa.Value, code wazevoapi.ExitCode) {
 	condDef := m.compiler.ValueDefinition(cond)
-	if !m.compiler.MatchInstr(condDef, ssa.OpcodeIcmp) {
+	var cc condFlag
+	cmp := m.allocateInstr()
+	switch {
+	case m.compiler.MatchInstr(condDef, ssa.OpcodeIcmp):
+		x, y, c := condDef.Instr.IcmpData()
+		cc = condFlagFromSSAIntegerCmpCond(c)
+
+		if x.Type() != y.Type() {
+			panic("TODO(maybe): support icmp with different types")
+		}
+
+		extMod := extModeOf(x.Type(), c.Signed())
+
+		// First operand must be in pure register form.
+		rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extMod)
+		// Second operand can be in any of Imm12, ER, SR, or NR form supported by the SUBS instructions.
+		rm := m.getOperand_Imm12_ER_SR_NR(m.compiler.ValueDefinition(y), extMod)
+		// subs zr, rn, rm
+		cmp.asALU(
+			aluOpSubS,
+			// We don't need the result, just need to set flags.
+			operandNR(xzrVReg),
+			rn,
+			rm,
+			x.Type().Bits() == 64,
+		)
+	case m.compiler.MatchInstr(condDef, ssa.OpcodeFcmp):
+		// The unordered comparison, i.e. with NaN, doesn't satisfy any condition but the inequality, so the
+		// execution exits unless the condition is FloatCmpCondNotEqual.
+		x, y, c := condDef.Instr.FcmpData()
+		cc = condFlagFromSSAFloatCmpCond(c)
+		rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+		rm := m.getOperand_NR(m.compiler.ValueDefinition(y), extModeNone)
+		cmp.asFpuCmp(rn, rm, x.Type().Bits() == 64)
+	default:
 		// We can have general case just like cachine.LowerConditionalBranch.
-		panic("TODO: OpcodeExitIfNotZeroWithCode must come after Icmp at the moment")
+		panic("TODO: OpcodeExitIfNotZeroWithCode must come after Icmp or Fcmp at the moment")
 	}
 	m.compiler.MarkLowered(condDef.Instr)
 
-	cvalInstr := condDef.Instr
-	x, y, c := cvalInstr.IcmpData()
-	cc, signed := condFlagFromSSAIntegerCmpCond(c), c.Signed()
-
-	if x.Type() != y.Type() {
-		panic("TODO(maybe): support icmp with different types")
+	exitSize := m.exitWithCodeEncodingSize(code)
+	var storeExitValue *instruction
+	if exitValue.Valid() {
+		// Resolve the operand here, since the constant is materialized at this point rather than in the exit sequence.
+		v := m.getOperand_NR(m.compiler.ValueDefinition(exitValue), extModeNone)
+		storeExitValue = m.allocateInstr()
+		storeExitValue.asStore(v,
+			addressMode{
+				kind: addressModeKindRegUnsignedImm12,
+				rn:   execCtxVReg, imm: wazevoapi.ExecutionContextOffsets.ExitValue.I64(),
+			}, 64)
+		exitSize += 4
 	}
 
-	extMod := extModeOf(x.Type(), signed)
-
-	// First operand must be in pure register form.
-	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extMod)
-	// Second operand can be in any of Imm12, ER, SR, or NR form supported by the SUBS instructions.
-	rm := m.getOperand_Imm12_ER_SR_NR(m.compiler.ValueDefinition(y), extMod)
-
-	alu := m.allocateInstr()
-	// subs zr, rn, rm
-	alu.asALU(
-		aluOpSubS,
-		// We don't need the result, just need to set flags.
-		operandNR(xzrVReg),
-		rn,
-		rm,
-		x.Type().Bits() == 64,
-	)
-	m.insert(alu)
+	m.insert(cmp)
 
 	// We have to skip the entire exit sequence if the condition is false.
 	cbr := m.allocateInstr()
 	cbr.asCondBr(cc.asCond(), invalidLabel, false /* ignored */)
-	cbr.condBrOffsetResolve(exitWithCodeEncodingSize + 4 /* br offset is from the beginning of this instruction */)
+	cbr.condBrOffsetResolve(exitSize + 4 /* br offset is from the beginning of this instruction */)
 	m.insert(cbr)
+	if storeExitValue != nil {
+		m.insert(storeExitValue)
+	}
 	m.lowerExitWithCode(execCtxVReg, code)
 }
@@ -1,6 +1,7 @@
 package arm64
 
 import (
+	stdbits "math/bits"
 	"strings"
 	"testing"
 
@@ -152,7 +153,7 @@ func TestMachine_LowerConditionalBranch(t *testing.T) {
 				return cmpInSameGroupFromParams(true, ssa.IntegerCmpCondInvalid, ssa.FloatCmpCondEqual, ctx, builder, m)
 			},
 			instructions: []string{
-				"fcmp s1, s2",
+				"fcmp d1, d2",
 				"b.ne L1",
 			},
 		},
@@ -162,7 +163,7 @@ func TestMachine_LowerConditionalBranch(t *testing.T) {
 				return cmpInSameGroupFromParams(false, ssa.IntegerCmpCondInvalid, ssa.FloatCmpCondGreaterThan, ctx, builder, m)
 			},
 			instructions: []string{
-				"fcmp s1, s2",
+				"fcmp d1, d2",
 				"b.gt L1",
 			},
 		},
@@ -275,3 +276,122 @@ func TestMachine_InsertMove(t *testing.T) {
 		})
 	}
 }
+
+func TestMachine_lowerRotate(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		left bool
+		typ  ssa.Type
+		exp  string
+	}{
+		{name: "rotl32", left: true, typ: ssa.TypeI32, exp: `
+and w1?, w101?, #0x1f
+lsl w2?, w100?, w1?
+sub w3?, wzr, w1?
+and w4?, w3?, #0x1f
+lsr w5?, w100?, w4?
+orr w102?, w2?, w5?
+`},
+		{name: "rotl64", left: true, typ: ssa.TypeI64, exp: `
+and x1?, x101?, #0x3f
+lsl x2?, x100?, x1?
+sub x3?, xzr, x1?
+and x4?, x3?, #0x3f
+lsr x5?, x100?, x4?
+orr x102?, x2?, x5?
+`},
+		{name: "rotr32", typ: ssa.TypeI32, exp: `
+ror w102?, w100?, w101?
+`},
+		{name: "rotr64", typ: ssa.TypeI64, exp: `
+ror x102?, x100?, x101?
+`},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, b, m := newSetupWithMockContext()
+			x, amount := b.CurrentBlock().AddParam(b, tc.typ), b.CurrentBlock().AddParam(b, tc.typ)
+			rot := b.AllocateInstruction()
+			if tc.left {
+				rot.AsRotl(x, amount)
+			} else {
+				rot.AsRotr(x, amount)
+			}
+			b.InsertInstruction(rot)
+			ctx.vRegMap[x], ctx.vRegMap[amount], ctx.vRegMap[rot.Return()] = intToVReg(100), intToVReg(101), intToVReg(102)
+			ctx.definitions[x] = &backend.SSAValueDefinition{BlkParamVReg: intToVReg(100), BlockParamValue: x}
+			ctx.definitions[amount] = &backend.SSAValueDefinition{BlkParamVReg: intToVReg(101), BlockParamValue: amount}
+			m.LowerInstr(rot)
+			require.Equal(t, tc.exp, "\n"+formatEmittedInstructionsInCurrentBlock(m)+"\n")
+
+			// Run the lowered instructions to make sure that they rotate by zero and by the full width correctly.
+			bits := tc.typ.Bits()
+			for _, n := range []uint64{0, 1, 7, uint64(bits) - 1, uint64(bits), uint64(bits) + 3} {
+				v := uint64(0x0123_4567_89ab_cdef)
+				regs := map[regalloc.VReg]uint64{intToVReg(100): v, intToVReg(101): n}
+				runALUInstructions(m, regs)
+				var exp uint64
+				switch {
+				case bits == 32 && tc.left:
+					exp = uint64(stdbits.RotateLeft32(uint32(v), int(n)))
+				case bits == 32:
+					exp = uint64(stdbits.RotateLeft32(uint32(v), -int(n)))
+				case tc.left:
+					exp = stdbits.RotateLeft64(v, int(n))
+				default:
+					exp = stdbits.RotateLeft64(v, -int(n))
+				}
+				require.Equal(t, exp, regs[intToVReg(102)], "amount %d", n)
+			}
+		})
+	}
+}
+
+// runALUInstructions interprets the ALU instructions emitted in the current block with the given register values,
+// so that the result of the lowering can be verified without running the machine code.
+func runALUInstructions(m *machine, regs map[regalloc.VReg]uint64) {
+	for cur := m.perBlockHead; cur != nil; cur = cur.next {
+		bits := uint64(32)
+		if cur.u3 == 1 {
+			bits = 64
+		}
+		read := func(r regalloc.VReg) uint64 {
+			if r == xzrVReg {
+				return 0
+			}
+			return regs[r]
+		}
+		rn := read(cur.rn.nr())
+		var rd uint64
+		switch cur.kind {
+		case aluRRBitmaskImm:
+			rd = rn & cur.u2
+		case aluRRR:
+			rm := read(cur.rm.nr())
+			switch aluOp(cur.u1) {
+			case aluOpSub:
+				rd = rn - rm
+			case aluOpOrr:
+				rd = rn | rm
+			case aluOpLsl:
+				rd = rn << (rm % bits)
+			case aluOpLsr:
+				rd = (rn & (1<<bits - 1)) >> (rm % bits)
+			case aluOpRotR:
+				if bits == 32 {
+					rd = uint64(stdbits.RotateLeft32(uint32(rn), -int(rm%bits)))
+				} else {
+					rd = stdbits.RotateLeft64(rn, -int(rm%bits))
+				}
+			default:
+				panic(cur.String())
+			}
+		default:
+			panic(cur.String())
+		}
+		if bits == 32 {
+			rd = uint64(uint32(rd))
+		}
+		regs[cur.rd.nr()] = rd
+	}
+}
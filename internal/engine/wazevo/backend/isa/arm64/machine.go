@@ -81,6 +81,10 @@ type (
 
 		maxRequiredStackSizeForCalls int64
 		stackBoundsCheckDisabled     bool
+		// flushDenormalsToZero is true if the compiled code runs with FPCR.FZ set. See EnableFlushDenormalsToZero.
+		flushDenormalsToZero bool
+		// roundingMode is FPCR.RMode the compiled code runs with. See SetRoundingMode.
+		roundingMode backend.RoundingMode
 	}
 
 	addend32 struct {
@@ -125,11 +129,20 @@ func (m *machine) Reset() {
 	m.instrPool.Reset()
 	m.currentSSABlk = nil
 	m.nextLabel = invalidLabel
+	// The labels are allocated from scratch for the next function, so the ones of the blocks must be forgotten.
+	for i := range m.ssaBlockIDToLabels {
+		m.ssaBlockIDToLabels[i] = invalidLabel
+	}
 	m.pendingInstructions = m.pendingInstructions[:0]
 	for _, v := range m.labelPositions {
 		v.begin, v.end = nil, nil
 	}
 	m.clobberedRegs = m.clobberedRegs[:0]
+	for id := range m.spillSlots {
+		delete(m.spillSlots, id)
+	}
+	m.spillSlotSize = 0
+	m.maxRequiredStackSizeForCalls = 0
 	m.orderedLabels = m.orderedLabels[:0]
 	m.regAllocFn.reset()
 	m.unresolvedAddressModes = m.unresolvedAddressModes[:0]
@@ -145,6 +158,64 @@ func (m *machine) DisableStackCheck() {
 	m.stackBoundsCheckDisabled = true
 }
 
+// EnableFlushDenormalsToZero implements backend.Machine EnableFlushDenormalsToZero.
+//
+// FPCR.FZ is set when entering the compiled code from Go, and cleared whenever returning to Go since
+// the Go runtime assumes the default FPCR, i.e. all zero.
+func (m *machine) EnableFlushDenormalsToZero() {
+	m.flushDenormalsToZero = true
+}
+
+// SetRoundingMode implements backend.Machine SetRoundingMode.
+//
+// Same as EnableFlushDenormalsToZero, FPCR.RMode is set when entering the compiled code from Go, and cleared
+// whenever returning to Go. All the floating point instructions emitted for Wasm use FPCR.RMode except for
+// the ones with the explicit rounding such as fcvtzs for the truncations, so they follow the rounding mode.
+func (m *machine) SetRoundingMode(mode backend.RoundingMode) {
+	m.roundingMode = mode
+}
+
+const (
+	// fpcrFZ is the FZ (flush-to-zero) bit of FPCR.
+	fpcrFZ = 1 << 24
+	// fpcrRModeShift is the bit offset of the RMode (rounding mode) field of FPCR.
+	fpcrRModeShift = 22
+)
+
+// fpcr returns the FPCR value the compiled code runs with, which is zero unless the compiled code needs
+// the non-default FPCR.
+func (m *machine) fpcr() uint64 {
+	fpcr := uint64(m.roundingMode) << fpcrRModeShift
+	if m.flushDenormalsToZero {
+		fpcr |= fpcrFZ
+	}
+	return fpcr
+}
+
+// setFPCR links the instructions to set FPCR to m.fpcr after `cur`, e.g. for FPCR.FZ:
+//
+//	movz tmp, #0x100, lsl #16
+//	msr fpcr, tmp
+//
+// Note: all the fields set by the machine are in the bits 16-31, so the single movz is enough.
+func (m *machine) setFPCR(cur *instruction, alloc func() *instruction) *instruction {
+	movz := alloc()
+	movz.asMOVZ(tmpRegVReg, m.fpcr()>>16, 1, true)
+	cur = linkInstr(cur, movz)
+	msr := alloc()
+	msr.asMovToFPCR(tmpRegVReg)
+	return linkInstr(cur, msr)
+}
+
+// clearFPCR links the instruction to restore the default FPCR after `cur`:
+//
+//	msr fpcr, xzr
+func (m *machine) clearFPCR(cur *instruction, alloc func() *instruction) *instruction {
+	msr := alloc()
+	msr.asMovToFPCR(xzrVReg)
+	return linkInstr(cur, msr)
+}
+
 // ABI implements backend.Machine.
 func (m *machine) ABI() backend.FunctionABI {
 	return m.currentABI
@@ -162,8 +233,8 @@ func (m *machine) SetCompiler(ctx backend.Compiler) {
 }
 
 // StartLoweringFunction implements backend.Machine.
-func (m *machine) StartLoweringFunction(n int) {
-	if len(m.ssaBlockIDToLabels) <= n {
+func (m *machine) StartLoweringFunction(maxBlockID ssa.BasicBlockID) {
+	if n := int(maxBlockID); len(m.ssaBlockIDToLabels) <= n {
 		// Eagerly allocate labels for the blocks since the underlying slice will be used for the next iteration.
 		m.ssaBlockIDToLabels = append(m.ssaBlockIDToLabels, make([]label, n+1)...)
 	}
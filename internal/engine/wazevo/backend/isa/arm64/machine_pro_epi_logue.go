@@ -249,13 +249,17 @@ func (m *machine) setupEpilogueAfter(cur *instruction) {
 // saveRequiredRegs is the set of registers that must be saved/restored during growing stack when there's insufficient
 // stack space left. Basically this is the combination of CalleeSavedRegisters plus argument registers execpt for x0,
 // which always points to the execution context whenever the native code is entered from Go.
-var saveRequiredRegs = []regalloc.VReg{
+var saveRequiredRegs = [...]regalloc.VReg{
 	x1VReg, x2VReg, x3VReg, x4VReg, x5VReg, x6VReg, x7VReg,
 	x18VReg, x19VReg, x20VReg, x21VReg, x22VReg, x23VReg, x24VReg, x25VReg, x26VReg, x28VReg, lrVReg,
 	v0VReg, v1VReg, v2VReg, v3VReg, v4VReg, v5VReg, v6VReg, v7VReg,
 	v18VReg, v19VReg, v20VReg, v21VReg, v22VReg, v23VReg, v24VReg, v25VReg, v26VReg, v27VReg, v28VReg, v29VReg, v30VReg, v31VReg,
 }
 
+// Each of saveRequiredRegs is saved in its own slot of the execution context's savedRegisters, so this fails to compile
+// if they don't fit, instead of silently overwriting the following fields of the execution context.
+var _ [wazevoapi.SavedRegistersSlots - len(saveRequiredRegs)]struct{}
+
 // insertStackBoundsCheck will insert the instructions after `cur` to check the
 // stack bounds, and if there's no sufficient spaces required for the function,
 // exit the execution and try growing it in Go world.
@@ -405,7 +409,11 @@ func (m *machine) insertStackBoundsCheck(requiredStackSize int64, cur *instructi
 
 	// Read the return address into tmp, and store it in the execution context.
 	adr := m.allocateInstrAfterLowering()
-	adr.asAdr(tmpRegVReg, exitSequenceSize+8)
+	returnAddrOffset := int64(exitSequenceSize + 8)
+	if m.fpcr() != 0 {
+		returnAddrOffset += 4 // msr fpcr, xzr
+	}
+	adr.asAdr(tmpRegVReg, returnAddrOffset)
 	adr.prev = cur
 	cur.next = adr
 	cur = adr
@@ -420,6 +428,10 @@ func (m *machine) insertStackBoundsCheck(requiredStackSize int64, cur *instructi
 	cur.next = storeReturnAddr
 	cur = storeReturnAddr
 
+	if m.fpcr() != 0 {
+		cur = m.clearFPCR(cur, m.allocateInstrAfterLowering)
+	}
+
 	// Exit the execution.
 	trapSeq := m.allocateInstrAfterLowering()
 	trapSeq.asExitSequence(x0VReg)
@@ -427,6 +439,11 @@ func (m *machine) insertStackBoundsCheck(requiredStackSize int64, cur *instructi
 	cur.next = trapSeq
 	cur = trapSeq
 
+	if m.fpcr() != 0 {
+		// The Go code resumes the execution here, so FPCR must be set again.
+		cur = m.setFPCR(cur, m.allocateInstrAfterLowering)
+	}
+
 	// After the exit, restore the saved registers.
 	offset = wazevoapi.ExecutionContextOffsets.SavedRegistersBegin.I64()
 	for _, v := range saveRequiredRegs {
@@ -378,3 +378,43 @@ func TestMachine_insertStackBoundsCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestMachine_insertStackBoundsCheck_flushDenormalsToZero(t *testing.T) {
+	_, _, m := newSetupWithMockContext()
+	m.EnableFlushDenormalsToZero()
+	m.rootInstr = m.allocateInstrAfterLowering()
+	m.rootInstr.asNop0()
+	m.insertStackBoundsCheck(0x10, m.rootInstr)
+	// FPCR is restored to the default before exiting, and FZ is set again after resuming at the return address.
+	require.Contains(t, m.Format(), `
+	adr x27, #0x20
+	str x27, [x0, #0x30]
+	msr fpcr, xzr
+	exit_sequence w0
+	movz x27, #0x100, LSL 16
+	msr fpcr, x27
+	ldr x1, [x0, #0x50]
+`)
+}
+
+func Test_saveRequiredRegs(t *testing.T) {
+	saved := map[regalloc.VReg]struct{}{}
+	for _, v := range saveRequiredRegs {
+		saved[v] = struct{}{}
+	}
+	require.Equal(t, len(saveRequiredRegs), len(saved), "duplicated registers")
+
+	// All the callee saved registers including the vector ones must survive the stack growth, as well as the argument
+	// registers except for x0 holding the execution context.
+	for r := range regInfo.CalleeSavedRegisters {
+		_, ok := saved[regInfo.RealRegToVReg[r]]
+		require.True(t, ok, regNames[r])
+	}
+	for _, v := range []regalloc.VReg{
+		x1VReg, x2VReg, x3VReg, x4VReg, x5VReg, x6VReg, x7VReg, lrVReg,
+		v0VReg, v1VReg, v2VReg, v3VReg, v4VReg, v5VReg, v6VReg, v7VReg,
+	} {
+		_, ok := saved[v]
+		require.True(t, ok, regNames[v.RealReg()])
+	}
+}
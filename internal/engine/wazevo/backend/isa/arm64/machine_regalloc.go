@@ -185,7 +185,7 @@ func (r *regAllocBlockImpl) ID() int {
 func (r *regAllocBlockImpl) Preds() []regalloc.Block {
 	sb := r.sb
 	r.f.predsSlice = r.f.predsSlice[:0]
-	for pred := sb.NextPredIterator(); pred != nil; pred = sb.NextPredIterator() {
+	for pred := sb.BeginPredIterator(); pred != nil; pred = sb.NextPredIterator() {
 		l := r.f.m.ssaBlockIDToLabels[pred.ID()]
 		index := r.f.labelToRegAllocBlockIndex[l]
 		r.f.predsSlice = append(r.f.predsSlice, &r.f.reversePostOrderBlocks[index])
@@ -36,6 +36,29 @@ func TestRegAllocFunctionImpl_addBlock(t *testing.T) {
 	require.Equal(t, sb2, rb2.sb)
 }
 
+func TestRegAllocBlockImpl_Preds(t *testing.T) {
+	ssab := ssa.NewBuilder()
+	sb1, sb2 := ssab.AllocateBasicBlock(), ssab.AllocateBasicBlock()
+	ssab.SetCurrentBlock(sb1)
+	jmp := ssab.AllocateInstruction()
+	jmp.AsJump(nil, sb2)
+	ssab.InsertInstruction(jmp)
+
+	m := &machine{ssaBlockIDToLabels: []label{10, 20}}
+	f := &regAllocFunctionImpl{m: m, labelToRegAllocBlockIndex: map[label]int{}}
+	f.addBlock(sb1, label(10), &labelPosition{})
+	f.addBlock(sb2, label(20), &labelPosition{})
+
+	rb2 := &f.reversePostOrderBlocks[1]
+	// Preds can be called multiple times on the same block.
+	for i := 0; i < 2; i++ {
+		preds := rb2.Preds()
+		require.Equal(t, 1, len(preds))
+		require.Equal(t, &f.reversePostOrderBlocks[0], preds[0])
+	}
+	require.Equal(t, 0, len(f.reversePostOrderBlocks[0].Preds()))
+}
+
 func TestRegAllocFunctionImpl_PostOrderBlockIterator(t *testing.T) {
 	f := &regAllocFunctionImpl{reversePostOrderBlocks: []regAllocBlockImpl{{}, {}, {}}}
 	blk := f.PostOrderBlockIteratorBegin()
@@ -253,6 +253,8 @@ func formatVRegSized(r regalloc.VReg, size byte) (ret string) {
 			}
 		case 'v':
 			switch size {
+			case 8:
+				ret = strings.Replace(ret, "v", "b", 1)
 			case 32:
 				ret = strings.Replace(ret, "v", "s", 1)
 			case 64:
@@ -276,6 +278,8 @@ func formatVRegSized(r regalloc.VReg, size byte) (ret string) {
 			}
 		case regalloc.RegTypeFloat:
 			switch size {
+			case 8:
+				ret = fmt.Sprintf("b%d?", r.ID())
 			case 32:
 				ret = fmt.Sprintf("s%d?", r.ID())
 			case 64:
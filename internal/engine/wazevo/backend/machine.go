@@ -10,6 +10,12 @@ type (
 	Machine interface {
 		DisableStackCheck()
 
+		// EnableFlushDenormalsToZero makes the compiled code flush denormal floating point operands and results to zero.
+		EnableFlushDenormalsToZero()
+
+		// SetRoundingMode sets the rounding mode of the floating point arithmetic and conversions in the compiled code.
+		SetRoundingMode(mode RoundingMode)
+
 		// RegisterInfo returns the set of registers that can be used for register allocation.
 		// This is only called once, and the result is shared across all compilations.
 		RegisterInfo() *regalloc.RegisterInfo
@@ -25,8 +31,8 @@ type (
 		SetCompiler(Compiler)
 
 		// StartLoweringFunction is called when the lowering of the given function is started.
-		// numBlks is the number of ssa.BasicBlock(s) existing in the function.
-		StartLoweringFunction(numBlks int)
+		// maxBlockID is the upper bound (exclusive) of ssa.BasicBlockID in the function. See ssa.Builder BlockIDMax.
+		StartLoweringFunction(maxBlockID ssa.BasicBlockID)
 
 		// StartBlock is called when the compilation of the given block is started.
 		// The order of this being called is the reverse post order of the ssa.BasicBlock(s) as we iterate with
@@ -96,3 +102,19 @@ type (
 		Encode()
 	}
 )
+
+// RoundingMode is the rounding mode of the floating point arithmetic and conversions in the compiled code.
+// The values are the same as the RMode field of the arm64 FPCR.
+type RoundingMode byte
+
+const (
+	// RoundingModeNearest rounds to the nearest value with the ties to even. This is the default, and the only
+	// rounding mode conforming to the spec.
+	RoundingModeNearest RoundingMode = iota
+	// RoundingModeTowardPositive rounds toward positive infinity.
+	RoundingModeTowardPositive
+	// RoundingModeTowardNegative rounds toward negative infinity.
+	RoundingModeTowardNegative
+	// RoundingModeTowardZero rounds toward zero, i.e. truncates.
+	RoundingModeTowardZero
+)
@@ -8,7 +8,7 @@ import (
 // mockMachine implements Machine for testing.
 type mockMachine struct {
 	abi                    mockABI
-	startLoweringFunction  func(numBlks int)
+	startLoweringFunction  func(maxBlockID ssa.BasicBlockID)
 	startBlock             func(block ssa.BasicBlock)
 	lowerSingleBranch      func(b *ssa.Instruction)
 	lowerConditionalBranch func(b *ssa.Instruction)
@@ -69,8 +69,8 @@ func (m mockMachine) ABI() FunctionABI { return m.abi }
 func (m mockMachine) SetCompiler(Compiler) {}
 
 // StartLoweringFunction implements Machine.StartLoweringFunction.
-func (m mockMachine) StartLoweringFunction(numBlks int) {
-	m.startLoweringFunction(numBlks)
+func (m mockMachine) StartLoweringFunction(maxBlockID ssa.BasicBlockID) {
+	m.startLoweringFunction(maxBlockID)
 }
 
 // StartBlock implements Machine.StartBlock.
@@ -129,6 +129,12 @@ func (m mockMachine) Format() string {
 // DisableStackCheck implements Machine.DisableStackCheck.
 func (m mockMachine) DisableStackCheck() {}
 
+// EnableFlushDenormalsToZero implements Machine.EnableFlushDenormalsToZero.
+func (m mockMachine) EnableFlushDenormalsToZero() {}
+
+// SetRoundingMode implements Machine.SetRoundingMode.
+func (m mockMachine) SetRoundingMode(RoundingMode) {}
+
 var _ Machine = (*mockMachine)(nil)
 
 // mockABI implements ABI for testing.
@@ -262,7 +262,10 @@ func (a *Allocator) upAndMarkStack(b Block, v VReg, depth int) {
 	// Now we can safely mark v as a part of live-in
 	info.liveIns[v] = struct{}{}
 	// and climb up the CFG.
-	for _, pred := range b.Preds() {
+	// Note: the returned slice of Preds must not be used across the recursive calls, which might reuse it for
+	// other blocks. See Block.Preds.
+	for i := 0; i < len(b.Preds()); i++ {
+		pred := b.Preds()[i]
 		a.blockInfoAt(pred.ID()).liveOuts[v] = struct{}{}
 		a.upAndMarkStack(pred, v, depth+1)
 	}
@@ -440,6 +443,7 @@ func (a *Allocator) allocateBlockInfo(blockID int) {
 	}
 	info := &a.blockInfos[blockID]
 	info.init()
+	info.reset()
 }
 
 func (a *Allocator) blockInfoAt(blockID int) (info *blockInfo) {
@@ -509,6 +513,31 @@ func (i *blockInfo) init() {
 	}
 }
 
+// reset clears the information of the block, which is reused across the functions for the blocks of the same ID.
+func (i *blockInfo) reset() {
+	for v := range i.liveOuts {
+		delete(i.liveOuts, v)
+	}
+	for v := range i.liveIns {
+		delete(i.liveIns, v)
+	}
+	for v := range i.defs {
+		delete(i.defs, v)
+	}
+	for v := range i.lastUses {
+		delete(i.lastUses, v)
+	}
+	for v := range i.kills {
+		delete(i.kills, v)
+	}
+	for v := range i.realRegUses {
+		delete(i.realRegUses, v)
+	}
+	for v := range i.realRegDefs {
+		delete(i.realRegDefs, v)
+	}
+}
+
 func (i *blockInfo) addRealRegUsage(v VReg, pc programCounter) {
 	defs := i.realRegDefs[v]
 	if len(defs) == 0 {
@@ -2,13 +2,17 @@ package wazevo
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
 	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmdebug"
 	"github.com/tetratelabs/wazero/internal/wasmruntime"
 )
 
@@ -16,12 +20,14 @@ type (
 	// callEngine implements api.Function.
 	callEngine struct {
 		internalapi.WazeroOnly
+		// stack is the stack of the ongoing call, or the last one when it's taken from compiledModule.stackArena.
 		stack []byte
 		// stackTop is the pointer to the *aligned* top of the stack. This must be updated
 		// whenever the stack is changed. This is passed to the assembly function
 		// at the very beginning of api.Function Call/CallWithStack.
 		stackTop uintptr
-		// executable is the pointer to the executable code for this function.
+		// executable is the pointer to the executable code for this function. This is nil until the first call
+		// if the module is compiled lazily.
 		executable *byte
 		// parent is the *moduleEngine from which this callEngine is created.
 		parent *moduleEngine
@@ -29,10 +35,31 @@ type (
 		indexInModule wasm.Index
 		// sizeOfParamResultSlice is the size of the parameter/result slice.
 		sizeOfParamResultSlice int
+		// numberOfResults is the number of results of the function.
+		numberOfResults int
+		// paramResultSlice is the parameter/result slice reused across the invocations of Call.
+		// This is not used by CallWithStack where the slice is given by the caller.
+		paramResultSlice []uint64
 		// execCtx holds various information to be read/written by assembly functions.
 		execCtx executionContext
 		// execCtxPtr holds the pointer to the executionContext which doesn't change after callEngine is created.
 		execCtxPtr uintptr
+		// selfRecursive is true if the function directly calls itself, in which case the stack starts with
+		// recursiveInitialStackSize. See compiledFunctionOffset.selfRecursive.
+		selfRecursive bool
+		// growStackCount is the number of times the stack has been grown by growStack.
+		growStackCount int
+		// metrics is the breakdown of the last call, and only recorded when engine.hostCallMetrics is enabled.
+		// See LastCallMetrics.
+		metrics CallMetrics
+	}
+
+	// CallMetrics is the breakdown of the time spent in a call of api.Function Call or CallWithStack.
+	CallMetrics struct {
+		// Total is the wall time of the whole call.
+		Total time.Duration
+		// TODO: record the time spent in the Go functions of host modules once the compiled code can call them.
+		// See compileHostModule.
 	}
 
 	// executionContext is the struct to be read/written by assembly functions.
@@ -58,23 +85,48 @@ type (
 		// _ is needed to align .savedRegisters at 16 bytes boundary.
 		_ uint64
 		// savedRegisters is the opaque spaces for save/restore registers.
-		// We want to align 16 bytes for each register, so we use [64][2]uint64.
-		savedRegisters [64][2]uint64
+		// We want to align 16 bytes for each register, so we use [wazevoapi.SavedRegistersSlots][2]uint64.
+		savedRegisters [wazevoapi.SavedRegistersSlots][2]uint64
+		// exitValue holds the operand of the exit only known at runtime, e.g. the end of the memory access range
+		// for wazevoapi.ExitCodeMemoryOutOfBounds. See wazevoapi.ExitCodeMemoryOutOfBoundsWithSize.
+		exitValue uint64
 	}
 )
 
 var initialStackSize uint64 = 512
 
+// recursiveInitialStackSize is the initial stack size of the self-recursive functions. The deep recursion would
+// otherwise grow the stack from initialStackSize many times, copying the whole stack each time.
+var recursiveInitialStackSize uint64 = 64 << 10
+
 func (c *callEngine) init() {
+	c.paramResultSlice = make([]uint64, c.sizeOfParamResultSlice)
+	c.initStack()
+	c.execCtxPtr = uintptr(unsafe.Pointer(&c.execCtx))
+}
+
+// initStack allocates the stack whose size depends on whether the function is self-recursive.
+// This is no-op when the stack is taken from compiledModule.stackArena on each call.
+func (c *callEngine) initStack() {
+	if c.parent.parent.stackArena != nil {
+		return
+	}
 	stackSize := initialStackSize
+	if c.selfRecursive {
+		stackSize = recursiveInitialStackSize
+	}
 	if c.sizeOfParamResultSlice > int(stackSize) {
 		stackSize = uint64(c.sizeOfParamResultSlice)
 	}
 
-	c.stack = make([]byte, stackSize)
-	c.stackTop = alignedStackTop(c.stack)
-	c.execCtx.stackBottomPtr = &c.stack[0]
-	c.execCtxPtr = uintptr(unsafe.Pointer(&c.execCtx))
+	c.setStack(make([]byte, stackSize))
+}
+
+// setStack sets the stack to the given one.
+func (c *callEngine) setStack(s []byte) {
+	c.stack = s
+	c.stackTop = alignedStackTop(s)
+	c.execCtx.stackBottomPtr = &s[0]
 }
 
 // alignedStackTop returns 16-bytes aligned stack top of given stack.
@@ -86,46 +138,167 @@ func alignedStackTop(s []byte) uintptr {
 
 // Definition implements api.Function.
 func (c *callEngine) Definition() api.FunctionDefinition {
-	return &c.parent.module.Source.FunctionDefinitionSection[c.indexInModule]
+	return c.parent.module.Source.FunctionDefinition(c.indexInModule)
 }
 
 // Call implements api.Function.
 func (c *callEngine) Call(ctx context.Context, params ...uint64) ([]uint64, error) {
-	paramResultSlice := make([]uint64, c.sizeOfParamResultSlice)
-	copy(paramResultSlice, params)
+	paramResultSlice := c.paramResultSlice
+	n := copy(paramResultSlice, params)
+	// Clears the rest so that the values of the previous invocation are not visible to this one.
+	for i := n; i < len(paramResultSlice); i++ {
+		paramResultSlice[i] = 0
+	}
 	if err := c.CallWithStack(ctx, paramResultSlice); err != nil {
 		return nil, err
 	}
-	return paramResultSlice, nil
+	if c.numberOfResults == 0 {
+		return nil, nil
+	}
+	// The results are returned without copying so that Call doesn't allocate, hence they are overwritten by the next
+	// invocation as documented on api.Function. The capacity is limited so that appending to the results doesn't write
+	// into paramResultSlice, and the caller won't see the params in the tail when len(params) > len(results).
+	return paramResultSlice[:c.numberOfResults:c.numberOfResults], nil
 }
 
 // CallWithStack implements api.Function.
 func (c *callEngine) CallWithStack(ctx context.Context, paramResultStack []uint64) error {
+	if c.parent.parent.hostCallMetrics {
+		c.metrics = CallMetrics{}
+		start := time.Now()
+		defer func() {
+			c.metrics.Total = time.Since(start)
+		}()
+	}
+	err := c.callWithStack(ctx, paramResultStack)
+	if err != nil {
+		c.resetExecutionContext()
+	}
+	return err
+}
+
+// resetExecutionContext clears the states left in the execution context by the call returning an error, e.g. the exit
+// code of the trap, so that the next call on this callEngine starts from a clean state. Notably, the machine code
+// doesn't write wazevoapi.ExitCodeOK on the normal return, so the stale exit code would fail the next call otherwise.
+func (c *callEngine) resetExecutionContext() {
+	// stackBottomPtr belongs to the stack rather than the call, so it's kept.
+	c.execCtx = executionContext{stackBottomPtr: c.execCtx.stackBottomPtr}
+}
+
+// callWithStack implements CallWithStack.
+func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint64) error {
+	// Note: paramResultPtr is nil for functions without params and results, which is fine since the Go entry preamble
+	// never dereferences it in that case.
 	var paramResultPtr *uint64
 	if len(paramResultStack) > 0 {
 		paramResultPtr = &paramResultStack[0]
 	}
 
+	if c.executable == nil {
+		if err := c.compileLazily(); err != nil {
+			return err
+		}
+	}
+
+	if arena := c.parent.parent.stackArena; arena != nil {
+		c.setStack(arena.acquire())
+		defer arena.release(c.stack)
+	}
+
 	entrypoint(c.executable, c.execCtxPtr, c.parent.opaquePtr, paramResultPtr, c.stackTop)
 	for {
-		switch c.execCtx.exitCode {
+		switch ec := c.execCtx.exitCode; ec & wazevoapi.ExitCodeMask {
 		case wazevoapi.ExitCodeOK:
 			return nil
 		case wazevoapi.ExitCodeGrowStack:
 			newsp, err := c.growStack()
 			if err != nil {
-				return err
+				return c.trapError(err, nil)
 			}
 			c.execCtx.exitCode = wazevoapi.ExitCodeOK
 			afterStackGrowEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr, newsp)
 		case wazevoapi.ExitCodeUnreachable:
-			return wasmruntime.ErrRuntimeUnreachable
+			var sources []string
+			if offset, ok := wazevoapi.UnreachableOffsetFromExitCode(ec); ok {
+				sources = []string{fmt.Sprintf("unreachable at offset %#x in the function body", offset)}
+			}
+			return c.handleTrap(ctx, ec, c.trapError(wasmruntime.ErrRuntimeUnreachable, sources))
 		case wazevoapi.ExitCodeMemoryOutOfBounds:
-			return wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess
+			var sources []string
+			if size, ok := wazevoapi.MemoryAccessSizeFromExitCode(ec); ok {
+				source := fmt.Sprintf("%d-byte memory access at address %#x", size, c.execCtx.exitValue-uint64(size))
+				if wazevoapi.BoundsCheckExtendedFromExitCode(ec) {
+					// The earlier accesses checked together with this one might be out of bounds as well.
+					source += " or an earlier access checked together with it"
+				}
+				sources = []string{source}
+			}
+			return c.handleTrap(ctx, ec, c.trapError(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess, sources))
+		case wazevoapi.ExitCodeUnalignedMemoryAccess:
+			return c.handleTrap(ctx, ec, c.trapError(wasmruntime.ErrRuntimeUnalignedMemoryAccess, nil))
+		case wazevoapi.ExitCodeTableOutOfBounds:
+			return c.handleTrap(ctx, ec, c.trapError(wasmruntime.ErrRuntimeInvalidTableAccess, nil))
+		case wazevoapi.ExitCodeIntegerDivisionByZero:
+			return c.handleTrap(ctx, ec, c.trapError(wasmruntime.ErrRuntimeIntegerDivideByZero, nil))
+		case wazevoapi.ExitCodeIntegerOverflow:
+			return c.handleTrap(ctx, ec, c.trapError(wasmruntime.ErrRuntimeIntegerOverflow, nil))
+		case wazevoapi.ExitCodeInvalidConversionToInteger:
+			return c.handleTrap(ctx, ec, c.trapError(wasmruntime.ErrRuntimeInvalidConversionToInteger, nil))
 		default:
-			panic("BUG")
+			// This must not happen unless the machine code is miscompiled or the execution context is corrupted,
+			// but we return the error rather than crashing the host process.
+			return fmt.Errorf("invalid exit code: %#x", uint32(ec))
+		}
+	}
+}
+
+// LastCallMetrics returns the breakdown of the last call of this function, including the one returning an error.
+// This is zero unless engine.hostCallMetrics is enabled.
+func (c *callEngine) LastCallMetrics() CallMetrics {
+	return c.metrics
+}
+
+// trapError returns the error for the trap `err` with the wasm stack trace, where the frame is named after the
+// function in the name section, or its index if it has no name. See wasm.FunctionDefinition DebugName.
+// sources are the lines describing where the trap happened, if known.
+//
+// Note: the frames are not unwound from the machine code stack, so the trace only has the frame of the called
+// function even if the trap happened in one of its callees.
+func (c *callEngine) trapError(err error, sources []string) error {
+	def := c.Definition()
+	builder := wasmdebug.NewErrorBuilder()
+	builder.AddFrame(def.DebugName(), def.ParamTypes(), def.ResultTypes(), sources)
+	return builder.FromRecovered(err)
+}
+
+// handleTrap returns the error of the trap with the exit code `ec`, which is the standard error `err` unless the
+// TrapHandler registered for ec returns another one. See moduleEngine.SetTrapHandler.
+func (c *callEngine) handleTrap(ctx context.Context, ec wazevoapi.ExitCode, err error) error {
+	code := ec & wazevoapi.ExitCodeMask
+	if h, ok := c.parent.trapHandlers[code]; ok {
+		if handled := h(ctx, code, err); handled != nil {
+			return handled
 		}
 	}
+	return err
+}
+
+// compileLazily resolves the executable of the function, which is compiled at this point unless it has been
+// compiled as a callee of another function. See lazyCompiledFunctions.
+func (c *callEngine) compileLazily() error {
+	localIndex := c.indexInModule - c.parent.module.Source.ImportFunctionCount
+	unit, err := c.parent.parent.unitOf(localIndex)
+	if err != nil {
+		return err
+	}
+	offset := &unit.functionOffsets[localIndex]
+	c.executable = &unit.executable[offset.offset]
+	if offset.selfRecursive && !c.selfRecursive {
+		// The recursion is only known once compiled, so the stack is allocated again before the first call.
+		c.selfRecursive = true
+		c.initStack()
+	}
+	return nil
 }
 
 const callStackCeiling = uintptr(5000000) // in uint64 (8 bytes) == 40000000 bytes in total == 40mb.
@@ -133,7 +306,8 @@ const callStackCeiling = uintptr(5000000) // in uint64 (8 bytes) == 40000000 byt
 // growStack grows the stack, and returns the new stack pointer.
 func (c *callEngine) growStack() (newSP uintptr, err error) {
 	currentLen := uintptr(len(c.stack))
-	if callStackCeiling < currentLen {
+	// The stacks of stackArena are never moved, so they overflow instead of growing.
+	if callStackCeiling < currentLen || c.parent.parent.stackArena != nil {
 		err = wasmruntime.ErrRuntimeStackOverflow
 		return
 	}
@@ -161,8 +335,37 @@ func (c *callEngine) growStack() (newSP uintptr, err error) {
 	}
 	copy(newStackAligned, prevStackAligned)
 
-	c.stack = newStack
-	c.stackTop = newTop
-	c.execCtx.stackBottomPtr = &newStack[0]
+	c.setStack(newStack)
+	c.growStackCount++
 	return
 }
+
+// deterministicStackSize is the size of the stacks of stackArena, which is about the largest size growStack grows to.
+const deterministicStackSize = 2 * callStackCeiling
+
+// stackArena is the pool of the fixed-size stacks which the functions are called on when engine.deterministicStack
+// is enabled. The stack released last is acquired first, so the calls which don't overlap run on the same stack at
+// the same address regardless of the modules and functions, while the concurrent or nested calls take different ones.
+type stackArena struct {
+	mux  sync.Mutex
+	free [][]byte
+}
+
+// acquire returns the stack which is not used by any other call until passed to release.
+func (a *stackArena) acquire() []byte {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if n := len(a.free); n > 0 {
+		s := a.free[n-1]
+		a.free = a.free[:n-1]
+		return s
+	}
+	return make([]byte, deterministicStackSize)
+}
+
+// release returns the stack taken by acquire to the arena.
+func (a *stackArena) release(s []byte) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	a.free = append(a.free, s)
+}
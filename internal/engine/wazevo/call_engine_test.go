@@ -6,6 +6,8 @@ import (
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 )
 
 func TestCallEngine_init(t *testing.T) {
@@ -13,6 +15,12 @@ func TestCallEngine_init(t *testing.T) {
 	c.init()
 	require.True(t, c.stackTop%16 == 0)
 	require.Equal(t, &c.stack[0], c.execCtx.stackBottomPtr)
+	require.Equal(t, initialStackSize, uint64(len(c.stack)))
+
+	c = &callEngine{selfRecursive: true}
+	c.init()
+	require.Equal(t, recursiveInitialStackSize, uint64(len(c.stack)))
+	require.Equal(t, &c.stack[0], c.execCtx.stackBottomPtr)
 }
 
 func TestCallEngine_growStack(t *testing.T) {
@@ -38,6 +46,7 @@ func TestCallEngine_growStack(t *testing.T) {
 		newSP, err := c.growStack()
 		require.NoError(t, err)
 		require.Equal(t, 160+32*2, len(c.stack))
+		require.Equal(t, 1, c.growStackCount)
 
 		require.True(t, c.stackTop%16 == 0)
 		require.Equal(t, &c.stack[0], c.execCtx.stackBottomPtr)
@@ -54,3 +63,36 @@ func TestCallEngine_growStack(t *testing.T) {
 		require.True(t, newSP <= c.stackTop)
 	})
 }
+
+func TestCallEngine_trapError(t *testing.T) {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}, {Body: []byte{wasm.OpcodeEnd}}},
+		NameSection: &wasm.NameSection{
+			ModuleName:    "named",
+			FunctionNames: wasm.NameMap{{Index: 1, Name: "trap"}},
+		},
+	}
+	parent := &moduleEngine{module: &wasm.ModuleInstance{Source: m}}
+
+	for _, tc := range []struct {
+		index  wasm.Index
+		expErr string
+	}{
+		// Falls back to the index without the name in the name section.
+		{index: 0, expErr: "wasm error: unreachable\nwasm stack trace:\n\tnamed.$0(i32)"},
+		{index: 1, expErr: "wasm error: unreachable\nwasm stack trace:\n\tnamed.trap(i32)"},
+	} {
+		c := &callEngine{parent: parent, indexInModule: tc.index}
+		err := c.trapError(wasmruntime.ErrRuntimeUnreachable, nil)
+		require.EqualError(t, err, tc.expErr)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeUnreachable)
+	}
+
+	t.Run("sources", func(t *testing.T) {
+		c := &callEngine{parent: parent, indexInModule: 1}
+		err := c.trapError(wasmruntime.ErrRuntimeUnreachable, []string{"unreachable at offset 0x3 in the function body"})
+		require.EqualError(t, err, "wasm error: unreachable\nwasm stack trace:\n\tnamed.trap(i32)\n\t\tunreachable at offset 0x3 in the function body")
+	})
+}
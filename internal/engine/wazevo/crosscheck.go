@@ -0,0 +1,114 @@
+package wazevo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/internalapi"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// crossCheckFunction implements api.Function in the cross-check mode enabled by engine.crossCheck.
+//
+// Each call runs the compiled function first, and then the same function in the interpreter with the same params,
+// starting from the same memory contents. The call fails with the error describing the divergence if their results,
+// traps or the resulting memory differ.
+//
+// Note: only the memory is restored before calling the interpreter, so functions mutating the other states of
+// the module instance like globals, as well as growing the memory, are not supported. Neither are the tables, since
+// their references point to the compiled functions which the interpreter cannot call.
+type crossCheckFunction struct {
+	internalapi.WazeroOnly
+	compiled, interpreted api.Function
+	// index is the index of the function in the module.
+	index wasm.Index
+	// paramNum and resultNum are the numbers of the params and results of the function.
+	paramNum, resultNum int
+	// mem is the memory of the module instance, or nil if it has no memory.
+	mem *wasm.MemoryInstance
+}
+
+// Definition implements api.Function.
+func (f *crossCheckFunction) Definition() api.FunctionDefinition {
+	return f.compiled.Definition()
+}
+
+// Call implements api.Function.
+func (f *crossCheckFunction) Call(ctx context.Context, params ...uint64) ([]uint64, error) {
+	size := f.paramNum
+	if f.resultNum > size {
+		size = f.resultNum
+	}
+	stack := make([]uint64, size)
+	copy(stack, params)
+	if err := f.CallWithStack(ctx, stack); err != nil {
+		return nil, err
+	}
+	if f.resultNum == 0 {
+		return nil, nil
+	}
+	return stack[:f.resultNum], nil
+}
+
+// CallWithStack implements api.Function.
+func (f *crossCheckFunction) CallWithStack(ctx context.Context, stack []uint64) error {
+	params := append([]uint64(nil), stack[:f.paramNum]...)
+
+	var memBefore, memCompiled []byte
+	if f.mem != nil {
+		memBefore = append([]byte(nil), f.mem.Buffer...)
+	}
+
+	err := f.compiled.CallWithStack(ctx, stack)
+
+	if f.mem != nil {
+		memCompiled = append([]byte(nil), f.mem.Buffer...)
+		// The interpreter must start from the same memory contents as the compiled function.
+		copy(f.mem.Buffer, memBefore)
+	}
+
+	interpretedStack := make([]uint64, len(stack))
+	copy(interpretedStack, params)
+	interpretedErr := f.interpreted.CallWithStack(ctx, interpretedStack)
+
+	if (err == nil) != (interpretedErr == nil) || (err != nil && !errors.Is(interpretedErr, trapOf(err))) {
+		return f.diverged(params, "compiled code returned the error %v, but the interpreter returned %v", err, interpretedErr)
+	} else if err != nil {
+		return err
+	}
+
+	results, interpretedResults := stack[:f.resultNum], interpretedStack[:f.resultNum]
+	for i := range results {
+		if results[i] != interpretedResults[i] {
+			return f.diverged(params, "compiled code returned the results %#x, but the interpreter returned %#x", results, interpretedResults)
+		}
+	}
+
+	if f.mem != nil && !bytes.Equal(memCompiled, f.mem.Buffer) {
+		offset := 0
+		for offset < len(memCompiled) && offset < len(f.mem.Buffer) && memCompiled[offset] == f.mem.Buffer[offset] {
+			offset++
+		}
+		return f.diverged(params, "the memory differs from the interpreter's at offset %#x", offset)
+	}
+	return nil
+}
+
+// trapOf returns the *wasmruntime.Error wrapped by err with the wasm stack trace, or err as is otherwise, so
+// that traps are compared regardless of the traces which differ between the compiled code and the interpreter.
+func trapOf(err error) error {
+	var trap *wasmruntime.Error
+	if errors.As(err, &trap) {
+		return trap
+	}
+	return err
+}
+
+// diverged returns the error reporting the divergence from the interpreter on the call with the given params.
+func (f *crossCheckFunction) diverged(params []uint64, format string, args ...interface{}) error {
+	return fmt.Errorf("cross-check failed on function[%d] with params %#x: %s", f.index, params, fmt.Sprintf(format, args...))
+}
@@ -40,9 +40,44 @@ func TestE2E(t *testing.T) {
 				{expResults: []uint64{1, 2, uint64(math.Float32bits(32.0)), math.Float64bits(64.0)}},
 			},
 		},
+		{
+			name: "i64_consts", m: testcases.I64Constants.Module,
+			calls: []callCase{
+				{expResults: []uint64{0x8000000000000000, 0x7fffffffffffffff, 0xffffffffffffffff}},
+			},
+		},
 		{
 			name: "unreachable", m: testcases.Unreachable.Module,
-			calls: []callCase{{expErr: "unreachable"}},
+			calls: []callCase{{expErr: "wasm error: unreachable\nwasm stack trace:\n\t.$0()\n\t\tunreachable at offset 0x0 in the function body"}},
+		},
+		{
+			name: "unreachable with name section",
+			m: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}}},
+				ExportSection:   []wasm.Export{{Name: testcases.ExportName, Index: 0, Type: wasm.ExternTypeFunc}},
+				NameSection: &wasm.NameSection{
+					ModuleName:    "named",
+					FunctionNames: wasm.NameMap{{Index: 0, Name: "trap"}},
+				},
+			},
+			calls: []callCase{{expErr: "wasm error: unreachable\nwasm stack trace:\n\tnamed.trap()\n\t\tunreachable at offset 0x0 in the function body"}},
+		},
+		{
+			name: "unreachable at multiple sites",
+			m: testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{wasm.ValueTypeI32}}, []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeIf, 0x40, // 0x40 is the v_v signature.
+				wasm.OpcodeUnreachable, // offset 0x4
+				wasm.OpcodeEnd,
+				wasm.OpcodeUnreachable, // offset 0x6
+				wasm.OpcodeEnd,
+			}, nil),
+			calls: []callCase{
+				{params: []uint64{1}, expErr: "wasm error: unreachable\nwasm stack trace:\n\t.$0(i32)\n\t\tunreachable at offset 0x4 in the function body"},
+				{params: []uint64{0}, expErr: "wasm error: unreachable\nwasm stack trace:\n\t.$0(i32)\n\t\tunreachable at offset 0x6 in the function body"},
+			},
 		},
 		{
 			name: "fibonacci_recursive", m: testcases.FibonacciRecursive.Module,
@@ -55,6 +90,29 @@ func TestE2E(t *testing.T) {
 				{params: []uint64{30}, expResults: []uint64{0xcb228}},
 			},
 		},
+		{
+			name: "recursive_sum", m: testcases.RecursiveSum.Module,
+			calls: []callCase{
+				{params: []uint64{0}, expResults: []uint64{0}},
+				{params: []uint64{1}, expResults: []uint64{1}},
+				{params: []uint64{100}, expResults: []uint64{5050}},
+				{params: []uint64{10000}, expResults: []uint64{50005000}},
+			},
+		},
+		{
+			name: "early_return_from_nested_blocks", m: testcases.EarlyReturnFromNestedBlocks.Module,
+			calls: []callCase{
+				{params: []uint64{1}, expResults: []uint64{30, 40}},
+				{params: []uint64{0}, expResults: []uint64{10, 50}},
+			},
+		},
+		{
+			name: "if_without_else_params", m: testcases.IfWithoutElseParams.Module,
+			calls: []callCase{
+				{params: []uint64{1}, expResults: []uint64{2}},
+				{params: []uint64{0}, expResults: []uint64{0}},
+			},
+		},
 		{name: "call", m: testcases.Call.Module, calls: []callCase{{expResults: []uint64{45, 45}}}},
 		{
 			name: "stack overflow",
@@ -65,7 +123,10 @@ func TestE2E(t *testing.T) {
 				ExportSection:   []wasm.Export{{Name: "f", Index: 0, Type: wasm.ExternTypeFunc}},
 			},
 			calls: []callCase{
-				{expErr: "stack overflow"}, {expErr: "stack overflow"}, {expErr: "stack overflow"}, {expErr: "stack overflow"},
+				{expErr: "wasm error: stack overflow\nwasm stack trace:\n\t.$0()"},
+				{expErr: "wasm error: stack overflow\nwasm stack trace:\n\t.$0()"},
+				{expErr: "wasm error: stack overflow\nwasm stack trace:\n\t.$0()"},
+				{expErr: "wasm error: stack overflow\nwasm stack trace:\n\t.$0()"},
 			},
 		},
 		{
@@ -80,6 +141,82 @@ func TestE2E(t *testing.T) {
 				{params: []uint64{100}, expResults: []uint64{100 * 100}},
 			},
 		},
+		{
+			name:     "call_distinct_types",
+			imported: testcases.ImportedFunctionCallDistinctTypes.Imported,
+			m:        testcases.ImportedFunctionCallDistinctTypes.Module,
+			calls: []callCase{
+				{params: []uint64{0}, expResults: []uint64{(10 + 0) * 2}},
+				{params: []uint64{5}, expResults: []uint64{(10 + 5) * 2}},
+				{params: []uint64{1 << 40}, expResults: []uint64{(10 + 1<<40) * 2}},
+			},
+		},
+		{
+			name:     "imported_global",
+			imported: testcases.ImportedGlobal.Imported,
+			m:        testcases.ImportedGlobal.Module,
+			calls: []callCase{
+				{params: []uint64{5}, expResults: []uint64{100, 105, 105, 1}},
+				{params: []uint64{10}, expResults: []uint64{105, 115, 115, 2}},
+				{params: []uint64{0xffffff9c /* -100 */}, expResults: []uint64{115, 15, 15, 3}},
+			},
+		},
+		{
+			name: "memory_stores",
+			m:    testcases.MemoryStores.Module,
+			calls: []callCase{
+				{
+					params:     []uint64{0x100, 0x12345678, 0x1122334455667788, uint64(math.Float32bits(1.5)), math.Float64bits(-2.25)},
+					expResults: []uint64{0x12345678, 0x1122334455667788, uint64(math.Float32bits(1.5)), math.Float64bits(-2.25), 0x78, 0x5678, 0x88, 0x7788, 0x55667788},
+				},
+				// Only the last i64.store32 is out of bounds.
+				{params: []uint64{uint64(wasm.MemoryPageSize) - 0x2f, 0, 0, 0, 0}, expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32,i32,i64,f32,f64) (i32,i64,f32,f64,i32,i32,i64,i64,i64)\n\t\t4-byte memory access at address 0xfffd or an earlier access checked together with it"},
+			},
+		},
+		{
+			name: "const_address_memory_accesses",
+			m:    testcases.ConstAddressMemoryAccesses.Module,
+			calls: []callCase{
+				{params: []uint64{5}, expResults: []uint64{5, 1}},
+				{params: []uint64{10}, expResults: []uint64{15, 2}},
+			},
+		},
+		{
+			name: "const_address_out_of_bounds",
+			m: func() *wasm.Module {
+				m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}}, []byte{
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeI32Eqz,
+					wasm.OpcodeIf, wasm.ValueTypeI32,
+					// The last 3 bytes are not enough for i32.
+					wasm.OpcodeI32Const, 0xfd, 0xff, 0x3, // 0xfffd
+					wasm.OpcodeI32Load, 0x2, 0x0,
+					wasm.OpcodeElse,
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeI32Const, 1,
+					wasm.OpcodeI32Eq,
+					wasm.OpcodeIf, wasm.ValueTypeI32,
+					// The last 4 bytes.
+					wasm.OpcodeI32Const, 0xfc, 0xff, 0x3, // 0xfffc
+					wasm.OpcodeI32Load, 0x2, 0x0,
+					wasm.OpcodeElse,
+					// The effective address 0xffffffff+1 overflows 32 bits.
+					wasm.OpcodeI32Const, 0x7f, // -1
+					wasm.OpcodeI32Load, 0x2, 0x1,
+					wasm.OpcodeEnd,
+					wasm.OpcodeEnd,
+					wasm.OpcodeEnd,
+				}, nil)
+				m.MemorySection = &wasm.Memory{Min: 1}
+				return m
+			}(),
+			calls: []callCase{
+				{params: []uint64{0}, expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32) i32\n\t\t4-byte memory access at address 0xfffd"},
+				{params: []uint64{1}, expResults: []uint64{0}},
+				// The address is reported without wrapping around 32 bits.
+				{params: []uint64{2}, expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32) i32\n\t\t4-byte memory access at address 0x100000000"},
+			},
+		},
 		{
 			name: "memory_load_basic",
 			m:    testcases.MemoryLoadBasic.Module,
@@ -95,16 +232,16 @@ func TestE2E(t *testing.T) {
 			name: "memory out of bounds",
 			m:    testcases.MemoryLoadBasic.Module,
 			calls: []callCase{
-				{params: []uint64{uint64(wasm.MemoryPageSize)}, expErr: "out of bounds memory access"},
+				{params: []uint64{uint64(wasm.MemoryPageSize)}, expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32) i32\n\t\t4-byte memory access at address 0x10000"},
 				// We load I32, so we can't load from the last 3 bytes.
-				{params: []uint64{uint64(wasm.MemoryPageSize) - 3}, expErr: "out of bounds memory access"},
+				{params: []uint64{uint64(wasm.MemoryPageSize) - 3}, expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32) i32\n\t\t4-byte memory access at address 0xfffd"},
 			},
 		},
 		{
 			name: "memory_loads",
 			m:    testcases.MemoryLoads.Module,
 			calls: []callCase{
-				// These expected results are derived by commenting out `configureWazevo(config)` below to run the old compiler, assuming that it is correct.
+				// These expected results are derived by commenting out `configureWazevo(config, wazevo.Config{})` below to run the old compiler, assuming that it is correct.
 				{params: []uint64{0}, expResults: []uint64{0x3020100, 0x706050403020100, 0x3020100, 0x706050403020100, 0x1211100f, 0x161514131211100f, 0x1211100f, 0x161514131211100f, 0x0, 0xf, 0x0, 0xf, 0x100, 0x100f, 0x100, 0x100f, 0x0, 0xf, 0x0, 0xf, 0x100, 0x100f, 0x100, 0x100f, 0x3020100, 0x1211100f, 0x3020100, 0x1211100f}},
 				{params: []uint64{1}, expResults: []uint64{0x4030201, 0x807060504030201, 0x4030201, 0x807060504030201, 0x13121110, 0x1716151413121110, 0x13121110, 0x1716151413121110, 0x1, 0x10, 0x1, 0x10, 0x201, 0x1110, 0x201, 0x1110, 0x1, 0x10, 0x1, 0x10, 0x201, 0x1110, 0x201, 0x1110, 0x4030201, 0x13121110, 0x4030201, 0x13121110}},
 				{params: []uint64{8}, expResults: []uint64{0xb0a0908, 0xf0e0d0c0b0a0908, 0xb0a0908, 0xf0e0d0c0b0a0908, 0x1a191817, 0x1e1d1c1b1a191817, 0x1a191817, 0x1e1d1c1b1a191817, 0x8, 0x17, 0x8, 0x17, 0x908, 0x1817, 0x908, 0x1817, 0x8, 0x17, 0x8, 0x17, 0x908, 0x1817, 0x908, 0x1817, 0xb0a0908, 0x1a191817, 0xb0a0908, 0x1a191817}},
@@ -115,13 +252,523 @@ func TestE2E(t *testing.T) {
 				{params: []uint64{0xf}, expResults: []uint64{0x1211100f, 0x161514131211100f, 0x1211100f, 0x161514131211100f, 0x21201f1e, 0x2524232221201f1e, 0x21201f1e, 0x2524232221201f1e, 0xf, 0x1e, 0xf, 0x1e, 0x100f, 0x1f1e, 0x100f, 0x1f1e, 0xf, 0x1e, 0xf, 0x1e, 0x100f, 0x1f1e, 0x100f, 0x1f1e, 0x1211100f, 0x21201f1e, 0x1211100f, 0x21201f1e}},
 			},
 		},
+		{
+			name: "memory_struct_read",
+			m:    testcases.MemoryStructRead.Module,
+			calls: []callCase{
+				{params: []uint64{0}, expResults: []uint64{0x0706050403020100, 0x0f0e0d0c0b0a0908}},
+				{params: []uint64{uint64(wasm.MemoryPageSize) - 16}, expResults: []uint64{0xf7f6f5f4f3f2f1f0, 0xfffefdfcfbfaf9f8}},
+				// The first field is in bounds, but the second is not.
+				{params: []uint64{uint64(wasm.MemoryPageSize) - 8}, expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32) (i64,i64)\n\t\t8-byte memory access at address 0x10000 or an earlier access checked together with it"},
+			},
+		},
+		{
+			name: "memory_struct_read_across_call",
+			m:    testcases.MemoryStructReadAcrossCall.Module,
+			calls: []callCase{
+				{params: []uint64{0}, expResults: []uint64{0x0706050403020100, 0x0f0e0d0c0b0a0908}},
+				// Unlike memory_struct_read, the second field is checked by itself after the call.
+				{params: []uint64{uint64(wasm.MemoryPageSize) - 8}, expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32) (i64,i64)\n\t\t8-byte memory access at address 0x10000"},
+			},
+		},
+		{
+			name: "tail_returns", m: testcases.TailReturns.Module,
+			calls: []callCase{
+				{params: []uint64{1, 2}, expResults: []uint64{1}},
+				{params: []uint64{0, 2}, expResults: []uint64{2}},
+				{params: []uint64{0xffffffff, 0}, expResults: []uint64{0xffffffff}},
+			},
+		},
+		{
+			name: "memory_same_base_loads",
+			m:    testcases.MemorySameBaseLoads.Module,
+			calls: []callCase{
+				{params: []uint64{0, 0x100}, expResults: []uint64{0x0706050403020100, 0x0706050403020100, 0x0f0e0d0c0b0a0908, 0x0f0e0d0c0b0a0908, 0x1716151413121110, 0x1716151413121110}},
+				// The first fields of both structs are in bounds, but the third of the first struct is not.
+				{params: []uint64{uint64(wasm.MemoryPageSize) - 16, 0}, expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32,i32) (i64,i64,i64,i64,i64,i64)\n\t\t8-byte memory access at address 0x10000 or an earlier access checked together with it"},
+			},
+		},
+		{
+			name: "memory_same_base_loads_br_if",
+			m:    testcases.MemorySameBaseLoadsBrIf.Module,
+			calls: []callCase{
+				// The second load is out of bounds, but never reached.
+				{params: []uint64{8, 1}, expResults: []uint64{0x0f0e0d0c0b0a0908}},
+				{params: []uint64{8, 0}, expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32,i32) (i64)\n\t\t8-byte memory access at address 0x10008"},
+			},
+		},
+		{
+			name: "integer_division",
+			m:    testcases.IntegerDivision.Module,
+			calls: []callCase{
+				{params: []uint64{0xfffffff9, 2, 0xfffffffffffffff9, 2}, expResults: []uint64{0xfffffffd, 0x7ffffffc, 0xfffffffffffffffd, 0x7ffffffffffffffc}},
+				// Dividing by -1 overflows only if the dividend is the minimum value.
+				{params: []uint64{5, 0xffffffff, 5, 0xffffffffffffffff}, expResults: []uint64{0xfffffffb, 0, 0xfffffffffffffffb, 0}},
+				{params: []uint64{0x80000000, 1, 0x8000000000000000, 1}, expResults: []uint64{0x80000000, 0x80000000, 0x8000000000000000, 0x8000000000000000}},
+				{params: []uint64{1, 0, 1, 1}, expErr: "wasm error: integer divide by zero\nwasm stack trace:\n\t.$0(i32,i32,i64,i64) (i32,i32,i64,i64)"},
+				{params: []uint64{1, 1, 1, 0}, expErr: "wasm error: integer divide by zero\nwasm stack trace:\n\t.$0(i32,i32,i64,i64) (i32,i32,i64,i64)"},
+				{params: []uint64{0x80000000, 0xffffffff, 1, 1}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(i32,i32,i64,i64) (i32,i32,i64,i64)"},
+				{params: []uint64{1, 1, 0x8000000000000000, 0xffffffffffffffff}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(i32,i32,i64,i64) (i32,i32,i64,i64)"},
+			},
+		},
+		{
+			name: "integer_remainder",
+			m:    testcases.IntegerRemainder.Module,
+			calls: []callCase{
+				{params: []uint64{0xfffffff9, 2, 0xfffffffffffffff9, 2}, expResults: []uint64{0xffffffff, 1, 0xffffffffffffffff, 1}},
+				{params: []uint64{7, 0xfffffffd, 7, 0xfffffffffffffffd}, expResults: []uint64{1, 7, 1, 7}},
+				// Unlike the division, the signed remainder of the minimum value divided by -1 doesn't overflow.
+				{params: []uint64{0x80000000, 0xffffffff, 0x8000000000000000, 0xffffffffffffffff}, expResults: []uint64{0, 0x80000000, 0, 0x8000000000000000}},
+				{params: []uint64{1, 0, 1, 1}, expErr: "wasm error: integer divide by zero\nwasm stack trace:\n\t.$0(i32,i32,i64,i64) (i32,i32,i64,i64)"},
+				{params: []uint64{1, 1, 1, 0}, expErr: "wasm error: integer divide by zero\nwasm stack trace:\n\t.$0(i32,i32,i64,i64) (i32,i32,i64,i64)"},
+			},
+		},
+		{
+			name: "integer_bitwise",
+			m:    testcases.IntegerBitwise.Module,
+			calls: []callCase{
+				{params: []uint64{0xff00ff00, 0x0ff00ff0, 0xff00ff00ff00ff00, 0x0ff00ff00ff00ff0}, expResults: []uint64{
+					0x0f000f00, 0xfff0fff0, 0xf0f0f0f0,
+					0x0f000f000f000f00, 0xfff0fff0fff0fff0, 0xf0f0f0f0f0f0f0f0,
+				}},
+				{params: []uint64{0, 0xffffffff, 0, 0xffffffffffffffff}, expResults: []uint64{
+					0, 0xffffffff, 0xffffffff,
+					0, 0xffffffffffffffff, 0xffffffffffffffff,
+				}},
+			},
+		},
+		{
+			name: "ref_type_locals",
+			m:    testcases.RefTypeLocals.Module,
+			calls: []callCase{
+				{params: []uint64{0}, expResults: []uint64{0, 0}},
+				{params: []uint64{0xdeadbeef}, expResults: []uint64{0, 0xdeadbeef}},
+			},
+		},
+		{
+			name: "integer_rotate",
+			m:    testcases.IntegerRotate.Module,
+			calls: []callCase{
+				{params: []uint64{0x80000001, 1, 0x8000000000000001, 1}, expResults: []uint64{
+					0x3, 0xc0000000, 0x3, 0xc000000000000000,
+				}},
+				// The amounts are taken modulo the bit width.
+				{params: []uint64{0x12345678, 36, 0x123456789abcdef0, 68}, expResults: []uint64{
+					0x23456781, 0x81234567, 0x23456789abcdef01, 0x0123456789abcdef,
+				}},
+				{params: []uint64{0x12345678, 0, 0x123456789abcdef0, 0}, expResults: []uint64{
+					0x12345678, 0x12345678, 0x123456789abcdef0, 0x123456789abcdef0,
+				}},
+			},
+		},
+		{
+			name: "integer_bit_counts",
+			m:    testcases.IntegerBitCounts.Module,
+			calls: []callCase{
+				// The leading and trailing zeros of zero are the bit width.
+				{params: []uint64{0, 0}, expResults: []uint64{32, 32, 0, 64, 64, 0}},
+				{params: []uint64{0x00f00100, 0x00000f0000000080}, expResults: []uint64{8, 8, 5, 20, 7, 5}},
+				{params: []uint64{0xffffffff, 0xffffffffffffffff}, expResults: []uint64{0, 0, 32, 0, 0, 64}},
+				{params: []uint64{0x80000001, 0x8000000000000001}, expResults: []uint64{0, 0, 2, 0, 0, 2}},
+			},
+		},
+		{
+			name: "integer_eqz",
+			m:    testcases.IntegerEqz.Module,
+			calls: []callCase{
+				{params: []uint64{0, 0}, expResults: []uint64{1, 1}},
+				{params: []uint64{1, 1}, expResults: []uint64{0, 0}},
+				// Only the lower 32 bits of i64 are zero.
+				{params: []uint64{0xffffffff, 0x1_00000000}, expResults: []uint64{0, 0}},
+			},
+		},
+		{
+			name: "table_const_indices",
+			m:    testcases.TableConstIndices.Module,
+			calls: []callCase{
+				{params: []uint64{0, 0xdead}, expResults: []uint64{0xdead}},
+				// The index 4 is beyond the minimum size, so it's still checked.
+				{params: []uint64{1, 0xdead}, expErr: "wasm error: invalid table access\nwasm stack trace:\n\t.$0(i32,externref) (externref)"},
+			},
+		},
+		{
+			name: "i32_wrap_i64",
+			m:    testcases.I32WrapI64.Module,
+			calls: []callCase{
+				{params: []uint64{0x1234567890abcdef}, expResults: []uint64{0x90abcdef, 0x21579bde}},
+				{params: []uint64{0xffffffff}, expResults: []uint64{0xffffffff, 0xfffffffe}},
+			},
+		},
+		{
+			name: "float_unary_ops",
+			m:    testcases.FloatUnaryOps.Module,
+			calls: []callCase{
+				{params: []uint64{uint64(math.Float32bits(4.0)), math.Float64bits(2.25)}, expResults: []uint64{
+					uint64(math.Float32bits(-4.0)), uint64(math.Float32bits(4.0)), uint64(math.Float32bits(2.0)),
+					math.Float64bits(-2.25), math.Float64bits(2.25), math.Float64bits(1.5),
+				}},
+				// The sign of zero is kept by the square root.
+				{params: []uint64{uint64(math.Float32bits(float32(math.Copysign(0, -1)))), math.Float64bits(math.Copysign(0, -1))}, expResults: []uint64{
+					0, 0, 0x80000000,
+					0, 0, 0x8000000000000000,
+				}},
+				// Only the sign bits of NaNs are changed by the negation and the absolute value.
+				{params: []uint64{0xffc00001, 0xfff8000000000001}, expResults: []uint64{
+					0x7fc00001, 0x7fc00001, 0xffc00001,
+					0x7ff8000000000001, 0x7ff8000000000001, 0xfff8000000000001,
+				}},
+			},
+		},
+		{
+			name: "float_rounding",
+			m:    testcases.FloatRounding.Module,
+			calls: []callCase{
+				{params: []uint64{uint64(math.Float32bits(-1.25)), math.Float64bits(1.75)}, expResults: []uint64{
+					uint64(math.Float32bits(-1.0)), uint64(math.Float32bits(-2.0)), uint64(math.Float32bits(-1.0)), uint64(math.Float32bits(-1.0)),
+					math.Float64bits(2.0), math.Float64bits(1.0), math.Float64bits(1.0), math.Float64bits(2.0),
+				}},
+				// The ties are rounded to even by nearest.
+				{params: []uint64{uint64(math.Float32bits(2.5)), math.Float64bits(-0.5)}, expResults: []uint64{
+					uint64(math.Float32bits(3.0)), uint64(math.Float32bits(2.0)), uint64(math.Float32bits(2.0)), uint64(math.Float32bits(2.0)),
+					0x8000000000000000, math.Float64bits(-1.0), 0x8000000000000000, 0x8000000000000000,
+				}},
+			},
+		},
+		{
+			name: "float_copysign",
+			m:    testcases.FloatCopysign.Module,
+			calls: []callCase{
+				{params: []uint64{uint64(math.Float32bits(1.5)), uint64(math.Float32bits(-2.0)), math.Float64bits(-1.5), math.Float64bits(2.0)}, expResults: []uint64{
+					uint64(math.Float32bits(-1.5)), math.Float64bits(1.5),
+				}},
+				// The sign bits are taken from NaNs, and the payloads of NaNs are kept.
+				{params: []uint64{0x7fc00001, 0xffc00000, 0xfff8000000000001, 0x7ff8000000000000}, expResults: []uint64{
+					0xffc00001, 0x7ff8000000000001,
+				}},
+			},
+		},
+		{
+			name: "block_params_br",
+			m:    testcases.BlockParamsBr.Module,
+			calls: []callCase{
+				{params: []uint64{5, 3}, expResults: []uint64{12}},
+				{params: []uint64{0, 3}, expResults: []uint64{57}},
+			},
+		},
+		{
+			name: "float_promote_demote",
+			m:    testcases.FloatPromoteDemote.Module,
+			calls: []callCase{
+				// 1+2^-24 is the tie between 1 and 1+2^-23, and rounds to the even 1.
+				{params: []uint64{uint64(math.Float32bits(1.5)), math.Float64bits(1 + math.Pow(2, -24))}, expResults: []uint64{
+					math.Float64bits(1.5), uint64(math.Float32bits(1)), uint64(math.Float32bits(1.5)),
+				}},
+				// 1+3*2^-24 is the tie between 1+2^-23 and 1+2^-22, and rounds to the even 1+2^-22.
+				{params: []uint64{0x80000000, math.Float64bits(1 + 3*math.Pow(2, -24))}, expResults: []uint64{
+					math.Float64bits(math.Copysign(0, -1)), uint64(math.Float32bits(1 + 1.0/(1<<22))), 0x80000000,
+				}},
+				// The payload of the quiet NaN survives the round trip, and the finite f64 overflows to infinity.
+				{params: []uint64{0x7fc12345, math.Float64bits(math.MaxFloat64)}, expResults: []uint64{
+					0x7ff82468a0000000, 0x7f800000, 0x7fc12345,
+				}},
+				{params: []uint64{0xff800000, math.Float64bits(math.Inf(-1))}, expResults: []uint64{
+					math.Float64bits(math.Inf(-1)), 0xff800000, 0xff800000,
+				}},
+			},
+		},
+		{
+			name: "float_to_i32_s",
+			m:    testcases.FloatToI32S.Module,
+			calls: []callCase{
+				{params: []uint64{uint64(math.Float32bits(1.9)), math.Float64bits(-3.99)}, expResults: []uint64{1, 0xfffffffd}},
+				// -2147483648.9 is truncated to the minimum value, and 2147483520 is the largest f32 below 2^31.
+				{params: []uint64{uint64(math.Float32bits(-(1 << 31))), math.Float64bits(-2147483648.9)}, expResults: []uint64{0x80000000, 0x80000000}},
+				{params: []uint64{uint64(math.Float32bits(2147483520)), math.Float64bits(2147483647.9)}, expResults: []uint64{0x7fffff80, 0x7fffffff}},
+				{params: []uint64{uint64(math.Float32bits(1 << 31)), 0}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i32,i32)"},
+				{params: []uint64{uint64(math.Float32bits(-2147483904)), 0}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i32,i32)"},
+				{params: []uint64{0x7f800000, 0}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i32,i32)"},
+				{params: []uint64{0, math.Float64bits(1 << 31)}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i32,i32)"},
+				{params: []uint64{0, math.Float64bits(-2147483649)}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i32,i32)"},
+				// NaN is the invalid conversion rather than the overflow regardless of the sign.
+				{params: []uint64{0xffc00000, 0}, expErr: "wasm error: invalid conversion to integer\nwasm stack trace:\n\t.$0(f32,f64) (i32,i32)"},
+				{params: []uint64{0, 0x7ff8000000000001}, expErr: "wasm error: invalid conversion to integer\nwasm stack trace:\n\t.$0(f32,f64) (i32,i32)"},
+			},
+		},
+		{
+			name: "float_to_i32_u",
+			m:    testcases.FloatToI32U.Module,
+			calls: []callCase{
+				// The values in (-1, 0) are truncated to zero.
+				{params: []uint64{uint64(math.Float32bits(-0.9)), math.Float64bits(-0.99)}, expResults: []uint64{0, 0}},
+				{params: []uint64{uint64(math.Float32bits(4294967040)), math.Float64bits(4294967295.9)}, expResults: []uint64{0xffffff00, 0xffffffff}},
+				{params: []uint64{uint64(math.Float32bits(-1)), 0}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i32,i32)"},
+				{params: []uint64{uint64(math.Float32bits(1 << 32)), 0}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i32,i32)"},
+				{params: []uint64{0, math.Float64bits(1 << 32)}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i32,i32)"},
+				{params: []uint64{0x7fc00000, 0}, expErr: "wasm error: invalid conversion to integer\nwasm stack trace:\n\t.$0(f32,f64) (i32,i32)"},
+			},
+		},
+		{
+			name: "float_to_i64_s",
+			m:    testcases.FloatToI64S.Module,
+			calls: []callCase{
+				{params: []uint64{uint64(math.Float32bits(-1.5)), math.Float64bits(2.5)}, expResults: []uint64{0xffffffffffffffff, 2}},
+				{params: []uint64{uint64(math.Float32bits(-(1 << 63))), math.Float64bits(-(1 << 63))}, expResults: []uint64{0x8000000000000000, 0x8000000000000000}},
+				// These are the largest f32 and f64 below 2^63.
+				{params: []uint64{uint64(math.Float32bits(1<<63 - 1<<39)), math.Float64bits(1<<63 - 1<<10)}, expResults: []uint64{0x7fffff8000000000, 0x7ffffffffffffc00}},
+				{params: []uint64{uint64(math.Float32bits(1 << 63)), 0}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i64,i64)"},
+				{params: []uint64{0, math.Float64bits(-(1<<63 + 1<<11))}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i64,i64)"},
+				{params: []uint64{0, math.Float64bits(math.Inf(-1))}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i64,i64)"},
+				{params: []uint64{0, math.Float64bits(math.NaN())}, expErr: "wasm error: invalid conversion to integer\nwasm stack trace:\n\t.$0(f32,f64) (i64,i64)"},
+			},
+		},
+		{
+			name: "float_to_i64_u",
+			m:    testcases.FloatToI64U.Module,
+			calls: []callCase{
+				{params: []uint64{uint64(math.Float32bits(-0.9)), math.Float64bits(-0.99)}, expResults: []uint64{0, 0}},
+				// These are the largest f32 and f64 below 2^64.
+				{params: []uint64{uint64(math.Float32bits(1<<64 - 1<<40)), math.Float64bits(1<<64 - 1<<11)}, expResults: []uint64{0xffffff0000000000, 0xfffffffffffff800}},
+				{params: []uint64{0, math.Float64bits(-1)}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i64,i64)"},
+				{params: []uint64{0, math.Float64bits(1 << 64)}, expErr: "wasm error: integer overflow\nwasm stack trace:\n\t.$0(f32,f64) (i64,i64)"},
+				{params: []uint64{0x7fc00000, 0}, expErr: "wasm error: invalid conversion to integer\nwasm stack trace:\n\t.$0(f32,f64) (i64,i64)"},
+			},
+		},
+		{
+			name: "float_to_int_sat",
+			m:    testcases.FloatToIntSat.Module,
+			calls: []callCase{
+				{params: []uint64{uint64(math.Float32bits(1.9)), math.Float64bits(-3.99)}, expResults: []uint64{
+					1, 1, 0xfffffffd, 0, 1, 1, 0xfffffffffffffffd, 0,
+				}},
+				// NaN is converted to zero regardless of the sign.
+				{params: []uint64{0x7fc00000, 0xfff8000000000001}, expResults: []uint64{0, 0, 0, 0, 0, 0, 0, 0}},
+				{params: []uint64{0x7f800000, math.Float64bits(math.Inf(1))}, expResults: []uint64{
+					0x7fffffff, 0xffffffff, 0x7fffffff, 0xffffffff,
+					0x7fffffffffffffff, 0xffffffffffffffff, 0x7fffffffffffffff, 0xffffffffffffffff,
+				}},
+				{params: []uint64{0xff800000, math.Float64bits(math.Inf(-1))}, expResults: []uint64{
+					0x80000000, 0, 0x80000000, 0, 0x8000000000000000, 0, 0x8000000000000000, 0,
+				}},
+				// 2147483520 is the largest f32 below 2^31.
+				{params: []uint64{uint64(math.Float32bits(2147483520)), math.Float64bits(4294967295.9)}, expResults: []uint64{
+					0x7fffff80, 0x7fffff80, 0x7fffffff, 0xffffffff, 0x7fffff80, 0x7fffff80, 0xffffffff, 0xffffffff,
+				}},
+				{params: []uint64{uint64(math.Float32bits(-(1 << 31))), math.Float64bits(-2147483648.9)}, expResults: []uint64{
+					0x80000000, 0, 0x80000000, 0, 0xffffffff80000000, 0, 0xffffffff80000000, 0,
+				}},
+				// The values in (-1, 0) are truncated to zero rather than saturated.
+				{params: []uint64{uint64(math.Float32bits(-0.9)), math.Float64bits(1<<64 - 1<<11)}, expResults: []uint64{
+					0, 0, 0x7fffffff, 0xffffffff, 0, 0, 0x7fffffffffffffff, 0xfffffffffffff800,
+				}},
+				{params: []uint64{uint64(math.Float32bits(1<<63 - 1<<39)), math.Float64bits(-(1 << 63))}, expResults: []uint64{
+					0x7fffffff, 0xffffffff, 0x80000000, 0, 0x7fffff8000000000, 0x7fffff8000000000, 0x8000000000000000, 0,
+				}},
+				{params: []uint64{uint64(math.Float32bits(1 << 64)), math.Float64bits(1 << 63)}, expResults: []uint64{
+					0x7fffffff, 0xffffffff, 0x7fffffff, 0xffffffff,
+					0x7fffffffffffffff, 0xffffffffffffffff, 0x7fffffffffffffff, 0x8000000000000000,
+				}},
+			},
+		},
+		{
+			name: "int_to_float",
+			m:    testcases.IntToFloat.Module,
+			calls: []callCase{
+				{params: []uint64{0xffffffff, 0xffffffffffffffff}, expResults: []uint64{
+					0xbf800000, 0x4f800000, 0xbf800000, 0x5f800000,
+					0xbff0000000000000, 0x41efffffffe00000, 0xbff0000000000000, 0x43f0000000000000,
+				}},
+				// The ties are rounded to even: 2^24+1 and 2^53+1 are rounded down, and 2^24+3 and 2^53+3 are rounded up.
+				{params: []uint64{1<<24 + 1, 1<<53 + 1}, expResults: []uint64{
+					0x4b800000, 0x4b800000, 0x5a000000, 0x5a000000,
+					0x4170000010000000, 0x4170000010000000, 0x4340000000000000, 0x4340000000000000,
+				}},
+				{params: []uint64{1<<24 + 3, 1<<53 + 3}, expResults: []uint64{
+					0x4b800002, 0x4b800002, 0x5a000000, 0x5a000000,
+					0x4170000030000000, 0x4170000030000000, 0x4340000000000002, 0x4340000000000002,
+				}},
+				{params: []uint64{0x80000000, 0x8000000000000400}, expResults: []uint64{
+					0xcf000000, 0x4f000000, 0xdf000000, 0x5f000000,
+					0xc1e0000000000000, 0x41e0000000000000, 0xc3dfffffffffffff, 0x43e0000000000000,
+				}},
+				{params: []uint64{0x7fffffff, 0x8000000000000401}, expResults: []uint64{
+					0x4f000000, 0x4f000000, 0xdf000000, 0x5f000000,
+					0x41dfffffffc00000, 0x41dfffffffc00000, 0xc3dfffffffffffff, 0x43e0000000000001,
+				}},
+				{params: []uint64{0, 0x8000018000000000}, expResults: []uint64{
+					0, 0, 0xdefffffd, 0x5f000002, 0, 0, 0xc3dfffffa0000000, 0x43e0000030000000,
+				}},
+			},
+		},
+		{
+			name: "nested_loops_br_to_block",
+			m:    testcases.NestedLoopsBrToBlock.Module,
+			calls: []callCase{
+				{params: []uint64{0}, expResults: []uint64{42, 420}},
+				{params: []uint64{42}, expResults: []uint64{63, 630}},
+				{params: []uint64{100}, expResults: []uint64{105, 1050}},
+				// The first multiple of 21 is zero after the increment wraps around, which is below 30.
+				{params: []uint64{0xffffffff}, expResults: []uint64{42, 420}},
+			},
+		},
+		{
+			name: "if_then_empty",
+			m:    testcases.IfThenEmpty.Module,
+			calls: []callCase{
+				{params: []uint64{1, 5}, expResults: []uint64{5}},
+				{params: []uint64{0, 5}, expResults: []uint64{56}},
+			},
+		},
+		{
+			name: "globals_mutated_by_call",
+			m:    testcases.GlobalsMutatedByCall.Module,
+			calls: []callCase{
+				{params: []uint64{5}, expResults: []uint64{10, 15, math.Float64bits(1.5)}},
+				{params: []uint64{0xfffffffffffffffe /* -2 */}, expResults: []uint64{15, 13, math.Float64bits(2)}},
+			},
+		},
+		{
+			name: "float_const_bit_patterns",
+			m:    testcases.FloatConstBitPatterns.Module,
+			calls: []callCase{
+				{params: []uint64{math.Float64bits(2)}, expResults: []uint64{0x7fc00000, 0x7fc00001, 0x7fc00000, 0, 0x8000000000000000, math.Float64bits(4.5)}},
+				{params: []uint64{math.Float64bits(-1)}, expResults: []uint64{0x7fc00000, 0x7fc00001, 0x7fc00000, 0, 0x8000000000000000, 0}},
+			},
+		},
+		{
+			name: "br_if_switch",
+			m:    testcases.BrIfSwitch.Module,
+			calls: []callCase{
+				{params: []uint64{0}, expResults: []uint64{10}},
+				{params: []uint64{1}, expResults: []uint64{21}},
+				{params: []uint64{2}, expResults: []uint64{32}},
+				{params: []uint64{3}, expResults: []uint64{43}},
+				{params: []uint64{0xffffffff}, expResults: []uint64{39}},
+			},
+		},
+		{
+			name: "integer_extension_chains",
+			m:    testcases.IntegerExtensionChains.Module,
+			calls: []callCase{
+				{
+					params: []uint64{0x80, 0x8080},
+					expResults: []uint64{
+						0xffffff80, 0xffffff80, 0xffffffffffffff80, 0x80,
+						0x80, 0x80, 0xffffffffffffff80, 0xffffffffffffff80,
+					},
+				},
+				{
+					params: []uint64{0xffff8000, 0x7f},
+					expResults: []uint64{
+						0, 0, 0, 0xffff8000,
+						0xffffffffffff8000, 0xffffffffffff8000, 0, 0x7f,
+					},
+				},
+				{
+					params:     []uint64{0x12345678, 0xffffffff80000001},
+					expResults: []uint64{0x78, 0x78, 0x78, 0x5678, 0x12345678, 0x12345678, 0x78, 0x1},
+				},
+			},
+		},
+		{
+			name: "table_get_set",
+			m:    testcases.TableGetSet.Module,
+			calls: []callCase{
+				{params: []uint64{1, 1, 0xdead, 0xbeef}, expResults: []uint64{0xdead, 0xbeef}},
+				// Tables are zero-initialized, i.e. hold null references.
+				{params: []uint64{2, 3, 0xdead, 0xbeef}, expResults: []uint64{0, 0}},
+				{params: []uint64{9, 2, 0xdead, 0xbeef}, expResults: []uint64{0xdead, 0xbeef}},
+				// The funcref table is shorter than the externref one.
+				{params: []uint64{10, 1, 0xdead, 0xbeef}, expErr: "wasm error: invalid table access\nwasm stack trace:\n\t.$0(i32,i32,unknown,externref) (unknown,externref)"},
+				{params: []uint64{1, 19, 0xdead, 0xbeef}, expErr: "wasm error: invalid table access\nwasm stack trace:\n\t.$0(i32,i32,unknown,externref) (unknown,externref)"},
+			},
+		},
+		{
+			name: "float_comparisons",
+			m:    testcases.FloatComparisons.Module,
+			calls: []callCase{
+				{
+					params:     []uint64{uint64(math.Float32bits(1.0)), uint64(math.Float32bits(2.0)), math.Float64bits(1.0), math.Float64bits(2.0)},
+					expResults: []uint64{0, 1, 1, 0, 1, 0, 0, 1, 1, 0, 1, 0},
+				},
+				{
+					params:     []uint64{uint64(math.Float32bits(2.0)), uint64(math.Float32bits(2.0)), math.Float64bits(2.0), math.Float64bits(2.0)},
+					expResults: []uint64{1, 0, 0, 0, 1, 1, 1, 0, 0, 0, 1, 1},
+				},
+				{
+					params:     []uint64{uint64(math.Float32bits(2.0)), uint64(math.Float32bits(1.0)), math.Float64bits(2.0), math.Float64bits(1.0)},
+					expResults: []uint64{0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1},
+				},
+				// Any comparison involving NaN is false except for ne.
+				{
+					params:     []uint64{uint64(math.Float32bits(float32(math.NaN()))), uint64(math.Float32bits(1.0)), math.Float64bits(math.NaN()), math.Float64bits(1.0)},
+					expResults: []uint64{0, 1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0},
+				},
+				{
+					params:     []uint64{uint64(math.Float32bits(1.0)), uint64(math.Float32bits(float32(math.NaN()))), math.Float64bits(1.0), math.Float64bits(math.NaN())},
+					expResults: []uint64{0, 1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0},
+				},
+				{
+					params:     []uint64{uint64(math.Float32bits(float32(math.NaN()))), uint64(math.Float32bits(float32(math.NaN()))), math.Float64bits(math.NaN()), math.Float64bits(math.NaN())},
+					expResults: []uint64{0, 1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0},
+				},
+				{
+					params:     []uint64{uint64(math.Float32bits(float32(math.Inf(1)))), uint64(math.Float32bits(float32(math.NaN()))), math.Float64bits(math.Inf(-1)), math.Float64bits(math.NaN())},
+					expResults: []uint64{0, 1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0},
+				},
+			},
+		},
+		{
+			name: "float_mul_add",
+			m:    testcases.FloatMulAdd.Module,
+			calls: []callCase{
+				{
+					params: []uint64{
+						uint64(math.Float32bits(1 + 1.0/(1<<13))), uint64(math.Float32bits(1 - 1.0/(1<<13))), uint64(math.Float32bits(-1)),
+						math.Float64bits(1 + 1.0/(1<<30)), math.Float64bits(1 - 1.0/(1<<30)), math.Float64bits(-1),
+					},
+					// The products are rounded to 1.0 before the addition, so the results are zero. Fused multiply-add
+					// would result in -2^-26 and -2^-60 respectively.
+					expResults: []uint64{0, 0},
+				},
+				{
+					params: []uint64{
+						uint64(math.Float32bits(1.5)), uint64(math.Float32bits(2)), uint64(math.Float32bits(0.25)),
+						math.Float64bits(1.5), math.Float64bits(2), math.Float64bits(0.25),
+					},
+					expResults: []uint64{uint64(math.Float32bits(3.25)), math.Float64bits(3.25)},
+				},
+			},
+		},
+		{
+			name: "selects",
+			m:    testcases.Selects.Module,
+			calls: []callCase{
+				{
+					params:     []uint64{1, 10, 20, 30, 40, uint64(math.Float32bits(1.5)), uint64(math.Float32bits(2.5)), math.Float64bits(3.5), math.Float64bits(4.5)},
+					expResults: []uint64{10, 30, uint64(math.Float32bits(1.5)), math.Float64bits(3.5)},
+				},
+				{
+					params:     []uint64{0, 10, 20, 30, 40, uint64(math.Float32bits(1.5)), uint64(math.Float32bits(2.5)), math.Float64bits(3.5), math.Float64bits(4.5)},
+					expResults: []uint64{20, 40, uint64(math.Float32bits(2.5)), math.Float64bits(4.5)},
+				},
+				{
+					// Non-canonical NaNs (signaling, with payloads and the sign bit) must be selected bit-for-bit.
+					params:     []uint64{0xffff_ffff, 0, 0, 0, 0, 0x7fa0_0001, 0, 0x7ff4_0000_0000_0001, 0},
+					expResults: []uint64{0, 0, 0x7fa0_0001, 0x7ff4_0000_0000_0001},
+				},
+				{
+					params:     []uint64{0, 0, 0, 0, 0, 0, 0xffc0_0123, 0, 0xfff8_0000_0000_0123},
+					expResults: []uint64{0, 0, 0xffc0_0123, 0xfff8_0000_0000_0123},
+				},
+			},
+		},
 	} {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			config := wazero.NewRuntimeConfigCompiler()
 
 			// Configure the new optimizing backend!
-			configureWazevo(config)
+			configureWazevo(config, wazevo.Config{})
 
 			ctx := context.Background()
 			r := wazero.NewRuntimeWithConfig(ctx, config)
@@ -166,9 +813,142 @@ func TestE2E(t *testing.T) {
 	}
 }
 
-// configureWazevo modifies wazero.RuntimeConfig and sets the wazevo implementation.
+func TestE2E_startFunction(t *testing.T) {
+	startSection := wasm.Index(0)
+	for _, tc := range []struct {
+		name   string
+		body   []byte
+		expErr string
+	}{
+		{
+			name: "writes memory",
+			body: []byte{
+				wasm.OpcodeI32Const, 0x10,
+				wasm.OpcodeI32Const, 0x2a,
+				wasm.OpcodeI32Store, 0x2, 0x0, // alignment=2 (natural alignment) staticOffset=0
+				wasm.OpcodeEnd,
+			},
+		},
+		{
+			name:   "traps",
+			body:   []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd},
+			expErr: "start function[0] failed: wasm error: unreachable\nwasm stack trace:\n\t.$0()\n\t\tunreachable at offset 0x0 in the function body",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			config := wazero.NewRuntimeConfigCompiler()
+			configureWazevo(config, wazevo.Config{})
+
+			ctx := context.Background()
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			m := &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: tc.body}},
+				MemorySection:   &wasm.Memory{Min: 1},
+				StartSection:    &startSection,
+			}
+			compiled, err := r.CompileModule(ctx, binaryencoding.EncodeModule(m))
+			require.NoError(t, err)
+
+			inst, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+			if tc.expErr != "" {
+				require.EqualError(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+
+			v, ok := inst.Memory().ReadUint32Le(0x10)
+			require.True(t, ok)
+			require.Equal(t, uint32(0x2a), v)
+		})
+	}
+}
+
+func TestE2E_memoryGrownByHost(t *testing.T) {
+	config := wazero.NewRuntimeConfigCompiler()
+	configureWazevo(config, wazevo.Config{})
+
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+	defer func() {
+		require.NoError(t, r.Close(ctx))
+	}()
+
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Load, 0x2, 0x0, // alignment=2 (natural alignment) staticOffset=0
+			wasm.OpcodeEnd,
+		}}},
+		MemorySection: &wasm.Memory{Min: 1, Max: 2, IsMaxEncoded: true},
+		ExportSection: []wasm.Export{{Name: testcases.ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+	}
+	compiled, err := r.CompileModule(ctx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+
+	inst, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+
+	f := inst.ExportedFunction(testcases.ExportName)
+	_, err = f.Call(ctx, uint64(wasm.MemoryPageSize))
+	require.EqualError(t, err, "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32) i32\n\t\t4-byte memory access at address 0x10000")
+
+	// The memory grown on the host side must be visible to the compiled code without trapping.
+	_, ok := inst.Memory().Grow(1)
+	require.True(t, ok)
+	require.True(t, inst.Memory().WriteUint32Le(wasm.MemoryPageSize, 0xdeadbeef))
+
+	result, err := f.Call(ctx, uint64(wasm.MemoryPageSize))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0xdeadbeef}, result)
+}
+
+func TestE2E_config(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cfg  wazevo.Config
+	}{
+		{name: "lazy compilation", cfg: wazevo.Config{LazyCompilation: true}},
+		{name: "shared trap blocks", cfg: wazevo.Config{SharedTrapBlocks: true}},
+		{name: "deterministic stack", cfg: wazevo.Config{DeterministicStack: true}},
+		{name: "regions", cfg: wazevo.Config{RegionSize: 16}},
+		{name: "cross-check", cfg: wazevo.Config{CrossCheck: true}},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			config := wazero.NewRuntimeConfigCompiler()
+			configureWazevo(config, tc.cfg)
+
+			ctx := context.Background()
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			compiled, err := r.CompileModule(ctx, binaryencoding.EncodeModule(testcases.FibonacciRecursive.Module))
+			require.NoError(t, err)
+
+			inst, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+			require.NoError(t, err)
+
+			result, err := inst.ExportedFunction(testcases.ExportName).Call(ctx, 20)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{6765}, result)
+		})
+	}
+}
+
+// configureWazevo modifies wazero.RuntimeConfig and sets the wazevo implementation configured with cfg.
 // This is a hack to avoid modifying outside the wazevo package while testing it end-to-end.
-func configureWazevo(config wazero.RuntimeConfig) {
+func configureWazevo(config wazero.RuntimeConfig, cfg wazevo.Config) {
 	// This is the internal representation of interface in Go.
 	// https://research.swtch.com/interfaces
 	type iface struct {
@@ -192,5 +972,5 @@ func configureWazevo(config wazero.RuntimeConfig) {
 	}
 	cm := (*runtimeConfig)(configInterface.data)
 	// Insert the wazevo implementation.
-	cm.newEngine = wazevo.NewEngine
+	cm.newEngine = wazevo.NewEngineWithConfig(cfg)
 }
@@ -2,13 +2,17 @@ package wazevo
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"runtime"
+	"sort"
 	"sync"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/engine/interpreter"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/frontend"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
@@ -25,35 +29,254 @@ type (
 		mux               sync.RWMutex
 		rels              []backend.RelocationInfo
 		refToBinaryOffset map[ssa.FuncRef]int
+		// coverageEnabled is true if the compiled code counts the executions of each basic block.
+		// See moduleEngine.Coverage.
+		coverageEnabled bool
+		// loopProfilingEnabled is true if the compiled code counts the back-edges of each loop.
+		// See moduleEngine.LoopCounters.
+		loopProfilingEnabled bool
+		// strictAlignment is true if the compiled code traps on memory accesses which are not aligned
+		// to the alignment hint of their memarg. See frontend.Compiler.SetStrictAlignment.
+		strictAlignment bool
+		// sharedTrapBlocks is true if the memory bounds checks in each function branch to the single trap block
+		// instead of inlining their exit sequences. See frontend.Compiler.SetSharedTrapBlocks.
+		sharedTrapBlocks bool
+		// preciseMemoryTraps is true if each memory access is checked against the bounds by itself so that the out of
+		// bounds memory access reports the faulting address precisely. See frontend.Compiler.SetPreciseMemoryTraps.
+		preciseMemoryTraps bool
+		// flushDenormalsToZero is true if the compiled code flushes denormal floating point operands and results to zero.
+		// See backend.Machine EnableFlushDenormalsToZero.
+		flushDenormalsToZero bool
+		// roundingMode is the rounding mode of the floating point arithmetic and conversions in the compiled code,
+		// which is round-to-nearest by default as the spec requires. See backend.Machine SetRoundingMode.
+		roundingMode backend.RoundingMode
+		// executableBudget is the maximum total size in bytes of the executables of compiled modules.
+		// Zero means unlimited.
+		executableBudget int
+		// executableSize is the current total size in bytes of the executables of compiled modules.
+		executableSize int
+		// lazyCompilation is true if the functions are compiled on their first call rather than in CompileModule.
+		// See lazyCompiledFunctions.
+		lazyCompilation bool
+		// compileHook, if non-nil, is called with the index of each function right before it's compiled.
+		compileHook func(index wasm.Index)
+		// ssaGraphHook, if non-nil, is called with the index of each function and the control flow graph of its SSA
+		// right before it's lowered to the machine code, as well as the ones of its regions if it's compiled in
+		// regions. This is for the tooling to visualize the SSA.
+		ssaGraphHook func(index wasm.Index, g *ssa.Graph)
+		// unoptimizedFunctions holds the indexes of the functions which are compiled without the SSA optimization
+		// passes, while the others are optimized. This is for narrowing down the miscompilation caused by the
+		// optimizations to a function. See ssa.Builder RunPassesWithoutOptimization.
+		unoptimizedFunctions map[wasm.Index]struct{}
+		// boundsCheckAudit is true if the memory accesses of each function are recorded with how their bounds are
+		// checked. See engine.BoundsCheckAudit.
+		boundsCheckAudit bool
+		// opcodeStats is true if the compilation statistics per Wasm opcode are collected. See engine.OpcodeStats.
+		opcodeStats bool
+		// crossCheck, if non-nil, is the interpreter engine which every module is also compiled by, and the calls
+		// of the compiled functions are checked against. This is for correctness auditing. See crossCheckFunction.
+		crossCheck wasm.Engine
+		// deterministicStack is true if the functions are called on the fixed-size stacks of stackArena rather than
+		// the growable ones, so that the stack addresses are consistent across calls for address-based profiling.
+		deterministicStack bool
+		// stackArena is shared by the modules compiled while deterministicStack is true.
+		stackArena *stackArena
+		// hostCallMetrics is true if each call records its wall time. See callEngine.LastCallMetrics.
+		hostCallMetrics bool
+		// regionSize, if positive, is the size in bytes of the function bodies beyond which the functions are compiled
+		// in regions of about this size, which trades the code quality for the bounded memory and time to compile
+		// huge functions. See frontend.Compiler SetRegionSize.
+		regionSize int
 	}
 
 	// compiledModule is a compiled variant of a wasm.Module and ready to be used for instantiation.
 	compiledModule struct {
-		executable      []byte
+		// compiledUnit holds all the local functions unless lazy is non-nil.
+		compiledUnit
+		offsets wazevoapi.ModuleContextOffsetData
+		// lazy is non-nil if the module is compiled with engine.lazyCompilation.
+		lazy *lazyCompiledFunctions
+		// coverageCounters is the number of coverage counters, and non-zero only when the coverage is enabled.
+		coverageCounters int
+		// coverageBlocks maps each basic block to the index of its coverage counter.
+		coverageBlocks map[CoverageBlock]int
+		// loopCounters is the number of loop back-edge counters, and non-zero only when the loop profiling is enabled.
+		loopCounters int
+		// usedFeatures is the set of features whose instructions are used by the module. See engine.UsedFeatures.
+		usedFeatures api.CoreFeatures
+		// memoryAccessAudits maps the index of each function to its memory accesses, and non-nil only when
+		// engine.boundsCheckAudit is enabled. See engine.BoundsCheckAudit.
+		memoryAccessAudits map[wasm.Index][]frontend.MemoryAccessAudit
+		// opcodeStats is the compilation statistics per Wasm opcode, and non-nil only when engine.opcodeStats is
+		// enabled. See engine.OpcodeStats.
+		opcodeStats *frontend.OpcodeStats
+		// stackArena is the engine.stackArena, and non-nil only when engine.deterministicStack is enabled.
+		stackArena *stackArena
+		// hostCallMetrics is the engine.hostCallMetrics at the time of the compilation.
+		hostCallMetrics bool
+	}
+
+	// compiledUnit is an executable holding the machine code of local functions.
+	compiledUnit struct {
+		executable []byte
+		// functionOffsets is indexed by the local function index, and only the entries of the functions
+		// compiled into the executable are valid.
 		functionOffsets []compiledFunctionOffset
-		offsets         wazevoapi.ModuleContextOffsetData
+	}
+
+	// lazyCompiledFunctions holds the executables of the functions compiled on their first call.
+	//
+	// The machine code calls local functions directly, so a function is compiled into the same compiledUnit
+	// as the local functions it calls, transitively. As a result, a function called by the ones compiled later
+	// might be compiled more than once, but a function which is never called is never compiled.
+	lazyCompiledFunctions struct {
+		engine *engine
+		module *wasm.Module
+		mux    sync.Mutex
+		// units maps the local index of each function compiled so far to the compiledUnit holding it.
+		units map[wasm.Index]*compiledUnit
+		// size is the total size of the executables of units.
+		size int
+	}
+
+	// CoverageBlock identifies a basic block of a local function whose execution is counted when the coverage is enabled.
+	CoverageBlock struct {
+		// FunctionIndex is the index of the function in the module.
+		FunctionIndex wasm.Index
+		// BlockID is the ID of the SSA basic block in the function.
+		BlockID ssa.BasicBlockID
 	}
 
 	// compiledFunctionOffset tells us that where in the executable a function begins.
 	compiledFunctionOffset struct {
-		// offset is the beggining of the function.
+		// offset is the beggining of the function, or -1 if the function is not compiled into the executable.
 		offset int
 		// goPreambleSize is the size of Go preamble of the function.
 		goPreambleSize int
+		// selfRecursive is true if the function directly calls itself. This is found from the relocations of
+		// the calls in the function, so mutual recursions are not detected.
+		selfRecursive bool
 	}
 )
 
 var _ wasm.Engine = (*engine)(nil)
 
+// Config is the configuration of the engines created by NewEngineWithConfig. The zero value is the default
+// configuration, which is the one of NewEngine.
+type Config struct {
+	// Coverage counts the executions of each basic block. See moduleEngine.Coverage.
+	Coverage bool
+	// LoopProfiling counts the back-edges of each loop. See moduleEngine.LoopCounters.
+	LoopProfiling bool
+	// StrictAlignment traps on the memory accesses which are not aligned to the alignment hint of their memarg.
+	StrictAlignment bool
+	// SharedTrapBlocks makes the memory bounds checks in each function branch to the single trap block.
+	SharedTrapBlocks bool
+	// PreciseMemoryTraps checks each memory access against the bounds by itself instead of extending the check of the
+	// earlier access from the same base address, so that the out of bounds memory access reports the first faulting
+	// address rather than the farthest one checked together with it.
+	PreciseMemoryTraps bool
+	// FlushDenormalsToZero flushes denormal floating point operands and results to zero.
+	FlushDenormalsToZero bool
+	// RoundingMode is the rounding mode of the floating point arithmetic and conversions.
+	RoundingMode backend.RoundingMode
+	// ExecutableBudget, if positive, is the maximum total size in bytes of the executables of compiled modules.
+	ExecutableBudget int
+	// LazyCompilation compiles the functions on their first call rather than in CompileModule. This cannot be used
+	// with Coverage or LoopProfiling.
+	LazyCompilation bool
+	// SSAGraphHook, if non-nil, is called with the control flow graph of each function right before it's lowered.
+	SSAGraphHook func(index wasm.Index, g *ssa.Graph)
+	// UnoptimizedFunctions holds the indexes of the functions compiled without the SSA optimization passes.
+	UnoptimizedFunctions map[wasm.Index]struct{}
+	// BoundsCheckAudit records the memory accesses of each function with how their bounds are checked.
+	// See engine.BoundsCheckAudit.
+	BoundsCheckAudit bool
+	// OpcodeStats collects the compilation statistics per Wasm opcode. See engine.OpcodeStats.
+	OpcodeStats bool
+	// CrossCheck checks the calls of the compiled functions against the interpreter. See crossCheckFunction.
+	CrossCheck bool
+	// DeterministicStack calls the functions on the fixed-size stacks so that their addresses are consistent
+	// across calls.
+	DeterministicStack bool
+	// HostCallMetrics records the wall time of each call. See callEngine.LastCallMetrics.
+	HostCallMetrics bool
+	// RegionSize, if positive, is the size in bytes of the function bodies beyond which the functions are compiled
+	// in regions of about this size.
+	RegionSize int
+}
+
 // NewEngine returns the implementation of wasm.Engine.
-func NewEngine(_ context.Context, _ api.CoreFeatures, _ filecache.Cache) wasm.Engine {
-	return &engine{compiledModules: make(map[wasm.ModuleID]*compiledModule), refToBinaryOffset: make(map[ssa.FuncRef]int)}
+func NewEngine(ctx context.Context, enabledFeatures api.CoreFeatures, fileCache filecache.Cache) wasm.Engine {
+	return newEngine(ctx, enabledFeatures, fileCache, Config{})
+}
+
+// NewEngineWithConfig returns the function creating the implementation of wasm.Engine with the given Config, which
+// is used in place of NewEngine.
+func NewEngineWithConfig(cfg Config) func(context.Context, api.CoreFeatures, filecache.Cache) wasm.Engine {
+	return func(ctx context.Context, enabledFeatures api.CoreFeatures, fileCache filecache.Cache) wasm.Engine {
+		return newEngine(ctx, enabledFeatures, fileCache, cfg)
+	}
+}
+
+func newEngine(ctx context.Context, enabledFeatures api.CoreFeatures, fileCache filecache.Cache, cfg Config) *engine {
+	e := &engine{
+		compiledModules:      make(map[wasm.ModuleID]*compiledModule),
+		refToBinaryOffset:    make(map[ssa.FuncRef]int),
+		coverageEnabled:      cfg.Coverage,
+		loopProfilingEnabled: cfg.LoopProfiling,
+		strictAlignment:      cfg.StrictAlignment,
+		sharedTrapBlocks:     cfg.SharedTrapBlocks,
+		preciseMemoryTraps:   cfg.PreciseMemoryTraps,
+		flushDenormalsToZero: cfg.FlushDenormalsToZero,
+		roundingMode:         cfg.RoundingMode,
+		executableBudget:     cfg.ExecutableBudget,
+		lazyCompilation:      cfg.LazyCompilation,
+		ssaGraphHook:         cfg.SSAGraphHook,
+		unoptimizedFunctions: cfg.UnoptimizedFunctions,
+		boundsCheckAudit:     cfg.BoundsCheckAudit,
+		opcodeStats:          cfg.OpcodeStats,
+		deterministicStack:   cfg.DeterministicStack,
+		hostCallMetrics:      cfg.HostCallMetrics,
+		regionSize:           cfg.RegionSize,
+	}
+	if cfg.CrossCheck {
+		e.crossCheck = interpreter.NewEngine(ctx, enabledFeatures, fileCache)
+	}
+	return e
 }
 
 // CompileModule implements wasm.Engine.
-func (e *engine) CompileModule(_ context.Context, module *wasm.Module, _ []experimental.FunctionListener, ensureTermination bool) error {
+func (e *engine) CompileModule(ctx context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination bool) error {
+	if e.crossCheck != nil {
+		if err := e.crossCheck.CompileModule(ctx, module, listeners, ensureTermination); err != nil {
+			return fmt.Errorf("cross-check: %w", err)
+		}
+	}
+
+	if module.IsHostModule {
+		e.compileHostModule(module)
+		return nil
+	}
+
 	e.rels = e.rels[:0]
-	cm := &compiledModule{offsets: wazevoapi.NewModuleContextOffsetData(module)}
+	cm := &compiledModule{offsets: wazevoapi.NewModuleContextOffsetData(module), hostCallMetrics: e.hostCallMetrics}
+	if e.coverageEnabled {
+		cm.offsets.AllocateCoverageBuffer()
+		cm.coverageBlocks = make(map[CoverageBlock]int)
+	}
+	if e.loopProfilingEnabled {
+		cm.offsets.AllocateLoopCounterBuffer()
+	}
+	if e.boundsCheckAudit {
+		cm.memoryAccessAudits = make(map[wasm.Index][]frontend.MemoryAccessAudit)
+	}
+	if e.deterministicStack {
+		if e.stackArena == nil {
+			e.stackArena = &stackArena{}
+		}
+		cm.stackArena = e.stackArena
+	}
 
 	importedFns, localFns := int(module.ImportFunctionCount), len(module.FunctionSection)
 	if importedFns+localFns == 0 {
@@ -61,6 +284,37 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, _ []exper
 		return nil
 	}
 
+	if e.lazyCompilation {
+		if cm.offsets.CoverageBufferBegin >= 0 || cm.offsets.LoopCounterBufferBegin >= 0 {
+			return errors.New("lazy compilation cannot be used with the coverage or the loop profiling")
+		}
+		cm.lazy = &lazyCompiledFunctions{engine: e, module: module, units: make(map[wasm.Index]*compiledUnit)}
+		e.addCompiledModule(module, cm)
+		return nil
+	}
+
+	localIndexes := make([]wasm.Index, localFns)
+	for i := range localIndexes {
+		localIndexes[i] = wasm.Index(i)
+	}
+	unit, rels, err := e.compileFunctions(module, cm, localIndexes, e.rels[:0], e.refToBinaryOffset)
+	if err != nil {
+		return err
+	}
+	e.rels = rels
+	cm.compiledUnit = *unit
+	e.addCompiledModule(module, cm)
+	return nil
+}
+
+// compileFunctions compiles the local functions at the given indexes, as well as the local functions they directly call,
+// into a new executable. The relocations of the calls are resolved with the given rels and refToBinaryOffset, which can
+// be reused across invocations, and rels is returned for that purpose.
+func (e *engine) compileFunctions(module *wasm.Module, cm *compiledModule, localIndexes []wasm.Index,
+	rels []backend.RelocationInfo, refToBinaryOffset map[ssa.FuncRef]int,
+) (*compiledUnit, []backend.RelocationInfo, error) {
+	importedFns, localFns := int(module.ImportFunctionCount), len(module.FunctionSection)
+
 	// TODO: reuse the map to avoid allocation.
 	exportedFnIndex := make(map[wasm.Index]struct{}, len(module.ExportSection))
 	for i := range module.ExportSection {
@@ -72,21 +326,52 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, _ []exper
 	// Creates new compiler instances which are reused for each function.
 	ssaBuilder := ssa.NewBuilder()
 	fe := frontend.NewFrontendCompiler(module, ssaBuilder, &cm.offsets)
+	fe.SetStrictAlignment(e.strictAlignment)
+	fe.SetSharedTrapBlocks(e.sharedTrapBlocks)
+	fe.SetPreciseMemoryTraps(e.preciseMemoryTraps)
+	fe.SetBoundsCheckAudit(cm.memoryAccessAudits != nil && cm.lazy == nil)
+	fe.SetOpcodeStats(e.opcodeStats && cm.lazy == nil)
+	fe.SetRegionSize(e.regionSize)
 	machine := newMachine()
+	if e.flushDenormalsToZero {
+		machine.EnableFlushDenormalsToZero()
+	}
+	machine.SetRoundingMode(e.roundingMode)
 	be := backend.NewCompiler(machine, ssaBuilder)
 
 	totalSize := 0 // Total binary size of the executable.
-	cm.functionOffsets = make([]compiledFunctionOffset, localFns)
+	unit := &compiledUnit{functionOffsets: make([]compiledFunctionOffset, localFns)}
+	for i := range unit.functionOffsets {
+		unit.functionOffsets[i].offset = -1
+	}
 	bodies := make([][]byte, localFns)
-	for i := range module.CodeSection {
+	// compiled[i] is true if the local function i is compiled or queued in localIndexes.
+	compiled := make([]bool, localFns)
+	// bodyCache is non-nil if the machine code of byte-identical functions can be shared. The instrumentations
+	// and the statistics are per function, so they require every function to be compiled on its own.
+	var bodyCache map[string]*cachedFunctionBody
+	if !fe.CoverageEnabled() && cm.offsets.LoopCounterBufferBegin < 0 && !e.opcodeStats && cm.memoryAccessAudits == nil {
+		bodyCache = make(map[string]*cachedFunctionBody)
+	}
+	for _, i := range localIndexes {
+		compiled[i] = true
+	}
+	for len(localIndexes) > 0 {
+		i := int(localIndexes[0])
+		localIndexes = localIndexes[1:]
+
 		fidx := wasm.Index(i + importedFns)
 		fref := frontend.FunctionIndexToFuncRef(fidx)
 
 		_, needGoEntryPreamble := exportedFnIndex[fidx]
+		if cm.lazy != nil {
+			// Any function can be the entry of a lazily compiled unit.
+			needGoEntryPreamble = true
+		}
 
 		// Align 16-bytes boundary.
 		totalSize = (totalSize + 15) &^ 15
-		compiledFuncOffset := &cm.functionOffsets[i]
+		compiledFuncOffset := &unit.functionOffsets[i]
 		compiledFuncOffset.offset = totalSize
 
 		typ := &module.TypeSection[module.FunctionSection[i]]
@@ -96,41 +381,122 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, _ []exper
 			panic("TODO: host module")
 		}
 
+		var cacheKey string
+		if _, unoptimized := e.unoptimizedFunctions[fidx]; bodyCache != nil && !unoptimized {
+			cacheKey = functionBodyCacheKey(typ, codeSeg, needGoEntryPreamble)
+			if cached, ok := bodyCache[cacheKey]; ok {
+				refToBinaryOffset[fref] = totalSize + cached.goPreambleSize
+				if needGoEntryPreamble {
+					compiledFuncOffset.goPreambleSize = cached.goPreambleSize
+				}
+				// The call targets are encoded in the body, so the duplicate calls the same functions as the cached one.
+				for _, r := range cached.rels {
+					r.Offset += int64(totalSize)
+					rels = append(rels, r)
+
+					if r.FuncRef == fref {
+						compiledFuncOffset.selfRecursive = true
+					}
+
+					if callee := int(r.FuncRef) - importedFns; callee < localFns && !compiled[callee] {
+						compiled[callee] = true
+						localIndexes = append(localIndexes, wasm.Index(callee))
+					}
+				}
+				bodies[i] = cached.body
+				totalSize += len(cached.body)
+				continue
+			}
+		}
+
+		if e.compileHook != nil {
+			e.compileHook(fidx)
+		}
+
 		// Initializes both frontend and backend compilers.
 		fe.Init(wasm.Index(i), typ, codeSeg.LocalTypes, codeSeg.Body)
+		var regions *compiledRegions
+		if fe.Regioned() {
+			var err error
+			if regions, err = e.compileRegions(fidx, fe, be, ssaBuilder); err != nil {
+				return nil, nil, err
+			}
+		}
 		be.Init(needGoEntryPreamble)
 
 		// Lower Wasm to SSA.
 		err := fe.LowerToSSA()
 		if err != nil {
-			return fmt.Errorf("wasm->ssa: %v", err)
+			return nil, nil, fmt.Errorf("wasm->ssa: %v", err)
 		}
 
-		// Run SSA-level optimization passes.
-		ssaBuilder.RunPasses()
+		if audits := fe.MemoryAccessAudits(); len(audits) > 0 {
+			cm.memoryAccessAudits[fidx] = append([]frontend.MemoryAccessAudit(nil), audits...)
+		}
+
+		if fe.CoverageEnabled() {
+			for id, index := range fe.CoverageBlocks() {
+				cm.coverageBlocks[CoverageBlock{FunctionIndex: fidx, BlockID: id}] = index
+			}
+		}
+
+		// Run SSA-level optimization passes unless the function is pinned to be unoptimized.
+		if _, ok := e.unoptimizedFunctions[fidx]; ok {
+			ssaBuilder.RunPassesWithoutOptimization()
+		} else {
+			ssaBuilder.RunPasses()
+		}
 
 		// Finalize the layout of SSA blocks which might use the optimization results.
 		ssaBuilder.LayoutBlocks()
 
+		if e.ssaGraphHook != nil {
+			e.ssaGraphHook(fidx, ssaBuilder.Graph())
+		}
+
 		// Now our ssaBuilder contains the necessary information to further lower them to
 		// machine code.
-		body, rels, goPreambleSize, err := be.Compile()
+		body, fnRels, goPreambleSize, err := be.Compile()
 		if err != nil {
-			return fmt.Errorf("ssa->machine code: %v", err)
+			return nil, nil, fmt.Errorf("ssa->machine code: %v", err)
 		}
 
-		e.refToBinaryOffset[fref] = totalSize +
+		refToBinaryOffset[fref] = totalSize +
 			// During the relocation, call target needs to be the beginning of function after Go entry preamble.
 			goPreambleSize
 		if needGoEntryPreamble {
 			compiledFuncOffset.goPreambleSize = goPreambleSize
 		}
 
+		if regions != nil {
+			// The regions follow the function body as if they were a part of it.
+			regionsOffset := (len(body) + 15) &^ 15
+			for ref, offset := range regions.offsets {
+				refToBinaryOffset[ref] = totalSize + regionsOffset + offset
+			}
+			body = append(body, make([]byte, regionsOffset-len(body))...)
+			body = append(body, regions.body...)
+			for _, r := range regions.rels {
+				r.Offset += int64(regionsOffset)
+				fnRels = append(fnRels, r)
+			}
+		}
+
 		// At this point, relocation offsets are relative to the start of the function body,
 		// so we adjust it to the start of the executable.
-		for _, r := range rels {
+		for _, r := range fnRels {
 			r.Offset += int64(totalSize)
-			e.rels = append(e.rels, r)
+			rels = append(rels, r)
+
+			if r.FuncRef == fref {
+				compiledFuncOffset.selfRecursive = true
+			}
+
+			// The callee must be in the same executable unless it's a region which is already compiled.
+			if callee := int(r.FuncRef) - importedFns; callee < localFns && !compiled[callee] {
+				compiled[callee] = true
+				localIndexes = append(localIndexes, wasm.Index(callee))
+			}
 		}
 
 		// TODO: optimize as zero copy.
@@ -138,6 +504,25 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, _ []exper
 		copy(copied, body)
 		bodies[i] = copied
 		totalSize += len(body)
+
+		if cacheKey != "" {
+			bodyCache[cacheKey] = &cachedFunctionBody{
+				body:           copied,
+				rels:           append([]backend.RelocationInfo(nil), fnRels...),
+				goPreambleSize: goPreambleSize,
+			}
+		}
+	}
+
+	if cm.lazy == nil {
+		cm.coverageCounters = fe.CoverageCounters()
+		cm.loopCounters = fe.LoopCounters()
+		cm.usedFeatures = fe.UsedFeatures()
+		cm.opcodeStats = fe.OpcodeStats()
+	}
+
+	if err := e.reserveExecutable(totalSize); err != nil {
+		return nil, nil, err
 	}
 
 	// Allocate executable memory and then copy the generated machine code.
@@ -145,38 +530,184 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, _ []exper
 	if err != nil {
 		panic(err)
 	}
-	cm.executable = executable
+	unit.executable = executable
 
 	for i, b := range bodies {
-		offset := cm.functionOffsets[i]
-		copy(executable[offset.offset:], b)
+		if offset := unit.functionOffsets[i]; offset.offset >= 0 {
+			copy(executable[offset.offset:], b)
+		}
 	}
 
 	// Resolve relocations for local function calls.
-	machine.ResolveRelocations(e.refToBinaryOffset, executable, e.rels)
+	machine.ResolveRelocations(refToBinaryOffset, executable, rels)
 
 	fmt.Println(hex.EncodeToString(executable))
 
 	if runtime.GOARCH == "arm64" {
 		// On arm64, we cannot give all of rwx at the same time, so we change it to exec.
 		if err = platform.MprotectRX(executable); err != nil {
-			return err
+			return nil, nil, err
 		}
 	}
-	e.compiledModules[module.ID] = cm
-	return nil
+	return unit, rels, nil
+}
+
+// cachedFunctionBody is the machine code of a local function, which is reused by the byte-identical functions
+// in the same module instead of compiling them again. See functionBodyCacheKey.
+type cachedFunctionBody struct {
+	body []byte
+	// rels are the relocations of body, and their offsets are relative to the start of body.
+	rels           []backend.RelocationInfo
+	goPreambleSize int
+}
+
+// compiledRegions is the machine code of the regions of a function following each other. See engine.compileRegions.
+type compiledRegions struct {
+	body []byte
+	// rels are the relocations of body, and their offsets are relative to the start of body.
+	rels []backend.RelocationInfo
+	// offsets maps the FuncRef of each region to the offset of its machine code in body.
+	offsets map[ssa.FuncRef]int
+}
+
+// compileRegions compiles the regions of the current function of fe which is frontend.Compiler Regioned.
+func (e *engine) compileRegions(fidx wasm.Index, fe *frontend.Compiler, be backend.Compiler, ssaBuilder ssa.Builder) (*compiledRegions, error) {
+	regions := &compiledRegions{offsets: make(map[ssa.FuncRef]int)}
+	for last := false; !last; {
+		be.Init(false)
+		ref, isLast, err := fe.LowerNextRegionToSSA()
+		if err != nil {
+			return nil, fmt.Errorf("wasm->ssa: %v", err)
+		}
+		last = isLast
+
+		if _, ok := e.unoptimizedFunctions[fidx]; ok {
+			ssaBuilder.RunPassesWithoutOptimization()
+		} else {
+			ssaBuilder.RunPasses()
+		}
+		ssaBuilder.LayoutBlocks()
+
+		if e.ssaGraphHook != nil {
+			e.ssaGraphHook(fidx, ssaBuilder.Graph())
+		}
+
+		body, rels, _, err := be.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("ssa->machine code: %v", err)
+		}
+
+		// Align 16-bytes boundary.
+		offset := (len(regions.body) + 15) &^ 15
+		regions.body = append(regions.body, make([]byte, offset-len(regions.body))...)
+		regions.offsets[ref] = offset
+		regions.body = append(regions.body, body...)
+		for _, r := range rels {
+			r.Offset += int64(offset)
+			regions.rels = append(regions.rels, r)
+		}
+	}
+	return regions, nil
+}
+
+// functionBodyCacheKey returns the key of cachedFunctionBody for the function of the given type and code.
+// The machine code only depends on them and whether the Go entry preamble is prepended, since the function
+// index is not encoded into the machine code.
+func functionBodyCacheKey(typ *wasm.FunctionType, code *wasm.Code, needGoEntryPreamble bool) string {
+	key := make([]byte, 0, len(code.LocalTypes)+len(code.Body)+32)
+	key = append(key, typ.String()...)
+	if needGoEntryPreamble {
+		key = append(key, 1)
+	} else {
+		key = append(key, 0)
+	}
+	key = binary.LittleEndian.AppendUint32(key, uint32(len(code.LocalTypes)))
+	key = append(key, code.LocalTypes...)
+	key = append(key, code.Body...)
+	return string(key)
+}
+
+// unitOf returns the compiledUnit holding the local function at the given index. When the module is compiled lazily,
+// this compiles the function on the first call.
+func (cm *compiledModule) unitOf(localIndex wasm.Index) (*compiledUnit, error) {
+	l := cm.lazy
+	if l == nil {
+		return &cm.compiledUnit, nil
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.units == nil {
+		return nil, errors.New("engine closed")
+	}
+	if unit, ok := l.units[localIndex]; ok {
+		return unit, nil
+	}
+
+	unit, _, err := l.engine.compileFunctions(l.module, cm, []wasm.Index{localIndex}, nil, make(map[ssa.FuncRef]int))
+	if err != nil {
+		return nil, err
+	}
+	for i, offset := range unit.functionOffsets {
+		if _, ok := l.units[wasm.Index(i)]; !ok && offset.offset >= 0 {
+			l.units[wasm.Index(i)] = unit
+		}
+	}
+	l.size += len(unit.executable)
+	return unit, nil
+}
+
+// executableSize returns the total size of the executables of the module.
+func (cm *compiledModule) executableSize() int {
+	l := cm.lazy
+	if l == nil {
+		return len(cm.executable)
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.size
+}
+
+// compileHostModule adds the compiledModule for the host module so that it can be instantiated. Its Go functions
+// can't be called from the machine code yet.
+//
+// TODO: compile the trampolines to be called by the machine code of the importing modules, which exit with
+// ExitCodeCallGoModuleFunction or ExitCodeCallGoFunction so that CallWithStack calls the Go functions with its ctx.
+func (e *engine) compileHostModule(module *wasm.Module) {
+	cm := &compiledModule{offsets: wazevoapi.ModuleContextOffsetData{
+		LocalMemoryBegin:       -1,
+		ImportedMemoryBegin:    -1,
+		ImportedFunctionsBegin: -1,
+		TablesBegin:            -1,
+		GlobalsBegin:           -1,
+		CoverageBufferBegin:    -1,
+		LoopCounterBufferBegin: -1,
+	}}
+	e.addCompiledModule(module, cm)
 }
 
 // Close implements wasm.Engine.
 func (e *engine) Close() (err error) {
 	e.mux.Lock()
-	defer e.mux.Unlock()
+	compiledModules := e.compiledModules
+	e.compiledModules = nil
+	e.executableSize = 0
+	e.mux.Unlock()
 
-	for _, cm := range e.compiledModules {
+	// The lazily compiled functions reserve their executables while holding the lock of lazyCompiledFunctions,
+	// so it must be taken without holding e.mux.
+	for _, cm := range compiledModules {
 		cm.executable = nil
 		cm.functionOffsets = nil
+		if l := cm.lazy; l != nil {
+			l.mux.Lock()
+			l.units = nil
+			l.mux.Unlock()
+		}
+	}
+	if e.crossCheck != nil {
+		return e.crossCheck.Close()
 	}
-	e.compiledModules = nil
 	return nil
 }
 
@@ -190,14 +721,123 @@ func (e *engine) CompiledModuleCount() uint32 {
 // DeleteCompiledModule implements wasm.Engine.
 func (e *engine) DeleteCompiledModule(m *wasm.Module) {
 	e.mux.Lock()
-	defer e.mux.Unlock()
+	cm, ok := e.compiledModules[m.ID]
 	delete(e.compiledModules, m.ID)
+	e.mux.Unlock()
+	if !ok {
+		return
+	}
+
+	if e.crossCheck != nil {
+		e.crossCheck.DeleteCompiledModule(m)
+	}
+
+	// The lazily compiled functions reserve their executables while holding the lock of lazyCompiledFunctions,
+	// so its size must be read without holding e.mux.
+	size := cm.executableSize()
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.executableSize -= size
 }
 
-func (e *engine) addCompiledModule(m *wasm.Module, cm *compiledModule) {
+// UsedFeatures returns the set of features whose instructions are used by the compiled module,
+// or false if the module is not compiled, including when it's compiled lazily. This allows embedders to inspect a module, e.g. whether it uses
+// api.CoreFeatureSIMD, before instantiating it. See frontend.Compiler.UsedFeatures for what is reported.
+func (e *engine) UsedFeatures(m *wasm.Module) (features api.CoreFeatures, ok bool) {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+	cm, ok := e.compiledModules[m.ID]
+	if !ok || cm.lazy != nil {
+		return 0, false
+	}
+	return cm.usedFeatures, true
+}
+
+// BoundsCheckAudit returns the memory accesses of each function in the compiled module with how their bounds are
+// checked, keyed by the function index, or false if the module is not compiled with engine.boundsCheckAudit, including
+// when it's compiled lazily. Functions without memory accesses are not included.
+// See frontend.Compiler.MemoryAccessAudits for what is reported.
+func (e *engine) BoundsCheckAudit(m *wasm.Module) (audits map[wasm.Index][]frontend.MemoryAccessAudit, ok bool) {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+	cm, ok := e.compiledModules[m.ID]
+	if !ok || cm.memoryAccessAudits == nil || cm.lazy != nil {
+		return nil, false
+	}
+	return cm.memoryAccessAudits, true
+}
+
+// OpcodeStats returns the report of the compilation statistics per Wasm opcode of the compiled module, or false if
+// the module is not compiled with engine.opcodeStats, including when it's compiled lazily.
+func (e *engine) OpcodeStats(m *wasm.Module) (stats *frontend.OpcodeStats, ok bool) {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+	cm, ok := e.compiledModules[m.ID]
+	if !ok || cm.opcodeStats == nil {
+		return nil, false
+	}
+	return cm.opcodeStats, true
+}
+
+// UnsupportedInstructions returns the names of the instructions in the module which wazevo doesn't lower yet, sorted
+// without duplicates, so that the embedders can tell whether the module compiles before compiling it.
+//
+// This only lowers the functions to SSA without optimizing or compiling them to the machine code. Notably, the
+// instructions following the first unsupported one in each function are not reported, since the lowering can't
+// proceed beyond it. Neither are the ones lowered to SSA but not supported by the backend of the platform.
+func (e *engine) UnsupportedInstructions(module *wasm.Module) []string {
+	if module.IsHostModule {
+		return nil
+	}
+	offsets := wazevoapi.NewModuleContextOffsetData(module)
+	fe := frontend.NewFrontendCompiler(module, ssa.NewBuilder(), &offsets)
+	names := map[string]struct{}{}
+	for i := range module.CodeSection {
+		codeSeg := &module.CodeSection[i]
+		fe.Init(wasm.Index(i), &module.TypeSection[module.FunctionSection[i]], codeSeg.LocalTypes, codeSeg.Body)
+		var unsupported *frontend.UnsupportedInstructionError
+		if err := fe.LowerToSSA(); errors.As(err, &unsupported) {
+			names[unsupported.Name] = struct{}{}
+		}
+	}
+
+	ret := make([]string, 0, len(names))
+	for name := range names {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// reserveExecutable accounts for the executable of the given size against executableBudget,
+// and returns an error if that exceeds the budget.
+func (e *engine) reserveExecutable(size int) error {
 	e.mux.Lock()
 	defer e.mux.Unlock()
+	if budget := e.executableBudget; budget > 0 && e.executableSize+size > budget {
+		return fmt.Errorf("compiled code size exceeds the budget: %d (current) + %d (new) > %d (budget)",
+			e.executableSize, size, budget)
+	}
+	e.executableSize += size
+	return nil
+}
+
+// addCompiledModule adds the compiledModule of the given module, and frees up the budget of the one it replaces,
+// e.g. when the same module is compiled again.
+func (e *engine) addCompiledModule(m *wasm.Module, cm *compiledModule) {
+	e.mux.Lock()
+	old, ok := e.compiledModules[m.ID]
 	e.compiledModules[m.ID] = cm
+	e.mux.Unlock()
+	if !ok {
+		return
+	}
+
+	// Same as DeleteCompiledModule, the size must be read without holding e.mux.
+	size := old.executableSize()
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.executableSize -= size
 }
 
 // NewModuleEngine implements wasm.Engine.
@@ -218,5 +858,24 @@ func (e *engine) NewModuleEngine(m *wasm.Module, mi *wasm.ModuleInstance) (wasm.
 		me.opaque = opaque
 		me.opaquePtr = &opaque[0]
 	}
+
+	if n := compiled.coverageCounters; n > 0 {
+		me.coverage = make([]uint64, n)
+	}
+	if n := compiled.loopCounters; n > 0 {
+		me.loopCounters = make([]uint64, n)
+	}
+
+	if e.crossCheck != nil {
+		// The interpreter requires the module instance whose Engine is its own, so it gets the separate one.
+		// Its states are shared with mi in moduleEngine.DoneInstantiation since they are not built yet.
+		crossCheckModule := &wasm.ModuleInstance{ModuleName: mi.ModuleName, TypeIDs: mi.TypeIDs, Source: m}
+		crossCheck, err := e.crossCheck.NewModuleEngine(m, crossCheckModule)
+		if err != nil {
+			return nil, fmt.Errorf("cross-check: %w", err)
+		}
+		crossCheckModule.Engine = crossCheck
+		me.crossCheck, me.crossCheckModule = crossCheck, crossCheckModule
+	}
 	return me, nil
 }
@@ -3,7 +3,11 @@ package frontend
 
 import (
 	"bytes"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -19,6 +23,17 @@ type Compiler struct {
 	// ssaBuilder is a ssa.Builder used by this frontend.
 	ssaBuilder ssa.Builder
 	signatures map[*wasm.FunctionType]*ssa.Signature
+	// hasDataCount is true if the module has the DataCount section, which is required by memory.init and data.drop.
+	hasDataCount bool
+	// dataSegments is the number of data segments in the module.
+	dataSegments uint32
+	// tableTypes holds the element type of each table including the imported ones.
+	tableTypes []wasm.RefType
+	// tableMinimums holds the minimum size of each table including the imported ones. The tables never shrink, and
+	// the imported ones are at least as large as declared by the imports.
+	tableMinimums []uint32
+	// globalTypes holds the type of each global including the imported ones.
+	globalTypes []wasm.GlobalType
 
 	// Followings are reset by per function.
 
@@ -36,6 +51,97 @@ type Compiler struct {
 	loweringState loweringState
 
 	execCtxPtrValue, moduleCtxPtrValue ssa.Value
+
+	// coverageCounters is the number of coverage counters assigned so far in the module. See insertCoverageProbe.
+	coverageCounters int
+	// coverageBlocks maps the ID of basic blocks in the current function to the index of their coverage counters.
+	coverageBlocks map[ssa.BasicBlockID]int
+	// loopCounters is the number of loop back-edge counters assigned so far in the module. See insertLoopBackEdgeCounter.
+	loopCounters int
+	// strictAlignment is true if memory accesses trap when the effective address is not aligned to the memarg alignment hint.
+	// See SetStrictAlignment.
+	strictAlignment bool
+	// sharedTrapBlocks is true if the memory bounds checks in a function branch to the single trap block instead of
+	// exiting by themselves. See SetSharedTrapBlocks.
+	sharedTrapBlocks bool
+	// preciseMemoryTraps is true if the memory bounds checks are never extended. See SetPreciseMemoryTraps.
+	preciseMemoryTraps bool
+	// usedFeatures is the set of features whose instructions have been lowered so far in the module. See UsedFeatures.
+	usedFeatures api.CoreFeatures
+	// boundsCheckAudit is true if the memory accesses are recorded in memoryAccessAudits. See SetBoundsCheckAudit.
+	boundsCheckAudit bool
+	// memoryAccessAudits holds the memory accesses in the current function. See MemoryAccessAudits.
+	memoryAccessAudits []MemoryAccessAudit
+	// opcodeStats is non-nil if the statistics per opcode are collected. See SetOpcodeStats.
+	opcodeStats *OpcodeStats
+	// regionSize is the size of the regions which the huge functions are lowered in. See SetRegionSize.
+	regionSize int
+	// regionCount is the number of the regions lowered so far in the module.
+	regionCount uint32
+	// region is the state of lowering the current function in regions.
+	region regionLowering
+}
+
+type (
+	// MemoryAccessAudit is the record of a memory access for auditing its bounds check. See SetBoundsCheckAudit.
+	MemoryAccessAudit struct {
+		// Offset is the byte offset of the load or store instruction in the function body.
+		Offset int
+		// BoundsCheck is how the bounds of the access are checked.
+		BoundsCheck BoundsCheck
+	}
+
+	// BoundsCheck is how the bounds of a memory access are checked.
+	BoundsCheck byte
+
+	// OpcodeStats is the report of the compilation statistics per Wasm opcode. See SetOpcodeStats.
+	OpcodeStats struct {
+		// Opcodes maps the opcodes to their statistics. The instructions with a prefix, such as wasm.OpcodeMiscPrefix,
+		// are tallied as the family of the prefix.
+		Opcodes map[wasm.Opcode]*OpcodeStat
+		// Instructions is the total number of the SSA instructions generated for the functions, which also includes
+		// the ones not attributed to any opcode, such as the initialization of the locals in the entry block.
+		Instructions int
+	}
+
+	// OpcodeStat is the compilation statistics of a Wasm opcode.
+	OpcodeStat struct {
+		// Count is the number of the lowered instructions with the opcode.
+		Count int
+		// Instructions is the number of the SSA instructions generated by lowering them.
+		Instructions int
+		// Duration is the total time taken to lower them.
+		Duration time.Duration
+	}
+)
+
+const (
+	// BoundsCheckNone means the access is not checked at all. This is never the case at the moment, as every access
+	// is checked regardless of the compile options, but this is what the audit is to catch.
+	BoundsCheckNone BoundsCheck = iota
+	// BoundsCheckEmitted means the check is emitted for the access.
+	BoundsCheckEmitted
+	// BoundsCheckExtended means the preceding check for the same base address in the same block is extended to
+	// cover the access. See Compiler.insertBoundsCheck.
+	BoundsCheckExtended
+)
+
+// Checked returns true if the access is checked, whether by its own check or the extended one.
+func (a MemoryAccessAudit) Checked() bool {
+	return a.BoundsCheck != BoundsCheckNone
+}
+
+// String implements fmt.Stringer.
+func (b BoundsCheck) String() string {
+	switch b {
+	case BoundsCheckNone:
+		return "none"
+	case BoundsCheckEmitted:
+		return "emitted"
+	case BoundsCheckExtended:
+		return "extended"
+	}
+	panic(int(b))
 }
 
 // NewFrontendCompiler returns a frontend Compiler.
@@ -46,6 +152,26 @@ func NewFrontendCompiler(m *wasm.Module, ssaBuilder ssa.Builder, offset *wazevoa
 		br:                  bytes.NewReader(nil),
 		wasmLocalToVariable: make(map[wasm.Index]ssa.Variable),
 		offset:              offset,
+		coverageBlocks:      make(map[ssa.BasicBlockID]int),
+		hasDataCount:        m.DataCountSection != nil,
+		dataSegments:        uint32(len(m.DataSection)),
+	}
+
+	for i := range m.ImportSection {
+		switch imp := &m.ImportSection[i]; imp.Type {
+		case wasm.ExternTypeTable:
+			c.tableTypes = append(c.tableTypes, imp.DescTable.Type)
+			c.tableMinimums = append(c.tableMinimums, imp.DescTable.Min)
+		case wasm.ExternTypeGlobal:
+			c.globalTypes = append(c.globalTypes, imp.DescGlobal)
+		}
+	}
+	for i := range m.TableSection {
+		c.tableTypes = append(c.tableTypes, m.TableSection[i].Type)
+		c.tableMinimums = append(c.tableMinimums, m.TableSection[i].Min)
+	}
+	for i := range m.GlobalSection {
+		c.globalTypes = append(c.globalTypes, m.GlobalSection[i].Type)
 	}
 
 	c.signatures = make(map[*wasm.FunctionType]*ssa.Signature, len(m.TypeSection))
@@ -80,6 +206,100 @@ func (c *Compiler) Init(idx wasm.Index, typ *wasm.FunctionType, localTypes []was
 	c.wasmFunctionTyp = typ
 	c.wasmFunctionLocalTypes = localTypes
 	c.wasmFunctionBody = body
+
+	for id := range c.coverageBlocks {
+		delete(c.coverageBlocks, id)
+	}
+	c.memoryAccessAudits = c.memoryAccessAudits[:0]
+	c.region.start, c.region.done = 0, false
+	c.region.stackTypes = c.region.stackTypes[:0]
+	c.region.refs, c.region.signatures = c.region.refs[:0], c.region.signatures[:0]
+}
+
+// CoverageEnabled returns true if the coverage probes are inserted at the beginning of each basic block.
+// This is enabled when the wazevoapi.ModuleContextOffsetData has the coverage buffer allocated.
+func (c *Compiler) CoverageEnabled() bool {
+	return c.offset.CoverageBufferBegin >= 0
+}
+
+// CoverageBlocks returns the map from the ID of basic blocks in the current function to the index of their coverage counters.
+// The returned map is reused for the next function, so the caller must copy the contents if necessary.
+func (c *Compiler) CoverageBlocks() map[ssa.BasicBlockID]int {
+	return c.coverageBlocks
+}
+
+// CoverageCounters returns the number of coverage counters assigned so far in the module.
+func (c *Compiler) CoverageCounters() int {
+	return c.coverageCounters
+}
+
+// LoopProfilingEnabled returns true if the counters are incremented at each loop back-edge, i.e. each branch to a loop header.
+// This is enabled when the wazevoapi.ModuleContextOffsetData has the loop counter buffer allocated.
+func (c *Compiler) LoopProfilingEnabled() bool {
+	return c.offset.LoopCounterBufferBegin >= 0
+}
+
+// LoopCounters returns the number of loop back-edge counters assigned so far in the module.
+// Each loop is assigned the ID in the order of lowering, which is the index of its counter.
+func (c *Compiler) LoopCounters() int {
+	return c.loopCounters
+}
+
+// UsedFeatures returns the set of features whose instructions have been lowered so far in the module,
+// e.g. api.CoreFeatureSIMD if any function contains a vector instruction, regardless of whether it is reachable.
+// Features which don't come with instructions, such as api.CoreFeatureMultiValue, are never reported.
+func (c *Compiler) UsedFeatures() api.CoreFeatures {
+	return c.usedFeatures
+}
+
+// SetStrictAlignment sets whether the memory accesses are checked against the alignment hint of their memarg.
+// When enabled, an access whose effective address is not aligned exits with wazevoapi.ExitCodeUnalignedMemoryAccess
+// instead of silently succeeding, which is allowed by the spec but not portable.
+func (c *Compiler) SetStrictAlignment(enabled bool) {
+	c.strictAlignment = enabled
+}
+
+// SetSharedTrapBlocks sets whether the memory bounds checks in a function branch to the single block which exits with
+// wazevoapi.ExitCodeMemoryOutOfBounds, instead of each check inlining its own exit sequence. This trades the
+// additional branches on the access paths for the smaller code of the functions with many memory accesses.
+func (c *Compiler) SetSharedTrapBlocks(enabled bool) {
+	c.sharedTrapBlocks = enabled
+}
+
+// SetPreciseMemoryTraps sets whether each memory access is checked against the bounds by itself, instead of extending
+// the check of the earlier access from the same base address. When enabled, the out of bounds memory access always
+// reports the address of the first faulting access at the cost of the additional checks. See insertBoundsCheck.
+func (c *Compiler) SetPreciseMemoryTraps(enabled bool) {
+	c.preciseMemoryTraps = enabled
+}
+
+// SetBoundsCheckAudit sets whether the memory accesses are recorded with how their bounds are checked, so that
+// auditors can verify every access in the compiled code is checked. See MemoryAccessAudits.
+func (c *Compiler) SetBoundsCheckAudit(enabled bool) {
+	c.boundsCheckAudit = enabled
+}
+
+// MemoryAccessAudits returns the memory accesses in the current function in the order of their offsets, which are
+// recorded only when enabled by SetBoundsCheckAudit. The accesses in the unreachable code are not included since no
+// machine code is emitted for them.
+// The returned slice is reused for the next function, so the caller must copy the contents if necessary.
+func (c *Compiler) MemoryAccessAudits() []MemoryAccessAudit {
+	return c.memoryAccessAudits
+}
+
+// SetOpcodeStats sets whether the number of generated SSA instructions and the time taken to lower are tallied per
+// Wasm opcode, which is for finding the lowerings dominating the compilation time. See OpcodeStats.
+func (c *Compiler) SetOpcodeStats(enabled bool) {
+	if enabled {
+		c.opcodeStats = &OpcodeStats{Opcodes: make(map[wasm.Opcode]*OpcodeStat)}
+	} else {
+		c.opcodeStats = nil
+	}
+}
+
+// OpcodeStats returns the statistics per opcode tallied so far in the module, or nil unless enabled by SetOpcodeStats.
+func (c *Compiler) OpcodeStats() *OpcodeStats {
+	return c.opcodeStats
 }
 
 // Note: this assumes 64-bit platform (I believe we won't have 32-bit backend ;)).
@@ -90,6 +310,9 @@ const executionContextPtrTyp, moduleContextPtrTyp = ssa.TypeI64, ssa.TypeI64
 //
 // Note that this only does the naive lowering, and do not do any optimization, instead the caller is expected to do so.
 func (c *Compiler) LowerToSSA() error {
+	if c.Regioned() {
+		return c.lowerRegionDriver()
+	}
 	builder := c.ssaBuilder
 
 	// Set up the entry block.
@@ -128,16 +351,56 @@ func (c *Compiler) LowerToSSA() error {
 	}
 	c.declareWasmLocals(entryBlock)
 	c.declareNecessaryVariables()
+	c.insertCoverageProbe()
 
-	c.lowerBody(entryBlock)
-	return nil
+	return c.lowerBody(entryBlock, builder.ReturnBlock())
 }
 
 // localVariable returns the SSA variable for the given Wasm local index.
 func (c *Compiler) localVariable(index wasm.Index) ssa.Variable {
+	if debug {
+		c.checkLocalIndex(index)
+	}
 	return c.wasmLocalToVariable[index]
 }
 
+// checkLocalIndex panics if the given Wasm local index is out of range of the current function's locals.
+// Otherwise, wasmLocalToVariable might return the stale variable of previously compiled functions.
+func (c *Compiler) checkLocalIndex(index wasm.Index) {
+	if n := len(c.wasmFunctionTyp.Params) + len(c.wasmFunctionLocalTypes); int(index) >= n {
+		panic(fmt.Sprintf("BUG: local index %d out of range of %d locals in function %d",
+			index, n, c.wasmLocalFunctionIndex))
+	}
+}
+
+// checkBlocksSealed panics if any basic block of the current function is not sealed after lowering.
+// Otherwise, the incomplete block params of unsealed blocks would silently be left unresolved and produce wrong values.
+func (c *Compiler) checkBlocksSealed() {
+	builder := c.ssaBuilder
+	var unsealed []string
+	for blk := builder.BlockIteratorBegin(); blk != nil; blk = builder.BlockIteratorNext() {
+		if !blk.Sealed() {
+			unsealed = append(unsealed, blk.Name())
+		}
+	}
+	if len(unsealed) > 0 {
+		panic(fmt.Sprintf("BUG: unsealed blocks in function %d: %s",
+			c.wasmLocalFunctionIndex, strings.Join(unsealed, ", ")))
+	}
+}
+
+// validateDataSegmentIndex returns an error if the data segment index used by the given bulk memory instruction is invalid.
+func (c *Compiler) validateDataSegmentIndex(op wasm.OpcodeMisc, index uint32) error {
+	if !c.hasDataCount {
+		return fmt.Errorf("%s requires data count section", wasm.MiscInstructionName(op))
+	}
+	if index >= c.dataSegments {
+		return fmt.Errorf("%s: data segment index %d out of range of data section(len=%d)",
+			wasm.MiscInstructionName(op), index, c.dataSegments)
+	}
+	return nil
+}
+
 // declareWasmLocals declares the SSA variables for the Wasm locals.
 func (c *Compiler) declareWasmLocals(entry ssa.BasicBlock) {
 	localCount := wasm.Index(len(c.wasmFunctionTyp.Params))
@@ -146,33 +409,38 @@ func (c *Compiler) declareWasmLocals(entry ssa.BasicBlock) {
 		variable := c.ssaBuilder.DeclareVariable(st)
 		c.wasmLocalToVariable[wasm.Index(i)+localCount] = variable
 
-		zeroInst := c.ssaBuilder.AllocateInstruction()
-		switch st {
-		case ssa.TypeI32:
-			zeroInst.AsIconst32(0)
-		case ssa.TypeI64:
-			zeroInst.AsIconst64(0)
-		case ssa.TypeF32:
-			zeroInst.AsF32const(0)
-		case ssa.TypeF64:
-			zeroInst.AsF64const(0)
-		default:
-			panic("TODO: " + wasm.ValueTypeName(typ))
-		}
-
-		c.ssaBuilder.InsertInstruction(zeroInst)
-		value := zeroInst.Return()
+		value := c.insertZeroValue(st)
 		c.ssaBuilder.DefineVariable(variable, value, entry)
 	}
 }
 
+// insertZeroValue inserts the constant instruction of the zero value of the given type, and returns the value.
+func (c *Compiler) insertZeroValue(st ssa.Type) ssa.Value {
+	zeroInst := c.ssaBuilder.AllocateInstruction()
+	switch st {
+	case ssa.TypeI32:
+		zeroInst.AsIconst32(0)
+	case ssa.TypeI64:
+		// This is also the null reference for funcref and externref.
+		zeroInst.AsIconst64(0)
+	case ssa.TypeF32:
+		zeroInst.AsF32const(0)
+	case ssa.TypeF64:
+		zeroInst.AsF64const(0)
+	default:
+		panic("TODO: " + st.String())
+	}
+	c.ssaBuilder.InsertInstruction(zeroInst)
+	return zeroInst.Return()
+}
+
 func (c *Compiler) declareNecessaryVariables() {
 	c.needMemory = len(c.m.ImportedMemories()) > 0 || c.m.MemorySection != nil
 	if c.needMemory {
 		c.memoryBaseVariable = c.ssaBuilder.DeclareVariable(ssa.TypeI64)
 		c.memoryLenVariable = c.ssaBuilder.DeclareVariable(ssa.TypeI64)
 	}
-	// TODO: add tables, globals.
+	// TODO: add tables.
 }
 
 // wasmToSSA converts wasm.ValueType to ssa.Type.
@@ -186,6 +454,9 @@ func wasmToSSA(vt wasm.ValueType) ssa.Type {
 		return ssa.TypeF32
 	case wasm.ValueTypeF64:
 		return ssa.TypeF64
+	case wasm.ValueTypeFuncref, wasm.ValueTypeExternref:
+		// References are opaque pointers.
+		return ssa.TypeI64
 	default:
 		panic("TODO: " + wasm.ValueTypeName(vt))
 	}
@@ -1,6 +1,8 @@
 package frontend
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -32,6 +34,16 @@ func TestCompiler_LowerToSSA(t *testing.T) {
 			exp: `
 blk0: (exec_ctx:i64, module_ctx:i64)
 	Jump blk_ret
+`,
+		},
+		{
+			name: "i64_consts", m: testcases.I64Constants.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	v2:i64 = Iconst_64 0x8000000000000000
+	v3:i64 = Iconst_64 0x7fffffffffffffff
+	v4:i64 = Iconst_64 0xffffffffffffffff
+	Jump blk_ret, v2, v3, v4
 `,
 		},
 		{
@@ -263,6 +275,25 @@ blk2: () <-- (blk0)
 
 blk3: () <-- (blk1,blk2)
 	Jump blk_ret
+`,
+		},
+		{
+			name: "if without else params", m: testcases.IfWithoutElseParams.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	Brz v2, blk2
+	Jump blk1
+
+blk1: () <-- (blk0)
+	v4:i32 = Iconst_32 0x1
+	v5:i32 = Iadd v2, v4
+	Jump blk3, v5
+
+blk2: () <-- (blk0)
+	Jump blk3, v2
+
+blk3: (v3:i32) <-- (blk1,blk2)
+	Jump blk_ret, v3
 `,
 		},
 		{
@@ -281,6 +312,34 @@ blk2: () <-- (blk0)
 
 blk3: () <-- (blk1)
 	Jump blk_ret
+`,
+		},
+		{
+			name: "early return from nested blocks", m: testcases.EarlyReturnFromNestedBlocks.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i32 = Iconst_32 0xa
+	v4:i32 = Iconst_32 0x14
+	Brz v2, blk4
+	Jump blk3
+
+blk1: () <-- (blk2)
+	v7:i32 = Iconst_32 0x32
+	Jump blk_ret, v3, v7
+
+blk2: () <-- (blk5)
+	Jump blk1
+
+blk3: () <-- (blk0)
+	v5:i32 = Iconst_32 0x1e
+	v6:i32 = Iconst_32 0x28
+	Return v5, v6
+
+blk4: () <-- (blk0)
+	Jump blk5
+
+blk5: () <-- (blk4)
+	Jump blk2
 `,
 		},
 		{
@@ -583,6 +642,43 @@ blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i64)
 	v9:i32 = SExtend v2, 8->32
 	v10:i32 = SExtend v2, 16->32
 	Jump blk_ret, v4, v5, v6, v7, v8, v9, v10
+`,
+		},
+		{
+			name: "integer extension chains", m: testcases.IntegerExtensionChains.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i64)
+	v4:i32 = SExtend v2, 8->32
+	v5:i32 = SExtend v4, 16->32
+	v6:i32 = SExtend v2, 16->32
+	v7:i32 = SExtend v6, 8->32
+	v8:i32 = SExtend v2, 8->32
+	v9:i64 = SExtend v8, 32->64
+	v10:i32 = SExtend v2, 16->32
+	v11:i64 = UExtend v10, 32->64
+	v12:i64 = SExtend v2, 32->64
+	v13:i64 = SExtend v12, 32->64
+	v14:i64 = UExtend v2, 32->64
+	v15:i64 = SExtend v14, 32->64
+	v16:i64 = UExtend v2, 32->64
+	v17:i64 = SExtend v16, 8->64
+	v18:i64 = SExtend v3, 8->64
+	v19:i64 = SExtend v18, 16->64
+	Jump blk_ret, v5, v7, v9, v11, v13, v15, v17, v19
+`,
+			expAfterOpt: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i64)
+	v4:i32 = SExtend v2, 8->32
+	v7:i32 = SExtend v2, 8->32
+	v9:i64 = SExtend v2, 8->64
+	v10:i32 = SExtend v2, 16->32
+	v11:i64 = UExtend v10, 32->64
+	v12:i64 = SExtend v2, 32->64
+	v14:i64 = UExtend v2, 32->64
+	v15:i64 = SExtend v14, 32->64
+	v17:i64 = SExtend v2, 8->64
+	v18:i64 = SExtend v3, 8->64
+	Jump blk_ret, v4, v7, v9, v11, v12, v15, v17, v18
 `,
 		},
 		{
@@ -602,6 +698,30 @@ blk0: (exec_ctx:i64, module_ctx:i64, v2:f32, v3:f32, v4:f64, v5:f64)
 	v16:i32 = Fcmp le, v4, v5
 	v17:i32 = Fcmp ge, v4, v5
 	Jump blk_ret, v6, v7, v8, v9, v10, v11, v12, v13, v14, v15, v16, v17
+`,
+		},
+		{
+			name: "float mul add", m: testcases.FloatMulAdd.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:f32, v3:f32, v4:f32, v5:f64, v6:f64, v7:f64)
+	v8:f32 = Fmul v2, v3
+	v9:f32 = Fadd v8, v4
+	v10:f64 = Fmul v5, v6
+	v11:f64 = Fadd v10, v7
+	Jump blk_ret, v9, v11
+`,
+		},
+		{
+			name: "selects", m: testcases.Selects.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32, v4:i32, v5:i64, v6:i64, v7:f32, v8:f32, v9:f64, v10:f64)
+	v11:i32 = Select v2, v3, v4
+	v12:i64 = Select v2, v5, v6
+	v13:f32 = Select v2, v7, v8
+	v14:i32 = Iconst_32 0x0
+	v15:i32 = Icmp neq, v2, v14
+	v16:f64 = Select v15, v9, v10
+	Jump blk_ret, v11, v12, v13, v16
 `,
 		},
 		{
@@ -870,7 +990,7 @@ blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
 	v5:i64 = Uload32 module_ctx, 0x8
 	v6:i64 = Iadd v4, v3
 	v7:i32 = Icmp ge_u, v5, v6
-	ExitIfNotZero v7, exec_ctx, memory_out_of_bounds
+	ExitIfNotZero v7, exec_ctx, v6, memory_out_of_bounds
 	v8:i64 = Load module_ctx, 0x0
 	v9:i64 = Iadd v8, v4
 	v10:i32 = Load v9, 0x0
@@ -905,7 +1025,7 @@ blk3: (v7:i32) <-- (blk1,blk2)
 	v10:i64 = Uload32 module_ctx, 0x8
 	v11:i64 = Iadd v9, v8
 	v12:i32 = Icmp ge_u, v10, v11
-	ExitIfNotZero v12, exec_ctx, memory_out_of_bounds
+	ExitIfNotZero v12, exec_ctx, v11, memory_out_of_bounds
 	v13:i64 = Load module_ctx, 0x0
 	v14:i64 = Iadd v13, v9
 	v15:i32 = Load v14, 0x0
@@ -935,7 +1055,7 @@ blk3: () <-- (blk1,blk2)
 	v10:i64 = Uload32 module_ctx, 0x8
 	v11:i64 = Iadd v9, v8
 	v12:i32 = Icmp ge_u, v10, v11
-	ExitIfNotZero v12, exec_ctx, memory_out_of_bounds
+	ExitIfNotZero v12, exec_ctx, v11, memory_out_of_bounds
 	v13:i64 = Load module_ctx, 0x0
 	v14:i64 = Iadd v13, v9
 	v15:i32 = Load v14, 0x0
@@ -954,244 +1074,2023 @@ blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
 	v4:i64 = Load module_ctx, 0x8
 	v5:i32 = CallIndirect v3:sig0, exec_ctx, v4, v2
 	Jump blk_ret, v5
+`,
+		},
+		{
+			name: "imported_function_call_distinct_types", m: testcases.ImportedFunctionCallDistinctTypes.Module,
+			exp: `
+signatures:
+	sig0: i64i64i64_i64
+	sig1: i64i64i32i64_i64
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i64)
+	v3:i32 = Iconst_32 0xa
+	Store module_ctx, exec_ctx, 0x8
+	v4:i64 = Load module_ctx, 0x0
+	v5:i64 = Load module_ctx, 0x8
+	v6:i64 = CallIndirect v4:sig1, exec_ctx, v5, v3, v2
+	Store module_ctx, exec_ctx, 0x8
+	v7:i64 = Call f2:sig0, exec_ctx, module_ctx, v6
+	Jump blk_ret, v7
+`,
+		},
+		{
+			name: "imported_global", m: testcases.ImportedGlobal.Module,
+			exp: `
+signatures:
+	sig0: i64i64_i32
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i64 = Load module_ctx, 0x10
+	v4:i32 = Load v3, 0x8
+	v5:i32 = Load v3, 0x8
+	v6:i32 = Iadd v5, v2
+	Store v6, v3, 0x8
+	v7:i32 = Load v3, 0x8
+	Store module_ctx, exec_ctx, 0x8
+	v8:i64 = Load module_ctx, 0x0
+	v9:i64 = Load module_ctx, 0x8
+	v10:i32 = CallIndirect v8:sig0, exec_ctx, v9
+	v11:i64 = Load module_ctx, 0x18
+	v12:i64 = Load v11, 0x8
+	v13:i64 = Iconst_64 0x1
+	v14:i64 = Iadd v12, v13
+	Store v14, v11, 0x8
+	v15:i64 = Load v11, 0x8
+	Jump blk_ret, v4, v7, v10, v15
+`,
+		},
+		{
+			name: "memory_stores", m: testcases.MemoryStores.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32, v4:i64, v5:f32, v6:f64)
+	v7:i64 = Iconst_64 0x4
+	v8:i64 = UExtend v2, 32->64
+	v9:i64 = Uload32 module_ctx, 0x8
+	v10:i64 = Iadd v8, v7
+	v11:i32 = Icmp ge_u, v9, v10
+	ExitIfNotZero v11, exec_ctx, v10, memory_out_of_bounds
+	v12:i64 = Load module_ctx, 0x0
+	v13:i64 = Iadd v12, v8
+	Store v3, v13, 0x0
+	v14:i64 = Iconst_64 0x10
+	v15:i64 = UExtend v2, 32->64
+	v16:i64 = Iadd v15, v14
+	v17:i32 = Icmp ge_u, v9, v16
+	ExitIfNotZero v17, exec_ctx, v16, memory_out_of_bounds
+	v18:i64 = Iadd v12, v15
+	Store v4, v18, 0x8
+	v19:i64 = Iconst_64 0x14
+	v20:i64 = UExtend v2, 32->64
+	v21:i64 = Iadd v20, v19
+	v22:i32 = Icmp ge_u, v9, v21
+	ExitIfNotZero v22, exec_ctx, v21, memory_out_of_bounds
+	v23:i64 = Iadd v12, v20
+	Store v5, v23, 0x10
+	v24:i64 = Iconst_64 0x20
+	v25:i64 = UExtend v2, 32->64
+	v26:i64 = Iadd v25, v24
+	v27:i32 = Icmp ge_u, v9, v26
+	ExitIfNotZero v27, exec_ctx, v26, memory_out_of_bounds
+	v28:i64 = Iadd v12, v25
+	Store v6, v28, 0x18
+	v29:i64 = Iconst_64 0x21
+	v30:i64 = UExtend v2, 32->64
+	v31:i64 = Iadd v30, v29
+	v32:i32 = Icmp ge_u, v9, v31
+	ExitIfNotZero v32, exec_ctx, v31, memory_out_of_bounds
+	v33:i64 = Iadd v12, v30
+	Istore8 v3, v33, 0x20
+	v34:i64 = Iconst_64 0x24
+	v35:i64 = UExtend v2, 32->64
+	v36:i64 = Iadd v35, v34
+	v37:i32 = Icmp ge_u, v9, v36
+	ExitIfNotZero v37, exec_ctx, v36, memory_out_of_bounds
+	v38:i64 = Iadd v12, v35
+	Istore16 v3, v38, 0x22
+	v39:i64 = Iconst_64 0x29
+	v40:i64 = UExtend v2, 32->64
+	v41:i64 = Iadd v40, v39
+	v42:i32 = Icmp ge_u, v9, v41
+	ExitIfNotZero v42, exec_ctx, v41, memory_out_of_bounds
+	v43:i64 = Iadd v12, v40
+	Istore8 v4, v43, 0x28
+	v44:i64 = Iconst_64 0x2c
+	v45:i64 = UExtend v2, 32->64
+	v46:i64 = Iadd v45, v44
+	v47:i32 = Icmp ge_u, v9, v46
+	ExitIfNotZero v47, exec_ctx, v46, memory_out_of_bounds
+	v48:i64 = Iadd v12, v45
+	Istore16 v4, v48, 0x2a
+	v49:i64 = Iconst_64 0x30
+	v50:i64 = UExtend v2, 32->64
+	v51:i64 = Iadd v50, v49
+	v52:i32 = Icmp ge_u, v9, v51
+	ExitIfNotZero v52, exec_ctx, v51, memory_out_of_bounds
+	v53:i64 = Iadd v12, v50
+	Istore32 v4, v53, 0x2c
+	v54:i64 = Iconst_64 0x30
+	v55:i64 = UExtend v2, 32->64
+	v56:i64 = Iadd v55, v54
+	v57:i32 = Icmp ge_u, v9, v56
+	ExitIfNotZero v57, exec_ctx, v56, memory_out_of_bounds
+	v58:i64 = Iadd v12, v55
+	v59:i32 = Load v58, 0x0
+	v60:i64 = UExtend v2, 32->64
+	v61:i64 = Iadd v12, v60
+	v62:i64 = Load v61, 0x8
+	v63:i64 = UExtend v2, 32->64
+	v64:i64 = Iadd v12, v63
+	v65:f32 = Load v64, 0x10
+	v66:i64 = UExtend v2, 32->64
+	v67:i64 = Iadd v12, v66
+	v68:f64 = Load v67, 0x18
+	v69:i64 = UExtend v2, 32->64
+	v70:i64 = Iadd v12, v69
+	v71:i32 = Uload8 v70, 0x20
+	v72:i64 = UExtend v2, 32->64
+	v73:i64 = Iadd v12, v72
+	v74:i32 = Uload16 v73, 0x22
+	v75:i64 = UExtend v2, 32->64
+	v76:i64 = Iadd v12, v75
+	v77:i64 = Uload8 v76, 0x28
+	v78:i64 = UExtend v2, 32->64
+	v79:i64 = Iadd v12, v78
+	v80:i64 = Uload16 v79, 0x2a
+	v81:i64 = UExtend v2, 32->64
+	v82:i64 = Iadd v12, v81
+	v83:i64 = Uload32 v82, 0x2c
+	Jump blk_ret, v59, v62, v65, v68, v71, v74, v77, v80, v83
+`,
+		},
+		{
+			name: "const_address_memory_accesses", m: testcases.ConstAddressMemoryAccesses.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i32 = Iconst_32 0x400
+	v4:i32 = Iconst_32 0x400
+	v5:i64 = Iconst_64 0x404
+	v6:i64 = Uload32 module_ctx, 0x8
+	v7:i32 = Icmp ge_u, v6, v5
+	ExitIfNotZero v7, exec_ctx, v5, memory_out_of_bounds
+	v8:i64 = Load module_ctx, 0x0
+	v9:i32 = Load v8, 0x400
+	v10:i32 = Iadd v9, v2
+	Store v10, v8, 0x400
+	v11:i32 = Iconst_32 0x0
+	v12:i32 = Iconst_32 0x0
+	v13:i64 = Iconst_64 0x410
+	v14:i32 = Icmp ge_u, v6, v13
+	ExitIfNotZero v14, exec_ctx, v13, memory_out_of_bounds
+	v15:i64 = Load v8, 0x408
+	v16:i64 = Iconst_64 0x1
+	v17:i64 = Iadd v15, v16
+	Store v17, v8, 0x408
+	v18:i32 = Iconst_32 0x400
+	v19:i64 = Iconst_64 0x410
+	v20:i32 = Icmp ge_u, v6, v19
+	ExitIfNotZero v20, exec_ctx, v19, memory_out_of_bounds
+	v21:i32 = Load v8, 0x400
+	v22:i32 = Iconst_32 0x0
+	v23:i64 = Load v8, 0x408
+	Jump blk_ret, v21, v23
 `,
 		},
 		{
 			name: "memory_loads", m: testcases.MemoryLoads.Module,
 			exp: `
 blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
-	v3:i64 = Iconst_64 0x4
+	v3:i64 = Iconst_64 0x17
 	v4:i64 = UExtend v2, 32->64
 	v5:i64 = Uload32 module_ctx, 0x8
 	v6:i64 = Iadd v4, v3
 	v7:i32 = Icmp ge_u, v5, v6
-	ExitIfNotZero v7, exec_ctx, memory_out_of_bounds
+	ExitIfNotZero v7, exec_ctx, v6, memory_out_of_bounds
 	v8:i64 = Load module_ctx, 0x0
 	v9:i64 = Iadd v8, v4
 	v10:i32 = Load v9, 0x0
-	v11:i64 = Iconst_64 0x8
-	v12:i64 = UExtend v2, 32->64
-	v13:i64 = Iadd v12, v11
-	v14:i32 = Icmp ge_u, v5, v13
-	ExitIfNotZero v14, exec_ctx, memory_out_of_bounds
-	v15:i64 = Iadd v8, v12
-	v16:i64 = Load v15, 0x0
-	v17:i64 = Iconst_64 0x4
+	v11:i64 = UExtend v2, 32->64
+	v12:i64 = Iadd v8, v11
+	v13:i64 = Load v12, 0x0
+	v14:i64 = UExtend v2, 32->64
+	v15:i64 = Iadd v8, v14
+	v16:f32 = Load v15, 0x0
+	v17:i64 = UExtend v2, 32->64
+	v18:i64 = Iadd v8, v17
+	v19:f64 = Load v18, 0x0
+	v20:i64 = UExtend v2, 32->64
+	v21:i64 = Iadd v8, v20
+	v22:i32 = Load v21, 0xf
+	v23:i64 = UExtend v2, 32->64
+	v24:i64 = Iadd v8, v23
+	v25:i64 = Load v24, 0xf
+	v26:i64 = UExtend v2, 32->64
+	v27:i64 = Iadd v8, v26
+	v28:f32 = Load v27, 0xf
+	v29:i64 = UExtend v2, 32->64
+	v30:i64 = Iadd v8, v29
+	v31:f64 = Load v30, 0xf
+	v32:i64 = UExtend v2, 32->64
+	v33:i64 = Iadd v8, v32
+	v34:i32 = Sload8 v33, 0x0
+	v35:i64 = UExtend v2, 32->64
+	v36:i64 = Iadd v8, v35
+	v37:i32 = Sload8 v36, 0xf
+	v38:i64 = UExtend v2, 32->64
+	v39:i64 = Iadd v8, v38
+	v40:i32 = Uload8 v39, 0x0
+	v41:i64 = UExtend v2, 32->64
+	v42:i64 = Iadd v8, v41
+	v43:i32 = Uload8 v42, 0xf
+	v44:i64 = UExtend v2, 32->64
+	v45:i64 = Iadd v8, v44
+	v46:i32 = Sload16 v45, 0x0
+	v47:i64 = UExtend v2, 32->64
+	v48:i64 = Iadd v8, v47
+	v49:i32 = Sload16 v48, 0xf
+	v50:i64 = UExtend v2, 32->64
+	v51:i64 = Iadd v8, v50
+	v52:i32 = Uload16 v51, 0x0
+	v53:i64 = UExtend v2, 32->64
+	v54:i64 = Iadd v8, v53
+	v55:i32 = Uload16 v54, 0xf
+	v56:i64 = UExtend v2, 32->64
+	v57:i64 = Iadd v8, v56
+	v58:i64 = Sload8 v57, 0x0
+	v59:i64 = UExtend v2, 32->64
+	v60:i64 = Iadd v8, v59
+	v61:i64 = Sload8 v60, 0xf
+	v62:i64 = UExtend v2, 32->64
+	v63:i64 = Iadd v8, v62
+	v64:i64 = Uload8 v63, 0x0
+	v65:i64 = UExtend v2, 32->64
+	v66:i64 = Iadd v8, v65
+	v67:i64 = Uload8 v66, 0xf
+	v68:i64 = UExtend v2, 32->64
+	v69:i64 = Iadd v8, v68
+	v70:i64 = Sload16 v69, 0x0
+	v71:i64 = UExtend v2, 32->64
+	v72:i64 = Iadd v8, v71
+	v73:i64 = Sload16 v72, 0xf
+	v74:i64 = UExtend v2, 32->64
+	v75:i64 = Iadd v8, v74
+	v76:i64 = Uload16 v75, 0x0
+	v77:i64 = UExtend v2, 32->64
+	v78:i64 = Iadd v8, v77
+	v79:i64 = Uload16 v78, 0xf
+	v80:i64 = UExtend v2, 32->64
+	v81:i64 = Iadd v8, v80
+	v82:i64 = Sload32 v81, 0x0
+	v83:i64 = UExtend v2, 32->64
+	v84:i64 = Iadd v8, v83
+	v85:i64 = Sload32 v84, 0xf
+	v86:i64 = UExtend v2, 32->64
+	v87:i64 = Iadd v8, v86
+	v88:i64 = Uload32 v87, 0x0
+	v89:i64 = UExtend v2, 32->64
+	v90:i64 = Iadd v8, v89
+	v91:i64 = Uload32 v90, 0xf
+	Jump blk_ret, v10, v13, v16, v19, v22, v25, v28, v31, v34, v37, v40, v43, v46, v49, v52, v55, v58, v61, v64, v67, v70, v73, v76, v79, v82, v85, v88, v91
+`,
+		},
+		{
+			name: "memory_struct_read", m: testcases.MemoryStructRead.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i64 = Iconst_64 0x10
+	v4:i64 = UExtend v2, 32->64
+	v5:i64 = Uload32 module_ctx, 0x8
+	v6:i64 = Iadd v4, v3
+	v7:i32 = Icmp ge_u, v5, v6
+	ExitIfNotZero v7, exec_ctx, v6, memory_out_of_bounds
+	v8:i64 = Load module_ctx, 0x0
+	v9:i64 = Iadd v8, v4
+	v10:i64 = Load v9, 0x0
+	v11:i64 = UExtend v2, 32->64
+	v12:i64 = Iadd v8, v11
+	v13:i64 = Load v12, 0x8
+	Jump blk_ret, v10, v13
+`,
+		},
+		{
+			name: "memory_struct_read_across_call", m: testcases.MemoryStructReadAcrossCall.Module,
+			exp: `
+signatures:
+	sig1: i64i64_v
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i64 = Iconst_64 0x8
+	v4:i64 = UExtend v2, 32->64
+	v5:i64 = Uload32 module_ctx, 0x8
+	v6:i64 = Iadd v4, v3
+	v7:i32 = Icmp ge_u, v5, v6
+	ExitIfNotZero v7, exec_ctx, v6, memory_out_of_bounds
+	v8:i64 = Load module_ctx, 0x0
+	v9:i64 = Iadd v8, v4
+	v10:i64 = Load v9, 0x0
+	Store module_ctx, exec_ctx, 0x8
+	Call f1:sig1, exec_ctx, module_ctx
+	v11:i64 = Load module_ctx, 0x0
+	v12:i64 = Uload32 module_ctx, 0x8
+	v13:i64 = Iconst_64 0x10
+	v14:i64 = UExtend v2, 32->64
+	v15:i64 = Iadd v14, v13
+	v16:i32 = Icmp ge_u, v12, v15
+	ExitIfNotZero v16, exec_ctx, v15, memory_out_of_bounds
+	v17:i64 = Iadd v11, v14
+	v18:i64 = Load v17, 0x8
+	Jump blk_ret, v10, v18
+`,
+		},
+		{
+			name: "tail_returns", m: testcases.TailReturns.Module,
+			// The end of the function is unreachable after the return, so no jump to blk_ret is inserted.
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	Brz v2, blk2
+	Jump blk1
+
+blk1: () <-- (blk0)
+	Return v2
+
+blk2: () <-- (blk0)
+	Jump blk3
+
+blk3: () <-- (blk2)
+	Return v3
+`,
+		},
+		{
+			name: "memory_same_base_loads", m: testcases.MemorySameBaseLoads.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	v4:i64 = Iconst_64 0x18
+	v5:i64 = UExtend v2, 32->64
+	v6:i64 = Uload32 module_ctx, 0x8
+	v7:i64 = Iadd v5, v4
+	v8:i32 = Icmp ge_u, v6, v7
+	ExitIfNotZero v8, exec_ctx, v7, memory_out_of_bounds
+	v9:i64 = Load module_ctx, 0x0
+	v10:i64 = Iadd v9, v5
+	v11:i64 = Load v10, 0x0
+	v12:i64 = Iconst_64 0x18
+	v13:i64 = UExtend v3, 32->64
+	v14:i64 = Iadd v13, v12
+	v15:i32 = Icmp ge_u, v6, v14
+	ExitIfNotZero v15, exec_ctx, v14, memory_out_of_bounds
+	v16:i64 = Iadd v9, v13
+	v17:i64 = Load v16, 0x0
 	v18:i64 = UExtend v2, 32->64
-	v19:i64 = Iadd v18, v17
-	v20:i32 = Icmp ge_u, v5, v19
-	ExitIfNotZero v20, exec_ctx, memory_out_of_bounds
-	v21:i64 = Iadd v8, v18
-	v22:f32 = Load v21, 0x0
-	v23:i64 = Iconst_64 0x8
+	v19:i64 = Iadd v9, v18
+	v20:i64 = Load v19, 0x8
+	v21:i64 = UExtend v3, 32->64
+	v22:i64 = Iadd v9, v21
+	v23:i64 = Load v22, 0x8
 	v24:i64 = UExtend v2, 32->64
-	v25:i64 = Iadd v24, v23
-	v26:i32 = Icmp ge_u, v5, v25
-	ExitIfNotZero v26, exec_ctx, memory_out_of_bounds
-	v27:i64 = Iadd v8, v24
-	v28:f64 = Load v27, 0x0
-	v29:i64 = Iconst_64 0x13
-	v30:i64 = UExtend v2, 32->64
-	v31:i64 = Iadd v30, v29
-	v32:i32 = Icmp ge_u, v5, v31
-	ExitIfNotZero v32, exec_ctx, memory_out_of_bounds
-	v33:i64 = Iadd v8, v30
-	v34:i32 = Load v33, 0xf
-	v35:i64 = Iconst_64 0x17
-	v36:i64 = UExtend v2, 32->64
-	v37:i64 = Iadd v36, v35
-	v38:i32 = Icmp ge_u, v5, v37
-	ExitIfNotZero v38, exec_ctx, memory_out_of_bounds
-	v39:i64 = Iadd v8, v36
-	v40:i64 = Load v39, 0xf
-	v41:i64 = Iconst_64 0x13
-	v42:i64 = UExtend v2, 32->64
-	v43:i64 = Iadd v42, v41
-	v44:i32 = Icmp ge_u, v5, v43
-	ExitIfNotZero v44, exec_ctx, memory_out_of_bounds
-	v45:i64 = Iadd v8, v42
-	v46:f32 = Load v45, 0xf
-	v47:i64 = Iconst_64 0x17
-	v48:i64 = UExtend v2, 32->64
-	v49:i64 = Iadd v48, v47
-	v50:i32 = Icmp ge_u, v5, v49
-	ExitIfNotZero v50, exec_ctx, memory_out_of_bounds
-	v51:i64 = Iadd v8, v48
-	v52:f64 = Load v51, 0xf
-	v53:i64 = Iconst_64 0x1
-	v54:i64 = UExtend v2, 32->64
-	v55:i64 = Iadd v54, v53
-	v56:i32 = Icmp ge_u, v5, v55
-	ExitIfNotZero v56, exec_ctx, memory_out_of_bounds
-	v57:i64 = Iadd v8, v54
-	v58:i32 = Sload8 v57, 0x0
-	v59:i64 = Iconst_64 0x10
-	v60:i64 = UExtend v2, 32->64
-	v61:i64 = Iadd v60, v59
-	v62:i32 = Icmp ge_u, v5, v61
-	ExitIfNotZero v62, exec_ctx, memory_out_of_bounds
-	v63:i64 = Iadd v8, v60
-	v64:i32 = Sload8 v63, 0xf
-	v65:i64 = Iconst_64 0x1
-	v66:i64 = UExtend v2, 32->64
-	v67:i64 = Iadd v66, v65
-	v68:i32 = Icmp ge_u, v5, v67
-	ExitIfNotZero v68, exec_ctx, memory_out_of_bounds
-	v69:i64 = Iadd v8, v66
-	v70:i32 = Uload8 v69, 0x0
-	v71:i64 = Iconst_64 0x10
-	v72:i64 = UExtend v2, 32->64
-	v73:i64 = Iadd v72, v71
-	v74:i32 = Icmp ge_u, v5, v73
-	ExitIfNotZero v74, exec_ctx, memory_out_of_bounds
-	v75:i64 = Iadd v8, v72
-	v76:i32 = Uload8 v75, 0xf
-	v77:i64 = Iconst_64 0x2
-	v78:i64 = UExtend v2, 32->64
-	v79:i64 = Iadd v78, v77
-	v80:i32 = Icmp ge_u, v5, v79
-	ExitIfNotZero v80, exec_ctx, memory_out_of_bounds
-	v81:i64 = Iadd v8, v78
-	v82:i32 = Sload16 v81, 0x0
-	v83:i64 = Iconst_64 0x11
-	v84:i64 = UExtend v2, 32->64
-	v85:i64 = Iadd v84, v83
-	v86:i32 = Icmp ge_u, v5, v85
-	ExitIfNotZero v86, exec_ctx, memory_out_of_bounds
-	v87:i64 = Iadd v8, v84
-	v88:i32 = Sload16 v87, 0xf
-	v89:i64 = Iconst_64 0x2
-	v90:i64 = UExtend v2, 32->64
-	v91:i64 = Iadd v90, v89
-	v92:i32 = Icmp ge_u, v5, v91
-	ExitIfNotZero v92, exec_ctx, memory_out_of_bounds
-	v93:i64 = Iadd v8, v90
-	v94:i32 = Uload16 v93, 0x0
-	v95:i64 = Iconst_64 0x11
-	v96:i64 = UExtend v2, 32->64
-	v97:i64 = Iadd v96, v95
-	v98:i32 = Icmp ge_u, v5, v97
-	ExitIfNotZero v98, exec_ctx, memory_out_of_bounds
-	v99:i64 = Iadd v8, v96
-	v100:i32 = Uload16 v99, 0xf
-	v101:i64 = Iconst_64 0x1
-	v102:i64 = UExtend v2, 32->64
-	v103:i64 = Iadd v102, v101
-	v104:i32 = Icmp ge_u, v5, v103
-	ExitIfNotZero v104, exec_ctx, memory_out_of_bounds
-	v105:i64 = Iadd v8, v102
-	v106:i64 = Sload8 v105, 0x0
-	v107:i64 = Iconst_64 0x10
-	v108:i64 = UExtend v2, 32->64
-	v109:i64 = Iadd v108, v107
-	v110:i32 = Icmp ge_u, v5, v109
-	ExitIfNotZero v110, exec_ctx, memory_out_of_bounds
-	v111:i64 = Iadd v8, v108
-	v112:i64 = Sload8 v111, 0xf
-	v113:i64 = Iconst_64 0x1
-	v114:i64 = UExtend v2, 32->64
-	v115:i64 = Iadd v114, v113
-	v116:i32 = Icmp ge_u, v5, v115
-	ExitIfNotZero v116, exec_ctx, memory_out_of_bounds
-	v117:i64 = Iadd v8, v114
-	v118:i64 = Uload8 v117, 0x0
-	v119:i64 = Iconst_64 0x10
-	v120:i64 = UExtend v2, 32->64
-	v121:i64 = Iadd v120, v119
-	v122:i32 = Icmp ge_u, v5, v121
-	ExitIfNotZero v122, exec_ctx, memory_out_of_bounds
-	v123:i64 = Iadd v8, v120
-	v124:i64 = Uload8 v123, 0xf
-	v125:i64 = Iconst_64 0x2
-	v126:i64 = UExtend v2, 32->64
-	v127:i64 = Iadd v126, v125
-	v128:i32 = Icmp ge_u, v5, v127
-	ExitIfNotZero v128, exec_ctx, memory_out_of_bounds
-	v129:i64 = Iadd v8, v126
-	v130:i64 = Sload16 v129, 0x0
-	v131:i64 = Iconst_64 0x11
-	v132:i64 = UExtend v2, 32->64
-	v133:i64 = Iadd v132, v131
-	v134:i32 = Icmp ge_u, v5, v133
-	ExitIfNotZero v134, exec_ctx, memory_out_of_bounds
-	v135:i64 = Iadd v8, v132
-	v136:i64 = Sload16 v135, 0xf
-	v137:i64 = Iconst_64 0x2
-	v138:i64 = UExtend v2, 32->64
-	v139:i64 = Iadd v138, v137
-	v140:i32 = Icmp ge_u, v5, v139
-	ExitIfNotZero v140, exec_ctx, memory_out_of_bounds
-	v141:i64 = Iadd v8, v138
-	v142:i64 = Uload16 v141, 0x0
-	v143:i64 = Iconst_64 0x11
-	v144:i64 = UExtend v2, 32->64
-	v145:i64 = Iadd v144, v143
-	v146:i32 = Icmp ge_u, v5, v145
-	ExitIfNotZero v146, exec_ctx, memory_out_of_bounds
-	v147:i64 = Iadd v8, v144
-	v148:i64 = Uload16 v147, 0xf
-	v149:i64 = Iconst_64 0x4
-	v150:i64 = UExtend v2, 32->64
-	v151:i64 = Iadd v150, v149
-	v152:i32 = Icmp ge_u, v5, v151
-	ExitIfNotZero v152, exec_ctx, memory_out_of_bounds
-	v153:i64 = Iadd v8, v150
-	v154:i64 = Sload32 v153, 0x0
-	v155:i64 = Iconst_64 0x13
-	v156:i64 = UExtend v2, 32->64
-	v157:i64 = Iadd v156, v155
-	v158:i32 = Icmp ge_u, v5, v157
-	ExitIfNotZero v158, exec_ctx, memory_out_of_bounds
-	v159:i64 = Iadd v8, v156
-	v160:i64 = Sload32 v159, 0xf
-	v161:i64 = Iconst_64 0x4
-	v162:i64 = UExtend v2, 32->64
-	v163:i64 = Iadd v162, v161
-	v164:i32 = Icmp ge_u, v5, v163
-	ExitIfNotZero v164, exec_ctx, memory_out_of_bounds
-	v165:i64 = Iadd v8, v162
-	v166:i64 = Uload32 v165, 0x0
-	v167:i64 = Iconst_64 0x13
-	v168:i64 = UExtend v2, 32->64
-	v169:i64 = Iadd v168, v167
-	v170:i32 = Icmp ge_u, v5, v169
-	ExitIfNotZero v170, exec_ctx, memory_out_of_bounds
-	v171:i64 = Iadd v8, v168
-	v172:i64 = Uload32 v171, 0xf
-	Jump blk_ret, v10, v16, v22, v28, v34, v40, v46, v52, v58, v64, v70, v76, v82, v88, v94, v100, v106, v112, v118, v124, v130, v136, v142, v148, v154, v160, v166, v172
+	v25:i64 = Iadd v9, v24
+	v26:i64 = Load v25, 0x10
+	v27:i64 = UExtend v3, 32->64
+	v28:i64 = Iadd v9, v27
+	v29:i64 = Load v28, 0x10
+	Jump blk_ret, v11, v17, v20, v23, v26, v29
 `,
 		},
-	} {
-		tc := tc
-		t.Run(tc.name, func(t *testing.T) {
-			// Just in case let's check the test module is valid.
-			err := tc.m.Validate(api.CoreFeaturesV2)
-			require.NoError(t, err, "invalid test case module!")
+		{
+			name: "memory_same_base_loads_br_if", m: testcases.MemorySameBaseLoadsBrIf.Module,
+			// The check of the second load is not hoisted across br_if, which might leave the block before it.
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	v5:i64 = Iconst_64 0x8
+	v6:i64 = UExtend v2, 32->64
+	v7:i64 = Uload32 module_ctx, 0x8
+	v8:i64 = Iadd v6, v5
+	v9:i32 = Icmp ge_u, v7, v8
+	ExitIfNotZero v9, exec_ctx, v8, memory_out_of_bounds
+	v10:i64 = Load module_ctx, 0x0
+	v11:i64 = Iadd v10, v6
+	v12:i64 = Load v11, 0x0
+	Brnz v3, blk1, v12
+	Jump blk2
 
-			b := ssa.NewBuilder()
+blk1: (v4:i64) <-- (blk0,blk2)
+	Jump blk_ret, v4
 
-			offset := wazevoapi.NewModuleContextOffsetData(tc.m)
-			fc := NewFrontendCompiler(tc.m, b, &offset)
-			typeIndex := tc.m.FunctionSection[tc.targetIndex]
-			code := &tc.m.CodeSection[tc.targetIndex]
-			fc.Init(tc.targetIndex, &tc.m.TypeSection[typeIndex], code.LocalTypes, code.Body)
+blk2: () <-- (blk0)
+	v13:i64 = Iconst_64 0x10008
+	v14:i64 = UExtend v2, 32->64
+	v15:i64 = Iadd v14, v13
+	v16:i32 = Icmp ge_u, v7, v15
+	ExitIfNotZero v16, exec_ctx, v15, memory_out_of_bounds
+	v17:i64 = Iadd v10, v14
+	v18:i64 = Load v17, 0x10000
+	Jump blk1, v18
+`,
+		},
+		{
+			// The divisor is checked against zero first, and then the signed divisions check the overflow.
+			name: "integer_division", m: testcases.IntegerDivision.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32, v4:i64, v5:i64)
+	v6:i32 = Iconst_32 0x0
+	v7:i32 = Icmp neq, v3, v6
+	ExitIfNotZero v7, exec_ctx, integer_division_by_zero
+	v8:i32 = Iconst_32 0xffffffff
+	v9:i32 = Icmp eq, v3, v8
+	v10:i32 = Iconst_32 0x0
+	v11:i32 = Select v9, v2, v10
+	v12:i32 = Iconst_32 0x80000000
+	v13:i32 = Icmp neq, v11, v12
+	ExitIfNotZero v13, exec_ctx, integer_overflow
+	v14:i32 = Sdiv v2, v3
+	v15:i32 = Iconst_32 0x0
+	v16:i32 = Icmp neq, v3, v15
+	ExitIfNotZero v16, exec_ctx, integer_division_by_zero
+	v17:i32 = Udiv v2, v3
+	v18:i64 = Iconst_64 0x0
+	v19:i32 = Icmp neq, v5, v18
+	ExitIfNotZero v19, exec_ctx, integer_division_by_zero
+	v20:i64 = Iconst_64 0xffffffffffffffff
+	v21:i32 = Icmp eq, v5, v20
+	v22:i64 = Iconst_64 0x0
+	v23:i64 = Select v21, v4, v22
+	v24:i64 = Iconst_64 0x8000000000000000
+	v25:i32 = Icmp neq, v23, v24
+	ExitIfNotZero v25, exec_ctx, integer_overflow
+	v26:i64 = Sdiv v4, v5
+	v27:i64 = Iconst_64 0x0
+	v28:i32 = Icmp neq, v5, v27
+	ExitIfNotZero v28, exec_ctx, integer_division_by_zero
+	v29:i64 = Udiv v4, v5
+	Jump blk_ret, v14, v17, v26, v29
+`,
+		},
+		{
+			// Only the divisor is checked as the remainder never overflows.
+			name: "integer_remainder", m: testcases.IntegerRemainder.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32, v4:i64, v5:i64)
+	v6:i32 = Iconst_32 0x0
+	v7:i32 = Icmp neq, v3, v6
+	ExitIfNotZero v7, exec_ctx, integer_division_by_zero
+	v8:i32 = Srem v2, v3
+	v9:i32 = Iconst_32 0x0
+	v10:i32 = Icmp neq, v3, v9
+	ExitIfNotZero v10, exec_ctx, integer_division_by_zero
+	v11:i32 = Urem v2, v3
+	v12:i64 = Iconst_64 0x0
+	v13:i32 = Icmp neq, v5, v12
+	ExitIfNotZero v13, exec_ctx, integer_division_by_zero
+	v14:i64 = Srem v4, v5
+	v15:i64 = Iconst_64 0x0
+	v16:i32 = Icmp neq, v5, v15
+	ExitIfNotZero v16, exec_ctx, integer_division_by_zero
+	v17:i64 = Urem v4, v5
+	Jump blk_ret, v8, v11, v14, v17
+`,
+		},
+		{
+			name: "integer_bitwise", m: testcases.IntegerBitwise.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32, v4:i64, v5:i64)
+	v6:i32 = Band v2, v3
+	v7:i32 = Bor v2, v3
+	v8:i32 = Bxor v2, v3
+	v9:i64 = Band v4, v5
+	v10:i64 = Bor v4, v5
+	v11:i64 = Bxor v4, v5
+	Jump blk_ret, v6, v7, v8, v9, v10, v11
+`,
+		},
+		{
+			name: "ref_type_locals", m: testcases.RefTypeLocals.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i64)
+	v3:i64 = Iconst_64 0x0
+	v4:i64 = Iconst_64 0x0
+	Jump blk_ret, v3, v2
+`,
+		},
+		{
+			name: "integer_rotate", m: testcases.IntegerRotate.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32, v4:i64, v5:i64)
+	v6:i32 = Rotl v2, v3
+	v7:i32 = Rotr v2, v3
+	v8:i64 = Rotl v4, v5
+	v9:i64 = Rotr v4, v5
+	Jump blk_ret, v6, v7, v8, v9
+`,
+		},
+		{
+			name: "integer_bit_counts", m: testcases.IntegerBitCounts.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i64)
+	v4:i32 = Clz v2
+	v5:i32 = Ctz v2
+	v6:i32 = Popcnt v2
+	v7:i64 = Clz v3
+	v8:i64 = Ctz v3
+	v9:i64 = Popcnt v3
+	Jump blk_ret, v4, v5, v6, v7, v8, v9
+`,
+		},
+		{
+			name: "integer_eqz", m: testcases.IntegerEqz.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i64)
+	v4:i32 = Iconst_32 0x0
+	v5:i32 = Icmp eq, v2, v4
+	v6:i64 = Iconst_64 0x0
+	v7:i32 = Icmp eq, v3, v6
+	Jump blk_ret, v5, v7
+`,
+		},
+		{
+			name: "table_const_indices", m: testcases.TableConstIndices.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i64)
+	v4:i32 = Iconst_32 0x0
+	v5:i64 = Load module_ctx, 0x0
+	v6:i64 = UExtend v4, 32->64
+	v7:i64 = Load v5, 0x0
+	v8:i64 = Iconst_64 0x3
+	v9:i64 = Ishl v6, v8
+	v10:i64 = Iadd v7, v9
+	Store v3, v10, 0x0
+	v11:i32 = Iconst_32 0x3
+	v12:i64 = Load module_ctx, 0x0
+	v13:i64 = UExtend v11, 32->64
+	v14:i64 = Load v12, 0x0
+	v15:i64 = Iconst_64 0x3
+	v16:i64 = Ishl v13, v15
+	v17:i64 = Iadd v14, v16
+	Store v3, v17, 0x0
+	Brz v2, blk2
+	Jump blk1
 
-			err = fc.LowerToSSA()
-			require.NoError(t, err)
+blk1: () <-- (blk0)
+	v19:i32 = Iconst_32 0x4
+	v20:i64 = Load module_ctx, 0x0
+	v21:i64 = Load v20, 0x8
+	v22:i64 = UExtend v19, 32->64
+	v23:i32 = Icmp gt_u, v21, v22
+	ExitIfNotZero v23, exec_ctx, table_out_of_bounds
+	v24:i64 = Load v20, 0x0
+	v25:i64 = Iconst_64 0x3
+	v26:i64 = Ishl v22, v25
+	v27:i64 = Iadd v24, v26
+	v28:i64 = Load v27, 0x0
+	Jump blk3, v28
 
-			actual := fc.formatBuilder()
-			fmt.Println(actual)
-			require.Equal(t, tc.exp, actual)
+blk2: () <-- (blk0)
+	v29:i32 = Iconst_32 0x3
+	v30:i64 = Load module_ctx, 0x0
+	v31:i64 = UExtend v29, 32->64
+	v32:i64 = Load v30, 0x0
+	v33:i64 = Iconst_64 0x3
+	v34:i64 = Ishl v31, v33
+	v35:i64 = Iadd v32, v34
+	v36:i64 = Load v35, 0x0
+	Jump blk3, v36
+
+blk3: (v18:i64) <-- (blk1,blk2)
+	Jump blk_ret, v18
+`,
+		},
+		{
+			name: "i32_wrap_i64", m: testcases.I32WrapI64.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i64)
+	v3:i32 = Ireduce v2
+	v4:i64 = Iadd v2, v2
+	v5:i32 = Ireduce v4
+	Jump blk_ret, v3, v5
+`,
+		},
+		{
+			name: "float_unary_ops", m: testcases.FloatUnaryOps.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:f32, v3:f64)
+	v4:f32 = Fneg v2
+	v5:f32 = Fabs v2
+	v6:f32 = Sqrt v2
+	v7:f64 = Fneg v3
+	v8:f64 = Fabs v3
+	v9:f64 = Sqrt v3
+	Jump blk_ret, v4, v5, v6, v7, v8, v9
+`,
+		},
+		{
+			name: "float_rounding", m: testcases.FloatRounding.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:f32, v3:f64)
+	v4:f32 = Ceil v2
+	v5:f32 = Floor v2
+	v6:f32 = Trunc v2
+	v7:f32 = Nearest v2
+	v8:f64 = Ceil v3
+	v9:f64 = Floor v3
+	v10:f64 = Trunc v3
+	v11:f64 = Nearest v3
+	Jump blk_ret, v4, v5, v6, v7, v8, v9, v10, v11
+`,
+		},
+		{
+			name: "float_copysign", m: testcases.FloatCopysign.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:f32, v3:f32, v4:f64, v5:f64)
+	v6:f32 = Fcopysign v2, v3
+	v7:f64 = Fcopysign v4, v5
+	Jump blk_ret, v6, v7
+`,
+		},
+		{
+			name: "block_params_br", m: testcases.BlockParamsBr.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	v4:i32 = Iconst_32 0xa
+	v6:i32 = Isub v2, v3
+	Brnz v2, blk1, v6
+	Jump blk2
 
-			b.RunPasses()
-			if expAfterOpt := tc.expAfterOpt; expAfterOpt != "" {
-				actualAfterOpt := fc.formatBuilder()
-				fmt.Println(actualAfterOpt)
-				require.Equal(t, expAfterOpt, actualAfterOpt)
-			}
+blk1: (v5:i32) <-- (blk0,blk2)
+	v9:i32 = Iadd v4, v5
+	Jump blk_ret, v9
 
-			// Dry-run without checking the results of LayoutBlocks function.
-			b.LayoutBlocks()
-		})
+blk2: () <-- (blk0)
+	v7:i32 = Iconst_32 0x32
+	v8:i32 = Iadd v6, v7
+	Jump blk1, v8
+`,
+		},
+		{
+			name: "float_promote_demote", m: testcases.FloatPromoteDemote.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:f32, v3:f64)
+	v4:f64 = Fpromote v2
+	v5:f32 = Fdemote v3
+	v6:f64 = Fpromote v2
+	v7:f32 = Fdemote v6
+	Jump blk_ret, v4, v5, v7
+`,
+		},
+		{
+			name: "float_to_i32_s", m: testcases.FloatToI32S.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:f32, v3:f64)
+	v4:i32 = Fcmp eq, v2, v2
+	ExitIfNotZero v4, exec_ctx, invalid_conversion_to_integer
+	v5:f32 = F32const -2147483648.000000
+	v6:i32 = Fcmp ge, v2, v5
+	ExitIfNotZero v6, exec_ctx, integer_overflow
+	v7:f32 = F32const 2147483648.000000
+	v8:i32 = Fcmp lt, v2, v7
+	ExitIfNotZero v8, exec_ctx, integer_overflow
+	v9:i32 = FcvtToSint v2
+	v10:i32 = Fcmp eq, v3, v3
+	ExitIfNotZero v10, exec_ctx, invalid_conversion_to_integer
+	v11:f64 = F64const -2147483649.000000
+	v12:i32 = Fcmp gt, v3, v11
+	ExitIfNotZero v12, exec_ctx, integer_overflow
+	v13:f64 = F64const 2147483648.000000
+	v14:i32 = Fcmp lt, v3, v13
+	ExitIfNotZero v14, exec_ctx, integer_overflow
+	v15:i32 = FcvtToSint v3
+	Jump blk_ret, v9, v15
+`,
+		},
+		{
+			name: "float_to_i64_u", m: testcases.FloatToI64U.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:f32, v3:f64)
+	v4:i32 = Fcmp eq, v2, v2
+	ExitIfNotZero v4, exec_ctx, invalid_conversion_to_integer
+	v5:f32 = F32const -1.000000
+	v6:i32 = Fcmp gt, v2, v5
+	ExitIfNotZero v6, exec_ctx, integer_overflow
+	v7:f32 = F32const 18446744073709551616.000000
+	v8:i32 = Fcmp lt, v2, v7
+	ExitIfNotZero v8, exec_ctx, integer_overflow
+	v9:i64 = FcvtToUint v2
+	v10:i32 = Fcmp eq, v3, v3
+	ExitIfNotZero v10, exec_ctx, invalid_conversion_to_integer
+	v11:f64 = F64const -1.000000
+	v12:i32 = Fcmp gt, v3, v11
+	ExitIfNotZero v12, exec_ctx, integer_overflow
+	v13:f64 = F64const 18446744073709551616.000000
+	v14:i32 = Fcmp lt, v3, v13
+	ExitIfNotZero v14, exec_ctx, integer_overflow
+	v15:i64 = FcvtToUint v3
+	Jump blk_ret, v9, v15
+`,
+		},
+		{
+			name: "float_to_int_sat", m: testcases.FloatToIntSat.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:f32, v3:f64)
+	v4:i32 = FcvtToSintSat v2
+	v5:i32 = FcvtToUintSat v2
+	v6:i32 = FcvtToSintSat v3
+	v7:i32 = FcvtToUintSat v3
+	v8:i64 = FcvtToSintSat v2
+	v9:i64 = FcvtToUintSat v2
+	v10:i64 = FcvtToSintSat v3
+	v11:i64 = FcvtToUintSat v3
+	Jump blk_ret, v4, v5, v6, v7, v8, v9, v10, v11
+`,
+		},
+		{
+			name: "if_then_empty", m: testcases.IfThenEmpty.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	Brz v2, blk2
+	Jump blk1
+
+blk1: () <-- (blk0)
+	Jump blk3, v3, v2
+
+blk2: () <-- (blk0)
+	v4:i32 = Iconst_32 0x1
+	v5:i32 = Iadd v3, v4
+	Jump blk3, v5, v2
+
+blk3: (v6:i32,v7:i32) <-- (blk1,blk2)
+	Brz v7, blk5
+	Jump blk4
+
+blk4: () <-- (blk3)
+	Jump blk6, v6
+
+blk5: () <-- (blk3)
+	v9:i32 = Iconst_32 0x32
+	v10:i32 = Iadd v6, v9
+	Jump blk6, v10
+
+blk6: (v8:i32) <-- (blk4,blk5)
+	Jump blk_ret, v8
+`,
+		},
+		{
+			name: "int_to_float", m: testcases.IntToFloat.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i64)
+	v4:f32 = FcvtFromSint v2
+	v5:f32 = FcvtFromUint v2
+	v6:f32 = FcvtFromSint v3
+	v7:f32 = FcvtFromUint v3
+	v8:f64 = FcvtFromSint v2
+	v9:f64 = FcvtFromUint v2
+	v10:f64 = FcvtFromSint v3
+	v11:f64 = FcvtFromUint v3
+	Jump blk_ret, v4, v5, v6, v7, v8, v9, v10, v11
+`,
+		},
+		{
+			name: "nested_loops_br_to_block", m: testcases.NestedLoopsBrToBlock.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i32 = Iconst_32 0x0
+	Jump blk1, v2
+
+blk1: (v22:i32) <-- (blk0,blk3)
+	Jump blk4, v22
+
+blk2: (v4:i32,v5:i32) <-- (blk10)
+	Jump blk_ret, v4, v5
+
+blk3: (v6:i32,v7:i32) <-- (blk9)
+	v23:i32 = Iconst_32 0x1e
+	v24:i32 = Icmp lt_u, v6, v23
+	Brnz v24, blk1, v6
+	Jump blk10
+
+blk4: (v21:i32) <-- (blk1,blk8)
+	Jump blk6, v21
+
+blk5: ()
+	Exit exec_ctx, unreachable
+
+blk6: (v8:i32) <-- (blk4,blk6)
+	v9:i32 = Iconst_32 0x1
+	v10:i32 = Iadd v8, v9
+	v11:i32 = Iconst_32 0x3
+	v12:i32 = Iconst_32 0x0
+	v13:i32 = Icmp neq, v11, v12
+	ExitIfNotZero v13, exec_ctx, integer_division_by_zero
+	v14:i32 = Urem v10, v11
+	Brnz v14, blk6, v10
+	Jump blk8
+
+blk7: ()
+
+blk8: () <-- (blk6)
+	v15:i32 = Iconst_32 0x7
+	v16:i32 = Iconst_32 0x0
+	v17:i32 = Icmp neq, v15, v16
+	ExitIfNotZero v17, exec_ctx, integer_division_by_zero
+	v18:i32 = Urem v10, v15
+	Brnz v18, blk4, v10
+	Jump blk9
+
+blk9: () <-- (blk8)
+	v19:i32 = Iconst_32 0xa
+	v20:i32 = Imul v10, v19
+	Jump blk3, v10, v20
+
+blk10: () <-- (blk3)
+	Jump blk2, v6, v7
+`,
+		},
+		{
+			name: "globals_mutated_by_call", m: testcases.GlobalsMutatedByCall.Module,
+			exp: `
+signatures:
+	sig1: i64i64i64_v
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i64)
+	v3:i64 = Load module_ctx, 0x0
+	v4:i64 = Load v3, 0x8
+	Store module_ctx, exec_ctx, 0x8
+	Call f1:sig1, exec_ctx, module_ctx, v2
+	v5:i64 = Load v3, 0x8
+	v6:i64 = Load module_ctx, 0x8
+	v7:f64 = Load v6, 0x8
+	Jump blk_ret, v4, v5, v7
+`,
+		},
+		{
+			name: "float_const_bit_patterns", m: testcases.FloatConstBitPatterns.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:f64)
+	v3:f32 = F32const NaN
+	v4:f32 = F32const NaN
+	v5:f64 = F64const 0.000000
+	v6:f64 = F64const -0.000000
+	v7:f64 = F64const 1.500000
+	v8:f64 = Fmul v2, v7
+	v9:f64 = Fadd v8, v7
+	Jump blk_ret, v3, v4, v3, v5, v6, v9
+`,
+		},
+		{
+			name: "br_if_switch", m: testcases.BrIfSwitch.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i32 = Iconst_32 0x0
+	v4:i32 = Icmp eq, v2, v3
+	Brnz v4, blk3
+	Jump blk4
+
+blk1: () <-- (blk5)
+	v15:i32 = Iconst_32 0x1e
+	v16:i32 = Iadd v2, v15
+	Jump blk_ret, v16
+
+blk2: () <-- (blk4)
+	v13:i32 = Iconst_32 0x14
+	v14:i32 = Iadd v2, v13
+	Return v14
+
+blk3: () <-- (blk0)
+	v11:i32 = Iconst_32 0xa
+	v12:i32 = Iadd v2, v11
+	Return v12
+
+blk4: () <-- (blk0)
+	v5:i32 = Iconst_32 0x1
+	v6:i32 = Icmp eq, v2, v5
+	Brnz v6, blk2
+	Jump blk5
+
+blk5: () <-- (blk4)
+	v7:i32 = Iconst_32 0x2
+	v8:i32 = Icmp eq, v2, v7
+	Brnz v8, blk1
+	Jump blk6
+
+blk6: () <-- (blk5)
+	v9:i32 = Iconst_32 0x28
+	v10:i32 = Iadd v2, v9
+	Return v10
+`,
+		},
+		{
+			name: "table_get_set", m: testcases.TableGetSet.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32, v4:i64, v5:i64)
+	v6:i64 = Load module_ctx, 0x0
+	v7:i64 = Load v6, 0x8
+	v8:i64 = UExtend v2, 32->64
+	v9:i32 = Icmp gt_u, v7, v8
+	ExitIfNotZero v9, exec_ctx, table_out_of_bounds
+	v10:i64 = Load v6, 0x0
+	v11:i64 = Iconst_64 0x3
+	v12:i64 = Ishl v8, v11
+	v13:i64 = Iadd v10, v12
+	Store v4, v13, 0x0
+	v14:i64 = Load module_ctx, 0x8
+	v15:i64 = Load v14, 0x8
+	v16:i64 = UExtend v2, 32->64
+	v17:i32 = Icmp gt_u, v15, v16
+	ExitIfNotZero v17, exec_ctx, table_out_of_bounds
+	v18:i64 = Load v14, 0x0
+	v19:i64 = Iconst_64 0x3
+	v20:i64 = Ishl v16, v19
+	v21:i64 = Iadd v18, v20
+	Store v5, v21, 0x0
+	v22:i64 = Load module_ctx, 0x0
+	v23:i64 = Load v22, 0x8
+	v24:i64 = UExtend v3, 32->64
+	v25:i32 = Icmp gt_u, v23, v24
+	ExitIfNotZero v25, exec_ctx, table_out_of_bounds
+	v26:i64 = Load v22, 0x0
+	v27:i64 = Iconst_64 0x3
+	v28:i64 = Ishl v24, v27
+	v29:i64 = Iadd v26, v28
+	v30:i64 = Load v29, 0x0
+	v31:i64 = Load module_ctx, 0x8
+	v32:i64 = Load v31, 0x8
+	v33:i64 = UExtend v3, 32->64
+	v34:i32 = Icmp gt_u, v32, v33
+	ExitIfNotZero v34, exec_ctx, table_out_of_bounds
+	v35:i64 = Load v31, 0x0
+	v36:i64 = Iconst_64 0x3
+	v37:i64 = Ishl v33, v36
+	v38:i64 = Iadd v35, v37
+	v39:i64 = Load v38, 0x0
+	Jump blk_ret, v30, v39
+`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			// Just in case let's check the test module is valid.
+			err := tc.m.Validate(api.CoreFeaturesV2)
+			require.NoError(t, err, "invalid test case module!")
+
+			b := ssa.NewBuilder()
+
+			offset := wazevoapi.NewModuleContextOffsetData(tc.m)
+			fc := NewFrontendCompiler(tc.m, b, &offset)
+			typeIndex := tc.m.FunctionSection[tc.targetIndex]
+			code := &tc.m.CodeSection[tc.targetIndex]
+			fc.Init(tc.targetIndex, &tc.m.TypeSection[typeIndex], code.LocalTypes, code.Body)
+
+			err = fc.LowerToSSA()
+			require.NoError(t, err)
+			fc.checkBlocksSealed()
+			require.NoError(t, b.Verify())
+
+			actual := fc.formatBuilder()
+			fmt.Println(actual)
+			require.Equal(t, tc.exp, actual)
+
+			b.RunPasses()
+			require.NoError(t, b.Verify())
+			if expAfterOpt := tc.expAfterOpt; expAfterOpt != "" {
+				actualAfterOpt := fc.formatBuilder()
+				fmt.Println(actualAfterOpt)
+				require.Equal(t, expAfterOpt, actualAfterOpt)
+			}
+
+			// Dry-run without checking the results of LayoutBlocks function.
+			b.LayoutBlocks()
+		})
+	}
+}
+
+func TestCompiler_LowerToSSA_boundsCheckTrapOrder(t *testing.T) {
+	// The second load from the same base doesn't extend the check of the first one across the exit or the store in
+	// between. Otherwise, the extended check would trap with out of bounds memory access before the exit is taken or
+	// the store is done.
+	i32 := wasm.ValueTypeI32
+	for _, tc := range []struct {
+		name string
+		// body is inserted between the loads from the first param with offset=0 and offset=1000, and pushes nothing.
+		body []byte
+		exp  string
+	}{
+		{
+			name: "table.get",
+			body: []byte{wasm.OpcodeLocalGet, 1, wasm.OpcodeTableGet, 0, wasm.OpcodeDrop},
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	v4:i64 = Iconst_64 0x4
+	v5:i64 = UExtend v2, 32->64
+	v6:i64 = Uload32 module_ctx, 0x8
+	v7:i64 = Iadd v5, v4
+	v8:i32 = Icmp ge_u, v6, v7
+	ExitIfNotZero v8, exec_ctx, v7, memory_out_of_bounds
+	v9:i64 = Load module_ctx, 0x0
+	v10:i64 = Iadd v9, v5
+	v11:i32 = Load v10, 0x0
+	v12:i64 = Load module_ctx, 0x10
+	v13:i64 = Load v12, 0x8
+	v14:i64 = UExtend v3, 32->64
+	v15:i32 = Icmp gt_u, v13, v14
+	ExitIfNotZero v15, exec_ctx, table_out_of_bounds
+	v16:i64 = Load v12, 0x0
+	v17:i64 = Iconst_64 0x3
+	v18:i64 = Ishl v14, v17
+	v19:i64 = Iadd v16, v18
+	v20:i64 = Load v19, 0x0
+	v21:i64 = Iconst_64 0x3ec
+	v22:i64 = UExtend v2, 32->64
+	v23:i64 = Iadd v22, v21
+	v24:i32 = Icmp ge_u, v6, v23
+	ExitIfNotZero v24, exec_ctx, v23, memory_out_of_bounds
+	v25:i64 = Iadd v9, v22
+	v26:i32 = Load v25, 0x3e8
+	Jump blk_ret, v11, v26
+`,
+		},
+		{
+			// The table bounds checks are elided for the constant index, so there's no exit in between.
+			name: "table.set",
+			body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0, wasm.OpcodeTableGet, 0, wasm.OpcodeTableSet, 0},
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	v4:i64 = Iconst_64 0x4
+	v5:i64 = UExtend v2, 32->64
+	v6:i64 = Uload32 module_ctx, 0x8
+	v7:i64 = Iadd v5, v4
+	v8:i32 = Icmp ge_u, v6, v7
+	ExitIfNotZero v8, exec_ctx, v7, memory_out_of_bounds
+	v9:i64 = Load module_ctx, 0x0
+	v10:i64 = Iadd v9, v5
+	v11:i32 = Load v10, 0x0
+	v12:i32 = Iconst_32 0x0
+	v13:i32 = Iconst_32 0x0
+	v14:i64 = Load module_ctx, 0x10
+	v15:i64 = UExtend v13, 32->64
+	v16:i64 = Load v14, 0x0
+	v17:i64 = Iconst_64 0x3
+	v18:i64 = Ishl v15, v17
+	v19:i64 = Iadd v16, v18
+	v20:i64 = Load v19, 0x0
+	v21:i64 = Load module_ctx, 0x10
+	v22:i64 = UExtend v12, 32->64
+	v23:i64 = Load v21, 0x0
+	v24:i64 = Iconst_64 0x3
+	v25:i64 = Ishl v22, v24
+	v26:i64 = Iadd v23, v25
+	Store v20, v26, 0x0
+	v27:i64 = Iconst_64 0x3ec
+	v28:i64 = UExtend v2, 32->64
+	v29:i64 = Iadd v28, v27
+	v30:i32 = Icmp ge_u, v6, v29
+	ExitIfNotZero v30, exec_ctx, v29, memory_out_of_bounds
+	v31:i64 = Iadd v9, v28
+	v32:i32 = Load v31, 0x3e8
+	Jump blk_ret, v11, v32
+`,
+		},
+		{
+			name: "i32.div_s",
+			body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32DivS, wasm.OpcodeDrop},
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	v4:i64 = Iconst_64 0x4
+	v5:i64 = UExtend v2, 32->64
+	v6:i64 = Uload32 module_ctx, 0x8
+	v7:i64 = Iadd v5, v4
+	v8:i32 = Icmp ge_u, v6, v7
+	ExitIfNotZero v8, exec_ctx, v7, memory_out_of_bounds
+	v9:i64 = Load module_ctx, 0x0
+	v10:i64 = Iadd v9, v5
+	v11:i32 = Load v10, 0x0
+	v12:i32 = Iconst_32 0x0
+	v13:i32 = Icmp neq, v3, v12
+	ExitIfNotZero v13, exec_ctx, integer_division_by_zero
+	v14:i32 = Iconst_32 0xffffffff
+	v15:i32 = Icmp eq, v3, v14
+	v16:i32 = Iconst_32 0x0
+	v17:i32 = Select v15, v2, v16
+	v18:i32 = Iconst_32 0x80000000
+	v19:i32 = Icmp neq, v17, v18
+	ExitIfNotZero v19, exec_ctx, integer_overflow
+	v20:i32 = Sdiv v2, v3
+	v21:i64 = Iconst_64 0x3ec
+	v22:i64 = UExtend v2, 32->64
+	v23:i64 = Iadd v22, v21
+	v24:i32 = Icmp ge_u, v6, v23
+	ExitIfNotZero v24, exec_ctx, v23, memory_out_of_bounds
+	v25:i64 = Iadd v9, v22
+	v26:i32 = Load v25, 0x3e8
+	Jump blk_ret, v11, v26
+`,
+		},
+		{
+			name: "i32.trunc_f32_s",
+			body: []byte{wasm.OpcodeF32Const, 0x0, 0x0, 0xc0, 0x7f, wasm.OpcodeI32TruncF32S, wasm.OpcodeDrop}, // NaN
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	v4:i64 = Iconst_64 0x4
+	v5:i64 = UExtend v2, 32->64
+	v6:i64 = Uload32 module_ctx, 0x8
+	v7:i64 = Iadd v5, v4
+	v8:i32 = Icmp ge_u, v6, v7
+	ExitIfNotZero v8, exec_ctx, v7, memory_out_of_bounds
+	v9:i64 = Load module_ctx, 0x0
+	v10:i64 = Iadd v9, v5
+	v11:i32 = Load v10, 0x0
+	v12:f32 = F32const NaN
+	v13:i32 = Fcmp eq, v12, v12
+	ExitIfNotZero v13, exec_ctx, invalid_conversion_to_integer
+	v14:f32 = F32const -2147483648.000000
+	v15:i32 = Fcmp ge, v12, v14
+	ExitIfNotZero v15, exec_ctx, integer_overflow
+	v16:f32 = F32const 2147483648.000000
+	v17:i32 = Fcmp lt, v12, v16
+	ExitIfNotZero v17, exec_ctx, integer_overflow
+	v18:i32 = FcvtToSint v12
+	v19:i64 = Iconst_64 0x3ec
+	v20:i64 = UExtend v2, 32->64
+	v21:i64 = Iadd v20, v19
+	v22:i32 = Icmp ge_u, v6, v21
+	ExitIfNotZero v22, exec_ctx, v21, memory_out_of_bounds
+	v23:i64 = Iadd v9, v20
+	v24:i32 = Load v23, 0x3e8
+	Jump blk_ret, v11, v24
+`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			body := []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load, 0x2, 0x0}
+			body = append(body, tc.body...)
+			body = append(body, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load, 0x2, 0xe8, 0x07, wasm.OpcodeEnd)
+			m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32, i32}}, body, nil)
+			m.MemorySection = &wasm.Memory{Min: 1}
+			m.TableSection = []wasm.Table{{Min: 1, Type: wasm.RefTypeFuncref}}
+			b := ssa.NewBuilder()
+			offset := wazevoapi.NewModuleContextOffsetData(m)
+			fc := NewFrontendCompiler(m, b, &offset)
+
+			fc.Init(0, &m.TypeSection[0], nil, m.CodeSection[0].Body)
+			err := fc.LowerToSSA()
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, fc.formatBuilder())
+		})
+	}
+}
+
+func TestCompiler_LowerToSSA_coverage(t *testing.T) {
+	m := testcases.IfElse.Module
+	b := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	offset.AllocateCoverageBuffer()
+	fc := NewFrontendCompiler(m, b, &offset)
+	require.True(t, fc.CoverageEnabled())
+
+	code := &m.CodeSection[0]
+	fc.Init(0, &m.TypeSection[m.FunctionSection[0]], code.LocalTypes, code.Body)
+	err := fc.LowerToSSA()
+	require.NoError(t, err)
+
+	actual := fc.formatBuilder()
+	fmt.Println(actual)
+	require.Equal(t, `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	v2:i32 = Iconst_32 0x0
+	v3:i64 = Load module_ctx, 0x0
+	v4:i64 = Load v3, 0x0
+	v5:i64 = Iconst_64 0x1
+	v6:i64 = Iadd v4, v5
+	Store v6, v3, 0x0
+	Brz v2, blk2
+	Jump blk1
+
+blk1: () <-- (blk0)
+	v7:i64 = Load module_ctx, 0x0
+	v8:i64 = Load v7, 0x8
+	v9:i64 = Iconst_64 0x1
+	v10:i64 = Iadd v8, v9
+	Store v10, v7, 0x8
+	Jump blk3
+
+blk2: () <-- (blk0)
+	v11:i64 = Load module_ctx, 0x0
+	v12:i64 = Load v11, 0x10
+	v13:i64 = Iconst_64 0x1
+	v14:i64 = Iadd v12, v13
+	Store v14, v11, 0x10
+	Jump blk_ret
+
+blk3: () <-- (blk1)
+	v15:i64 = Load module_ctx, 0x0
+	v16:i64 = Load v15, 0x18
+	v17:i64 = Iconst_64 0x1
+	v18:i64 = Iadd v16, v17
+	Store v18, v15, 0x18
+	Jump blk_ret
+`, actual)
+	require.Equal(t, 4, fc.CoverageCounters())
+	require.Equal(t, map[ssa.BasicBlockID]int{0: 0, 1: 1, 2: 2, 3: 3}, fc.CoverageBlocks())
+}
+
+func TestCompiler_LowerToSSA_loopProfiling(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		m    *wasm.Module
+		exp  string
+	}{
+		{
+			name: "br",
+			m:    testcases.LoopBr.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	Jump blk1
+
+blk1: () <-- (blk0,blk1)
+	v2:i64 = Load module_ctx, 0x0
+	v3:i64 = Load v2, 0x0
+	v4:i64 = Iconst_64 0x1
+	v5:i64 = Iadd v3, v4
+	Store v5, v2, 0x0
+	Jump blk1
+
+blk2: ()
+`,
+		},
+		{
+			name: "br_if with params",
+			m:    testcases.LoopBrWithParamResults.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	Jump blk1, v2, v3
+
+blk1: (v4:i32,v5:i32) <-- (blk0,blk3)
+	v7:i32 = Iconst_32 0x1
+	Brnz v7, blk3
+	Jump blk4
+
+blk2: (v6:i32) <-- (blk4)
+	Jump blk_ret, v6
+
+blk3: () <-- (blk1)
+	v8:i64 = Load module_ctx, 0x0
+	v9:i64 = Load v8, 0x0
+	v10:i64 = Iconst_64 0x1
+	v11:i64 = Iadd v9, v10
+	Store v11, v8, 0x0
+	Jump blk1, v4, v5
+
+blk4: () <-- (blk1)
+	Jump blk2, v4
+`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			b := ssa.NewBuilder()
+			offset := wazevoapi.NewModuleContextOffsetData(tc.m)
+			offset.AllocateLoopCounterBuffer()
+			fc := NewFrontendCompiler(tc.m, b, &offset)
+			require.True(t, fc.LoopProfilingEnabled())
+
+			code := &tc.m.CodeSection[0]
+			fc.Init(0, &tc.m.TypeSection[tc.m.FunctionSection[0]], code.LocalTypes, code.Body)
+			err := fc.LowerToSSA()
+			require.NoError(t, err)
+			require.NoError(t, b.Verify())
+			require.Equal(t, tc.exp, fc.formatBuilder())
+			require.Equal(t, 1, fc.LoopCounters())
+		})
+	}
+}
+
+func TestCompiler_LowerToSSA_strictAlignment(t *testing.T) {
+	m := testcases.MemoryLoadBasic.Module
+	b := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, b, &offset)
+	fc.SetStrictAlignment(true)
+
+	code := &m.CodeSection[0]
+	fc.Init(0, &m.TypeSection[m.FunctionSection[0]], code.LocalTypes, code.Body)
+	err := fc.LowerToSSA()
+	require.NoError(t, err)
+
+	actual := fc.formatBuilder()
+	fmt.Println(actual)
+	require.Equal(t, `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i64 = Iconst_64 0x4
+	v4:i64 = UExtend v2, 32->64
+	v5:i64 = Uload32 module_ctx, 0x8
+	v6:i64 = Iadd v4, v3
+	v7:i32 = Icmp ge_u, v5, v6
+	ExitIfNotZero v7, exec_ctx, v6, memory_out_of_bounds
+	v8:i64 = Iconst_64 0x0
+	v9:i64 = Iadd v4, v8
+	v10:i64 = Iconst_64 0x3e
+	v11:i64 = Ishl v9, v10
+	v12:i64 = Iconst_64 0x0
+	v13:i32 = Icmp eq, v11, v12
+	ExitIfNotZero v13, exec_ctx, unaligned_memory_access
+	v14:i64 = Load module_ctx, 0x0
+	v15:i64 = Iadd v14, v4
+	v16:i32 = Load v15, 0x0
+	Jump blk_ret, v16
+`, actual)
+}
+
+func TestCompiler_LowerToSSA_strictAlignmentConstantAddress(t *testing.T) {
+	// The aligned constant address is folded, but the misaligned one falls back to the alignment check at runtime.
+	i32 := wasm.ValueTypeI32
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Results: []wasm.ValueType{i32, i32}}, []byte{
+		wasm.OpcodeI32Const, 4,
+		wasm.OpcodeI32Load, 0x2, 0x0,
+		wasm.OpcodeI32Const, 2,
+		wasm.OpcodeI32Load, 0x2, 0x0,
+		wasm.OpcodeEnd,
+	}, nil)
+	m.MemorySection = &wasm.Memory{Min: 1}
+	b := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, b, &offset)
+	fc.SetStrictAlignment(true)
+
+	fc.Init(0, &m.TypeSection[0], nil, m.CodeSection[0].Body)
+	err := fc.LowerToSSA()
+	require.NoError(t, err)
+
+	require.Equal(t, `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	v2:i32 = Iconst_32 0x4
+	v3:i64 = Iconst_64 0x8
+	v4:i64 = Uload32 module_ctx, 0x8
+	v5:i32 = Icmp ge_u, v4, v3
+	ExitIfNotZero v5, exec_ctx, v3, memory_out_of_bounds
+	v6:i64 = Load module_ctx, 0x0
+	v7:i32 = Load v6, 0x4
+	v8:i32 = Iconst_32 0x2
+	v9:i64 = Iconst_64 0x4
+	v10:i64 = UExtend v8, 32->64
+	v11:i64 = Iadd v10, v9
+	v12:i32 = Icmp ge_u, v4, v11
+	ExitIfNotZero v12, exec_ctx, v11, memory_out_of_bounds
+	v13:i64 = Iconst_64 0x0
+	v14:i64 = Iadd v10, v13
+	v15:i64 = Iconst_64 0x3e
+	v16:i64 = Ishl v14, v15
+	v17:i64 = Iconst_64 0x0
+	v18:i32 = Icmp eq, v16, v17
+	ExitIfNotZero v18, exec_ctx, unaligned_memory_access
+	v19:i64 = Iadd v6, v10
+	v20:i32 = Load v19, 0x0
+	Jump blk_ret, v7, v20
+`, fc.formatBuilder())
+}
+
+func TestCompiler_LowerToSSA_preciseMemoryTraps(t *testing.T) {
+	// The same loads as TestCompiler_LowerToSSA_sharedTrapBlocks, but each of them is checked by itself.
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32, i64, i32}}, []byte{
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI32Load, 0x2, 0x0,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI64Load, 0x3, 0x8,
+		wasm.OpcodeI32Const, 16,
+		wasm.OpcodeI32Load8U, 0x0, 0x0,
+		wasm.OpcodeEnd,
+	}, nil)
+	m.MemorySection = &wasm.Memory{Min: 1}
+	b := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, b, &offset)
+	fc.SetPreciseMemoryTraps(true)
+
+	fc.Init(0, &m.TypeSection[0], nil, m.CodeSection[0].Body)
+	err := fc.LowerToSSA()
+	require.NoError(t, err)
+
+	require.Equal(t, `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i64 = Iconst_64 0x4
+	v4:i64 = UExtend v2, 32->64
+	v5:i64 = Uload32 module_ctx, 0x8
+	v6:i64 = Iadd v4, v3
+	v7:i32 = Icmp ge_u, v5, v6
+	ExitIfNotZero v7, exec_ctx, v6, memory_out_of_bounds
+	v8:i64 = Load module_ctx, 0x0
+	v9:i64 = Iadd v8, v4
+	v10:i32 = Load v9, 0x0
+	v11:i64 = Iconst_64 0x10
+	v12:i64 = UExtend v2, 32->64
+	v13:i64 = Iadd v12, v11
+	v14:i32 = Icmp ge_u, v5, v13
+	ExitIfNotZero v14, exec_ctx, v13, memory_out_of_bounds
+	v15:i64 = Iadd v8, v12
+	v16:i64 = Load v15, 0x8
+	v17:i32 = Iconst_32 0x10
+	v18:i64 = Iconst_64 0x11
+	v19:i32 = Icmp ge_u, v5, v18
+	ExitIfNotZero v19, exec_ctx, v18, memory_out_of_bounds
+	v20:i32 = Uload8 v8, 0x10
+	Jump blk_ret, v10, v16, v20
+`, fc.formatBuilder())
+}
+
+func TestCompiler_LowerToSSA_sharedTrapBlocks(t *testing.T) {
+	// The second load extends the check of the first one, which passes the exit code of the extended check of the
+	// 8-byte access to the trap block shared with the check of the constant address.
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32, i64, i32}}, []byte{
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI32Load, 0x2, 0x0,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI64Load, 0x3, 0x8,
+		wasm.OpcodeI32Const, 16,
+		wasm.OpcodeI32Load8U, 0x0, 0x0,
+		wasm.OpcodeEnd,
+	}, nil)
+	m.MemorySection = &wasm.Memory{Min: 1}
+	b := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, b, &offset)
+	fc.SetSharedTrapBlocks(true)
+
+	fc.Init(0, &m.TypeSection[0], nil, m.CodeSection[0].Body)
+	err := fc.LowerToSSA()
+	require.NoError(t, err)
+
+	require.Equal(t, `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i64 = Iconst_64 0x10
+	v4:i64 = UExtend v2, 32->64
+	v5:i64 = Uload32 module_ctx, 0x8
+	v6:i64 = Iadd v4, v3
+	v7:i32 = Icmp ge_u, v5, v6
+	v8:i32 = Iconst_32 0x800803
+	Brnz v7, blk1
+	Jump blk2, v6, v8
+
+blk1: () <-- (blk0)
+	v11:i64 = Load module_ctx, 0x0
+	v12:i64 = Iadd v11, v4
+	v13:i32 = Load v12, 0x0
+	v14:i64 = UExtend v2, 32->64
+	v15:i64 = Iadd v11, v14
+	v16:i64 = Load v15, 0x8
+	v17:i32 = Iconst_32 0x10
+	v18:i64 = Iconst_64 0x11
+	v19:i32 = Icmp ge_u, v5, v18
+	v20:i32 = Iconst_32 0x103
+	Brnz v19, blk3
+	Jump blk2, v18, v20
+
+blk2: (v9:i64,v10:i32) <-- (blk0,blk1)
+	ExitWithDynamicCode exec_ctx, v10, v9
+
+blk3: () <-- (blk1)
+	v21:i32 = Uload8 v11, 0x10
+	Jump blk_ret, v13, v16, v21
+`, fc.formatBuilder())
+}
+
+func TestCompiler_LowerToSSA_ifWithoutElseResults(t *testing.T) {
+	// The empty Else block of an if without else passes the params through as the results, so the if must not
+	// produce results other than its params. Such a module is rejected by the validator, hence never compiled.
+	i32 := wasm.ValueTypeI32
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Results: []wasm.ValueType{i32}}, []byte{
+		wasm.OpcodeI32Const, 1,
+		wasm.OpcodeIf, i32,
+		wasm.OpcodeI32Const, 2,
+		wasm.OpcodeEnd,
+		wasm.OpcodeEnd,
+	}, nil)
+	err := m.Validate(api.CoreFeaturesV2)
+	require.EqualError(t, err, `invalid function[0] export["f"]: not enough results in else block
+	have ()
+	want (i32)`)
+
+	// On the other hand, the if without else whose params and results are the same is valid, and
+	// the Else block passes the params to the following block. See "if without else params" in TestCompiler_LowerToSSA.
+	require.NoError(t, testcases.IfWithoutElseParams.Module.Validate(api.CoreFeaturesV2))
+}
+
+func TestCompiler_LowerToSSA_dataSegmentIndex(t *testing.T) {
+	one := uint32(1)
+	for _, tc := range []struct {
+		name      string
+		dataCount *uint32
+		body      []byte
+		expErr    string
+	}{
+		{
+			name:      "data.drop out of range",
+			dataCount: &one,
+			body:      []byte{wasm.OpcodeMiscPrefix, wasm.OpcodeMiscDataDrop, 5, wasm.OpcodeEnd},
+			expErr:    "data.drop: data segment index 5 out of range of data section(len=1)",
+		},
+		{
+			name:      "memory.init out of range",
+			dataCount: &one,
+			body: []byte{
+				wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryInit, 1, 0,
+				wasm.OpcodeEnd,
+			},
+			expErr: "memory.init: data segment index 1 out of range of data section(len=1)",
+		},
+		{
+			name: "memory.init without data count",
+			body: []byte{
+				wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryInit, 0, 0,
+				wasm.OpcodeEnd,
+			},
+			expErr: "memory.init requires data count section",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m := testcases.SingleFunctionModule(wasm.FunctionType{}, tc.body, nil)
+			m.MemorySection = &wasm.Memory{Min: 1}
+			m.DataSection = []wasm.DataSegment{{Passive: true}}
+			m.DataCountSection = tc.dataCount
+
+			b := ssa.NewBuilder()
+			offset := wazevoapi.NewModuleContextOffsetData(m)
+			fc := NewFrontendCompiler(m, b, &offset)
+			fc.Init(0, &m.TypeSection[0], nil, m.CodeSection[0].Body)
+			err := fc.LowerToSSA()
+			require.EqualError(t, err, tc.expErr)
+		})
+	}
+}
+
+func TestCompiler_LowerToSSA_recoverPanic(t *testing.T) {
+	m := testcases.SingleFunctionModule(wasm.FunctionType{}, []byte{
+		wasm.OpcodeNop,
+		// SIMD instructions are not supported yet, and panic while lowering.
+		wasm.OpcodeVecPrefix, wasm.OpcodeVecV128Const, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		wasm.OpcodeDrop,
+		wasm.OpcodeEnd,
+	}, nil)
+
+	b := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, b, &offset)
+	fc.Init(5, &m.TypeSection[0], nil, m.CodeSection[0].Body)
+	err := fc.LowerToSSA()
+	require.EqualError(t, err, "function 5: panic at offset 1: TODO: unsupported in wazevo yet: v128.const")
+	var unsupported *UnsupportedInstructionError
+	require.True(t, errors.As(err, &unsupported))
+	require.Equal(t, "v128.const", unsupported.Name)
+}
+
+func TestCompiler_LowerToSSA_regions(t *testing.T) {
+	m := testcases.SingleFunctionModule(wasm.FunctionType{
+		Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32},
+	}, []byte{
+		// The first region ends with the empty stack.
+		wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeLocalSet, 1,
+		// The second region ends with two values on the stack.
+		wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Mul, wasm.OpcodeLocalGet, 0,
+		// The third region returns from the function, or ends with a value on the stack.
+		wasm.OpcodeIf, 0x40, // 0x40 is the v_v block type.
+		wasm.OpcodeLocalGet, 1, wasm.OpcodeReturn, wasm.OpcodeEnd,
+		// The last region.
+		wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Add,
+		wasm.OpcodeEnd,
+	}, []wasm.ValueType{wasm.ValueTypeI32})
+
+	b := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, b, &offset)
+	fc.SetRegionSize(6)
+	fc.Init(0, &m.TypeSection[0], m.CodeSection[0].LocalTypes, m.CodeSection[0].Body)
+	require.True(t, fc.Regioned())
+
+	var regions []string
+	for last := false; !last; {
+		ref, isLast, err := fc.LowerNextRegionToSSA()
+		require.NoError(t, err)
+		require.Equal(t, ssa.FuncRef(1+len(regions)), ref)
+		regions = append(regions, fc.formatBuilder())
+		last = isLast
+	}
+	require.Equal(t, []string{`
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	v5:i32 = Iconst_32 0x1
+	v6:i32 = Iadd v2, v5
+	v7:i32 = Iconst_32 0x0
+	v8:i32 = Iconst_32 0x0
+	Return v7, v8, v2, v6
+
+blk1: (v4:i32)
+`, `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	v5:i32 = Imul v3, v2
+	v6:i32 = Iconst_32 0x0
+	v7:i32 = Iconst_32 0x0
+	Return v6, v7, v2, v3, v5, v2
+
+blk1: (v4:i32)
+`, `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32, v4:i32, v5:i32)
+	Brz v5, blk3
+	Jump blk2
+
+blk1: (v6:i32) <-- (blk2)
+	v9:i32 = Iconst_32 0x1
+	v10:i32 = Iconst_32 0x0
+	v11:i32 = Iconst_32 0x0
+	v12:i32 = Iconst_32 0x0
+	Return v9, v6, v10, v11, v12
+
+blk2: () <-- (blk0)
+	Jump blk1, v3
+
+blk3: () <-- (blk0)
+	Jump blk4
+
+blk4: () <-- (blk3)
+	v7:i32 = Iconst_32 0x0
+	v8:i32 = Iconst_32 0x0
+	Return v7, v8, v2, v3, v4
+`, `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32, v4:i32)
+	v6:i32 = Iadd v4, v3
+	Jump blk1, v6
+
+blk1: (v5:i32) <-- (blk0)
+	v7:i32 = Iconst_32 0x1
+	v8:i32 = Iconst_32 0x0
+	v9:i32 = Iconst_32 0x0
+	Return v7, v5, v8, v9
+`}, regions)
+
+	err := fc.LowerToSSA()
+	require.NoError(t, err)
+	require.Equal(t, `
+signatures:
+	sig1: i64i64i32i32_i32i32i32i32
+	sig2: i64i64i32i32_i32i32i32i32i32i32
+	sig3: i64i64i32i32i32i32_i32i32i32i32i32
+	sig4: i64i64i32i32i32_i32i32i32i32
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i32 = Iconst_32 0x0
+	v4:i32, v5:i32, v6:i32, v7:i32 = Call f1:sig1, exec_ctx, module_ctx, v2, v3
+	Brz v4, blk1, v6, v7
+	Jump blk_ret, v5
+
+blk1: (v8:i32,v9:i32) <-- (blk0)
+	v10:i32, v11:i32, v12:i32, v13:i32, v14:i32, v15:i32 = Call f2:sig2, exec_ctx, module_ctx, v8, v9
+	Brz v10, blk2, v12, v13, v14, v15
+	Jump blk_ret, v11
+
+blk2: (v16:i32,v17:i32,v18:i32,v19:i32) <-- (blk1)
+	v20:i32, v21:i32, v22:i32, v23:i32, v24:i32 = Call f3:sig3, exec_ctx, module_ctx, v16, v17, v18, v19
+	Brz v20, blk3, v22, v23, v24
+	Jump blk_ret, v21
+
+blk3: (v25:i32,v26:i32,v27:i32) <-- (blk2)
+	v28:i32, v29:i32, v30:i32, v31:i32 = Call f4:sig4, exec_ctx, module_ctx, v25, v26, v27
+	Jump blk_ret, v29
+`, fc.formatBuilder())
+}
+
+func TestCompiler_LowerToSSA_unknownMiscOpcode(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		body   []byte
+		expErr string
+	}{
+		{
+			name:   "reachable",
+			body:   []byte{wasm.OpcodeMiscPrefix, 0x12, wasm.OpcodeEnd},
+			expErr: "unknown misc sub-opcode 0x12 at offset 1",
+		},
+		{
+			name:   "unreachable",
+			body:   []byte{wasm.OpcodeUnreachable, wasm.OpcodeMiscPrefix, 0xff, wasm.OpcodeEnd},
+			expErr: "unknown misc sub-opcode 0xff at offset 2",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m := testcases.SingleFunctionModule(wasm.FunctionType{}, tc.body, nil)
+
+			b := ssa.NewBuilder()
+			offset := wazevoapi.NewModuleContextOffsetData(m)
+			fc := NewFrontendCompiler(m, b, &offset)
+			fc.Init(0, &m.TypeSection[0], nil, m.CodeSection[0].Body)
+			err := fc.LowerToSSA()
+			require.EqualError(t, err, tc.expErr)
+			require.Equal(t, api.CoreFeatures(0), fc.UsedFeatures())
+		})
+	}
+}
+
+func TestCompiler_LowerToSSA_graph(t *testing.T) {
+	m := testcases.LoopBrIf.Module
+	b := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, b, &offset)
+	fc.Init(0, &m.TypeSection[0], nil, m.CodeSection[0].Body)
+	err := fc.LowerToSSA()
+	require.NoError(t, err)
+	b.RunPasses()
+
+	g := b.Graph()
+	// The entry, the loop header and the block following the loop. The unreachable block after the return is removed.
+	require.Equal(t, 3, len(g.Blocks))
+	// The jump into the loop, the back-edge of the loop, and the jump out of the loop.
+	require.Equal(t, 3, len(g.Edges))
+	require.True(t, g.Blocks[1].LoopHeader)
+
+	encoded, err := json.Marshal(g)
+	require.NoError(t, err)
+	require.Equal(t, `{"blocks":[`+
+		`{"id":0,"name":"blk0","params":["exec_ctx:i64","module_ctx:i64"],"instructions":["Jump blk1"],"loop_header":false},`+
+		`{"id":1,"name":"blk1","params":[],"instructions":["v2:i32 = Iconst_32 0x1","Brnz v2, blk1","Jump blk3"],"loop_header":true},`+
+		`{"id":3,"name":"blk3","params":[],"instructions":["Return"],"loop_header":false}],`+
+		`"edges":[`+
+		`{"from":0,"to":1,"branch":"Jump","args":[]},`+
+		`{"from":1,"to":1,"branch":"Brnz","args":[]},`+
+		`{"from":1,"to":3,"branch":"Jump","args":[]}]}`, string(encoded))
+
+	require.Equal(t, `digraph {
+	node [shape=box, fontname=monospace];
+	blk0 [label="blk0: (exec_ctx:i64, module_ctx:i64)\lJump blk1\l"];
+	blk1 [label="blk1: ()\lv2:i32 = Iconst_32 0x1\lBrnz v2, blk1\lJump blk3\l"];
+	blk3 [label="blk3: ()\lReturn\l"];
+	blk0 -> blk1 [label="Jump"];
+	blk1 -> blk1 [label="Brnz"];
+	blk1 -> blk3 [label="Jump"];
+}
+`, g.DOT())
+
+	// Splitting the critical edge of the back-edge adds a block and an edge.
+	b.LayoutBlocks()
+	g = b.Graph()
+	require.Equal(t, 4, len(g.Blocks))
+	require.Equal(t, 4, len(g.Edges))
+}
+
+func TestCompiler_UsedFeatures(t *testing.T) {
+	v128Const := append([]byte{wasm.OpcodeVecPrefix, wasm.OpcodeVecV128Const}, make([]byte, 16)...)
+	for _, tc := range []struct {
+		name string
+		m    *wasm.Module
+		exp  api.CoreFeatures
+	}{
+		{name: "plain", m: testcases.IfElse.Module},
+		{
+			name: "sign extension",
+			m: testcases.SingleFunctionModule(wasm.FunctionType{
+				Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32},
+			}, []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Extend8S, wasm.OpcodeEnd,
+			}, nil),
+			exp: api.CoreFeatureSignExtensionOps,
+		},
+		{
+			name: "simd",
+			m: testcases.SingleFunctionModule(wasm.FunctionType{},
+				append(v128Const, wasm.OpcodeDrop, wasm.OpcodeEnd), nil),
+			exp: api.CoreFeatureSIMD,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			b := ssa.NewBuilder()
+			offset := wazevoapi.NewModuleContextOffsetData(tc.m)
+			fc := NewFrontendCompiler(tc.m, b, &offset)
+			code := &tc.m.CodeSection[0]
+			fc.Init(0, &tc.m.TypeSection[tc.m.FunctionSection[0]], code.LocalTypes, code.Body)
+			if tc.exp&api.CoreFeatureSIMD != 0 {
+				// Vector instructions are not supported yet, but their use must be recorded before bailing out.
+				err := fc.LowerToSSA()
+				require.Contains(t, err.Error(), "unsupported in wazevo yet")
+			} else {
+				require.NoError(t, fc.LowerToSSA())
+			}
+			require.Equal(t, tc.exp, fc.UsedFeatures())
+		})
+	}
+}
+
+func TestCompiler_checkLocalIndex(t *testing.T) {
+	m := testcases.LocalsParams.Module
+	b := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, b, &offset)
+	code := &m.CodeSection[0]
+	fc.Init(0, &m.TypeSection[m.FunctionSection[0]], code.LocalTypes, code.Body)
+
+	n := len(m.TypeSection[m.FunctionSection[0]].Params) + len(code.LocalTypes)
+	for i := 0; i < n; i++ {
+		fc.checkLocalIndex(wasm.Index(i))
+	}
+
+	err := require.CapturePanic(func() { fc.checkLocalIndex(wasm.Index(n)) })
+	require.EqualError(t, err, fmt.Sprintf("BUG: local index %d out of range of %d locals in function 0", n, n))
+}
+
+func TestCompiler_checkBlocksSealed(t *testing.T) {
+	m := testcases.IfElse.Module
+	b := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, b, &offset)
+	code := &m.CodeSection[0]
+	fc.Init(0, &m.TypeSection[m.FunctionSection[0]], code.LocalTypes, code.Body)
+	err := fc.LowerToSSA()
+	require.NoError(t, err)
+	fc.checkBlocksSealed()
+
+	// Emulates a lowering which forgets to seal the blocks it allocates.
+	b.AllocateBasicBlock()
+	b.AllocateBasicBlock()
+	err = require.CapturePanic(fc.checkBlocksSealed)
+	require.EqualError(t, err, "BUG: unsealed blocks in function 0: blk4, blk5")
+}
+
+// brIfDenseModule returns the module whose function has n consecutive br_if instructions, each of which reads locals
+// in the fall-through path, like the switch-like code emitted by some toolchains.
+func brIfDenseModule(n int) *wasm.Module {
+	i32 := wasm.ValueTypeI32
+	body := []byte{wasm.OpcodeBlock, 0x40} // 0x40 is the v_v block type.
+	for i := 0; i < n; i++ {
+		body = append(body,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Add,
+			wasm.OpcodeLocalSet, 1,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Eq,
+			wasm.OpcodeBrIf, 0,
+		)
+	}
+	body = append(body, wasm.OpcodeEnd, wasm.OpcodeLocalGet, 1, wasm.OpcodeEnd)
+	return &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{LocalTypes: []wasm.ValueType{i32}, Body: body}},
+	}
+}
+
+func BenchmarkCompiler_LowerToSSA_brIfDense(b *testing.B) {
+	m := brIfDenseModule(1000)
+	ssab := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, ssab, &offset)
+	code := &m.CodeSection[0]
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fc.Init(0, &m.TypeSection[0], code.LocalTypes, code.Body)
+		if err := fc.LowerToSSA(); err != nil {
+			b.Fatal(err)
+		}
+		ssab.RunPasses()
+		ssab.LayoutBlocks()
+	}
+}
+
+func TestCompiler_LowerToSSA_unreachableOffset(t *testing.T) {
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{wasm.ValueTypeI32}}, []byte{
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeIf, 0x40, // 0x40 is the v_v signature.
+		wasm.OpcodeUnreachable, // offset 0x4
+		wasm.OpcodeEnd,
+		wasm.OpcodeUnreachable, // offset 0x6
+		wasm.OpcodeEnd,
+	}, nil)
+	b := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, b, &offset)
+	fc.Init(0, &m.TypeSection[0], nil, m.CodeSection[0].Body)
+	err := fc.LowerToSSA()
+	require.NoError(t, err)
+
+	// Each exit records the offset of its own unreachable instruction.
+	var offsets []int
+	for blk := b.BlockIteratorBegin(); blk != nil; blk = b.BlockIteratorNext() {
+		for instr := blk.Root(); instr != nil; instr = instr.Next() {
+			if instr.Opcode() == ssa.OpcodeExitWithCode {
+				_, code := instr.ExitWithCodeData()
+				require.Equal(t, wazevoapi.ExitCodeUnreachable, code&wazevoapi.ExitCodeMask)
+				o, ok := wazevoapi.UnreachableOffsetFromExitCode(code)
+				require.True(t, ok)
+				offsets = append(offsets, o)
+			}
+		}
+	}
+	require.Equal(t, []int{4, 6}, offsets)
+}
+
+func TestCompiler_LowerToSSA_boundsCheckAudit(t *testing.T) {
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{wasm.ValueTypeI32}}, []byte{
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI64Load, 0x3, 0x0, // offset 0x2
+		wasm.OpcodeDrop,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI64Load, 0x3, 0x8, // offset 0x8
+		wasm.OpcodeDrop,
+		wasm.OpcodeI32Const, 4,
+		wasm.OpcodeI32Load, 0x2, 0x0, // offset 0xe
+		wasm.OpcodeDrop,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI64Load, 0x3, 0x10, // offset 0x14
+		wasm.OpcodeDrop,
+		wasm.OpcodeEnd,
+	}, nil)
+	m.MemorySection = &wasm.Memory{Min: 1}
+
+	for _, enabled := range []bool{false, true} {
+		b := ssa.NewBuilder()
+		offset := wazevoapi.NewModuleContextOffsetData(m)
+		fc := NewFrontendCompiler(m, b, &offset)
+		fc.SetBoundsCheckAudit(enabled)
+		fc.Init(0, &m.TypeSection[0], nil, m.CodeSection[0].Body)
+		err := fc.LowerToSSA()
+		require.NoError(t, err)
+
+		if !enabled {
+			require.Equal(t, 0, len(fc.MemoryAccessAudits()))
+			continue
+		}
+		// The following loads from the same base are covered by the extended check of the first one, even if the
+		// constant address load is in between.
+		require.Equal(t, []MemoryAccessAudit{
+			{Offset: 0x2, BoundsCheck: BoundsCheckEmitted},
+			{Offset: 0x8, BoundsCheck: BoundsCheckExtended},
+			{Offset: 0xe, BoundsCheck: BoundsCheckEmitted},
+			{Offset: 0x14, BoundsCheck: BoundsCheckExtended},
+		}, fc.MemoryAccessAudits())
+	}
+}
+
+func TestCompiler_LowerToSSA_opcodeStats(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Load, 0x2, 0x0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeCall, 1,
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Sub,
+				wasm.OpcodeEnd,
+			}},
+		},
+		MemorySection: &wasm.Memory{Min: 1},
+	}
+
+	b := ssa.NewBuilder()
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, b, &offset)
+	require.Nil(t, fc.OpcodeStats())
+	fc.SetOpcodeStats(true)
+
+	var instructions int
+	for i := range m.CodeSection {
+		fc.Init(wasm.Index(i), &m.TypeSection[0], nil, m.CodeSection[i].Body)
+		require.NoError(t, fc.LowerToSSA())
+		for blk := b.BlockIteratorBegin(); blk != nil; blk = b.BlockIteratorNext() {
+			for cur := blk.Root(); cur != nil; cur = cur.Next() {
+				instructions++
+			}
+		}
+		b.RunPasses() // Required to reuse the builder for the next function.
+	}
+
+	stats := fc.OpcodeStats()
+	require.Equal(t, instructions, stats.Instructions)
+
+	counts := map[wasm.Opcode]int{}
+	var attributed int
+	for op, stat := range stats.Opcodes {
+		counts[op] = stat.Count
+		attributed += stat.Instructions
 	}
+	require.Equal(t, map[wasm.Opcode]int{
+		wasm.OpcodeLocalGet: 3,
+		wasm.OpcodeI32Load:  1,
+		wasm.OpcodeI32Add:   1,
+		wasm.OpcodeCall:     1,
+		wasm.OpcodeI32Const: 1,
+		wasm.OpcodeI32Sub:   1,
+		wasm.OpcodeEnd:      2,
+	}, counts)
+	// The rest are the ones not attributed to any opcode, e.g. loading the memory base in the entry block.
+	require.True(t, attributed <= stats.Instructions)
+	require.Equal(t, 1, stats.Opcodes[wasm.OpcodeI32Add].Instructions)
+	require.Equal(t, 0, stats.Opcodes[wasm.OpcodeLocalGet].Instructions)
 }
@@ -4,7 +4,9 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"math/bits"
 	"strings"
+	"time"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
@@ -22,6 +24,49 @@ type (
 		unreachable      bool
 		unreachableDepth int
 		pc               int
+		// err is set when the function body turns out to be invalid during lowering.
+		err error
+		// boundsChecks are the memory bounds checks in the current block which can be extended by the following
+		// memory accesses, at most one per base address. See Compiler.insertBoundsCheck.
+		boundsChecks []boundsCheck
+		// globalInstancePtrs caches the pointers to wasm.GlobalInstance loaded in globalInstancePtrsBlk, keyed by
+		// the global index. See Compiler.getGlobalInstancePtr.
+		globalInstancePtrs    map[wasm.Index]ssa.Value
+		globalInstancePtrsBlk ssa.BasicBlock
+		// i32Consts maps the values defined by i32.const in the current function to their constants, so that
+		// the memory accesses can fold the constant addresses. See Compiler.memoryAccessAddress.
+		i32Consts map[ssa.Value]uint32
+		// floatConsts caches the values defined by f32.const and f64.const in floatConstsBlk, keyed by the type and
+		// the bit pattern of the constants. See Compiler.getFloatConst.
+		floatConsts    map[floatConst]ssa.Value
+		floatConstsBlk ssa.BasicBlock
+		// memoryTrapBlk is the block shared by the memory bounds checks in the current function to exit with
+		// wazevoapi.ExitCodeMemoryOutOfBounds, and nil until the first check. Only used if SetSharedTrapBlocks is enabled.
+		memoryTrapBlk ssa.BasicBlock
+	}
+	// boundsCheck holds the information of a memory bounds check, i.e. `memLen >= extend(baseAddr) + ceil`.
+	boundsCheck struct {
+		// blk is the basic block where the check is inserted.
+		blk ssa.BasicBlock
+		// baseAddr and memLen are the operands of the check. baseAddr is ssa.ValueInvalid if the check is for
+		// the constant addresses. See Compiler.insertConstantAddressBoundsCheck.
+		baseAddr, memLen ssa.Value
+		// ceil is the current value of ceilConst.
+		ceil uint64
+		// ceilConst is the Iconst instruction for the ceil of the check, and nil if there's no check to extend.
+		ceilConst *ssa.Instruction
+		// exit is the ExitIfNotZeroWithCode instruction of the check, whose exit code holds the size of the access
+		// reaching ceil. See wazevoapi.ExitCodeMemoryOutOfBoundsWithSize.
+		exit *ssa.Instruction
+		// codeConst is the Iconst instruction for the exit code passed to memoryTrapBlk instead of exit, and nil
+		// unless SetSharedTrapBlocks is enabled.
+		codeConst *ssa.Instruction
+	}
+	// floatConst identifies a float constant by its bit pattern rather than its value, so that the NaNs with the
+	// different payloads as well as the positive and negative zeros are kept apart.
+	floatConst struct {
+		typ  ssa.Type
+		bits uint64
 	}
 	controlFrame struct {
 		kind controlFrameKind
@@ -35,6 +80,8 @@ type (
 		blockType *wasm.FunctionType
 		// clonedArgs hold the arguments to Else block.
 		clonedArgs []ssa.Value
+		// loopID is the index of the back-edge counter of this loop, and only valid when the loop profiling is enabled.
+		loopID int
 	}
 
 	controlFrameKind byte
@@ -69,6 +116,14 @@ func (l *loweringState) reset() {
 	l.pc = 0
 	l.unreachable = false
 	l.unreachableDepth = 0
+	l.err = nil
+	l.boundsChecks = l.boundsChecks[:0]
+	l.globalInstancePtrsBlk = nil
+	l.floatConstsBlk = nil
+	l.memoryTrapBlk = nil
+	for v := range l.i32Consts {
+		delete(l.i32Consts, v)
+	}
 }
 
 func (l *loweringState) pop() (ret ssa.Value) {
@@ -121,27 +176,106 @@ func (l *loweringState) ctrlPeekAt(n int) (ret *controlFrame) {
 const debug = false
 
 // lowerBody lowers the body of the Wasm function to the SSA form.
-func (c *Compiler) lowerBody(entryBlk ssa.BasicBlock) {
+func (c *Compiler) lowerBody(entryBlk, returnBlk ssa.BasicBlock) (err error) {
+	defer func() {
+		// A single bad function must not crash the embedder, so the panics while lowering it, e.g. on the unsupported
+		// opcodes or the bugs, are returned as its compilation error instead.
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = fmt.Errorf("function %d: panic at offset %d: %w", c.wasmLocalFunctionIndex, c.loweringState.pc, e)
+			} else {
+				err = fmt.Errorf("function %d: panic at offset %d: %v", c.wasmLocalFunctionIndex, c.loweringState.pc, r)
+			}
+		}
+	}()
+
 	c.ssaBuilder.Seal(entryBlk)
 
 	// Pushes the empty control frame which corresponds to the function return.
 	c.loweringState.ctrlPush(controlFrame{
 		kind:           controlFrameKindFunction,
 		blockType:      c.wasmFunctionTyp,
-		followingBlock: c.ssaBuilder.ReturnBlock(),
+		followingBlock: returnBlk,
 	})
 
 	for c.loweringState.pc < len(c.wasmFunctionBody) {
+		if c.atRegionBoundary() {
+			break
+		}
 		op := c.wasmFunctionBody[c.loweringState.pc]
-		c.lowerOpcode(op)
+		c.recordFeatureUsage(op)
+		if c.opcodeStats != nil {
+			c.lowerOpcodeWithStats(op)
+		} else {
+			c.lowerOpcode(op)
+		}
 		if debug {
 			fmt.Println("--------- Translated " + wasm.InstructionName(op) + " --------")
 			fmt.Println("Stack: " + c.loweringState.String())
 			fmt.Println(c.formatBuilder())
 			fmt.Println("--------------------------")
 		}
+		if err := c.loweringState.err; err != nil {
+			return err
+		}
 		c.loweringState.pc++
 	}
+	if c.region.active {
+		c.endRegion(c.loweringState.pc == len(c.wasmFunctionBody))
+	}
+	if blk := c.loweringState.memoryTrapBlk; blk != nil {
+		// All the bounds checks branching to the trap block have been inserted.
+		c.ssaBuilder.Seal(blk)
+	}
+	if debug {
+		c.checkBlocksSealed()
+		if err := c.ssaBuilder.Verify(); err != nil {
+			panic(fmt.Sprintf("BUG: function %d: %v", c.wasmLocalFunctionIndex, err))
+		}
+	}
+	if c.opcodeStats != nil {
+		c.opcodeStats.Instructions += c.ssaBuilder.InsertedInstructions()
+	}
+	return nil
+}
+
+// lowerOpcodeWithStats lowers the instruction `op` at the current pc, and tallies it into opcodeStats.
+func (c *Compiler) lowerOpcodeWithStats(op wasm.Opcode) {
+	inserted, start := c.ssaBuilder.InsertedInstructions(), time.Now()
+	c.lowerOpcode(op)
+	elapsed := time.Since(start)
+
+	stat, ok := c.opcodeStats.Opcodes[op]
+	if !ok {
+		stat = &OpcodeStat{}
+		c.opcodeStats.Opcodes[op] = stat
+	}
+	stat.Count++
+	stat.Instructions += c.ssaBuilder.InsertedInstructions() - inserted
+	stat.Duration += elapsed
+}
+
+// recordFeatureUsage adds the feature of the instruction `op` at the current pc to usedFeatures.
+func (c *Compiler) recordFeatureUsage(op wasm.Opcode) {
+	switch op {
+	case wasm.OpcodeI32Extend8S, wasm.OpcodeI32Extend16S,
+		wasm.OpcodeI64Extend8S, wasm.OpcodeI64Extend16S, wasm.OpcodeI64Extend32S:
+		c.usedFeatures |= api.CoreFeatureSignExtensionOps
+	case wasm.OpcodeTypedSelect, wasm.OpcodeRefNull, wasm.OpcodeRefIsNull, wasm.OpcodeRefFunc,
+		wasm.OpcodeTableGet, wasm.OpcodeTableSet:
+		c.usedFeatures |= api.CoreFeatureReferenceTypes
+	case wasm.OpcodeVecPrefix:
+		c.usedFeatures |= api.CoreFeatureSIMD
+	case wasm.OpcodeMiscPrefix:
+		switch miscOp := c.wasmFunctionBody[c.loweringState.pc+1]; {
+		case miscOp <= wasm.OpcodeMiscI64TruncSatF64U:
+			c.usedFeatures |= api.CoreFeatureNonTrappingFloatToIntConversion
+		case miscOp <= wasm.OpcodeMiscTableCopy:
+			c.usedFeatures |= api.CoreFeatureBulkMemoryOperations
+		case miscOp <= wasm.OpcodeMiscTableFill: // table.grow, table.size and table.fill.
+			c.usedFeatures |= api.CoreFeatureReferenceTypes
+		}
+	}
 }
 
 func (c *Compiler) lowerOpcode(op wasm.Opcode) {
@@ -159,6 +293,10 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		builder.InsertInstruction(iconst)
 		value := iconst.Return()
 		state.push(value)
+		if state.i32Consts == nil {
+			state.i32Consts = make(map[ssa.Value]uint32)
+		}
+		state.i32Consts[value] = uint32(c)
 	case wasm.OpcodeI64Const:
 		c := c.readI64s()
 		if state.unreachable {
@@ -173,20 +311,12 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		if state.unreachable {
 			return
 		}
-		f32const := builder.AllocateInstruction()
-		f32const.AsF32const(c.readF32())
-		builder.InsertInstruction(f32const)
-		value := f32const.Return()
-		state.push(value)
+		state.push(c.getFloatConst(ssa.TypeF32, uint64(math.Float32bits(c.readF32()))))
 	case wasm.OpcodeF64Const:
 		if state.unreachable {
 			return
 		}
-		f64const := builder.AllocateInstruction()
-		f64const.AsF64const(c.readF64())
-		builder.InsertInstruction(f64const)
-		value := f64const.Return()
-		state.push(value)
+		state.push(c.getFloatConst(ssa.TypeF64, math.Float64bits(c.readF64())))
 	case wasm.OpcodeI32Add, wasm.OpcodeI64Add:
 		if state.unreachable {
 			return
@@ -227,6 +357,68 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		builder.InsertInstruction(imul)
 		value := imul.Return()
 		state.push(value)
+	case wasm.OpcodeI32And, wasm.OpcodeI64And:
+		if state.unreachable {
+			return
+		}
+		y, x := state.pop(), state.pop()
+		and := builder.AllocateInstruction()
+		and.AsBand(x, y)
+		builder.InsertInstruction(and)
+		value := and.Return()
+		state.push(value)
+	case wasm.OpcodeI32Or, wasm.OpcodeI64Or:
+		if state.unreachable {
+			return
+		}
+		y, x := state.pop(), state.pop()
+		or := builder.AllocateInstruction()
+		or.AsBor(x, y)
+		builder.InsertInstruction(or)
+		value := or.Return()
+		state.push(value)
+	case wasm.OpcodeI32Xor, wasm.OpcodeI64Xor:
+		if state.unreachable {
+			return
+		}
+		y, x := state.pop(), state.pop()
+		xor := builder.AllocateInstruction()
+		xor.AsBxor(x, y)
+		builder.InsertInstruction(xor)
+		value := xor.Return()
+		state.push(value)
+	case wasm.OpcodeI32DivS, wasm.OpcodeI64DivS, wasm.OpcodeI32DivU, wasm.OpcodeI64DivU:
+		if state.unreachable {
+			return
+		}
+		y, x := state.pop(), state.pop()
+		signed := op == wasm.OpcodeI32DivS || op == wasm.OpcodeI64DivS
+		c.insertIntegerDivisionChecks(x, y, signed)
+		div := builder.AllocateInstruction()
+		if signed {
+			div.AsSdiv(x, y)
+		} else {
+			div.AsUdiv(x, y)
+		}
+		builder.InsertInstruction(div)
+		value := div.Return()
+		state.push(value)
+	case wasm.OpcodeI32RemS, wasm.OpcodeI64RemS, wasm.OpcodeI32RemU, wasm.OpcodeI64RemU:
+		if state.unreachable {
+			return
+		}
+		y, x := state.pop(), state.pop()
+		// The remainder never overflows, so only the divisor is checked.
+		c.insertIntegerDivisionChecks(x, y, false)
+		rem := builder.AllocateInstruction()
+		if op == wasm.OpcodeI32RemS || op == wasm.OpcodeI64RemS {
+			rem.AsSrem(x, y)
+		} else {
+			rem.AsUrem(x, y)
+		}
+		builder.InsertInstruction(rem)
+		value := rem.Return()
+		state.push(value)
 	case wasm.OpcodeF32Sub, wasm.OpcodeF64Sub:
 		if state.unreachable {
 			return
@@ -277,6 +469,143 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		builder.InsertInstruction(isub)
 		value := isub.Return()
 		state.push(value)
+	case wasm.OpcodeF32Copysign, wasm.OpcodeF64Copysign:
+		if state.unreachable {
+			return
+		}
+		y, x := state.pop(), state.pop()
+		copysign := builder.AllocateInstruction()
+		copysign.AsFcopysign(x, y)
+		builder.InsertInstruction(copysign)
+		value := copysign.Return()
+		state.push(value)
+	case wasm.OpcodeF32Neg, wasm.OpcodeF64Neg:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		neg := builder.AllocateInstruction()
+		neg.AsFneg(x)
+		builder.InsertInstruction(neg)
+		value := neg.Return()
+		state.push(value)
+	case wasm.OpcodeF32Abs, wasm.OpcodeF64Abs:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		abs := builder.AllocateInstruction()
+		abs.AsFabs(x)
+		builder.InsertInstruction(abs)
+		value := abs.Return()
+		state.push(value)
+	case wasm.OpcodeF32Sqrt, wasm.OpcodeF64Sqrt:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		sqrt := builder.AllocateInstruction()
+		sqrt.AsSqrt(x)
+		builder.InsertInstruction(sqrt)
+		value := sqrt.Return()
+		state.push(value)
+	case wasm.OpcodeF32Ceil, wasm.OpcodeF64Ceil:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		ceil := builder.AllocateInstruction()
+		ceil.AsCeil(x)
+		builder.InsertInstruction(ceil)
+		value := ceil.Return()
+		state.push(value)
+	case wasm.OpcodeF32Floor, wasm.OpcodeF64Floor:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		floor := builder.AllocateInstruction()
+		floor.AsFloor(x)
+		builder.InsertInstruction(floor)
+		value := floor.Return()
+		state.push(value)
+	case wasm.OpcodeF32Trunc, wasm.OpcodeF64Trunc:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		trunc := builder.AllocateInstruction()
+		trunc.AsTrunc(x)
+		builder.InsertInstruction(trunc)
+		value := trunc.Return()
+		state.push(value)
+	case wasm.OpcodeF32Nearest, wasm.OpcodeF64Nearest:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		nearest := builder.AllocateInstruction()
+		nearest.AsNearest(x)
+		builder.InsertInstruction(nearest)
+		value := nearest.Return()
+		state.push(value)
+	case wasm.OpcodeF64PromoteF32:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		promote := builder.AllocateInstruction()
+		promote.AsFpromote(x)
+		builder.InsertInstruction(promote)
+		value := promote.Return()
+		state.push(value)
+	case wasm.OpcodeF32DemoteF64:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		demote := builder.AllocateInstruction()
+		demote.AsFdemote(x)
+		builder.InsertInstruction(demote)
+		value := demote.Return()
+		state.push(value)
+	case wasm.OpcodeI32TruncF32S, wasm.OpcodeI32TruncF32U, wasm.OpcodeI32TruncF64S, wasm.OpcodeI32TruncF64U,
+		wasm.OpcodeI64TruncF32S, wasm.OpcodeI64TruncF32U, wasm.OpcodeI64TruncF64S, wasm.OpcodeI64TruncF64U:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		ret64 := op >= wasm.OpcodeI64TruncF32S
+		signed := op == wasm.OpcodeI32TruncF32S || op == wasm.OpcodeI32TruncF64S ||
+			op == wasm.OpcodeI64TruncF32S || op == wasm.OpcodeI64TruncF64S
+		c.insertFloatToIntChecks(x, signed, ret64)
+		cvt := builder.AllocateInstruction()
+		if signed {
+			cvt.AsFcvtToSint(x, ret64)
+		} else {
+			cvt.AsFcvtToUint(x, ret64)
+		}
+		builder.InsertInstruction(cvt)
+		value := cvt.Return()
+		state.push(value)
+	case wasm.OpcodeF32ConvertI32S, wasm.OpcodeF32ConvertI32U, wasm.OpcodeF32ConvertI64S, wasm.OpcodeF32ConvertI64U,
+		wasm.OpcodeF64ConvertI32S, wasm.OpcodeF64ConvertI32U, wasm.OpcodeF64ConvertI64S, wasm.OpcodeF64ConvertI64U:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		ret64 := op >= wasm.OpcodeF64ConvertI32S
+		signed := op == wasm.OpcodeF32ConvertI32S || op == wasm.OpcodeF32ConvertI64S ||
+			op == wasm.OpcodeF64ConvertI32S || op == wasm.OpcodeF64ConvertI64S
+		cvt := builder.AllocateInstruction()
+		if signed {
+			cvt.AsFcvtFromSint(x, ret64)
+		} else {
+			cvt.AsFcvtFromUint(x, ret64)
+		}
+		builder.InsertInstruction(cvt)
+		value := cvt.Return()
+		state.push(value)
 	case wasm.OpcodeI64Extend8S:
 		if state.unreachable {
 			return
@@ -307,6 +636,34 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 			return
 		}
 		c.insertIntegerExtend(true, 16, 32)
+	case wasm.OpcodeI32WrapI64:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		wrap := builder.AllocateInstruction()
+		wrap.AsIreduce(x, ssa.TypeI32)
+		builder.InsertInstruction(wrap)
+		value := wrap.Return()
+		state.push(value)
+	case wasm.OpcodeI32Eqz, wasm.OpcodeI64Eqz:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		zero := builder.AllocateInstruction()
+		if op == wasm.OpcodeI64Eqz {
+			zero.AsIconst64(0)
+		} else {
+			zero.AsIconst32(0)
+		}
+		builder.InsertInstruction(zero)
+		// The result is i32 regardless of the type of x.
+		icmp := builder.AllocateInstruction()
+		icmp.AsIcmp(x, zero.Return(), ssa.IntegerCmpCondEqual)
+		builder.InsertInstruction(icmp)
+		value := icmp.Return()
+		state.push(value)
 	case wasm.OpcodeI32Eq, wasm.OpcodeI64Eq:
 		if state.unreachable {
 			return
@@ -418,6 +775,57 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		builder.InsertInstruction(ishl)
 		value := ishl.Return()
 		state.push(value)
+	case wasm.OpcodeI32Clz, wasm.OpcodeI64Clz:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		clz := builder.AllocateInstruction()
+		clz.AsClz(x)
+		builder.InsertInstruction(clz)
+		value := clz.Return()
+		state.push(value)
+	case wasm.OpcodeI32Ctz, wasm.OpcodeI64Ctz:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		ctz := builder.AllocateInstruction()
+		ctz.AsCtz(x)
+		builder.InsertInstruction(ctz)
+		value := ctz.Return()
+		state.push(value)
+	case wasm.OpcodeI32Popcnt, wasm.OpcodeI64Popcnt:
+		if state.unreachable {
+			return
+		}
+		x := state.pop()
+		popcnt := builder.AllocateInstruction()
+		popcnt.AsPopcnt(x)
+		builder.InsertInstruction(popcnt)
+		value := popcnt.Return()
+		state.push(value)
+	case wasm.OpcodeI32Rotl, wasm.OpcodeI64Rotl:
+		if state.unreachable {
+			return
+		}
+		y, x := state.pop(), state.pop()
+		rotl := builder.AllocateInstruction()
+		// The amount is taken modulo the bit width by the backends.
+		rotl.AsRotl(x, y)
+		builder.InsertInstruction(rotl)
+		value := rotl.Return()
+		state.push(value)
+	case wasm.OpcodeI32Rotr, wasm.OpcodeI64Rotr:
+		if state.unreachable {
+			return
+		}
+		y, x := state.pop(), state.pop()
+		rotr := builder.AllocateInstruction()
+		rotr.AsRotr(x, y)
+		builder.InsertInstruction(rotr)
+		value := rotr.Return()
+		state.push(value)
 	case wasm.OpcodeLocalGet:
 		index := c.readI32u()
 		if state.unreachable {
@@ -434,6 +842,28 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		variable := c.localVariable(index)
 		v := state.pop()
 		builder.DefineVariableInCurrentBB(variable, v)
+	case wasm.OpcodeGlobalGet:
+		index := c.readI32u()
+		if state.unreachable {
+			return
+		}
+		typ := wasmToSSA(c.globalTypes[index].ValType)
+		load := builder.AllocateInstruction()
+		load.AsLoad(c.getGlobalInstancePtr(index), wazevoapi.GlobalInstanceValueOffset.U32(), typ)
+		builder.InsertInstruction(load)
+		state.push(load.Return())
+	case wasm.OpcodeGlobalSet:
+		index := c.readI32u()
+		if state.unreachable {
+			return
+		}
+		v := state.pop()
+		store := builder.AllocateInstruction()
+		store.AsStore(ssa.OpcodeStore, v, c.getGlobalInstancePtr(index), wazevoapi.GlobalInstanceValueOffset.U32())
+		builder.InsertInstruction(store)
+
+		// Same as the memory stores, the global might be observed after the following out of bounds access traps.
+		state.boundsChecks = state.boundsChecks[:0]
 	case wasm.OpcodeI32Load,
 		wasm.OpcodeI64Load,
 		wasm.OpcodeF32Load,
@@ -448,64 +878,34 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		wasm.OpcodeI64Load16U,
 		wasm.OpcodeI64Load32S,
 		wasm.OpcodeI64Load32U:
-		_, offset := c.readMemArg()
+		accessPC := state.pc
+		align, offset := c.readMemArg()
 		if state.unreachable {
 			return
 		}
 
-		ceil := offset
+		var opSize uint32
 		switch op {
 		case wasm.OpcodeI32Load, wasm.OpcodeF32Load:
-			ceil += 4
+			opSize = 4
 		case wasm.OpcodeI64Load, wasm.OpcodeF64Load:
-			ceil += 8
+			opSize = 8
 		case wasm.OpcodeI32Load8S, wasm.OpcodeI32Load8U:
-			ceil += 1
+			opSize = 1
 		case wasm.OpcodeI32Load16S, wasm.OpcodeI32Load16U:
-			ceil += 2
+			opSize = 2
 		case wasm.OpcodeI64Load8S, wasm.OpcodeI64Load8U:
-			ceil += 1
+			opSize = 1
 		case wasm.OpcodeI64Load16S, wasm.OpcodeI64Load16U:
-			ceil += 2
+			opSize = 2
 		case wasm.OpcodeI64Load32S, wasm.OpcodeI64Load32U:
-			ceil += 4
+			opSize = 4
 		default:
 			panic("BUG")
 		}
 
-		ceilConst := builder.AllocateInstruction()
-		ceilConst.AsIconst64(uint64(ceil))
-		builder.InsertInstruction(ceilConst)
-
-		// We calculate the offset in 64-bit space.
 		baseAddr := state.pop()
-		extBaseAddr := builder.AllocateInstruction()
-		extBaseAddr.AsUExtend(baseAddr, 32, 64)
-		builder.InsertInstruction(extBaseAddr)
-
-		// Note: memLen is already zero extended to 64-bit space at the load time.
-		memLen := c.getMemoryLenValue()
-
-		// baseAddrPlusCeil = baseAddr + ceil
-		baseAddrPlusCeil := builder.AllocateInstruction()
-		baseAddrPlusCeil.AsIadd(extBaseAddr.Return(), ceilConst.Return())
-		builder.InsertInstruction(baseAddrPlusCeil)
-
-		// Check for out of bounds memory access: `memLen >= baseAddrPlusCeil`.
-		cmp := builder.AllocateInstruction()
-		cmp.AsIcmp(memLen, baseAddrPlusCeil.Return(), ssa.IntegerCmpCondUnsignedGreaterThanOrEqual)
-		builder.InsertInstruction(cmp)
-		exitIfNZ := builder.AllocateInstruction()
-		exitIfNZ.AsExitIfNotZeroWithCode(c.execCtxPtrValue, cmp.Return(), wazevoapi.ExitCodeMemoryOutOfBounds)
-		builder.InsertInstruction(exitIfNZ)
-
-		// Load the value from memBase + extBaseAddr.
-		memBase := c.getMemoryBaseValue()
-		addrCalc := builder.AllocateInstruction()
-		addrCalc.AsIadd(memBase, extBaseAddr.Return())
-		builder.InsertInstruction(addrCalc)
-
-		addr := addrCalc.Return()
+		addr, offset := c.memoryAccessAddress(accessPC, baseAddr, offset, opSize, align)
 		load := builder.AllocateInstruction()
 		switch op {
 		case wasm.OpcodeI32Load:
@@ -541,6 +941,47 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		}
 		builder.InsertInstruction(load)
 		state.push(load.Return())
+	case wasm.OpcodeI32Store,
+		wasm.OpcodeI64Store,
+		wasm.OpcodeF32Store,
+		wasm.OpcodeF64Store,
+		wasm.OpcodeI32Store8,
+		wasm.OpcodeI32Store16,
+		wasm.OpcodeI64Store8,
+		wasm.OpcodeI64Store16,
+		wasm.OpcodeI64Store32:
+		accessPC := state.pc
+		align, offset := c.readMemArg()
+		if state.unreachable {
+			return
+		}
+
+		var opSize uint32
+		var opcode ssa.Opcode
+		switch op {
+		case wasm.OpcodeI32Store, wasm.OpcodeF32Store:
+			opcode, opSize = ssa.OpcodeStore, 4
+		case wasm.OpcodeI64Store, wasm.OpcodeF64Store:
+			opcode, opSize = ssa.OpcodeStore, 8
+		case wasm.OpcodeI32Store8, wasm.OpcodeI64Store8:
+			opcode, opSize = ssa.OpcodeIstore8, 1
+		case wasm.OpcodeI32Store16, wasm.OpcodeI64Store16:
+			opcode, opSize = ssa.OpcodeIstore16, 2
+		case wasm.OpcodeI64Store32:
+			opcode, opSize = ssa.OpcodeIstore32, 4
+		default:
+			panic("BUG")
+		}
+
+		value, baseAddr := state.pop(), state.pop()
+		addr, offset := c.memoryAccessAddress(accessPC, baseAddr, offset, opSize, align)
+		store := builder.AllocateInstruction()
+		store.AsStore(opcode, value, addr, offset)
+		builder.InsertInstruction(store)
+
+		// The bounds checks must not be extended beyond this store. Otherwise, the following out of bounds access
+		// would trap before the memory is written by this store, which is observable unlike the loads.
+		state.boundsChecks = state.boundsChecks[:0]
 	case wasm.OpcodeBlock:
 		// Note: we do not need to create a BB for this as that would always have only one predecessor
 		// which is the current BB, and therefore it's always ok to merge them in any way.
@@ -573,6 +1014,12 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		c.addBlockParamsFromWasmTypes(bt.Params, loopHeader)
 		c.addBlockParamsFromWasmTypes(bt.Results, afterLoopBlock)
 
+		var loopID int
+		if c.LoopProfilingEnabled() {
+			loopID = c.loopCounters
+			c.loopCounters++
+		}
+
 		originalLen := len(state.values) - len(bt.Params)
 		state.ctrlPush(controlFrame{
 			originalStackLenWithoutParam: originalLen,
@@ -580,6 +1027,7 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 			blk:                          loopHeader,
 			followingBlock:               afterLoopBlock,
 			blockType:                    bt,
+			loopID:                       loopID,
 		})
 
 		var args []ssa.Value
@@ -614,7 +1062,7 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 
 		var args []ssa.Value
 		if len(bt.Params) > 0 {
-			args = cloneValuesList(state.values[len(state.values)-1-len(bt.Params):])
+			args = cloneValuesList(state.values[len(state.values)-len(bt.Params):])
 		}
 
 		// Insert the conditional jump to the Else block.
@@ -641,6 +1089,7 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		// Then and Else (if exists) have only one predecessor.
 		builder.Seal(thenBlk)
 		builder.Seal(elseBlk)
+		c.insertCoverageProbe()
 	case wasm.OpcodeElse:
 		ifctrl := state.ctrlPeekAt(0)
 		ifctrl.kind = controlFrameKindIfWithElse
@@ -668,6 +1117,7 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		}
 
 		builder.SetCurrentBlock(elseBlk)
+		c.insertCoverageProbe()
 
 	case wasm.OpcodeEnd:
 		ctrl := state.ctrlPop()
@@ -697,9 +1147,12 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 			builder.Seal(ctrl.blk)
 		case controlFrameKindIfWithoutElse:
 			// If this is the end of Then block, we have to emit the empty Else block.
+			// The validator ensures that the params and results of the block type are the same for if without else,
+			// so the empty Else block passes the params through as the results.
 			elseBlk := ctrl.blk
 			builder.SetCurrentBlock(elseBlk)
-			c.insertJumpToBlock(nil, followingBlk)
+			c.insertCoverageProbe()
+			c.insertJumpToBlock(ctrl.clonedArgs, followingBlk)
 		}
 
 		builder.Seal(ctrl.followingBlock)
@@ -722,6 +1175,9 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 			targetBlk, argNum = targetFrame.followingBlock, len(targetFrame.blockType.Results)
 		}
 		args := c.loweringState.nPeekDup(argNum)
+		if targetFrame.isLoop() {
+			c.insertLoopBackEdgeCounter(targetFrame.loopID)
+		}
 		c.insertJumpToBlock(args, targetBlk)
 
 		state.unreachable = true
@@ -744,6 +1200,15 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		}
 		args := c.loweringState.nPeekDup(argNum)
 
+		var backEdgeBlk ssa.BasicBlock
+		var backEdgeArgs []ssa.Value
+		if targetFrame.isLoop() && c.LoopProfilingEnabled() {
+			// The counter must be incremented only when the branch is taken, so we branch to
+			// the dedicated block which increments it and then jumps to the loop header.
+			backEdgeBlk = builder.AllocateBasicBlock()
+			targetBlk, args, backEdgeArgs = backEdgeBlk, nil, args
+		}
+
 		// Insert the conditional jump to the target block.
 		brnz := builder.AllocateInstruction()
 		brnz.AsBrnz(v, args, targetBlk)
@@ -752,13 +1217,35 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		// Insert the unconditional jump to the Else block which corresponds to after br_if.
 		elseBlk := builder.AllocateBasicBlock()
 		c.insertJumpToBlock(nil, elseBlk)
+		// The jump above is the only predecessor of the Else block.
+		builder.Seal(elseBlk)
+
+		if backEdgeBlk != nil {
+			// The brnz above is the only predecessor of the back-edge block.
+			builder.Seal(backEdgeBlk)
+			builder.SetCurrentBlock(backEdgeBlk)
+			c.insertLoopBackEdgeCounter(targetFrame.loopID)
+			c.insertJumpToBlock(backEdgeArgs, targetFrame.blk)
+		}
 
 		// Now start translating the instructions after br_if.
 		builder.SetCurrentBlock(elseBlk)
+		c.insertCoverageProbe()
 
 	case wasm.OpcodeNop:
 	case wasm.OpcodeReturn:
+		if debug {
+			if len(state.values) < c.results() {
+				panic(fmt.Sprintf("BUG: return requires %d values but the stack has only %d", c.results(), len(state.values)))
+			}
+		}
 		results := c.loweringState.nPeekDup(c.results())
+		if c.region.active {
+			// The region returns from the Wasm function through its exit block. See regionLowering.
+			c.insertJumpToBlock(results, c.region.exit)
+			state.unreachable = true
+			return
+		}
 		instr := builder.AllocateInstruction()
 
 		instr.AsReturn(results)
@@ -766,9 +1253,8 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		state.unreachable = true
 
 	case wasm.OpcodeUnreachable:
-		exit := builder.AllocateInstruction()
-		exit.AsExitWithCode(c.execCtxPtrValue, wazevoapi.ExitCodeUnreachable)
-		builder.InsertInstruction(exit)
+		// The offset of this instruction is recorded in the exit code so that the trap can tell where it happened.
+		c.insertExitWithCode(wazevoapi.ExitCodeUnreachableWithOffset(state.pc))
 		state.unreachable = true
 
 	case wasm.OpcodeCall:
@@ -783,8 +1269,9 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		// TODO: maybe this can be optimized out if this is in-module function calls. Investigate later.
 		c.storeCallerModuleContext()
 
-		typIndex := c.m.FunctionSection[fnIndex]
-		typ := &c.m.TypeSection[typIndex]
+		// Note: the type of an imported function is the one declared by the import in this module, so the signature
+		// is resolved against this module's type section regardless of how the exporting module orders its types.
+		typ := c.m.TypeOfFunction(fnIndex)
 
 		// TODO: reuse slice?
 		argN := len(typ.Params)
@@ -815,23 +1302,614 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		}
 
 		first, rest := call.Returns()
-		state.push(first)
+		if first.Valid() {
+			state.push(first)
+		}
 		for _, v := range rest {
 			state.push(v)
 		}
 
+		// The callee might have grown the memory or trapped, so the bounds checks before the call must not be extended.
+		state.boundsChecks = state.boundsChecks[:0]
+
 		// After calling any function, memory buffer might have changed. So we need to re-defined the variable.
 		if c.needMemory {
 			// When these are not used in the following instructions, they will be optimized out.
 			// So in any ways, we define them!
-			_ = c.getMemoryBaseValue()
-			_ = c.getMemoryLenValue()
+			c.reloadMemoryBaseLen()
 		}
 	case wasm.OpcodeDrop:
 		_ = state.pop()
+	case wasm.OpcodeSelect, wasm.OpcodeTypedSelect:
+		if op == wasm.OpcodeTypedSelect {
+			// Skips the vector of result types, which must be the same as the operands' type.
+			typeCount := c.readI32u()
+			state.pc += int(typeCount)
+		}
+		if state.unreachable {
+			return
+		}
+
+		cond, y, x := state.pop(), state.pop(), state.pop()
+		sl := builder.AllocateInstruction()
+		sl.AsSelect(cond, x, y)
+		builder.InsertInstruction(sl)
+		state.push(sl.Return())
+	case wasm.OpcodeTableGet:
+		tableIndex := c.readI32u()
+		if state.unreachable {
+			return
+		}
+		elementOffset := state.pop()
+		elementAddr, typ := c.lowerTableElementAddress(tableIndex, elementOffset)
+		load := builder.AllocateInstruction()
+		load.AsLoad(elementAddr, 0, typ)
+		builder.InsertInstruction(load)
+		state.push(load.Return())
+	case wasm.OpcodeTableSet:
+		tableIndex := c.readI32u()
+		if state.unreachable {
+			return
+		}
+		r, elementOffset := state.pop(), state.pop()
+		elementAddr, _ := c.lowerTableElementAddress(tableIndex, elementOffset)
+		store := builder.AllocateInstruction()
+		store.AsStore(ssa.OpcodeStore, r, elementAddr, 0)
+		builder.InsertInstruction(store)
+
+		// Same as the memory stores, the element might be observed after the following out of bounds access traps.
+		// The exit of the table bounds check clears them as well, but it is elided for the constant indices.
+		state.boundsChecks = state.boundsChecks[:0]
+	case wasm.OpcodeMiscPrefix:
+		state.pc++
+		miscOp := c.wasmFunctionBody[state.pc]
+		switch miscOp {
+		case wasm.OpcodeMiscI32TruncSatF32S, wasm.OpcodeMiscI32TruncSatF32U, wasm.OpcodeMiscI32TruncSatF64S, wasm.OpcodeMiscI32TruncSatF64U,
+			wasm.OpcodeMiscI64TruncSatF32S, wasm.OpcodeMiscI64TruncSatF32U, wasm.OpcodeMiscI64TruncSatF64S, wasm.OpcodeMiscI64TruncSatF64U:
+			if state.unreachable {
+				return
+			}
+			x := state.pop()
+			ret64 := miscOp >= wasm.OpcodeMiscI64TruncSatF32S
+			signed := miscOp == wasm.OpcodeMiscI32TruncSatF32S || miscOp == wasm.OpcodeMiscI32TruncSatF64S ||
+				miscOp == wasm.OpcodeMiscI64TruncSatF32S || miscOp == wasm.OpcodeMiscI64TruncSatF64S
+			// Unlike the trapping ones, no checks are needed since the conversion itself saturates.
+			cvt := builder.AllocateInstruction()
+			if signed {
+				cvt.AsFcvtToSintSat(x, ret64)
+			} else {
+				cvt.AsFcvtToUintSat(x, ret64)
+			}
+			builder.InsertInstruction(cvt)
+			value := cvt.Return()
+			state.push(value)
+			return
+		case wasm.OpcodeMiscMemoryInit, wasm.OpcodeMiscDataDrop:
+			index := c.readI32u()
+			if miscOp == wasm.OpcodeMiscMemoryInit {
+				state.pc++ // Skips the memory index which is always zero.
+			}
+			if err := c.validateDataSegmentIndex(miscOp, index); err != nil {
+				state.err = err
+				return
+			}
+			if state.unreachable {
+				return
+			}
+		default:
+			if miscOp > wasm.OpcodeMiscTableFill {
+				// The sub-opcode is unknown even in the unreachable code, so the body is malformed.
+				state.err = fmt.Errorf("unknown misc sub-opcode %#x at offset %d", miscOp, state.pc)
+				return
+			}
+		}
+		panic(&UnsupportedInstructionError{Name: wasm.MiscInstructionName(miscOp)})
+	case wasm.OpcodeVecPrefix:
+		vecOp, _, _ := leb128.LoadUint32(c.wasmFunctionBody[state.pc+1:])
+		panic(&UnsupportedInstructionError{Name: wasm.VectorInstructionName(wasm.OpcodeVec(vecOp))})
 	default:
-		panic("TODO: unsupported in wazevo yet: " + wasm.InstructionName(op))
+		panic(&UnsupportedInstructionError{Name: wasm.InstructionName(op)})
+	}
+}
+
+// UnsupportedInstructionError is the error of lowering the instruction which is not supported by wazevo yet.
+// LowerToSSA returns the error wrapping this.
+type UnsupportedInstructionError struct {
+	// Name is the name of the instruction, e.g. "f32.ceil".
+	Name string
+}
+
+// Error implements error.
+func (e *UnsupportedInstructionError) Error() string {
+	return "TODO: unsupported in wazevo yet: " + e.Name
+}
+
+// insertExitWithCode inserts the exit with `code`. See insertExitIfNotZeroWithCode.
+func (c *Compiler) insertExitWithCode(code wazevoapi.ExitCode) {
+	c.loweringState.boundsChecks = c.loweringState.boundsChecks[:0]
+	exit := c.ssaBuilder.AllocateInstruction()
+	exit.AsExitWithCode(c.execCtxPtrValue, code)
+	c.ssaBuilder.InsertInstruction(exit)
+}
+
+// insertExitIfNotZeroWithCode inserts the exit with `code` which is taken unless `cond` holds. All the exits except
+// for the memory bounds checks must be inserted via this or insertExitWithCode, since the bounds checks before the
+// exit must not be extended beyond it. Otherwise, an out of bounds access following the exit would trap even when
+// the exit is taken first. See insertBoundsCheck.
+func (c *Compiler) insertExitIfNotZeroWithCode(cond ssa.Value, code wazevoapi.ExitCode) {
+	c.loweringState.boundsChecks = c.loweringState.boundsChecks[:0]
+	exit := c.ssaBuilder.AllocateInstruction()
+	exit.AsExitIfNotZeroWithCode(c.execCtxPtrValue, cond, code)
+	c.ssaBuilder.InsertInstruction(exit)
+}
+
+// insertIntegerDivisionChecks inserts the checks of the integer division or remainder `x / y` which trap with
+// ExitCodeIntegerDivisionByZero if y is zero, and with ExitCodeIntegerOverflow if checkOverflow and the signed
+// x / y overflows, i.e. x is the minimum value and y is -1. The divisor is checked against zero first as the spec
+// requires.
+func (c *Compiler) insertIntegerDivisionChecks(x, y ssa.Value, checkOverflow bool) {
+	builder := c.ssaBuilder
+	is64 := x.Type() == ssa.TypeI64
+	iconst := func(v32 uint32, v64 uint64) ssa.Value {
+		instr := builder.AllocateInstruction()
+		if is64 {
+			instr.AsIconst64(v64)
+		} else {
+			instr.AsIconst32(v32)
+		}
+		builder.InsertInstruction(instr)
+		return instr.Return()
+	}
+
+	// Same as the memory bounds check, the condition is the one under which the execution continues.
+	nonZero := builder.AllocateInstruction()
+	nonZero.AsIcmp(y, iconst(0, 0), ssa.IntegerCmpCondNotEqual)
+	builder.InsertInstruction(nonZero)
+	c.insertExitIfNotZeroWithCode(nonZero.Return(), wazevoapi.ExitCodeIntegerDivisionByZero)
+
+	if !checkOverflow {
+		return
+	}
+
+	// The overflow happens only if x is the minimum value and y is -1. In order to check them with a single exit,
+	// x is compared against the minimum value only when y is -1, and otherwise zero is, which never matches it.
+	minusOne := builder.AllocateInstruction()
+	minusOne.AsIcmp(y, iconst(0xffffffff, 0xffffffffffffffff), ssa.IntegerCmpCondEqual)
+	builder.InsertInstruction(minusOne)
+	dividend := builder.AllocateInstruction()
+	dividend.AsSelect(minusOne.Return(), x, iconst(0, 0))
+	builder.InsertInstruction(dividend)
+	noOverflow := builder.AllocateInstruction()
+	noOverflow.AsIcmp(dividend.Return(), iconst(0x80000000, 0x8000000000000000), ssa.IntegerCmpCondNotEqual)
+	builder.InsertInstruction(noOverflow)
+	c.insertExitIfNotZeroWithCode(noOverflow.Return(), wazevoapi.ExitCodeIntegerOverflow)
+}
+
+// insertFloatToIntChecks inserts the checks of the trapping conversion of the float x to the integer, which trap
+// with ExitCodeInvalidConversionToInteger if x is NaN, and with ExitCodeIntegerOverflow if x truncated towards zero
+// doesn't fit in the integer type. NaN is checked first as the spec distinguishes them, and it fails the range checks
+// as well.
+func (c *Compiler) insertFloatToIntChecks(x ssa.Value, signed, ret64 bool) {
+	builder := c.ssaBuilder
+	fconst := func(v float64) ssa.Value {
+		instr := builder.AllocateInstruction()
+		if x.Type() == ssa.TypeF64 {
+			instr.AsF64const(v)
+		} else {
+			instr.AsF32const(float32(v))
+		}
+		builder.InsertInstruction(instr)
+		return instr.Return()
+	}
+	// Same as insertIntegerDivisionChecks, each condition is the one under which the execution continues.
+	exitUnless := func(y ssa.Value, cond ssa.FloatCmpCond, code wazevoapi.ExitCode) {
+		cmp := builder.AllocateInstruction()
+		cmp.AsFcmp(x, y, cond)
+		builder.InsertInstruction(cmp)
+		c.insertExitIfNotZeroWithCode(cmp.Return(), code)
+	}
+
+	// Only NaN is unordered with itself.
+	exitUnless(x, ssa.FloatCmpCondEqual, wazevoapi.ExitCodeInvalidConversionToInteger)
+
+	bits := 32
+	if ret64 {
+		bits = 64
+	}
+	// The bounds are exact in the type of x, and the range check is done before truncating towards zero.
+	var lower, upper float64
+	lowerCond := ssa.FloatCmpCondGreaterThan
+	if signed {
+		upper = math.Ldexp(1, bits-1)
+		if bits == 32 && x.Type() == ssa.TypeF64 {
+			// f64 has the values in (-2^31-1, -2^31), which truncate to -2^31.
+			lower = -upper - 1
+		} else {
+			// f32 has no values in (-2^31-1, -2^31), and neither f32 nor f64 has ones in (-2^63-1, -2^63).
+			lower, lowerCond = -upper, ssa.FloatCmpCondGreaterThanOrEqual
+		}
+	} else {
+		// The values in (-1, 0) truncate to zero.
+		lower, upper = -1, math.Ldexp(1, bits)
+	}
+	exitUnless(fconst(lower), lowerCond, wazevoapi.ExitCodeIntegerOverflow)
+	exitUnless(fconst(upper), ssa.FloatCmpCondLessThan, wazevoapi.ExitCodeIntegerOverflow)
+}
+
+// memoryAccessAddress inserts the checks of the memory access of `size` bytes at `baseAddr + offset`, and returns
+// the address and the offset to be used by the load or store instruction. accessPC is the offset of the instruction
+// in the function body, which is recorded for SetBoundsCheckAudit.
+//
+// If baseAddr is defined by i32.const, the effective address is known at compile time. In that case, the bounds check
+// compares the memory length against the constant ceil, and the effective address is folded into the returned offset
+// from the memory base, so that no address calculation is emitted for the access itself.
+func (c *Compiler) memoryAccessAddress(accessPC int, baseAddr ssa.Value, offset, size, align uint32) (addr ssa.Value, addrOffset uint32) {
+	if base, ok := c.loweringState.i32Consts[baseAddr]; ok {
+		effectiveAddr := uint64(base) + uint64(offset)
+		// If the effective address overflows 32 bits or is misaligned, the access is lowered in the same way as the
+		// others below, where the checks always fail at runtime.
+		if effectiveAddr <= math.MaxUint32 && (!c.strictAlignment || effectiveAddr&(1<<align-1) == 0) {
+			extended := c.insertConstantAddressBoundsCheck(effectiveAddr+uint64(size), size)
+			c.auditMemoryAccess(accessPC, extended)
+			return c.getMemoryBaseValue(), uint32(effectiveAddr)
+		}
+	}
+
+	extBaseAddr, extended := c.insertBoundsCheck(baseAddr, uint64(offset)+uint64(size), size)
+	c.auditMemoryAccess(accessPC, extended)
+	if c.strictAlignment && align > 0 {
+		c.insertAlignmentCheck(extBaseAddr, offset, align)
+	}
+
+	// The address is memBase + extBaseAddr, and the static offset is applied by the load or store.
+	builder := c.ssaBuilder
+	addrCalc := builder.AllocateInstruction()
+	addrCalc.AsIadd(c.getMemoryBaseValue(), extBaseAddr)
+	builder.InsertInstruction(addrCalc)
+	return addrCalc.Return(), offset
+}
+
+// auditMemoryAccess records the memory access at accessPC if enabled by SetBoundsCheckAudit. extended is true if the
+// access is covered by the extended check rather than the one emitted for it.
+func (c *Compiler) auditMemoryAccess(accessPC int, extended bool) {
+	if !c.boundsCheckAudit {
+		return
+	}
+	check := BoundsCheckEmitted
+	if extended {
+		check = BoundsCheckExtended
+	}
+	c.memoryAccessAudits = append(c.memoryAccessAudits, MemoryAccessAudit{Offset: accessPC, BoundsCheck: check})
+}
+
+// insertConstantAddressBoundsCheck inserts the check that the constant `ceil` doesn't exceed the memory length, and
+// exits with wazevoapi.ExitCodeMemoryOutOfBounds otherwise. This is the variant of insertBoundsCheck for the accesses
+// whose effective addresses are constants, and is extended by the following ones in the same block regardless of
+// their addresses. extended is true if the preceding check is extended instead of inserting a new one.
+func (c *Compiler) insertConstantAddressBoundsCheck(ceil uint64, size uint32) (extended bool) {
+	builder := c.ssaBuilder
+
+	if c.extendBoundsCheck(ssa.ValueInvalid, ceil, size) {
+		return true
+	}
+
+	ceilConst := builder.AllocateInstruction()
+	ceilConst.AsIconst64(ceil)
+	builder.InsertInstruction(ceilConst)
+
+	memLen := c.getMemoryLenValue()
+	cmp := builder.AllocateInstruction()
+	cmp.AsIcmp(memLen, ceilConst.Return(), ssa.IntegerCmpCondUnsignedGreaterThanOrEqual)
+	builder.InsertInstruction(cmp)
+	c.insertBoundsCheckExit(boundsCheck{baseAddr: ssa.ValueInvalid, memLen: memLen, ceil: ceil, ceilConst: ceilConst},
+		cmp.Return(), ceilConst.Return(), size)
+	return false
+}
+
+// insertBoundsCheck inserts the check that `baseAddr + ceil` doesn't exceed the memory length for the access of `size`
+// bytes, and exits with wazevoapi.ExitCodeMemoryOutOfBounds otherwise, passing `baseAddr + ceil` to the exit handler
+// to report the faulting address. The returned value is baseAddr zero-extended to 64-bit, and extended is true if
+// the latest check is extended as below instead of inserting a new one.
+//
+// If there's a bounds check for the same baseAddr and memory length earlier in the same block, and nothing which can
+// exit other than the bounds checks or change the memory has been inserted since then, that check is extended to `ceil`
+// instead of inserting a new one. In other words, the check is hoisted to the first access from baseAddr in the block.
+// The other exits are inserted via insertExitIfNotZeroWithCode, which stops the checks before it from being extended.
+// For example, the loads `i64.load offset=0` and `i64.load offset=8` from the same base result in the single check
+// against 16 even if the loads from the other bases are in between. This doesn't change whether the function traps,
+// since the extended check fails only when the following access traps anyway, and the accesses in between are the
+// loads which don't have side effects and trap in the same way.
+//
+// The check is never hoisted across the blocks, since the access in the following block might not be executed at
+// all, e.g. if br_if in between leaves the Wasm block.
+//
+// When the extended check fails, the reported access is the one reaching the extended ceil, which is out of bounds
+// even though an access before it in the block might be out of bounds as well. The exit code tells the handler so,
+// see wazevoapi.ExitCodeMemoryOutOfBoundsExtended. The checks are never extended if SetPreciseMemoryTraps is enabled.
+func (c *Compiler) insertBoundsCheck(baseAddr ssa.Value, ceil uint64, size uint32) (extBaseAddr ssa.Value, extended bool) {
+	builder := c.ssaBuilder
+
+	if c.extendBoundsCheck(baseAddr, ceil, size) {
+		return c.extendAddress(baseAddr), true
+	}
+
+	ceilConst := builder.AllocateInstruction()
+	ceilConst.AsIconst64(ceil)
+	builder.InsertInstruction(ceilConst)
+
+	// We calculate the offset in 64-bit space.
+	extBaseAddr = c.extendAddress(baseAddr)
+
+	// Note: memLen is already zero extended to 64-bit space at the load time.
+	memLen := c.getMemoryLenValue()
+
+	// baseAddrPlusCeil = baseAddr + ceil
+	baseAddrPlusCeil := builder.AllocateInstruction()
+	baseAddrPlusCeil.AsIadd(extBaseAddr, ceilConst.Return())
+	builder.InsertInstruction(baseAddrPlusCeil)
+
+	// Check for out of bounds memory access: `memLen >= baseAddrPlusCeil`.
+	cmp := builder.AllocateInstruction()
+	cmp.AsIcmp(memLen, baseAddrPlusCeil.Return(), ssa.IntegerCmpCondUnsignedGreaterThanOrEqual)
+	builder.InsertInstruction(cmp)
+	c.insertBoundsCheckExit(boundsCheck{baseAddr: baseAddr, memLen: memLen, ceil: ceil, ceilConst: ceilConst},
+		cmp.Return(), baseAddrPlusCeil.Return(), size)
+	return
+}
+
+// insertBoundsCheckExit inserts the exit of the bounds `check` for the access of `size` bytes, which is taken unless
+// `cond` holds and passes `exitValue` to the exit handler, and then records the check so that the following accesses
+// can extend it.
+//
+// If SetSharedTrapBlocks is enabled, the check jumps to memoryTrapBlk with the exit code and exitValue instead of
+// exiting by itself, and the following instructions are inserted into the new block branched to from the check.
+func (c *Compiler) insertBoundsCheckExit(check boundsCheck, cond, exitValue ssa.Value, size uint32) {
+	builder := c.ssaBuilder
+	state := &c.loweringState
+	code := wazevoapi.ExitCodeMemoryOutOfBoundsWithSize(size)
+
+	if !c.sharedTrapBlocks {
+		exitIfNZ := builder.AllocateInstruction()
+		exitIfNZ.AsExitIfNotZeroWithCodeAndValue(c.execCtxPtrValue, cond, exitValue, code)
+		builder.InsertInstruction(exitIfNZ)
+		check.exit = exitIfNZ
+	} else {
+		codeConst := builder.AllocateInstruction()
+		codeConst.AsIconst32(uint32(code))
+		builder.InsertInstruction(codeConst)
+		check.codeConst = codeConst
+
+		// The trap block is the last successor so that it's laid out after all the checks, out of the paths
+		// where the checks pass. See ssa.Builder LayoutBlocks.
+		next := builder.AllocateBasicBlock()
+		brnz := builder.AllocateInstruction()
+		brnz.AsBrnz(cond, nil, next)
+		builder.InsertInstruction(brnz)
+		c.insertJumpToBlock([]ssa.Value{exitValue, codeConst.Return()}, c.memoryTrapBlock())
+		builder.Seal(next)
+		builder.SetCurrentBlock(next)
+		// The checks in the preceding block can still be extended from the new block, since it's only entered
+		// from there.
+		for i := range state.boundsChecks {
+			state.boundsChecks[i].blk = next
+		}
+	}
+
+	check.blk = builder.CurrentBlock()
+	state.boundsChecks = append(state.boundsChecks, check)
+}
+
+// memoryTrapBlock returns the block shared by the memory bounds checks in the current function, allocating it on the
+// first call. The block takes the exit value and the exit code as its params, and exits with them.
+func (c *Compiler) memoryTrapBlock() ssa.BasicBlock {
+	state := &c.loweringState
+	if state.memoryTrapBlk != nil {
+		return state.memoryTrapBlk
+	}
+
+	builder := c.ssaBuilder
+	blk := builder.AllocateBasicBlock()
+	exitValue := blk.AddParam(builder, ssa.TypeI64)
+	code := blk.AddParam(builder, ssa.TypeI32)
+
+	current := builder.CurrentBlock()
+	builder.SetCurrentBlock(blk)
+	exit := builder.AllocateInstruction()
+	exit.AsExitWithDynamicCode(c.execCtxPtrValue, code, exitValue)
+	builder.InsertInstruction(exit)
+	builder.SetCurrentBlock(current)
+
+	state.memoryTrapBlk = blk
+	return blk
+}
+
+// extendBoundsCheck extends the bounds check for baseAddr in the current block to `ceil` of the access of `size` bytes
+// if exists, and returns true in that case. baseAddr is ssa.ValueInvalid for the check of the constant addresses.
+func (c *Compiler) extendBoundsCheck(baseAddr ssa.Value, ceil uint64, size uint32) bool {
+	if c.preciseMemoryTraps {
+		return false
+	}
+
+	state := &c.loweringState
+	if len(state.boundsChecks) > 0 && state.boundsChecks[0].blk != c.ssaBuilder.CurrentBlock() {
+		// All the checks are in the same block, and none of them can be extended from the other blocks.
+		state.boundsChecks = state.boundsChecks[:0]
+		return false
+	}
+
+	for i := range state.boundsChecks {
+		check := &state.boundsChecks[i]
+		// Note: getMemoryLenValue doesn't insert any instruction here since the check in this block has defined it.
+		if check.baseAddr == baseAddr && check.memLen == c.getMemoryLenValue() {
+			if ceil > check.ceil {
+				check.ceil = ceil
+				check.ceilConst.AsIconst64(ceil)
+				code := wazevoapi.ExitCodeMemoryOutOfBoundsExtended(size)
+				if check.codeConst != nil {
+					check.codeConst.AsIconst32(uint32(code))
+				} else {
+					ctx, cond, _ := check.exit.ExitIfNotZeroWithCodeData()
+					check.exit.AsExitIfNotZeroWithCodeAndValue(ctx, cond, check.exit.ExitValue(), code)
+				}
+			}
+			return true
+		}
 	}
+	return false
+}
+
+// lowerTableElementAddress inserts the bounds check of `elementOffset` against the length of the table at `tableIndex`,
+// which exits with wazevoapi.ExitCodeTableOutOfBounds on failure, and returns the address of the element as well as
+// its SSA type, which depends on the element type of the table.
+//
+// If elementOffset is defined by i32.const and below the minimum size of the table, the bounds check is elided since
+// it always passes.
+func (c *Compiler) lowerTableElementAddress(tableIndex wasm.Index, elementOffset ssa.Value) (elementAddr ssa.Value, typ ssa.Type) {
+	builder := c.ssaBuilder
+	typ = wasmToSSA(c.tableTypes[tableIndex])
+
+	loadTableInstancePtr := builder.AllocateInstruction()
+	loadTableInstancePtr.AsLoad(c.moduleCtxPtrValue, c.offset.TableOffset(tableIndex).U32(), ssa.TypeI64)
+	builder.InsertInstruction(loadTableInstancePtr)
+	tableInstancePtr := loadTableInstancePtr.Return()
+
+	index, isConst := c.loweringState.i32Consts[elementOffset]
+	elideBoundsCheck := isConst && index < c.tableMinimums[tableIndex]
+
+	var tableLen ssa.Value
+	if !elideBoundsCheck {
+		loadTableLen := builder.AllocateInstruction()
+		loadTableLen.AsLoad(tableInstancePtr, wazevoapi.TableInstanceLenOffset.U32(), ssa.TypeI64)
+		builder.InsertInstruction(loadTableLen)
+		tableLen = loadTableLen.Return()
+	}
+
+	extElementOffset := c.extendAddress(elementOffset)
+
+	if !elideBoundsCheck {
+		// Same as the memory bounds check, the condition is the one under which the execution continues.
+		cmp := builder.AllocateInstruction()
+		cmp.AsIcmp(tableLen, extElementOffset, ssa.IntegerCmpCondUnsignedGreaterThan)
+		builder.InsertInstruction(cmp)
+		c.insertExitIfNotZeroWithCode(cmp.Return(), wazevoapi.ExitCodeTableOutOfBounds)
+	}
+
+	loadTableBase := builder.AllocateInstruction()
+	loadTableBase.AsLoad(tableInstancePtr, wazevoapi.TableInstanceBaseAddressOffset.U32(), ssa.TypeI64)
+	builder.InsertInstruction(loadTableBase)
+
+	// elementAddr = tableBase + elementOffset * the size of the element.
+	shiftAmount := builder.AllocateInstruction()
+	shiftAmount.AsIconst64(uint64(bits.TrailingZeros8(typ.Size())))
+	builder.InsertInstruction(shiftAmount)
+	byteOffset := builder.AllocateInstruction()
+	byteOffset.AsIshl(extElementOffset, shiftAmount.Return())
+	builder.InsertInstruction(byteOffset)
+	add := builder.AllocateInstruction()
+	add.AsIadd(loadTableBase.Return(), byteOffset.Return())
+	builder.InsertInstruction(add)
+	return add.Return(), typ
+}
+
+// getGlobalInstancePtr returns the pointer to the wasm.GlobalInstance of the given global index, which is loaded from
+// the module context. Imported globals live in the module instances exporting them, so both the imported and local
+// globals are reached via this pointer.
+//
+// The pointer never changes during the lifetime of the module instance, so it is loaded only once per block.
+func (c *Compiler) getGlobalInstancePtr(index wasm.Index) ssa.Value {
+	builder := c.ssaBuilder
+	state := &c.loweringState
+	if blk := builder.CurrentBlock(); state.globalInstancePtrsBlk != blk {
+		if state.globalInstancePtrs == nil {
+			state.globalInstancePtrs = make(map[wasm.Index]ssa.Value)
+		}
+		for i := range state.globalInstancePtrs {
+			delete(state.globalInstancePtrs, i)
+		}
+		state.globalInstancePtrsBlk = blk
+	} else if ptr, ok := state.globalInstancePtrs[index]; ok {
+		return ptr
+	}
+
+	load := builder.AllocateInstruction()
+	load.AsLoad(c.moduleCtxPtrValue, c.offset.GlobalOffset(index).U32(), ssa.TypeI64)
+	builder.InsertInstruction(load)
+	ptr := load.Return()
+	state.globalInstancePtrs[index] = ptr
+	return ptr
+}
+
+// getFloatConst returns the value of the float constant of `typ` with the bit pattern `bits`. The constant is defined
+// at most once per block, and the later uses in the same block share the value.
+//
+// The constants are never shared across the blocks, since the block defining one might not dominate the others.
+func (c *Compiler) getFloatConst(typ ssa.Type, bits uint64) ssa.Value {
+	builder := c.ssaBuilder
+	state := &c.loweringState
+	key := floatConst{typ: typ, bits: bits}
+	if blk := builder.CurrentBlock(); state.floatConstsBlk != blk {
+		if state.floatConsts == nil {
+			state.floatConsts = make(map[floatConst]ssa.Value)
+		}
+		for k := range state.floatConsts {
+			delete(state.floatConsts, k)
+		}
+		state.floatConstsBlk = blk
+	} else if v, ok := state.floatConsts[key]; ok {
+		return v
+	}
+
+	fconst := builder.AllocateInstruction()
+	if typ == ssa.TypeF32 {
+		fconst.AsF32const(math.Float32frombits(uint32(bits)))
+	} else {
+		fconst.AsF64const(math.Float64frombits(bits))
+	}
+	builder.InsertInstruction(fconst)
+	v := fconst.Return()
+	state.floatConsts[key] = v
+	return v
+}
+
+// extendAddress zero-extends the 32-bit Wasm address to 64-bit.
+func (c *Compiler) extendAddress(addr ssa.Value) ssa.Value {
+	builder := c.ssaBuilder
+	ext := builder.AllocateInstruction()
+	ext.AsUExtend(addr, 32, 64)
+	builder.InsertInstruction(ext)
+	return ext.Return()
+}
+
+// insertAlignmentCheck inserts the check that the effective address `extBaseAddr + offset` is a multiple of
+// `1 << align`, and exits with wazevoapi.ExitCodeUnalignedMemoryAccess otherwise.
+func (c *Compiler) insertAlignmentCheck(extBaseAddr ssa.Value, offset, align uint32) {
+	builder := c.ssaBuilder
+	offsetConst := builder.AllocateInstruction()
+	offsetConst.AsIconst64(uint64(offset))
+	builder.InsertInstruction(offsetConst)
+
+	effectiveAddr := builder.AllocateInstruction()
+	effectiveAddr.AsIadd(extBaseAddr, offsetConst.Return())
+	builder.InsertInstruction(effectiveAddr)
+
+	// Shifting out all the bits but the lowest `align` ones leaves zero iff the address is aligned.
+	amount := builder.AllocateInstruction()
+	amount.AsIconst64(uint64(64 - align))
+	builder.InsertInstruction(amount)
+
+	lowBits := builder.AllocateInstruction()
+	lowBits.AsIshl(effectiveAddr.Return(), amount.Return())
+	builder.InsertInstruction(lowBits)
+
+	zero := builder.AllocateInstruction()
+	zero.AsIconst64(0)
+	builder.InsertInstruction(zero)
+
+	// Same as the bounds check, the condition is the one under which the execution continues.
+	cmp := builder.AllocateInstruction()
+	cmp.AsIcmp(lowBits.Return(), zero.Return(), ssa.IntegerCmpCondEqual)
+	builder.InsertInstruction(cmp)
+	c.insertExitIfNotZeroWithCode(cmp.Return(), wazevoapi.ExitCodeUnalignedMemoryAccess)
 }
 
 func (c *Compiler) getMemoryBaseValue() ssa.Value {
@@ -848,11 +1926,26 @@ func (c *Compiler) getMemoryLenValue() ssa.Value {
 	return c.getModuleCtxValue(c.memoryLenVariable, c.offset.LocalMemoryLen(), true)
 }
 
+// reloadMemoryBaseLen loads the memory base and length from the module context and redefines their variables,
+// regardless of whether they are already defined in the current block.
+func (c *Compiler) reloadMemoryBaseLen() {
+	if c.offset.LocalMemoryBegin < 0 {
+		panic("TODO: imported memory")
+	}
+	c.loadModuleCtxValue(c.memoryBaseVariable, c.offset.LocalMemoryBase(), false)
+	c.loadModuleCtxValue(c.memoryLenVariable, c.offset.LocalMemoryLen(), true)
+}
+
 func (c *Compiler) getModuleCtxValue(variable ssa.Variable, offset wazevoapi.Offset, zeroExt bool) ssa.Value {
-	builder := c.ssaBuilder
-	if v := builder.FindValue(variable); v.Valid() {
+	if v := c.ssaBuilder.FindValue(variable); v.Valid() {
 		return v
 	}
+	return c.loadModuleCtxValue(variable, offset, zeroExt)
+}
+
+// loadModuleCtxValue loads the value at the offset in the module context, and defines the variable with it.
+func (c *Compiler) loadModuleCtxValue(variable ssa.Variable, offset wazevoapi.Offset, zeroExt bool) ssa.Value {
+	builder := c.ssaBuilder
 	load := builder.AllocateInstruction()
 	if zeroExt {
 		load.AsExtLoad(ssa.OpcodeUload32, c.moduleCtxPtrValue, uint32(offset), true)
@@ -885,12 +1978,65 @@ func (c *Compiler) insertFcmp(cond ssa.FloatCmpCond) {
 	state.push(value)
 }
 
+// insertCoverageProbe inserts the increment of the coverage counter for the current block if the coverage is enabled.
+// This must be called right after switching to a new basic block.
+func (c *Compiler) insertCoverageProbe() {
+	if !c.CoverageEnabled() || c.loweringState.unreachable {
+		return
+	}
+	builder := c.ssaBuilder
+	blk := builder.CurrentBlock()
+	if _, ok := c.coverageBlocks[blk.ID()]; ok {
+		return
+	}
+	index := c.coverageCounters
+	c.coverageCounters++
+	c.coverageBlocks[blk.ID()] = index
+	c.insertCounterIncrement(c.offset.CoverageBufferBegin, index)
+}
+
+// insertLoopBackEdgeCounter inserts the increment of the back-edge counter of the loop `loopID` if the loop profiling is enabled.
+// This must be called right before the branch to the loop header.
+func (c *Compiler) insertLoopBackEdgeCounter(loopID int) {
+	if !c.LoopProfilingEnabled() {
+		return
+	}
+	c.insertCounterIncrement(c.offset.LoopCounterBufferBegin, loopID)
+}
+
+// insertCounterIncrement inserts the increment of the `index`-th uint64 counter in the buffer
+// whose pointer is stored at `bufOffset` in the moduleContextOpaque.
+func (c *Compiler) insertCounterIncrement(bufOffset wazevoapi.Offset, index int) {
+	builder := c.ssaBuilder
+	loadBuf := builder.AllocateInstruction()
+	loadBuf.AsLoad(c.moduleCtxPtrValue, bufOffset.U32(), ssa.TypeI64)
+	builder.InsertInstruction(loadBuf)
+	buf := loadBuf.Return()
+
+	offset := uint32(index) * 8
+	loadCounter := builder.AllocateInstruction()
+	loadCounter.AsLoad(buf, offset, ssa.TypeI64)
+	builder.InsertInstruction(loadCounter)
+
+	one := builder.AllocateInstruction()
+	one.AsIconst64(1)
+	builder.InsertInstruction(one)
+
+	add := builder.AllocateInstruction()
+	add.AsIadd(loadCounter.Return(), one.Return())
+	builder.InsertInstruction(add)
+
+	store := builder.AllocateInstruction()
+	store.AsStore(ssa.OpcodeStore, add.Return(), buf, offset)
+	builder.InsertInstruction(store)
+}
+
 // storeCallerModuleContext stores the current module's moduleContextPtr into execContext.callerModuleContextPtr.
 func (c *Compiler) storeCallerModuleContext() {
 	builder := c.ssaBuilder
 	execCtx := c.execCtxPtrValue
 	store := builder.AllocateInstruction()
-	store.AsStore(c.moduleCtxPtrValue, execCtx, wazevoapi.ExecutionContextOffsets.CallerModuleContextPtr.U32())
+	store.AsStore(ssa.OpcodeStore, c.moduleCtxPtrValue, execCtx, wazevoapi.ExecutionContextOffsets.CallerModuleContextPtr.U32())
 	builder.InsertInstruction(store)
 }
 
@@ -1004,6 +2150,7 @@ func (c *Compiler) switchTo(originalStackLen int, targetBlk ssa.BasicBlock) {
 		value := targetBlk.Param(i)
 		c.loweringState.push(value)
 	}
+	c.insertCoverageProbe()
 }
 
 // cloneValuesList clones the given values list.
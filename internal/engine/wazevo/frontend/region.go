@@ -0,0 +1,244 @@
+package frontend
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// regionLowering is the state of lowering the current function in regions. See SetRegionSize.
+//
+// Each region is lowered as a function of its own taking the Wasm locals and the values on the Wasm stack at its
+// start as the params, and returning them at its end as the results, which are spilled and reloaded by the calling
+// convention as necessary. The results are prefixed by the flag which is non-zero if the region returns from the
+// Wasm function, followed by the results of the Wasm function, so the layout of the results of a region is
+//
+//	[returned(i32), wasm results..., locals..., stack values...]
+//
+// where the Wasm results are zeros unless returned, and the locals and the stack values are zeros otherwise. Then the
+// Wasm function is lowered as the driver which calls the regions in order until one of them returns.
+// See Compiler.lowerRegionDriver.
+type regionLowering struct {
+	// active is true while lowering a region rather than the whole function or the driver.
+	active bool
+	// start is the offset of the first instruction of the next region in the function body.
+	start int
+	// stackTypes are the types of the values on the Wasm stack at the start of the next region.
+	stackTypes []ssa.Type
+	// exit is the block of the current region which the returns from the Wasm function jump to. Its params are
+	// the results of the Wasm function.
+	exit ssa.BasicBlock
+	// done is true if the last region of the current function has been lowered.
+	done bool
+	// refs and signatures are of the regions of the current function lowered so far.
+	refs       []ssa.FuncRef
+	signatures []*ssa.Signature
+}
+
+// SetRegionSize sets the size in bytes of the function bodies beyond which the functions are lowered in regions of
+// about this size, so that the memory and the time taken to compile a huge function are bounded by the size of the
+// regions at the cost of the code quality. Zero disables the regions, which is the default.
+//
+// The regions are only split at the instructions which are not nested in any block, so a function consisting of a
+// single huge block or loop is lowered as a whole regardless. The regions are not used when the coverage is enabled
+// since the coverage blocks are per function.
+func (c *Compiler) SetRegionSize(size int) {
+	c.regionSize = size
+}
+
+// Regioned returns true if the current function is lowered in regions. If so, the caller must lower the regions
+// by LowerNextRegionToSSA until the last one before lowering the function by LowerToSSA. See SetRegionSize.
+func (c *Compiler) Regioned() bool {
+	return c.regionSize > 0 && len(c.wasmFunctionBody) > c.regionSize && !c.CoverageEnabled()
+}
+
+// LowerNextRegionToSSA lowers the next region of the current function to SSA function held by ssaBuilder in the same
+// way as LowerToSSA, and returns its FuncRef which is unique in the module, and whether it's the last region.
+func (c *Compiler) LowerNextRegionToSSA() (ref ssa.FuncRef, last bool, err error) {
+	builder := c.ssaBuilder
+	r := &c.region
+	if r.done {
+		panic("BUG: all the regions have been lowered")
+	}
+
+	// The regions follow the functions in the module for their FuncRef, and follow the types for their signature ID.
+	ref = ssa.FuncRef(c.m.ImportFunctionCount + uint32(len(c.m.FunctionSection)) + c.regionCount)
+	sig := &ssa.Signature{ID: ssa.SignatureID(uint32(len(c.m.TypeSection)) + c.regionCount)}
+	c.regionCount++
+	sig.Params = append(sig.Params, executionContextPtrTyp, moduleContextPtrTyp)
+	sig.Params = c.appendLocalTypes(sig.Params)
+	sig.Params = append(sig.Params, r.stackTypes...)
+	builder.DeclareSignature(sig)
+	builder.Init(sig)
+	c.loweringState.reset()
+	c.loweringState.pc = r.start
+
+	entryBlock := builder.AllocateBasicBlock()
+	builder.SetCurrentBlock(entryBlock)
+	c.execCtxPtrValue = entryBlock.AddParam(builder, executionContextPtrTyp)
+	c.moduleCtxPtrValue = entryBlock.AddParam(builder, moduleContextPtrTyp)
+	builder.AnnotateValue(c.execCtxPtrValue, "exec_ctx")
+	builder.AnnotateValue(c.moduleCtxPtrValue, "module_ctx")
+	for i, st := range sig.Params[2 : len(sig.Params)-len(r.stackTypes)] {
+		variable := builder.DeclareVariable(st)
+		value := entryBlock.AddParam(builder, st)
+		builder.DefineVariable(variable, value, entryBlock)
+		c.wasmLocalToVariable[wasm.Index(i)] = variable
+	}
+	for _, st := range r.stackTypes {
+		c.loweringState.push(entryBlock.AddParam(builder, st))
+	}
+	c.declareNecessaryVariables()
+
+	r.exit = builder.AllocateBasicBlock()
+	c.addBlockParamsFromWasmTypes(c.wasmFunctionTyp.Results, r.exit)
+
+	r.active = true
+	defer func() {
+		r.active = false
+	}()
+	if err = c.lowerBody(entryBlock, r.exit); err != nil {
+		return
+	}
+
+	r.refs = append(r.refs, ref)
+	r.signatures = append(r.signatures, sig)
+	return ref, r.done, nil
+}
+
+// atRegionBoundary returns true if the current region ends before the instruction at the current pc.
+func (c *Compiler) atRegionBoundary() bool {
+	state := &c.loweringState
+	return c.region.active && len(state.controlFrames) == 1 && !state.unreachable &&
+		state.pc-c.region.start >= c.regionSize
+}
+
+// endRegion ends the current region before the instruction at the current pc, or at the end of the function body
+// if last is true, by returning the locals and the values on the stack to the driver which passes them to the next
+// region. See regionLowering.
+func (c *Compiler) endRegion(last bool) {
+	builder := c.ssaBuilder
+	state := &c.loweringState
+	r := &c.region
+	sig := builder.Signature()
+
+	r.stackTypes = r.stackTypes[:0]
+	sig.Results = append(sig.Results[:0], ssa.TypeI32)
+	for _, typ := range c.wasmFunctionTyp.Results {
+		sig.Results = append(sig.Results, wasmToSSA(typ))
+	}
+	sig.Results = c.appendLocalTypes(sig.Results)
+	if !last {
+		r.start = state.pc
+		for _, v := range state.values {
+			r.stackTypes = append(r.stackTypes, v.Type())
+		}
+		sig.Results = append(sig.Results, r.stackTypes...)
+
+		// The next region starts here.
+		results := []ssa.Value{c.insertZeroValue(ssa.TypeI32)}
+		for _, typ := range c.wasmFunctionTyp.Results {
+			results = append(results, c.insertZeroValue(wasmToSSA(typ)))
+		}
+		for i := range sig.Results[len(results) : len(sig.Results)-len(r.stackTypes)] {
+			results = append(results, builder.MustFindValue(c.localVariable(wasm.Index(i))))
+		}
+		results = append(results, state.values...)
+		ret := builder.AllocateInstruction()
+		ret.AsReturn(results)
+		builder.InsertInstruction(ret)
+	}
+	r.done = last
+
+	// The exit block is only reachable by the returns from the Wasm function in this region.
+	builder.Seal(r.exit)
+	if r.exit.Preds() == 0 {
+		return
+	}
+	builder.SetCurrentBlock(r.exit)
+	returned := builder.AllocateInstruction()
+	returned.AsIconst32(1)
+	builder.InsertInstruction(returned)
+	results := []ssa.Value{returned.Return()}
+	for i := 0; i < r.exit.Params(); i++ {
+		results = append(results, r.exit.Param(i))
+	}
+	for _, st := range sig.Results[len(results):] {
+		results = append(results, c.insertZeroValue(st))
+	}
+	ret := builder.AllocateInstruction()
+	ret.AsReturn(results)
+	builder.InsertInstruction(ret)
+}
+
+// lowerRegionDriver lowers the current function as the driver calling the regions lowered by LowerNextRegionToSSA.
+func (c *Compiler) lowerRegionDriver() error {
+	builder := c.ssaBuilder
+	r := &c.region
+	if !r.done {
+		return fmt.Errorf("function %d: the regions are not lowered yet", c.wasmLocalFunctionIndex)
+	}
+	builder.Init(c.signatures[c.wasmFunctionTyp])
+
+	entryBlock := builder.AllocateBasicBlock()
+	builder.SetCurrentBlock(entryBlock)
+	c.execCtxPtrValue = entryBlock.AddParam(builder, executionContextPtrTyp)
+	c.moduleCtxPtrValue = entryBlock.AddParam(builder, moduleContextPtrTyp)
+	builder.AnnotateValue(c.execCtxPtrValue, "exec_ctx")
+	builder.AnnotateValue(c.moduleCtxPtrValue, "module_ctx")
+	builder.Seal(entryBlock)
+
+	// The first region takes the params followed by the zero values of the other locals.
+	args := []ssa.Value{c.execCtxPtrValue, c.moduleCtxPtrValue}
+	for _, typ := range c.wasmFunctionTyp.Params {
+		args = append(args, entryBlock.AddParam(builder, wasmToSSA(typ)))
+	}
+	for _, typ := range c.wasmFunctionLocalTypes {
+		args = append(args, c.insertZeroValue(wasmToSSA(typ)))
+	}
+
+	resultN := len(c.wasmFunctionTyp.Results)
+	for i, ref := range r.refs {
+		sig := r.signatures[i]
+		call := builder.AllocateInstruction()
+		call.AsCall(ref, sig, args)
+		builder.InsertInstruction(call)
+		first, rest := call.Returns()
+		returned, results, next := first, rest[:resultN], rest[resultN:]
+
+		if i == len(r.refs)-1 {
+			c.insertJumpToBlock(results, builder.ReturnBlock())
+			break
+		}
+
+		// Unless the region has returned from the Wasm function, the next region takes over the rest of its results.
+		nextBlk := builder.AllocateBasicBlock()
+		for _, st := range sig.Results[1+resultN:] {
+			nextBlk.AddParam(builder, st)
+		}
+		brz := builder.AllocateInstruction()
+		brz.AsBrz(returned, next, nextBlk)
+		builder.InsertInstruction(brz)
+		c.insertJumpToBlock(results, builder.ReturnBlock())
+		builder.Seal(nextBlk)
+		builder.SetCurrentBlock(nextBlk)
+
+		args = []ssa.Value{c.execCtxPtrValue, c.moduleCtxPtrValue}
+		for j := 0; j < nextBlk.Params(); j++ {
+			args = append(args, nextBlk.Param(j))
+		}
+	}
+	return nil
+}
+
+// appendLocalTypes appends the SSA types of the locals of the current function including the params to ts.
+func (c *Compiler) appendLocalTypes(ts []ssa.Type) []ssa.Type {
+	for _, typ := range c.wasmFunctionTyp.Params {
+		ts = append(ts, wasmToSSA(typ))
+	}
+	for _, typ := range c.wasmFunctionLocalTypes {
+		ts = append(ts, wasmToSSA(typ))
+	}
+	return ts
+}
@@ -1,10 +1,15 @@
 package wazevo
 
 import (
+	"context"
 	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sync"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
@@ -16,8 +21,27 @@ type (
 		parent    *compiledModule
 		module    *wasm.ModuleInstance
 		opaque    moduleContextOpaque
+		// coverage holds the coverage counters of basic blocks, and non-nil only when the coverage is enabled.
+		coverage []uint64
+		// loopCounters holds the back-edge counters of loops, and non-nil only when the loop profiling is enabled.
+		loopCounters []uint64
+		// crossCheck is the interpreter's wasm.ModuleEngine for the same module instance, and non-nil only
+		// in the cross-check mode. See engine.crossCheck.
+		crossCheck wasm.ModuleEngine
+		// crossCheckModule is the module instance of crossCheck, which shares the states with module.
+		crossCheckModule *wasm.ModuleInstance
+		// trapHandlers maps the exit codes of traps to their handlers. See SetTrapHandler.
+		trapHandlers map[wazevoapi.ExitCode]TrapHandler
 	}
 
+	// TrapHandler is called when the function of the module traps with the exit code it is registered for with
+	// moduleEngine.SetTrapHandler. err is the standard error of the trap with the wasm stack trace, e.g. the one
+	// wrapping wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess. The call of the function returns the error returned by
+	// the handler instead, or err if it returns nil.
+	//
+	// Note: the execution never resumes after the trap, so the handler can only decide how the trap is surfaced.
+	TrapHandler func(ctx context.Context, code wazevoapi.ExitCode, err error) error
+
 	// moduleContextOpaque is the opaque byte slice of Module instance specific contents whose size
 	// is only Wasm-compile-time known, hence dynamic. Its contents are basically the pointers to the module instance,
 	// specific objects as well as functions. This is sometimes called "VMContext" in other Wasm runtimes.
@@ -32,23 +56,38 @@ type (
 	// 	        executable      *byte
 	// 	        opaqueCtx       *moduleContextOpaque
 	// 	    }
-	// 	    TODO: add more fields, like tables and globals
+	// 	    tables [len(tables)]*wasm.TableInstance   the total size depends on # of tables including imported ones.
+	// 	    globals [len(globals)]*wasm.GlobalInstance the total size depends on # of globals including imported ones.
+	// 	    coverageBuffer                            *uint64              (optional)
+	// 	    loopCounterBuffer                         *uint64              (optional)
 	// 	}
 	//
 	// See wazevoapi.NewModuleContextOffsetData for the details of the offsets.
 	moduleContextOpaque []byte
 )
 
+// SetTrapHandler registers the handler of the traps with the given exit code, e.g. wazevoapi.ExitCodeMemoryOutOfBounds,
+// for the functions of this module. The nil handler unregisters the existing one, so that the standard error is
+// returned again. This is not goroutine-safe, and must be called before the functions are called.
+func (m *moduleEngine) SetTrapHandler(code wazevoapi.ExitCode, h TrapHandler) {
+	code &= wazevoapi.ExitCodeMask
+	if h == nil {
+		delete(m.trapHandlers, code)
+		return
+	}
+	if m.trapHandlers == nil {
+		m.trapHandlers = make(map[wazevoapi.ExitCode]TrapHandler)
+	}
+	m.trapHandlers[code] = h
+}
+
 func (m *moduleEngine) setupOpaque() {
 	inst := m.module
 	offsets := &m.parent.offsets
 	opaque := m.opaque
 
 	if lm := offsets.LocalMemoryBegin; lm >= 0 {
-		b := uint64(uintptr(unsafe.Pointer(&inst.MemoryInstance.Buffer[0])))
-		s := uint64(len(inst.MemoryInstance.Buffer))
-		binary.LittleEndian.PutUint64(opaque[lm:], b)
-		binary.LittleEndian.PutUint64(opaque[lm+8:], s)
+		m.putLocalMemory()
 	}
 
 	if im := offsets.ImportedMemoryBegin; im >= 0 {
@@ -56,9 +95,75 @@ func (m *moduleEngine) setupOpaque() {
 		binary.LittleEndian.PutUint64(opaque[im:], b)
 	}
 
+	if tb := offsets.TablesBegin; tb >= 0 {
+		for i, table := range inst.Tables {
+			b := uint64(uintptr(unsafe.Pointer(table)))
+			binary.LittleEndian.PutUint64(opaque[offsets.TableOffset(wasm.Index(i)):], b)
+		}
+	}
+
+	if gb := offsets.GlobalsBegin; gb >= 0 {
+		for i, global := range inst.Globals {
+			b := uint64(uintptr(unsafe.Pointer(global)))
+			binary.LittleEndian.PutUint64(opaque[offsets.GlobalOffset(wasm.Index(i)):], b)
+		}
+	}
+
+	if cb := offsets.CoverageBufferBegin; cb >= 0 && len(m.coverage) > 0 {
+		b := uint64(uintptr(unsafe.Pointer(&m.coverage[0])))
+		binary.LittleEndian.PutUint64(opaque[cb:], b)
+	}
+
+	if lc := offsets.LoopCounterBufferBegin; lc >= 0 && len(m.loopCounters) > 0 {
+		b := uint64(uintptr(unsafe.Pointer(&m.loopCounters[0])))
+		binary.LittleEndian.PutUint64(opaque[lc:], b)
+	}
+
 	// Note: imported functions are resolved in ResolveImportedFunction.
 }
 
+// putLocalMemory writes the base address and the length of the local memory buffer into the opaque.
+func (m *moduleEngine) putLocalMemory() {
+	mem := m.module.MemoryInstance
+	offset := m.parent.offsets.LocalMemoryBegin
+	// Note: the buffer is empty when the memory has zero pages, so we cannot take the address of its first element.
+	b := uint64((*reflect.SliceHeader)(unsafe.Pointer(&mem.Buffer)).Data)
+	s := uint64(len(mem.Buffer))
+	binary.LittleEndian.PutUint64(m.opaque[offset:], b)
+	binary.LittleEndian.PutUint64(m.opaque[offset+8:], s)
+}
+
+// Coverage returns the coverage counters of basic blocks and the map from each basic block to the index of its counter.
+// The counters are incremented every time the corresponding block is executed. Both are nil unless the coverage is enabled.
+func (m *moduleEngine) Coverage() (counters []uint64, blocks map[CoverageBlock]int) {
+	return m.coverage, m.parent.coverageBlocks
+}
+
+// LoopCounters returns the back-edge counters of loops indexed by the loop ID, which is assigned to each reachable loop in
+// the module in the order of appearance. The counters are incremented every time a branch to the loop header is taken,
+// i.e. not when entering the loop. This is nil unless the loop profiling is enabled.
+func (m *moduleEngine) LoopCounters() []uint64 {
+	return m.loopCounters
+}
+
+// NewFunctionByName returns the api.Function of the function exported under the given name, which is the same as
+// NewFunction with the index of the exported function. This returns an error if the name is not exported, or the
+// export is not a function.
+func (m *moduleEngine) NewFunctionByName(name string) (api.Function, error) {
+	for i := range m.module.Source.ExportSection {
+		exp := &m.module.Source.ExportSection[i]
+		if exp.Name != name {
+			continue
+		}
+		if exp.Type != wasm.ExternTypeFunc {
+			return nil, fmt.Errorf("export %q in module %q is a %s, not a %s", name, m.module.ModuleName,
+				wasm.ExternTypeName(exp.Type), wasm.ExternTypeName(wasm.ExternTypeFunc))
+		}
+		return m.NewFunction(exp.Index), nil
+	}
+	return nil, fmt.Errorf("%q is not exported in module %q", name, m.module.ModuleName)
+}
+
 // NewFunction implements wasm.ModuleEngine.
 func (m *moduleEngine) NewFunction(index wasm.Index) api.Function {
 	localIndex := index
@@ -75,33 +180,113 @@ func (m *moduleEngine) NewFunction(index wasm.Index) api.Function {
 		sizeOfParamResultSlice = ps
 	}
 	p := m.parent
-	offset := p.functionOffsets[localIndex]
 
 	ce := &callEngine{
 		indexInModule:          index,
-		executable:             &p.executable[offset.offset],
 		parent:                 m,
 		sizeOfParamResultSlice: sizeOfParamResultSlice,
+		numberOfResults:        len(typ.Results),
+	}
+	// When the module is compiled lazily, the executable is resolved on the first call. See callEngine.compileLazily.
+	if p.lazy == nil {
+		offset := p.functionOffsets[localIndex]
+		ce.executable = &p.executable[offset.offset]
+		ce.selfRecursive = offset.selfRecursive
 	}
 	ce.init()
+
+	if m.crossCheck != nil {
+		return &crossCheckFunction{
+			compiled:    ce,
+			interpreted: m.crossCheck.NewFunction(index),
+			index:       index,
+			paramNum:    len(typ.Params),
+			resultNum:   len(typ.Results),
+			mem:         m.module.MemoryInstance,
+		}
+	}
 	return ce
 }
 
+// FunctionPool hands out the api.Function of a function for concurrent callers. A callEngine has its own stack and
+// execution context which must not be shared by concurrent calls, so each caller takes one from the pool, and puts it
+// back when done so that it's reused instead of allocating a new one per call.
+//
+// Note: the memory and the other states of the module instance are still shared across the concurrent calls.
+type FunctionPool struct {
+	pool sync.Pool
+}
+
+// NewFunctionPool returns a FunctionPool of the function at the given index. See NewFunction.
+func (m *moduleEngine) NewFunctionPool(index wasm.Index) *FunctionPool {
+	return &FunctionPool{pool: sync.Pool{New: func() interface{} { return m.NewFunction(index) }}}
+}
+
+// Get returns the api.Function which is not used by any other goroutine until passed to Put.
+func (p *FunctionPool) Get() api.Function {
+	return p.pool.Get().(api.Function)
+}
+
+// Put returns the api.Function taken by Get to the pool. The function must not be used after this.
+func (p *FunctionPool) Put(f api.Function) {
+	p.pool.Put(f)
+}
+
+// Call is a convenience to call the function with the api.Function taken from the pool.
+// This is safe to be called concurrently.
+func (p *FunctionPool) Call(ctx context.Context, params ...uint64) ([]uint64, error) {
+	f := p.Get()
+	defer p.Put(f)
+	results, err := f.Call(ctx, params...)
+	if len(results) == 0 {
+		return results, err
+	}
+	// The results of callEngine.Call are overwritten by its next invocation, which is made by another caller once
+	// f is put back, so they must be copied out.
+	return append([]uint64(nil), results...), err
+}
+
 // ResolveImportedFunction implements wasm.ModuleEngine.
 func (m *moduleEngine) ResolveImportedFunction(index, indexInImportedModule wasm.Index, importedModuleEngine wasm.ModuleEngine) {
 	ptr, moduleCtx := m.parent.offsets.ImportedFunctionOffset(index)
 	importedME := importedModuleEngine.(*moduleEngine)
 
-	offset := importedME.parent.functionOffsets[indexInImportedModule]
+	// The imported function is compiled here if the imported module is compiled lazily, since the machine code
+	// calls it without going through callEngine.
+	unit, err := importedME.parent.unitOf(indexInImportedModule)
+	if err != nil {
+		panic(fmt.Errorf("compiling imported function[%d]: %w", indexInImportedModule, err))
+	}
+	offset := unit.functionOffsets[indexInImportedModule]
 	// When calling imported function from the machine code, we need to skip the Go preamble.
-	executable := &importedME.parent.executable[offset.offset+offset.goPreambleSize]
+	executable := &unit.executable[offset.offset+offset.goPreambleSize]
 	binary.LittleEndian.PutUint64(m.opaque[ptr:], uint64(uintptr(unsafe.Pointer(executable))))
 	binary.LittleEndian.PutUint64(m.opaque[moduleCtx:], uint64(uintptr(unsafe.Pointer(importedME.opaquePtr))))
+
+	if m.crossCheck != nil {
+		m.crossCheck.ResolveImportedFunction(index, indexInImportedModule, importedME.crossCheck)
+	}
 }
 
 // DoneInstantiation implements wasm.ModuleEngine.
 func (m *moduleEngine) DoneInstantiation() {
 	m.setupOpaque()
+	if m.crossCheck != nil {
+		inst, shared := m.module, m.crossCheckModule
+		shared.Globals, shared.MemoryInstance, shared.Tables = inst.Globals, inst.MemoryInstance, inst.Tables
+		shared.DataInstances, shared.ElementInstances, shared.Sys = inst.DataInstances, inst.ElementInstances, inst.Sys
+		m.crossCheck.DoneInstantiation()
+	}
+}
+
+// MemoryGrown implements wasm.ModuleEngine.
+//
+// The compiled code reloads the memory base and length from the opaque after every function call, so this makes
+// the memory grown by a Go function visible to the Wasm function calling it.
+func (m *moduleEngine) MemoryGrown() {
+	if m.parent.offsets.LocalMemoryBegin >= 0 {
+		m.putLocalMemory()
+	}
 }
 
 // LookupFunction implements wasm.ModuleEngine.
@@ -63,6 +63,38 @@ func TestModuleEngine_setupOpaque(t *testing.T) {
 	}
 }
 
+func TestModuleEngine_MemoryGrown(t *testing.T) {
+	offset := wazevoapi.ModuleContextOffsetData{
+		TotalSize:              16,
+		LocalMemoryBegin:       0,
+		ImportedMemoryBegin:    -1,
+		ImportedFunctionsBegin: -1,
+		TablesBegin:            -1,
+		GlobalsBegin:           -1,
+		CoverageBufferBegin:    -1,
+		LoopCounterBufferBegin: -1,
+	}
+	// The memory has zero pages at the beginning.
+	mem := wasm.NewMemoryInstance(&wasm.Memory{Min: 0, Cap: 1, Max: 2})
+	m := &moduleEngine{
+		parent: &compiledModule{offsets: offset},
+		module: &wasm.ModuleInstance{MemoryInstance: mem},
+		opaque: make([]byte, offset.TotalSize),
+	}
+	m.setupOpaque()
+	require.Equal(t, uint64(0), binary.LittleEndian.Uint64(m.opaque[8:]))
+
+	for _, delta := range []uint32{1, 1} { // Grows within the capacity, and then beyond it.
+		mem.Grow(delta)
+		m.MemoryGrown()
+
+		actualPtr := uintptr(binary.LittleEndian.Uint64(m.opaque[0:]))
+		require.Equal(t, uintptr(unsafe.Pointer(&mem.Buffer[0])), actualPtr)
+		actualLen := int(binary.LittleEndian.Uint64(m.opaque[8:]))
+		require.Equal(t, len(mem.Buffer), actualLen)
+	}
+}
+
 func TestModuleEngine_ResolveImportedFunction(t *testing.T) {
 	const begin = 5000
 	m := &moduleEngine{opaque: make([]byte, 10000), parent: &compiledModule{offsets: wazevoapi.ModuleContextOffsetData{
@@ -72,17 +104,17 @@ func TestModuleEngine_ResolveImportedFunction(t *testing.T) {
 	var op1, op2 byte = 0xaa, 0xbb
 	im1 := &moduleEngine{
 		opaquePtr: &op1,
-		parent: &compiledModule{
+		parent: &compiledModule{compiledUnit: compiledUnit{
 			executable:      make([]byte, 1000),
 			functionOffsets: []compiledFunctionOffset{{offset: 1, goPreambleSize: 4}, {offset: 5, goPreambleSize: 4}, {offset: 10, goPreambleSize: 4}},
-		},
+		}},
 	}
 	im2 := &moduleEngine{
 		opaquePtr: &op2,
-		parent: &compiledModule{
+		parent: &compiledModule{compiledUnit: compiledUnit{
 			executable:      make([]byte, 1000),
 			functionOffsets: []compiledFunctionOffset{{offset: 50, goPreambleSize: 4}},
-		},
+		}},
 	}
 
 	m.ResolveImportedFunction(0, 0, im1)
@@ -109,3 +141,66 @@ func TestModuleEngine_ResolveImportedFunction(t *testing.T) {
 		require.Equal(t, expOpaquePtr, actualOpaquePtr)
 	}
 }
+
+func TestModuleEngine_setupOpaque_coverage(t *testing.T) {
+	m := &moduleEngine{
+		parent:   &compiledModule{offsets: wazevoapi.ModuleContextOffsetData{LocalMemoryBegin: -1, ImportedMemoryBegin: -1, ImportedFunctionsBegin: -1, CoverageBufferBegin: 8}},
+		module:   &wasm.ModuleInstance{},
+		opaque:   make([]byte, 16),
+		coverage: make([]uint64, 10),
+	}
+	m.setupOpaque()
+
+	actualPtr := uintptr(binary.LittleEndian.Uint64(m.opaque[8:]))
+	require.Equal(t, uintptr(unsafe.Pointer(&m.coverage[0])), actualPtr)
+}
+
+func TestModuleEngine_setupOpaque_loopCounters(t *testing.T) {
+	m := &moduleEngine{
+		parent: &compiledModule{offsets: wazevoapi.ModuleContextOffsetData{
+			LocalMemoryBegin: -1, ImportedMemoryBegin: -1, ImportedFunctionsBegin: -1, CoverageBufferBegin: -1, LoopCounterBufferBegin: 8,
+		}},
+		module:       &wasm.ModuleInstance{},
+		opaque:       make([]byte, 16),
+		loopCounters: make([]uint64, 3),
+	}
+	m.setupOpaque()
+
+	actualPtr := uintptr(binary.LittleEndian.Uint64(m.opaque[8:]))
+	require.Equal(t, uintptr(unsafe.Pointer(&m.loopCounters[0])), actualPtr)
+}
+
+func TestModuleEngine_setupOpaque_tables(t *testing.T) {
+	tables := []*wasm.TableInstance{{}, {}}
+	m := &moduleEngine{
+		parent: &compiledModule{offsets: wazevoapi.ModuleContextOffsetData{
+			LocalMemoryBegin: -1, ImportedMemoryBegin: -1, ImportedFunctionsBegin: -1, CoverageBufferBegin: -1, LoopCounterBufferBegin: -1, TablesBegin: 8,
+		}},
+		module: &wasm.ModuleInstance{Tables: tables},
+		opaque: make([]byte, 24),
+	}
+	m.setupOpaque()
+
+	for i, table := range tables {
+		actual := *(**wasm.TableInstance)(unsafe.Pointer(&m.opaque[8+8*i]))
+		require.Equal(t, table, actual)
+	}
+}
+
+func TestModuleEngine_setupOpaque_globals(t *testing.T) {
+	globals := []*wasm.GlobalInstance{{Val: 1}, {Val: 2}, {Val: 3}}
+	m := &moduleEngine{
+		parent: &compiledModule{offsets: wazevoapi.ModuleContextOffsetData{
+			LocalMemoryBegin: -1, ImportedMemoryBegin: -1, ImportedFunctionsBegin: -1, TablesBegin: -1,
+			CoverageBufferBegin: -1, LoopCounterBufferBegin: -1, GlobalsBegin: 8,
+		}},
+		module: &wasm.ModuleInstance{Globals: globals},
+		opaque: make([]byte, 32),
+	}
+	m.setupOpaque()
+
+	for i, global := range globals {
+		actual := *(**wasm.GlobalInstance)(unsafe.Pointer(&m.opaque[8+8*i]))
+		require.Equal(t, global, actual)
+	}
+}
@@ -52,6 +52,8 @@ type BasicBlock interface {
 
 	// Valid is true if this block is still valid even after optimizations.
 	Valid() bool
+	// Sealed is true if this block has been sealed by Builder.Seal.
+	Sealed() bool
 	// BeginPredIterator returns the first predecessor of this block.
 	BeginPredIterator() BasicBlock
 	// NextPredIterator returns the next predecessor of this block.
@@ -178,6 +180,11 @@ func (bb *basicBlock) Valid() bool {
 	return !bb.invalid
 }
 
+// Sealed implements BasicBlock.Sealed.
+func (bb *basicBlock) Sealed() bool {
+	return bb.sealed
+}
+
 // InsertInstruction implements BasicBlock.InsertInstruction.
 func (bb *basicBlock) InsertInstruction(next *Instruction) {
 	current := bb.currentInstr
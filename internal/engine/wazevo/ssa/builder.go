@@ -16,8 +16,10 @@ type Builder interface {
 	// Signature returns the Signature of the currently-compiled function.
 	Signature() *Signature
 
-	// Blocks returns the number of BasicBlocks(s) existing in the currently-compiled function.
-	Blocks() int
+	// BlockIDMax returns the upper bound (exclusive) of BasicBlockID assigned to BasicBlock(s) in the currently-compiled
+	// function. Notably, this counts the blocks eliminated as unreachable by the passes, so it can be greater than the
+	// number of blocks iterated by BlockIteratorReversePostOrderBegin.
+	BlockIDMax() BasicBlockID
 
 	// AllocateBasicBlock creates a basic block in SSA function.
 	AllocateBasicBlock() BasicBlock
@@ -45,6 +47,9 @@ type Builder interface {
 	// InsertInstruction executes BasicBlock.InsertInstruction for the currently handled basic block.
 	InsertInstruction(raw *Instruction)
 
+	// InsertedInstructions returns the number of instructions inserted by InsertInstruction since Init.
+	InsertedInstructions() int
+
 	// allocateValue allocates an unused Value.
 	allocateValue(typ Type) Value
 
@@ -74,9 +79,17 @@ type Builder interface {
 	// RunPasses runs various passes on the constructed SSA function.
 	RunPasses()
 
+	// RunPassesWithoutOptimization is the same as RunPasses except that it skips the optional optimization passes.
+	// This is for debugging the miscompilations caused by the optimizations.
+	RunPassesWithoutOptimization()
+
 	// Format returns the debugging string of the SSA function.
 	Format() string
 
+	// Graph returns the control flow graph of the SSA function in a machine-readable form for the tooling,
+	// e.g. visualization. The blocks are in the layout order after LayoutBlocks.
+	Graph() *Graph
+
 	// BlockIteratorBegin initializes the state to iterate over all the valid BasicBlock(s) compiled.
 	// Combined with BlockIteratorNext, we can use this like:
 	//
@@ -110,6 +123,11 @@ type Builder interface {
 	// This is available after RunPasses is run.
 	BlockIteratorReversePostOrderNext() BasicBlock
 
+	// Verify checks the invariants of the SSA function, and returns the error describing all the violations if any:
+	// every value is defined before use, the args of each branch match the params of the target block,
+	// and all the blocks are sealed. This is meant to catch miscompilations during development.
+	Verify() error
+
 	// ReturnBlock returns the BasicBlock which is used to return from the function.
 	ReturnBlock() BasicBlock
 }
@@ -135,6 +153,9 @@ type builder struct {
 	signatures       map[SignatureID]*Signature
 	currentSignature *Signature
 
+	// insertedInstructions is the number of instructions inserted since Init. See InsertedInstructions.
+	insertedInstructions int
+
 	// reversePostOrderedBasicBlocks are the BasicBlock(s) ordered in the reverse post-order after passCalculateImmediateDominators.
 	reversePostOrderedBasicBlocks []*basicBlock
 	currentBB                     *basicBlock
@@ -189,6 +210,7 @@ func (b *builder) Init(s *Signature) {
 	b.currentSignature = s
 	b.returnBlk.reset()
 	b.instructionsPool.Reset()
+	b.insertedInstructions = 0
 	b.donePasses = false
 	for _, sig := range b.signatures {
 		sig.used = false
@@ -213,8 +235,13 @@ func (b *builder) Init(s *Signature) {
 	for v := ValueID(0); v < b.nextValueID; v++ {
 		delete(b.valueAnnotations, v)
 		delete(b.valueIDAliases, v)
-		b.valueRefCounts[v] = 0
-		b.valueIDToInstruction[v] = nil
+	}
+	// These are only sized by the passes, so might be shorter than nextValueID if the passes haven't run.
+	for i := range b.valueRefCounts {
+		b.valueRefCounts[i] = 0
+	}
+	for i := range b.valueIDToInstruction {
+		b.valueIDToInstruction[i] = nil
 	}
 	b.nextValueID = 0
 	b.reversePostOrderedBasicBlocks = b.reversePostOrderedBasicBlocks[:0]
@@ -230,6 +257,11 @@ func (b *builder) AnnotateValue(value Value, a string) {
 	b.valueAnnotations[value.ID()] = a
 }
 
+// InsertedInstructions implements Builder.InsertedInstructions.
+func (b *builder) InsertedInstructions() int {
+	return b.insertedInstructions
+}
+
 // AllocateInstruction implements Builder.AllocateInstruction.
 func (b *builder) AllocateInstruction() *Instruction {
 	instr := b.instructionsPool.Allocate()
@@ -280,6 +312,7 @@ func (b *builder) allocateBasicBlock() *basicBlock {
 // InsertInstruction implements Builder.InsertInstruction.
 func (b *builder) InsertInstruction(instr *Instruction) {
 	b.currentBB.InsertInstruction(instr)
+	b.insertedInstructions++
 
 	resultTypesFn := instructionReturnTypes[instr.opcode]
 	if resultTypesFn == nil {
@@ -394,7 +427,13 @@ func (b *builder) findValue(typ Type, variable Variable, blk *basicBlock, must b
 	if pred := blk.singlePred; pred != nil {
 		// If this block is sealed and have only one predecessor,
 		// we can use the value in that block without ambiguity on definition.
-		return b.findValue(typ, variable, pred, must)
+		value := b.findValue(typ, variable, pred, must)
+		if value.Valid() {
+			// Cache the definition in this block so that the subsequent lookups don't walk the chain of the single
+			// predecessors again, e.g. the fall-through blocks of consecutive br_if instructions.
+			blk.lastDefinitions[variable] = value
+		}
+		return value
 	} else if len(blk.preds) == 0 {
 		// This case the value is not defined.
 		if must {
@@ -450,10 +489,9 @@ func (b *builder) Seal(raw BasicBlock) {
 		blk.addParamOn(typ, phiValue)
 		for i := range blk.preds {
 			pred := &blk.preds[i]
-			predValue := b.findValue(typ, variable, pred.blk, false)
-			if !predValue.Valid() {
-				panic("BUG: value is not defined anywhere in the predecessors in the CFG")
-			}
+			// The predecessor might not be sealed yet, e.g. the header of the outer loop when this is the header of
+			// the inner one, in which case the placeholder is defined there as well and resolved when it's sealed.
+			predValue := b.findValue(typ, variable, pred.blk, true)
 			pred.branch.addArgumentBranchInst(predValue)
 		}
 	}
@@ -590,6 +628,10 @@ func (b *builder) resolveArgumentAlias(instr *Instruction) {
 		instr.v2 = b.resolveAlias(instr.v2)
 	}
 
+	if instr.v3.Valid() {
+		instr.v3 = b.resolveAlias(instr.v3)
+	}
+
 	for i, v := range instr.vs {
 		instr.vs[i] = b.resolveAlias(v)
 	}
@@ -627,9 +669,9 @@ func (b *builder) isDominatedBy(n *basicBlock, d *basicBlock) bool {
 	return n == d
 }
 
-// Blocks implements Builder.Blocks.
-func (b *builder) Blocks() int {
-	return len(b.reversePostOrderedBasicBlocks)
+// BlockIDMax implements Builder.BlockIDMax.
+func (b *builder) BlockIDMax() BasicBlockID {
+	return BasicBlockID(b.basicBlocksPool.Allocated())
 }
 
 // LayoutBlocks implements Builder.LayoutBlocks. This re-organizes builder.reversePostOrderedBasicBlocks.
@@ -733,7 +775,9 @@ func (b *builder) LayoutBlocks() {
 		}
 
 		for _, trampoline := range uninsertedTrampolines {
-			if trampoline.success[0].reversePostOrder < trampoline.reversePostOrder {
+			// "<=" rather than "<" since the target might be the block itself, or the return block which is never
+			// inserted and whose order is zero like the entry block's.
+			if trampoline.success[0].reversePostOrder <= trampoline.reversePostOrder {
 				// This means the critical edge was backward, so we insert after the current block immediately.
 				b.reversePostOrderedBasicBlocks = append(b.reversePostOrderedBasicBlocks, trampoline)
 				inserted[trampoline] = 0 // mark as inserted, the value is not used.
@@ -827,6 +871,8 @@ invert:
 	condBranch.InvertBrx()
 	condBranch.blk = fallthroughTarget
 	fallthroughBranch.blk = condTarget
+	// The arguments are for the targets, so they are swapped as well.
+	condBranch.vs, fallthroughBranch.vs = fallthroughBranch.vs, condBranch.vs
 	return true
 }
 
@@ -860,6 +906,9 @@ func (b *builder) splitCriticalEdge(pred, succ *basicBlock, predInfo *basicBlock
 	newBranch := b.AllocateInstruction()
 	newBranch.opcode = originalBranch.opcode
 	newBranch.blk = trampoline
+	// The new branch takes the place of the original one, so it belongs to the same InstructionGroupID, which allows
+	// the backend to lower the condition together with it.
+	newBranch.gid = originalBranch.gid
 	switch originalBranch.opcode {
 	case OpcodeJump:
 	case OpcodeBrz, OpcodeBrnz:
@@ -163,6 +163,33 @@ func Test_maybeInvertBranch(t *testing.T) {
 			},
 			exp: true,
 		},
+		{
+			name: "conditional target is the next block with arguments",
+			setup: func(b *builder) (now, next *basicBlock, verify func(t *testing.T)) {
+				now, next = b.allocateBasicBlock(), b.allocateBasicBlock()
+				nowTarget := b.allocateBasicBlock()
+				next.AddParam(b, TypeI64)
+				b.SetCurrentBlock(now)
+				arg := b.AllocateInstruction()
+				arg.AsIconst64(1)
+				b.InsertInstruction(arg)
+				insertBrz(b, now, next)
+				conditionalBr := now.currentInstr
+				conditionalBr.vs = []Value{arg.Return()}
+				insertJump(b, now, nowTarget)
+				tail := now.currentInstr
+
+				verify = func(t *testing.T) {
+					require.Equal(t, next, tail.blk)
+					require.Equal(t, nowTarget, conditionalBr.blk)
+					// The arguments follow the target.
+					require.Equal(t, []Value{arg.Return()}, tail.vs)
+					require.Equal(t, 0, len(conditionalBr.vs))
+				}
+				return
+			},
+			exp: true,
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			b := NewBuilder().(*builder)
@@ -332,6 +359,22 @@ func TestBuilder_LayoutBlocks(t *testing.T) {
 			},
 			exp: []BasicBlockID{0, 2, 1, 3},
 		},
+		{
+			name: "entry branching to return",
+			// 0 -> 1 -> return
+			// |         ^
+			// v         |
+			// +---------+
+			setup: func(b *builder) {
+				b0, b1 := b.allocateBasicBlock(), b.allocateBasicBlock()
+				ret := b.returnBlk
+				insertBrz(b, b0, ret)
+				insertJump(b, b0, b1)
+				insertJump(b, b1, ret)
+			},
+			// The trampoline 2 to return is placed right after 0 though both have the same order as the return block.
+			exp: []BasicBlockID{0, 2, 1},
+		},
 		{
 			name: "loop towards loop header in fallthrough",
 			//    0
@@ -491,3 +534,91 @@ func TestBuilder_LayoutBlocks(t *testing.T) {
 		})
 	}
 }
+
+func TestBuilder_FindValue_singlePredChain(t *testing.T) {
+	b := NewBuilder().(*builder)
+	b.Init(&Signature{})
+	variable := b.DeclareVariable(TypeI32)
+
+	entry := b.allocateBasicBlock()
+	b.SetCurrentBlock(entry)
+	iconst := b.AllocateInstruction()
+	iconst.AsIconst32(1)
+	b.InsertInstruction(iconst)
+	b.DefineVariableInCurrentBB(variable, iconst.Return())
+
+	// Build the chain of blocks each of which has the previous one as the single predecessor,
+	// like the fall-through blocks of consecutive br_if instructions.
+	chain := []*basicBlock{entry}
+	for i := 0; i < 5; i++ {
+		prev, next := chain[len(chain)-1], b.allocateBasicBlock()
+		b.SetCurrentBlock(prev)
+		jump := b.AllocateInstruction()
+		jump.AsJump(nil, next)
+		b.InsertInstruction(jump)
+		b.Seal(next)
+		chain = append(chain, next)
+	}
+	b.Seal(entry)
+
+	b.SetCurrentBlock(chain[len(chain)-1])
+	require.Equal(t, iconst.Return(), b.MustFindValue(variable))
+	// The definition is cached in every block on the way.
+	for _, blk := range chain {
+		require.Equal(t, iconst.Return(), blk.lastDefinitions[variable])
+	}
+}
+
+func TestBuilder_Seal_unsealedPredecessor(t *testing.T) {
+	b := NewBuilder().(*builder)
+	b.Init(&Signature{})
+	variable := b.DeclareVariable(TypeI32)
+
+	entry, outer, inner := b.allocateBasicBlock(), b.allocateBasicBlock(), b.allocateBasicBlock()
+	b.SetCurrentBlock(entry)
+	iconst := b.AllocateInstruction()
+	iconst.AsIconst32(1)
+	b.InsertInstruction(iconst)
+	b.DefineVariableInCurrentBB(variable, iconst.Return())
+	jump := b.AllocateInstruction()
+	jump.AsJump(nil, outer)
+	b.InsertInstruction(jump)
+	b.Seal(entry)
+
+	// The outer and inner blocks are the headers of the nested loops, each of which has the back-edge.
+	b.SetCurrentBlock(outer)
+	jump = b.AllocateInstruction()
+	jump.AsJump(nil, inner)
+	b.InsertInstruction(jump)
+
+	b.SetCurrentBlock(inner)
+	v := b.MustFindValue(variable)
+	brnz := b.AllocateInstruction()
+	brnz.AsBrnz(v, nil, inner)
+	b.InsertInstruction(brnz)
+	jump = b.AllocateInstruction()
+	jump.AsJump(nil, outer)
+	b.InsertInstruction(jump)
+
+	// The inner loop is sealed first while the outer one is still unsealed.
+	b.Seal(inner)
+	require.Equal(t, 1, inner.Params())
+	require.Equal(t, v, inner.Param(0))
+	b.Seal(outer)
+	require.Equal(t, 1, outer.Params())
+	// The back-edge of the outer loop passes the param of the inner loop header.
+	require.Equal(t, []Value{v}, jump.vs)
+}
+
+func TestBuilder_Init_withoutPasses(t *testing.T) {
+	b := NewBuilder().(*builder)
+	b.Init(&Signature{})
+	b.SetCurrentBlock(b.allocateBasicBlock())
+	iconst := b.AllocateInstruction()
+	iconst.AsIconst32(1)
+	b.InsertInstruction(iconst)
+
+	// The function can be discarded without running the passes, e.g. when only the lowering is of interest.
+	b.Init(&Signature{})
+	require.Equal(t, ValueID(0), b.nextValueID)
+}
@@ -0,0 +1,116 @@
+package ssa
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// Graph is the machine-readable representation of the control flow graph of an SSA function, which can be
+	// encoded as JSON with encoding/json, or as Graphviz DOT with Graph.DOT. See Builder.Graph.
+	Graph struct {
+		// Blocks are the valid blocks of the function in the same order as Builder.Format.
+		Blocks []GraphBlock `json:"blocks"`
+		// Edges are the edges between Blocks, each of which corresponds to a branch instruction.
+		Edges []GraphEdge `json:"edges"`
+	}
+
+	// GraphBlock is a basic block in Graph.
+	GraphBlock struct {
+		ID   BasicBlockID `json:"id"`
+		Name string       `json:"name"`
+		// Params are the parameters of this block formatted with their types, e.g. "v1:i32".
+		Params []string `json:"params"`
+		// Instructions are the instructions of this block formatted in the same way as Builder.Format.
+		Instructions []string `json:"instructions"`
+		// LoopHeader is true if this block is the header of a loop, which is only known after Builder.RunPasses.
+		LoopHeader bool `json:"loop_header"`
+	}
+
+	// GraphEdge is an edge from the block ending with a branch instruction to its target in Graph.
+	GraphEdge struct {
+		From BasicBlockID `json:"from"`
+		To   BasicBlockID `json:"to"`
+		// Branch is the opcode of the branch instruction, e.g. "Jump" or "Brnz".
+		Branch string `json:"branch"`
+		// Args are the arguments passed to the params of the target block.
+		Args []string `json:"args"`
+	}
+)
+
+// Graph implements Builder.Graph.
+func (b *builder) Graph() *Graph {
+	g := &Graph{}
+	var iterBegin, iterNext func() *basicBlock
+	if b.doneBlockLayout {
+		iterBegin, iterNext = b.blockIteratorReversePostOrderBegin, b.blockIteratorReversePostOrderNext
+	} else {
+		iterBegin, iterNext = b.blockIteratorBegin, b.blockIteratorNext
+	}
+
+	blocks := make([]*basicBlock, 0, b.basicBlocksPool.Allocated()+1)
+	for bb := iterBegin(); bb != nil; bb = iterNext() {
+		blocks = append(blocks, bb)
+	}
+	if len(b.returnBlk.preds) > 0 {
+		// The return block is not allocated from the pool, so it's only included when it's the target of branches.
+		blocks = append(blocks, b.returnBlk)
+	}
+
+	for _, bb := range blocks {
+		gb := GraphBlock{
+			ID: bb.id, Name: bb.Name(), LoopHeader: bb.loopHeader,
+			Params: make([]string, len(bb.params)), Instructions: []string{},
+		}
+		for i, p := range bb.params {
+			gb.Params[i] = p.value.formatWithType(b)
+		}
+		for cur := bb.rootInstr; cur != nil; cur = cur.next {
+			gb.Instructions = append(gb.Instructions, cur.Format(b))
+		}
+		g.Blocks = append(g.Blocks, gb)
+
+		for _, pred := range bb.preds {
+			if pred.blk.invalid {
+				continue
+			}
+			e := GraphEdge{From: pred.blk.id, To: bb.id, Branch: pred.branch.opcode.String(), Args: make([]string, len(pred.branch.vs))}
+			for i, v := range pred.branch.vs {
+				e.Args[i] = v.Format(b)
+			}
+			g.Edges = append(g.Edges, e)
+		}
+	}
+	return g
+}
+
+// DOT returns the Graphviz DOT representation of this Graph, where each node is labeled with the instructions
+// of the block, and each edge is labeled with the branch opcode and the arguments.
+func (g *Graph) DOT() string {
+	names := make(map[BasicBlockID]string, len(g.Blocks))
+	var str strings.Builder
+	str.WriteString("digraph {\n")
+	str.WriteString("\tnode [shape=box, fontname=monospace];\n")
+	for _, blk := range g.Blocks {
+		names[blk.ID] = blk.Name
+		label := fmt.Sprintf("%s: (%s)\\l", blk.Name, strings.Join(blk.Params, ", "))
+		for _, instr := range blk.Instructions {
+			label += dotEscape(instr) + "\\l"
+		}
+		fmt.Fprintf(&str, "\t%s [label=\"%s\"];\n", blk.Name, label)
+	}
+	for _, e := range g.Edges {
+		label := e.Branch
+		if len(e.Args) > 0 {
+			label += fmt.Sprintf("(%s)", strings.Join(e.Args, ", "))
+		}
+		fmt.Fprintf(&str, "\t%s -> %s [label=\"%s\"];\n", names[e.From], names[e.To], dotEscape(label))
+	}
+	str.WriteString("}\n")
+	return str.String()
+}
+
+// dotEscape escapes the characters which have special meanings in the quoted string of DOT.
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
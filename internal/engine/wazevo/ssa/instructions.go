@@ -20,6 +20,7 @@ type Instruction struct {
 	u64        uint64
 	v          Value
 	v2         Value
+	v3         Value
 	vs         []Value
 	typ        Type
 	blk        BasicBlock
@@ -46,6 +47,7 @@ func (i *Instruction) reset() {
 	*i = Instruction{}
 	i.v = ValueInvalid
 	i.v2 = ValueInvalid
+	i.v3 = ValueInvalid
 	i.rValue = ValueInvalid
 	i.typ = typeInvalid
 }
@@ -77,8 +79,8 @@ func (i *Instruction) Return() (first Value) {
 }
 
 // Args returns the arguments to this instruction.
-func (i *Instruction) Args() (v1, v2 Value, vs []Value) {
-	return i.v, i.v2, i.vs
+func (i *Instruction) Args() (v1, v2, v3 Value, vs []Value) {
+	return i.v, i.v2, i.v3, i.vs
 }
 
 // Arg returns the first argument to this instruction.
@@ -135,6 +137,11 @@ const (
 	// OpcodeExitIfNotZeroWithCode exits the execution immediately if the value `c` is not zero.
 	OpcodeExitIfNotZeroWithCode
 
+	// OpcodeExitWithDynamicCode exits the execution immediately with the exit code given by the i32 value `code`
+	// rather than the constant, and passes the 64-bit value `v` to the exit handler: `ExitWithDynamicCode ctx, code, v`.
+	// This is for the trap block shared by the checks exiting with the different codes.
+	OpcodeExitWithDynamicCode
+
 	// OpcodeReturn returns from the function: `return rvalues`.
 	OpcodeReturn
 
@@ -215,7 +222,7 @@ const (
 	// OpcodeSload8 loads the 8-bit value from the [base + offset] address, sign-extended to 64 bits: `v = Sload8 base, offset`.
 	OpcodeSload8
 
-	// OpcodeIstore8 stores the 8-bit value to the [base + offset] address, sign-extended to 64 bits: `Istore8 v, base, offset`.
+	// OpcodeIstore8 stores the lower 8 bits of the integer value to the [base + offset] address: `Istore8 v, base, offset`.
 	OpcodeIstore8
 
 	// OpcodeUload16 loads the 16-bit value from the [base + offset] address, zero-extended to 64 bits: `v = Uload16 base, offset`.
@@ -224,7 +231,7 @@ const (
 	// OpcodeSload16 loads the 16-bit value from the [base + offset] address, sign-extended to 64 bits: `v = Sload16 base, offset`.
 	OpcodeSload16
 
-	// OpcodeIstore16 stores the 16-bit value to the [base + offset] address, zero-extended to 64 bits: `Istore16 v, base, offset`.
+	// OpcodeIstore16 stores the lower 16 bits of the integer value to the [base + offset] address: `Istore16 v, base, offset`.
 	OpcodeIstore16
 
 	// OpcodeUload32 loads the 32-bit value from the [base + offset] address, zero-extended to 64 bits: `v = Uload32 base, offset`.
@@ -233,7 +240,7 @@ const (
 	// OpcodeSload32 loads the 32-bit value from the [base + offset] address, sign-extended to 64 bits: `v = Sload32 base, offset`.
 	OpcodeSload32
 
-	// OpcodeIstore32 stores the 32-bit value to the [base + offset] address, zero-extended to 64 bits: `Istore16 v, base, offset`.
+	// OpcodeIstore32 stores the lower 32 bits of the integer value to the [base + offset] address: `Istore32 v, base, offset`.
 	OpcodeIstore32
 
 	// OpcodeUload8x8 ...
@@ -315,8 +322,8 @@ const (
 	// `nop`.
 	OpcodeNop
 
-	// OpcodeSelect ...
-	// `v = select c, x, y`.
+	// OpcodeSelect chooses between two values based on a condition `c`: `v = select c, x, y`.
+	// The result is x if c is non-zero, otherwise y. Float values are chosen bitwise, so NaN payloads are preserved.
 	OpcodeSelect
 
 	// OpcodeSelectSpectreGuard ...
@@ -723,12 +730,10 @@ const (
 	// OpcodeSExtend sign-extends the given integer: `v = SExtend x, from->to`.
 	OpcodeSExtend
 
-	// OpcodeFpromote ...
-	// `v = fpromote x`.
+	// OpcodeFpromote promotes the given 32-bit floating-point value to 64-bit: `v = Fpromote x`.
 	OpcodeFpromote
 
-	// OpcodeFdemote ...
-	// `v = fdemote x`.
+	// OpcodeFdemote demotes the given 64-bit floating-point value to 32-bit: `v = Fdemote x`.
 	OpcodeFdemote
 
 	// OpcodeFvdemote ...
@@ -739,28 +744,32 @@ const (
 	// `x = fvpromote_low a`.
 	OpcodeFvpromoteLow
 
-	// OpcodeFcvtToUint ...
-	// `v = fcvt_to_uint x`.
+	// OpcodeFcvtToUint converts the floating-point value to the unsigned integer by truncating towards zero:
+	// `v = FcvtToUint x`. NaN and the values out of the range of the result type must be excluded beforehand,
+	// e.g. by OpcodeExitIfNotZeroWithCode.
 	OpcodeFcvtToUint
 
-	// OpcodeFcvtToSint ...
-	// `v = fcvt_to_sint x`.
+	// OpcodeFcvtToSint converts the floating-point value to the signed integer by truncating towards zero:
+	// `v = FcvtToSint x`. NaN and the values out of the range of the result type must be excluded beforehand,
+	// e.g. by OpcodeExitIfNotZeroWithCode.
 	OpcodeFcvtToSint
 
-	// OpcodeFcvtToUintSat ...
-	// `v = fcvt_to_uint_sat x`.
+	// OpcodeFcvtToUintSat converts the floating-point value to the unsigned integer by truncating towards zero, which
+	// saturates the values out of the range of the result type to the minimum or maximum value, and NaN to zero:
+	// `v = FcvtToUintSat x`.
 	OpcodeFcvtToUintSat
 
-	// OpcodeFcvtToSintSat ...
-	// `v = fcvt_to_sint_sat x`.
+	// OpcodeFcvtToSintSat converts the floating-point value to the signed integer by truncating towards zero, which
+	// saturates the values out of the range of the result type to the minimum or maximum value, and NaN to zero:
+	// `v = FcvtToSintSat x`.
 	OpcodeFcvtToSintSat
 
-	// OpcodeFcvtFromUint ...
-	// `v = fcvt_from_uint x`.
+	// OpcodeFcvtFromUint converts the unsigned integer to the floating-point value, which rounds to the nearest with
+	// ties to even if it is not exact: `v = FcvtFromUint x`.
 	OpcodeFcvtFromUint
 
-	// OpcodeFcvtFromSint ...
-	// `v = fcvt_from_sint x`.
+	// OpcodeFcvtFromSint converts the signed integer to the floating-point value, which rounds to the nearest with
+	// ties to even if it is not exact: `v = FcvtFromSint x`.
 	OpcodeFcvtFromSint
 
 	// OpcodeFcvtLowFromSint ...
@@ -832,6 +841,13 @@ var instructionSideEffects = [opcodeEnd]sideEffect{
 	OpcodeCallIndirect:          sideEffectTrue,
 	OpcodeIadd:                  sideEffectFalse,
 	OpcodeImul:                  sideEffectFalse,
+	OpcodeSdiv:                  sideEffectFalse,
+	OpcodeUdiv:                  sideEffectFalse,
+	OpcodeSrem:                  sideEffectFalse,
+	OpcodeUrem:                  sideEffectFalse,
+	OpcodeBand:                  sideEffectFalse,
+	OpcodeBor:                   sideEffectFalse,
+	OpcodeBxor:                  sideEffectFalse,
 	OpcodeIsub:                  sideEffectFalse,
 	OpcodeIcmp:                  sideEffectFalse,
 	OpcodeFcmp:                  sideEffectFalse,
@@ -851,9 +867,19 @@ var instructionSideEffects = [opcodeEnd]sideEffect{
 	OpcodeIshl:                  sideEffectFalse,
 	OpcodeSshr:                  sideEffectFalse,
 	OpcodeUshr:                  sideEffectFalse,
+	OpcodeRotl:                  sideEffectFalse,
+	OpcodeRotr:                  sideEffectFalse,
+	OpcodeClz:                   sideEffectFalse,
+	OpcodeCtz:                   sideEffectFalse,
+	OpcodePopcnt:                sideEffectFalse,
+	OpcodeIreduce:               sideEffectFalse,
 	OpcodeStore:                 sideEffectTrue,
+	OpcodeIstore8:               sideEffectTrue,
+	OpcodeIstore16:              sideEffectTrue,
+	OpcodeIstore32:              sideEffectTrue,
 	OpcodeExitWithCode:          sideEffectTrue,
 	OpcodeExitIfNotZeroWithCode: sideEffectTrue,
+	OpcodeExitWithDynamicCode:   sideEffectTrue,
 	OpcodeReturn:                sideEffectTrue,
 	OpcodeBrz:                   sideEffectTrue,
 	OpcodeBrnz:                  sideEffectTrue,
@@ -861,6 +887,23 @@ var instructionSideEffects = [opcodeEnd]sideEffect{
 	OpcodeFmul:                  sideEffectFalse,
 	OpcodeFmax:                  sideEffectFalse,
 	OpcodeFmin:                  sideEffectFalse,
+	OpcodeFneg:                  sideEffectFalse,
+	OpcodeFabs:                  sideEffectFalse,
+	OpcodeSqrt:                  sideEffectFalse,
+	OpcodeCeil:                  sideEffectFalse,
+	OpcodeFloor:                 sideEffectFalse,
+	OpcodeTrunc:                 sideEffectFalse,
+	OpcodeNearest:               sideEffectFalse,
+	OpcodeFcopysign:             sideEffectFalse,
+	OpcodeFpromote:              sideEffectFalse,
+	OpcodeFdemote:               sideEffectFalse,
+	OpcodeFcvtToSint:            sideEffectFalse,
+	OpcodeFcvtToUint:            sideEffectFalse,
+	OpcodeFcvtToSintSat:         sideEffectFalse,
+	OpcodeFcvtToUintSat:         sideEffectFalse,
+	OpcodeFcvtFromSint:          sideEffectFalse,
+	OpcodeFcvtFromUint:          sideEffectFalse,
+	OpcodeSelect:                sideEffectFalse,
 }
 
 // HasSideEffects returns true if this instruction has side effects.
@@ -877,12 +920,18 @@ var instructionReturnTypes = [opcodeEnd]returnTypesFn{
 	OpcodeIshl:    returnTypesFnSingle,
 	OpcodeSshr:    returnTypesFnSingle,
 	OpcodeUshr:    returnTypesFnSingle,
+	OpcodeRotl:    returnTypesFnSingle,
+	OpcodeRotr:    returnTypesFnSingle,
+	OpcodeClz:     returnTypesFnSingle,
+	OpcodeCtz:     returnTypesFnSingle,
+	OpcodePopcnt:  returnTypesFnSingle,
+	OpcodeIreduce: returnTypesFnSingle,
 	OpcodeJump:    returnTypesFnNoReturns,
 	OpcodeIconst:  returnTypesFnSingle,
 	OpcodeSExtend: returnTypesFnSingle,
 	OpcodeUExtend: returnTypesFnSingle,
 	OpcodeCallIndirect: func(b *builder, instr *Instruction) (t1 Type, ts []Type) {
-		sigID := SignatureID(instr.v)
+		_, sigID, _ := instr.CallIndirectData()
 		sig, ok := b.signatures[sigID]
 		if !ok {
 			panic("BUG")
@@ -898,7 +947,7 @@ var instructionReturnTypes = [opcodeEnd]returnTypesFn{
 		return
 	},
 	OpcodeCall: func(b *builder, instr *Instruction) (t1 Type, ts []Type) {
-		sigID := SignatureID(instr.v)
+		_, sigID, _ := instr.CallData()
 		sig, ok := b.signatures[sigID]
 		if !ok {
 			panic("BUG")
@@ -917,6 +966,13 @@ var instructionReturnTypes = [opcodeEnd]returnTypesFn{
 	OpcodeIadd:                  returnTypesFnSingle,
 	OpcodeIsub:                  returnTypesFnSingle,
 	OpcodeImul:                  returnTypesFnSingle,
+	OpcodeSdiv:                  returnTypesFnSingle,
+	OpcodeUdiv:                  returnTypesFnSingle,
+	OpcodeSrem:                  returnTypesFnSingle,
+	OpcodeUrem:                  returnTypesFnSingle,
+	OpcodeBand:                  returnTypesFnSingle,
+	OpcodeBor:                   returnTypesFnSingle,
+	OpcodeBxor:                  returnTypesFnSingle,
 	OpcodeIcmp:                  returnTypesFnI32,
 	OpcodeFcmp:                  returnTypesFnI32,
 	OpcodeFadd:                  returnTypesFnSingle,
@@ -925,11 +981,31 @@ var instructionReturnTypes = [opcodeEnd]returnTypesFn{
 	OpcodeFmul:                  returnTypesFnSingle,
 	OpcodeFmax:                  returnTypesFnSingle,
 	OpcodeFmin:                  returnTypesFnSingle,
+	OpcodeFneg:                  returnTypesFnSingle,
+	OpcodeFabs:                  returnTypesFnSingle,
+	OpcodeSqrt:                  returnTypesFnSingle,
+	OpcodeCeil:                  returnTypesFnSingle,
+	OpcodeFloor:                 returnTypesFnSingle,
+	OpcodeTrunc:                 returnTypesFnSingle,
+	OpcodeNearest:               returnTypesFnSingle,
+	OpcodeFcopysign:             returnTypesFnSingle,
+	OpcodeFpromote:              returnTypesFnF64,
+	OpcodeFdemote:               returnTypesFnF32,
+	OpcodeFcvtToSint:            returnTypesFnSingle,
+	OpcodeFcvtToUint:            returnTypesFnSingle,
+	OpcodeFcvtToSintSat:         returnTypesFnSingle,
+	OpcodeFcvtToUintSat:         returnTypesFnSingle,
+	OpcodeFcvtFromSint:          returnTypesFnSingle,
+	OpcodeFcvtFromUint:          returnTypesFnSingle,
 	OpcodeF32const:              returnTypesFnF32,
 	OpcodeF64const:              returnTypesFnF64,
 	OpcodeStore:                 returnTypesFnNoReturns,
+	OpcodeIstore8:               returnTypesFnNoReturns,
+	OpcodeIstore16:              returnTypesFnNoReturns,
+	OpcodeIstore32:              returnTypesFnNoReturns,
 	OpcodeExitWithCode:          returnTypesFnNoReturns,
 	OpcodeExitIfNotZeroWithCode: returnTypesFnNoReturns,
+	OpcodeExitWithDynamicCode:   returnTypesFnNoReturns,
 	OpcodeReturn:                returnTypesFnNoReturns,
 	OpcodeBrz:                   returnTypesFnNoReturns,
 	OpcodeBrnz:                  returnTypesFnNoReturns,
@@ -939,6 +1015,7 @@ var instructionReturnTypes = [opcodeEnd]returnTypesFn{
 	OpcodeSload8:                returnTypesFnSingle,
 	OpcodeSload16:               returnTypesFnSingle,
 	OpcodeSload32:               returnTypesFnSingle,
+	OpcodeSelect:                returnTypesFnSingle,
 }
 
 // AsLoad initializes this instruction as a store instruction with OpcodeLoad.
@@ -966,12 +1043,26 @@ func (i *Instruction) LoadData() (ptr Value, offset uint32, typ Type) {
 	return i.v, uint32(i.u64), i.typ
 }
 
-// AsStore initializes this instruction as a store instruction with OpcodeStore.
-func (i *Instruction) AsStore(value, ptr Value, offset uint32) {
-	i.opcode = OpcodeStore
+// AsStore initializes this instruction as a store instruction with the given store opcode, which is one of
+// OpcodeStore, OpcodeIstore8, OpcodeIstore16 and OpcodeIstore32.
+func (i *Instruction) AsStore(storeOp Opcode, value, ptr Value, offset uint32) {
+	var sizeInBits uint64
+	switch storeOp {
+	case OpcodeStore:
+		sizeInBits = uint64(value.Type().Bits())
+	case OpcodeIstore8:
+		sizeInBits = 8
+	case OpcodeIstore16:
+		sizeInBits = 16
+	case OpcodeIstore32:
+		sizeInBits = 32
+	default:
+		panic("BUG: invalid store opcode: " + storeOp.String())
+	}
+	i.opcode = storeOp
 	i.v = value
 	i.v2 = ptr
-	i.u64 = uint64(offset) | uint64(value.Type().Bits())<<32
+	i.u64 = uint64(offset) | sizeInBits<<32
 }
 
 // StoreData returns the operands for a store instruction.
@@ -1014,6 +1105,67 @@ func (i *Instruction) AsImul(x, y Value) {
 	i.typ = x.Type()
 }
 
+// AsSdiv initializes this instruction as a signed integer division instruction with OpcodeSdiv.
+// The divisor must be checked against zero, and the overflow must be checked before this instruction.
+func (i *Instruction) AsSdiv(x, y Value) {
+	i.opcode = OpcodeSdiv
+	i.v = x
+	i.v2 = y
+	i.typ = x.Type()
+}
+
+// AsUdiv initializes this instruction as an unsigned integer division instruction with OpcodeUdiv.
+// The divisor must be checked against zero before this instruction.
+func (i *Instruction) AsUdiv(x, y Value) {
+	i.opcode = OpcodeUdiv
+	i.v = x
+	i.v2 = y
+	i.typ = x.Type()
+}
+
+// AsSrem initializes this instruction as a signed integer remainder instruction with OpcodeSrem.
+// The divisor must be checked against zero before this instruction. Unlike OpcodeSdiv, this never overflows:
+// the remainder of the minimum value divided by -1 is zero.
+func (i *Instruction) AsSrem(x, y Value) {
+	i.opcode = OpcodeSrem
+	i.v = x
+	i.v2 = y
+	i.typ = x.Type()
+}
+
+// AsUrem initializes this instruction as an unsigned integer remainder instruction with OpcodeUrem.
+// The divisor must be checked against zero before this instruction.
+func (i *Instruction) AsUrem(x, y Value) {
+	i.opcode = OpcodeUrem
+	i.v = x
+	i.v2 = y
+	i.typ = x.Type()
+}
+
+// AsBand initializes this instruction as an integer bitwise and instruction with OpcodeBand.
+func (i *Instruction) AsBand(x, y Value) {
+	i.opcode = OpcodeBand
+	i.v = x
+	i.v2 = y
+	i.typ = x.Type()
+}
+
+// AsBor initializes this instruction as an integer bitwise or instruction with OpcodeBor.
+func (i *Instruction) AsBor(x, y Value) {
+	i.opcode = OpcodeBor
+	i.v = x
+	i.v2 = y
+	i.typ = x.Type()
+}
+
+// AsBxor initializes this instruction as an integer bitwise xor instruction with OpcodeBxor.
+func (i *Instruction) AsBxor(x, y Value) {
+	i.opcode = OpcodeBxor
+	i.v = x
+	i.v2 = y
+	i.typ = x.Type()
+}
+
 // AsIsub initializes this instruction as an integer subtraction instruction with OpcodeIsub.
 func (i *Instruction) AsIsub(x, y Value) {
 	i.opcode = OpcodeIsub
@@ -1040,6 +1192,20 @@ func (i *Instruction) AsFcmp(x, y Value, c FloatCmpCond) {
 	i.typ = TypeI32
 }
 
+// AsSelect initializes this instruction as a select instruction with OpcodeSelect.
+func (i *Instruction) AsSelect(c, x, y Value) {
+	i.opcode = OpcodeSelect
+	i.v = c
+	i.v2 = x
+	i.v3 = y
+	i.typ = x.Type()
+}
+
+// SelectData returns the select data for this instruction necessary for backends.
+func (i *Instruction) SelectData() (c, x, y Value) {
+	return i.v, i.v2, i.v3
+}
+
 // AsIshl initializes this instruction as an integer shift left instruction with OpcodeIshl.
 func (i *Instruction) AsIshl(x, amount Value) {
 	i.opcode = OpcodeIshl
@@ -1064,6 +1230,47 @@ func (i *Instruction) AsSshr(x, amount Value) {
 	i.typ = x.Type()
 }
 
+// AsRotl initializes this instruction as a word rotate left instruction with OpcodeRotl.
+// The amount is taken modulo the bit width of x as in Wasm.
+func (i *Instruction) AsRotl(x, amount Value) {
+	i.opcode = OpcodeRotl
+	i.v = x
+	i.v2 = amount
+	i.typ = x.Type()
+}
+
+// AsRotr initializes this instruction as a word rotate right instruction with OpcodeRotr.
+// The amount is taken modulo the bit width of x as in Wasm.
+func (i *Instruction) AsRotr(x, amount Value) {
+	i.opcode = OpcodeRotr
+	i.v = x
+	i.v2 = amount
+	i.typ = x.Type()
+}
+
+// AsClz initializes this instruction as a count leading zero bits instruction with OpcodeClz.
+// The result is the bit width of x when x is zero as in Wasm.
+func (i *Instruction) AsClz(x Value) {
+	i.opcode = OpcodeClz
+	i.v = x
+	i.typ = x.Type()
+}
+
+// AsCtz initializes this instruction as a count trailing zero bits instruction with OpcodeCtz.
+// The result is the bit width of x when x is zero as in Wasm.
+func (i *Instruction) AsCtz(x Value) {
+	i.opcode = OpcodeCtz
+	i.v = x
+	i.typ = x.Type()
+}
+
+// AsPopcnt initializes this instruction as a count of one bits instruction with OpcodePopcnt.
+func (i *Instruction) AsPopcnt(x Value) {
+	i.opcode = OpcodePopcnt
+	i.v = x
+	i.typ = x.Type()
+}
+
 // IcmpData returns the operands and comparison condition of this integer comparison instruction.
 func (i *Instruction) IcmpData() (x, y Value, c IntegerCmpCond) {
 	return i.v, i.v2, IntegerCmpCond(i.u64)
@@ -1122,6 +1329,138 @@ func (i *Instruction) AsFmax(x, y Value) {
 	i.typ = x.Type()
 }
 
+// AsFneg initializes this instruction as a floating-point negation instruction with OpcodeFneg.
+// This only flips the sign bit, so NaN is negated as well and its payload is kept as in Wasm.
+func (i *Instruction) AsFneg(x Value) {
+	i.opcode = OpcodeFneg
+	i.v = x
+	i.typ = x.Type()
+}
+
+// AsFabs initializes this instruction as a floating-point absolute value instruction with OpcodeFabs.
+// This only clears the sign bit, so the sign of NaN is cleared as well and its payload is kept as in Wasm.
+func (i *Instruction) AsFabs(x Value) {
+	i.opcode = OpcodeFabs
+	i.v = x
+	i.typ = x.Type()
+}
+
+// AsSqrt initializes this instruction as a floating-point square root instruction with OpcodeSqrt.
+// As IEEE 754 requires, the square root of -0.0 is -0.0, and the one of a negative number is NaN.
+func (i *Instruction) AsSqrt(x Value) {
+	i.opcode = OpcodeSqrt
+	i.v = x
+	i.typ = x.Type()
+}
+
+// AsFcopysign initializes this instruction as a floating-point copysign instruction with OpcodeFcopysign, which takes
+// the magnitude from x and the sign bit from y. The sign bit is taken even when y is NaN.
+func (i *Instruction) AsFcopysign(x, y Value) {
+	i.opcode = OpcodeFcopysign
+	i.v = x
+	i.v2 = y
+	i.typ = x.Type()
+}
+
+// AsCeil initializes this instruction as a floating-point ceiling instruction with OpcodeCeil.
+func (i *Instruction) AsCeil(x Value) {
+	i.opcode = OpcodeCeil
+	i.v = x
+	i.typ = x.Type()
+}
+
+// AsFloor initializes this instruction as a floating-point floor instruction with OpcodeFloor.
+func (i *Instruction) AsFloor(x Value) {
+	i.opcode = OpcodeFloor
+	i.v = x
+	i.typ = x.Type()
+}
+
+// AsTrunc initializes this instruction as a floating-point truncation instruction with OpcodeTrunc.
+func (i *Instruction) AsTrunc(x Value) {
+	i.opcode = OpcodeTrunc
+	i.v = x
+	i.typ = x.Type()
+}
+
+// AsNearest initializes this instruction as a floating-point rounding instruction with OpcodeNearest.
+// This rounds to the nearest integer with ties to even, e.g. 0.5 and 2.5 to 0.0 and 2.0, unlike C's round().
+func (i *Instruction) AsNearest(x Value) {
+	i.opcode = OpcodeNearest
+	i.v = x
+	i.typ = x.Type()
+}
+
+// AsFpromote initializes this instruction as a floating-point promotion instruction with OpcodeFpromote,
+// which is exact.
+func (i *Instruction) AsFpromote(x Value) {
+	i.opcode = OpcodeFpromote
+	i.v = x
+	i.typ = TypeF64
+}
+
+// AsFdemote initializes this instruction as a floating-point demotion instruction with OpcodeFdemote,
+// which rounds to the nearest with ties to even, and overflows to infinity.
+func (i *Instruction) AsFdemote(x Value) {
+	i.opcode = OpcodeFdemote
+	i.v = x
+	i.typ = TypeF32
+}
+
+// AsFcvtToSint initializes this instruction as a floating-point to signed integer conversion instruction with
+// OpcodeFcvtToSint, whose result is 64-bit if ret64 is true, and 32-bit otherwise.
+func (i *Instruction) AsFcvtToSint(x Value, ret64 bool) {
+	i.opcode = OpcodeFcvtToSint
+	i.v = x
+	i.typ = TypeI32
+	if ret64 {
+		i.typ = TypeI64
+	}
+}
+
+// AsFcvtToUint initializes this instruction as a floating-point to unsigned integer conversion instruction with
+// OpcodeFcvtToUint, whose result is 64-bit if ret64 is true, and 32-bit otherwise.
+func (i *Instruction) AsFcvtToUint(x Value, ret64 bool) {
+	i.opcode = OpcodeFcvtToUint
+	i.v = x
+	i.typ = TypeI32
+	if ret64 {
+		i.typ = TypeI64
+	}
+}
+
+// AsFcvtToSintSat initializes this instruction as a saturating floating-point to signed integer conversion instruction
+// with OpcodeFcvtToSintSat, whose result is 64-bit if ret64 is true, and 32-bit otherwise.
+func (i *Instruction) AsFcvtToSintSat(x Value, ret64 bool) {
+	i.AsFcvtToSint(x, ret64)
+	i.opcode = OpcodeFcvtToSintSat
+}
+
+// AsFcvtToUintSat initializes this instruction as a saturating floating-point to unsigned integer conversion
+// instruction with OpcodeFcvtToUintSat, whose result is 64-bit if ret64 is true, and 32-bit otherwise.
+func (i *Instruction) AsFcvtToUintSat(x Value, ret64 bool) {
+	i.AsFcvtToUint(x, ret64)
+	i.opcode = OpcodeFcvtToUintSat
+}
+
+// AsFcvtFromSint initializes this instruction as a signed integer to floating-point conversion instruction with
+// OpcodeFcvtFromSint, whose result is f64 if ret64 is true, and f32 otherwise.
+func (i *Instruction) AsFcvtFromSint(x Value, ret64 bool) {
+	i.opcode = OpcodeFcvtFromSint
+	i.v = x
+	i.typ = TypeF32
+	if ret64 {
+		i.typ = TypeF64
+	}
+}
+
+// AsFcvtFromUint initializes this instruction as an unsigned integer to floating-point conversion instruction with
+// OpcodeFcvtFromUint, whose result is f64 if ret64 is true, and f32 otherwise.
+func (i *Instruction) AsFcvtFromUint(x Value, ret64 bool) {
+	i.AsFcvtFromSint(x, ret64)
+	i.opcode = OpcodeFcvtFromUint
+}
+
 // AsF32const initializes this instruction as a 32-bit floating-point constant instruction with OpcodeF32const.
 func (i *Instruction) AsF32const(f float32) {
 	i.opcode = OpcodeF32const
@@ -1162,6 +1501,27 @@ func (i *Instruction) AsExitIfNotZeroWithCode(ctx, c Value, code wazevoapi.ExitC
 	i.u64 = uint64(code)
 }
 
+// AsExitIfNotZeroWithCodeAndValue initializes this instruction as a trap instruction with OpcodeExitIfNotZeroWithCode,
+// which also passes the 64-bit value `v` to the exit handler. See ExitValue.
+func (i *Instruction) AsExitIfNotZeroWithCodeAndValue(ctx, c, v Value, code wazevoapi.ExitCode) {
+	i.AsExitIfNotZeroWithCode(ctx, c, code)
+	i.v3 = v
+}
+
+// AsExitWithDynamicCode initializes this instruction as a trap instruction with OpcodeExitWithDynamicCode.
+func (i *Instruction) AsExitWithDynamicCode(ctx, code, v Value) {
+	i.opcode = OpcodeExitWithDynamicCode
+	i.v = ctx
+	i.v2 = code
+	i.v3 = v
+}
+
+// ExitWithDynamicCodeData returns the context, exit code and the value passed to the exit handler of
+// OpcodeExitWithDynamicCode.
+func (i *Instruction) ExitWithDynamicCodeData() (ctx, code, v Value) {
+	return i.v, i.v2, i.v3
+}
+
 // ExitWithCodeData returns the context and exit code of OpcodeExitWithCode.
 func (i *Instruction) ExitWithCodeData() (ctx Value, code wazevoapi.ExitCode) {
 	return i.v, wazevoapi.ExitCode(i.u64)
@@ -1172,6 +1532,11 @@ func (i *Instruction) ExitIfNotZeroWithCodeData() (ctx, c Value, code wazevoapi.
 	return i.v, i.v2, wazevoapi.ExitCode(i.u64)
 }
 
+// ExitValue returns the value passed to the exit handler by OpcodeExitIfNotZeroWithCode, or ValueInvalid if none.
+func (i *Instruction) ExitValue() Value {
+	return i.v3
+}
+
 // InvertBrx inverts either OpcodeBrz or OpcodeBrnz to the other.
 func (i *Instruction) InvertBrx() {
 	switch i.opcode {
@@ -1242,9 +1607,9 @@ func (i *Instruction) AsBrnz(v Value, args []Value, target BasicBlock) {
 func (i *Instruction) AsCall(ref FuncRef, sig *Signature, args []Value) {
 	i.opcode = OpcodeCall
 	i.typ = TypeF64
-	i.u64 = uint64(ref)
+	// The signature is not a Value, so it's kept out of v which is subject to the alias resolution.
+	i.u64 = uint64(ref) | uint64(sig.ID)<<32
 	i.vs = args
-	i.v = Value(sig.ID)
 	sig.used = true
 }
 
@@ -1254,7 +1619,7 @@ func (i *Instruction) CallData() (ref FuncRef, sigID SignatureID, args []Value)
 		panic("BUG: CallData only available for OpcodeCall")
 	}
 	ref = FuncRef(i.u64)
-	sigID = SignatureID(i.v)
+	sigID = SignatureID(i.u64 >> 32)
 	args = i.vs
 	return
 }
@@ -1264,7 +1629,7 @@ func (i *Instruction) AsCallIndirect(funcPtr Value, sig *Signature, args []Value
 	i.opcode = OpcodeCallIndirect
 	i.typ = TypeF64
 	i.vs = args
-	i.v = Value(sig.ID)
+	i.u64 = uint64(sig.ID)
 	i.v2 = funcPtr
 	sig.used = true
 }
@@ -1275,7 +1640,7 @@ func (i *Instruction) CallIndirectData() (funcPtr Value, sigID SignatureID, args
 		panic("BUG: CallIndirectData only available for OpcodeCallIndirect")
 	}
 	funcPtr = i.v2
-	sigID = SignatureID(i.v)
+	sigID = SignatureID(i.u64)
 	args = i.vs
 	return
 }
@@ -1292,6 +1657,14 @@ func (i *Instruction) AsSExtend(v Value, from, to byte) {
 	}
 }
 
+// AsIreduce initializes this instruction as a reduction instruction with OpcodeIreduce, which truncates the integer
+// value v to the narrower integer type dstType.
+func (i *Instruction) AsIreduce(v Value, dstType Type) {
+	i.opcode = OpcodeIreduce
+	i.v = v
+	i.typ = dstType
+}
+
 // AsUExtend initializes this instruction as an unsigned extension instruction with OpcodeUExtend.
 func (i *Instruction) AsUExtend(v Value, from, to byte) {
 	i.opcode = OpcodeUExtend
@@ -1328,10 +1701,19 @@ func (i *Instruction) Format(b Builder) string {
 	switch i.opcode {
 	case OpcodeExitWithCode:
 		instSuffix = fmt.Sprintf(" %s, %s", i.v.Format(b), wazevoapi.ExitCode(i.u64))
+	case OpcodeExitWithDynamicCode:
+		instSuffix = fmt.Sprintf(" %s, %s, %s", i.v.Format(b), i.v2.Format(b), i.v3.Format(b))
 	case OpcodeExitIfNotZeroWithCode:
-		instSuffix = fmt.Sprintf(" %s, %s, %s", i.v2.Format(b), i.v.Format(b), wazevoapi.ExitCode(i.u64))
-	case OpcodeIadd, OpcodeIsub, OpcodeImul, OpcodeFadd, OpcodeFsub, OpcodeFmin, OpcodeFmax, OpcodeFdiv, OpcodeFmul:
+		if i.v3.Valid() {
+			instSuffix = fmt.Sprintf(" %s, %s, %s, %s", i.v2.Format(b), i.v.Format(b), i.v3.Format(b), wazevoapi.ExitCode(i.u64))
+		} else {
+			instSuffix = fmt.Sprintf(" %s, %s, %s", i.v2.Format(b), i.v.Format(b), wazevoapi.ExitCode(i.u64))
+		}
+	case OpcodeIadd, OpcodeIsub, OpcodeImul, OpcodeSdiv, OpcodeUdiv, OpcodeSrem, OpcodeUrem, OpcodeBand, OpcodeBor, OpcodeBxor, OpcodeFadd, OpcodeFsub, OpcodeFmin, OpcodeFmax, OpcodeFdiv, OpcodeFmul,
+		OpcodeFcopysign:
 		instSuffix = fmt.Sprintf(" %s, %s", i.v.Format(b), i.v2.Format(b))
+	case OpcodeSelect:
+		instSuffix = fmt.Sprintf(" %s, %s, %s", i.v.Format(b), i.v2.Format(b), i.v3.Format(b))
 	case OpcodeIcmp:
 		instSuffix = fmt.Sprintf(" %s, %s, %s", IntegerCmpCond(i.u64), i.v.Format(b), i.v2.Format(b))
 	case OpcodeFcmp:
@@ -1344,11 +1726,11 @@ func (i *Instruction) Format(b Builder) string {
 			vs[idx] = i.vs[idx].Format(b)
 		}
 		if i.opcode == OpcodeCallIndirect {
-			instSuffix = fmt.Sprintf(" %s:%s, %s", i.v2.Format(b), SignatureID(i.v), strings.Join(vs, ", "))
+			instSuffix = fmt.Sprintf(" %s:%s, %s", i.v2.Format(b), SignatureID(i.u64), strings.Join(vs, ", "))
 		} else {
-			instSuffix = fmt.Sprintf(" %s:%s, %s", FuncRef(i.u64), SignatureID(i.v), strings.Join(vs, ", "))
+			instSuffix = fmt.Sprintf(" %s:%s, %s", FuncRef(i.u64), SignatureID(i.u64>>32), strings.Join(vs, ", "))
 		}
-	case OpcodeStore:
+	case OpcodeStore, OpcodeIstore8, OpcodeIstore16, OpcodeIstore32:
 		instSuffix = fmt.Sprintf(" %s, %s, %#x", i.v.Format(b), i.v2.Format(b), int32(i.u64))
 	case OpcodeLoad:
 		instSuffix = fmt.Sprintf(" %s, %#x", i.v.Format(b), int32(i.u64))
@@ -1394,8 +1776,12 @@ func (i *Instruction) Format(b Builder) string {
 			vs[idx+2] = i.vs[idx].Format(b)
 		}
 		instSuffix = strings.Join(vs, ", ")
-	case OpcodeIshl, OpcodeSshr, OpcodeUshr:
+	case OpcodeIshl, OpcodeSshr, OpcodeUshr, OpcodeRotl, OpcodeRotr:
 		instSuffix = fmt.Sprintf(" %s, %s", i.v.Format(b), i.v2.Format(b))
+	case OpcodeClz, OpcodeCtz, OpcodePopcnt, OpcodeIreduce, OpcodeFneg, OpcodeFabs, OpcodeSqrt,
+		OpcodeCeil, OpcodeFloor, OpcodeTrunc, OpcodeNearest, OpcodeFpromote, OpcodeFdemote,
+		OpcodeFcvtToSint, OpcodeFcvtToUint, OpcodeFcvtToSintSat, OpcodeFcvtToUintSat, OpcodeFcvtFromSint, OpcodeFcvtFromUint:
+		instSuffix = " " + i.v.Format(b)
 	default:
 		panic(fmt.Sprintf("TODO: format for %s", i.opcode))
 	}
@@ -1466,6 +1852,8 @@ func (o Opcode) String() (ret string) {
 		return "Exit"
 	case OpcodeExitIfNotZeroWithCode:
 		return "ExitIfNotZero"
+	case OpcodeExitWithDynamicCode:
+		return "ExitWithDynamicCode"
 	case OpcodeReturn:
 		return "Return"
 	case OpcodeCall:
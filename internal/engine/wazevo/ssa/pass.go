@@ -7,10 +7,27 @@ package ssa
 // Note that passes suffixed with "Opt" are the optimization passes, meaning that they edit the instructions and blocks
 // while the other passes are not, like passEstimateBranchProbabilities does not edit them, but only calculates the additional information.
 func (b *builder) RunPasses() {
+	b.runPasses(true)
+}
+
+// RunPassesWithoutOptimization implements Builder.RunPassesWithoutOptimization.
+func (b *builder) RunPassesWithoutOptimization() {
+	b.runPasses(false)
+}
+
+// runPasses runs the passes, skipping the optional optimization passes unless `optimize` is true.
+// passDeadBlockEliminationOpt and passDeadCodeEliminationOpt always run since the later stages rely on their results,
+// i.e. the invalid flags of the unreachable blocks, and the reference counts and the instruction groups respectively.
+func (b *builder) runPasses(optimize bool) {
 	passDeadBlockEliminationOpt(b)
-	passRedundantPhiEliminationOpt(b)
+	if optimize {
+		passRedundantPhiEliminationOpt(b)
+	}
 	// The result of passCalculateImmediateDominators will be used by various passes below.
 	passCalculateImmediateDominators(b)
+	if optimize {
+		passRedundantExtendEliminationOpt(b)
+	}
 
 	// TODO: implement either conversion of irreducible CFG into reducible one, or irreducible CFG detection where we panic.
 	// 	WebAssembly program shouldn't result in irreducible CFG, but we should handle it properly in just in case.
@@ -145,6 +162,79 @@ func passRedundantPhiEliminationOpt(b *builder) {
 	b.ints = redundantParameterIndexes
 }
 
+// passRedundantExtendEliminationOpt eliminates the extensions which don't change the value, and folds the
+// extension of an extension into one instruction. For example,
+//
+//	v1:i32 = SExtend v0, 8->32
+//	v2:i32 = SExtend v1, 16->32 ;; no-op since v1 is already sign-extended from 8 bits.
+//	v3:i64 = SExtend v1, 32->64 ;; equivalent to `SExtend v0, 8->64`.
+//
+// The eliminated extensions are aliased to their equivalent values, and removed by passDeadCodeEliminationOpt.
+// This must run after passCalculateImmediateDominators since this iterates the blocks in reverse post-order
+// so that the definition of an argument is always visited before its uses.
+func passRedundantExtendEliminationOpt(b *builder) {
+	if nvid := int(b.nextValueID); nvid >= len(b.valueIDToInstruction) {
+		b.valueIDToInstruction = append(b.valueIDToInstruction, make([]*Instruction, nvid)...)
+	}
+
+	for blk := b.blockIteratorReversePostOrderBegin(); blk != nil; blk = b.blockIteratorReversePostOrderNext() {
+		for cur := blk.rootInstr; cur != nil; cur = cur.next {
+			if cur.opcode != OpcodeSExtend && cur.opcode != OpcodeUExtend {
+				continue
+			}
+
+			from, to, signed := cur.ExtendData()
+			arg := b.resolveAlias(cur.v)
+			if from == to {
+				// Extending a value to its own size is a no-op.
+				b.alias(cur.Return(), arg)
+				continue
+			}
+
+			inner := b.valueIDToInstruction[arg.ID()]
+			if inner == nil {
+				// The argument is not an extension, so we keep this as-is.
+				b.valueIDToInstruction[cur.Return().ID()] = cur
+				continue
+			}
+
+			innerFrom, innerTo, innerSigned := inner.ExtendData()
+			// Finds the single extension equivalent to `cur` applied to the result of `inner`.
+			var foldedFrom byte
+			var foldedSigned bool
+			switch {
+			case innerFrom > from:
+				// Only the lower `from` bits of the inner result are used, and they are the same as the inner argument's.
+				foldedFrom, foldedSigned = from, signed
+			case innerSigned == signed:
+				// Two sign/zero-extensions are equivalent to one from the smaller size.
+				foldedFrom, foldedSigned = innerFrom, signed
+			case !innerSigned && innerFrom < from:
+				// The sign bit of the lower `from` bits is zero, so sign-extension is equivalent to zero-extension.
+				foldedFrom, foldedSigned = innerFrom, false
+			default:
+				// Zero-extension of a sign-extended value cannot be folded.
+				b.valueIDToInstruction[cur.Return().ID()] = cur
+				continue
+			}
+
+			if to == innerTo && foldedFrom == innerFrom && foldedSigned == innerSigned {
+				// `cur` produces exactly the same value as `inner`.
+				b.alias(cur.Return(), inner.Return())
+				continue
+			}
+
+			innerArg := b.resolveAlias(inner.v)
+			if foldedSigned {
+				cur.AsSExtend(innerArg, foldedFrom, to)
+			} else {
+				cur.AsUExtend(innerArg, foldedFrom, to)
+			}
+			b.valueIDToInstruction[cur.Return().ID()] = cur
+		}
+	}
+}
+
 // passDeadCodeEliminationOpt traverses all the instructions, and calculates the reference count of each Value, and
 // eliminates all the unnecessary instructions whose ref count is zero.
 // The results are stored at builder.valueRefCounts. This also assigns a InstructionGroupID to each Instruction
@@ -200,7 +290,7 @@ func passDeadCodeEliminationOpt(b *builder) {
 		// Before we walk, we need to resolve the alias first.
 		b.resolveArgumentAlias(live)
 
-		v1, v2, vs := live.Args()
+		v1, v2, v3, vs := live.Args()
 		if v1.Valid() {
 			producingInst := b.valueIDToInstruction[v1.ID()]
 			if producingInst != nil {
@@ -215,6 +305,13 @@ func passDeadCodeEliminationOpt(b *builder) {
 			}
 		}
 
+		if v3.Valid() {
+			producingInst := b.valueIDToInstruction[v3.ID()]
+			if producingInst != nil {
+				liveInstructions = append(liveInstructions, producingInst)
+			}
+		}
+
 		for _, v := range vs {
 			producingInst := b.valueIDToInstruction[v.ID()]
 			if producingInst != nil {
@@ -241,13 +338,16 @@ func passDeadCodeEliminationOpt(b *builder) {
 
 			// If the value alive, we can be sure that arguments are used definitely.
 			// Hence, we can increment the value reference counts.
-			v1, v2, vs := cur.Args()
+			v1, v2, v3, vs := cur.Args()
 			if v1.Valid() {
 				b.valueRefCounts[v1.ID()]++
 			}
 			if v2.Valid() {
 				b.valueRefCounts[v2.ID()]++
 			}
+			if v3.Valid() {
+				b.valueRefCounts[v3.ID()]++
+			}
 			for _, v := range vs {
 				b.valueRefCounts[v.ID()]++
 			}
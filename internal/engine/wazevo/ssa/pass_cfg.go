@@ -150,6 +150,10 @@ func subPassLoopDetection(b *builder) {
 			if pred.invalid {
 				continue
 			}
+			if pred.reversePostOrder < blk.reversePostOrder {
+				// A dominator precedes the dominated blocks in reverse post-order, so this cannot be a back-edge.
+				continue
+			}
 			if b.isDominatedBy(pred, blk) {
 				blk.loopHeader = true
 			}
@@ -184,6 +184,123 @@ blk1: (v0:i32) <-- (blk0,blk1)
 
 blk2: () <-- (blk1)
 	Return
+`,
+		},
+		{
+			name: "redundant extends",
+			pass: func(b *builder) {
+				passCalculateImmediateDominators(b)
+				passRedundantExtendEliminationOpt(b)
+				passDeadCodeEliminationOpt(b)
+			},
+			setup: func(b *builder) func(*testing.T) {
+				entry, next := b.AllocateBasicBlock(), b.AllocateBasicBlock()
+				i32Param, i64Param := entry.AddParam(b, TypeI32), entry.AddParam(b, TypeI64)
+				extend := func(v Value, from, to byte, signed bool) Value {
+					ext := b.AllocateInstruction()
+					if signed {
+						ext.AsSExtend(v, from, to)
+					} else {
+						ext.AsUExtend(v, from, to)
+					}
+					b.InsertInstruction(ext)
+					return ext.Return()
+				}
+
+				b.SetCurrentBlock(entry)
+				identity := extend(i64Param, 64, 64, false)
+				sext8 := extend(i32Param, 8, 32, true)
+				jmp := b.AllocateInstruction()
+				jmp.AsJump(nil, next)
+				b.InsertInstruction(jmp)
+
+				// The extensions of the values defined in the dominating block.
+				b.SetCurrentBlock(next)
+				ret := b.AllocateInstruction()
+				ret.AsReturn([]Value{
+					identity,
+					extend(sext8, 16, 32, true),
+					extend(extend(i32Param, 16, 32, true), 8, 32, true),
+					extend(sext8, 32, 64, true),
+					extend(extend(i32Param, 8, 32, false), 16, 32, true),
+					extend(extend(i32Param, 32, 64, false), 8, 64, true),
+					extend(sext8, 32, 64, false),
+				})
+				b.InsertInstruction(ret)
+				return nil
+			},
+			before: `
+blk0: (v0:i32, v1:i64)
+	v2:i64 = UExtend v1, 64->64
+	v3:i32 = SExtend v0, 8->32
+	Jump blk1
+
+blk1: () <-- (blk0)
+	v4:i32 = SExtend v3, 16->32
+	v5:i32 = SExtend v0, 16->32
+	v6:i32 = SExtend v5, 8->32
+	v7:i64 = SExtend v3, 32->64
+	v8:i32 = UExtend v0, 8->32
+	v9:i32 = SExtend v8, 16->32
+	v10:i64 = UExtend v0, 32->64
+	v11:i64 = SExtend v10, 8->64
+	v12:i64 = UExtend v3, 32->64
+	Return v2, v4, v6, v7, v9, v11, v12
+`,
+			after: `
+blk0: (v0:i32, v1:i64)
+	v3:i32 = SExtend v0, 8->32
+	Jump blk1
+
+blk1: () <-- (blk0)
+	v6:i32 = SExtend v0, 8->32
+	v7:i64 = SExtend v0, 8->64
+	v8:i32 = UExtend v0, 8->32
+	v11:i64 = SExtend v0, 8->64
+	v12:i64 = UExtend v3, 32->64
+	Return v1, v3, v6, v7, v8, v11, v12
+`,
+		},
+		{
+			name: "without optimization",
+			pass: (*builder).RunPassesWithoutOptimization,
+			setup: func(b *builder) func(*testing.T) {
+				entry := b.AllocateBasicBlock()
+				i32Param := entry.AddParam(b, TypeI32)
+
+				b.SetCurrentBlock(entry)
+				sext8 := b.AllocateInstruction()
+				sext8.AsSExtend(i32Param, 8, 32)
+				b.InsertInstruction(sext8)
+				// This is redundant, but kept since the optimization passes are skipped.
+				redundant := b.AllocateInstruction()
+				redundant.AsSExtend(sext8.Return(), 8, 32)
+				b.InsertInstruction(redundant)
+				// On the other hand, the dead code is still eliminated.
+				dead := b.AllocateInstruction()
+				dead.AsIconst32(0)
+				b.InsertInstruction(dead)
+
+				ret := b.AllocateInstruction()
+				ret.AsReturn([]Value{redundant.Return()})
+				b.InsertInstruction(ret)
+				return func(t *testing.T) {
+					require.True(t, b.donePasses)
+					require.Equal(t, 1, b.valueRefCounts[sext8.Return().ID()])
+				}
+			},
+			before: `
+blk0: (v0:i32)
+	v1:i32 = SExtend v0, 8->32
+	v2:i32 = SExtend v1, 8->32
+	v3:i32 = Iconst_32 0x0
+	Return v2
+`,
+			after: `
+blk0: (v0:i32)
+	v1:i32 = SExtend v0, 8->32
+	v2:i32 = SExtend v1, 8->32
+	Return v2
 `,
 		},
 		{
@@ -200,7 +317,7 @@ blk2: () <-- (blk1)
 
 				// This has side effect.
 				store := b.AllocateInstruction()
-				store.AsStore(refThriceVal, refThriceVal, 0)
+				store.AsStore(OpcodeStore, refThriceVal, refThriceVal, 0)
 				b.InsertInstruction(store)
 
 				iconstDeadInst := b.AllocateInstruction()
@@ -0,0 +1,121 @@
+package ssa
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Verify implements Builder.Verify.
+func (b *builder) Verify() error {
+	var violations []string
+	report := func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Sprintf(format, args...))
+	}
+
+	// First, we gather the blocks where each value is defined, either as a block param or a result of an instruction.
+	defBlocks := make(map[ValueID]*basicBlock)
+	for blk := b.blockIteratorBegin(); blk != nil; blk = b.blockIteratorNext() {
+		for _, p := range blk.params {
+			defBlocks[p.value.ID()] = blk
+		}
+		for cur := blk.rootInstr; cur != nil; cur = cur.next {
+			r1, rs := cur.Returns()
+			if r1.Valid() {
+				defBlocks[r1.ID()] = blk
+			}
+			for _, r := range rs {
+				defBlocks[r.ID()] = blk
+			}
+		}
+	}
+
+	definedInBlock := make(map[ValueID]struct{})
+	var uses []Value
+	for blk := b.blockIteratorBegin(); blk != nil; blk = b.blockIteratorNext() {
+		if !blk.sealed {
+			report("%s is not sealed", blk.Name())
+		}
+
+		for k := range definedInBlock {
+			delete(definedInBlock, k)
+		}
+		for _, p := range blk.params {
+			definedInBlock[p.value.ID()] = struct{}{}
+		}
+
+		for cur := blk.rootInstr; cur != nil; cur = cur.next {
+			uses = instructionUses(cur, uses[:0])
+			for _, v := range uses {
+				v = b.resolveAlias(v)
+				defBlk, ok := defBlocks[v.ID()]
+				switch {
+				case !ok:
+					report("%s in %s: %s is not defined", cur.Format(b), blk.Name(), v.Format(b))
+				case defBlk == blk:
+					if _, ok := definedInBlock[v.ID()]; !ok {
+						report("%s in %s: %s is used before its definition", cur.Format(b), blk.Name(), v.Format(b))
+					}
+				case len(b.dominators) > 0 && !b.isDominatedBy(blk, defBlk):
+					// Dominators are only available after passCalculateImmediateDominators.
+					report("%s in %s: %s is defined in %s which doesn't dominate %s",
+						cur.Format(b), blk.Name(), v.Format(b), defBlk.Name(), blk.Name())
+				}
+			}
+
+			r1, rs := cur.Returns()
+			if r1.Valid() {
+				definedInBlock[r1.ID()] = struct{}{}
+			}
+			for _, r := range rs {
+				definedInBlock[r.ID()] = struct{}{}
+			}
+
+			if cur.opcode == OpcodeJump || cur.opcode == OpcodeBrz || cur.opcode == OpcodeBrnz {
+				b.verifyBranchArgs(blk, cur, report)
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return errors.New("invalid SSA:\n\t" + strings.Join(violations, "\n\t"))
+	}
+	return nil
+}
+
+// verifyBranchArgs reports if the arguments of the branch instruction `branch` in `blk` don't match the params of the target block.
+func (b *builder) verifyBranchArgs(blk *basicBlock, branch *Instruction, report func(format string, args ...interface{})) {
+	_, args, raw := branch.BranchData()
+	target := raw.(*basicBlock)
+
+	var paramTypes []Type
+	if target.ReturnBlock() {
+		paramTypes = b.currentSignature.Results
+	} else {
+		paramTypes = make([]Type, len(target.params))
+		for i, p := range target.params {
+			paramTypes[i] = p.typ
+		}
+	}
+
+	if len(args) != len(paramTypes) {
+		report("%s -> %s: %d args for %d params", blk.Name(), target.Name(), len(args), len(paramTypes))
+		return
+	}
+	for i, arg := range args {
+		if typ := b.resolveAlias(arg).Type(); typ != paramTypes[i] {
+			report("%s -> %s: arg #%d %s is %s but the param is %s", blk.Name(), target.Name(), i, arg.Format(b), typ, paramTypes[i])
+		}
+	}
+}
+
+// instructionUses appends the values used by the given instruction to `uses` and returns it.
+func instructionUses(instr *Instruction, uses []Value) []Value {
+	v1, v2, v3, vs := instr.Args()
+	for _, v := range [...]Value{v1, v2, v3} {
+		if v.Valid() {
+			uses = append(uses, v)
+		}
+	}
+	return append(uses, vs...)
+}
@@ -0,0 +1,179 @@
+package ssa
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestBuilder_Verify(t *testing.T) {
+	insertIconst32 := func(b *builder, c uint32) Value {
+		iconst := b.AllocateInstruction()
+		iconst.AsIconst32(c)
+		b.InsertInstruction(iconst)
+		return iconst.Return()
+	}
+
+	insertJump := func(b *builder, args []Value, target BasicBlock) {
+		jump := b.AllocateInstruction()
+		jump.AsJump(args, target)
+		b.InsertInstruction(jump)
+	}
+
+	for _, tc := range []struct {
+		name string
+		// setup creates the SSA function in the given *builder.
+		setup  func(b *builder)
+		expErr string
+	}{
+		{
+			name: "valid",
+			setup: func(b *builder) {
+				entry, next := b.AllocateBasicBlock(), b.AllocateBasicBlock()
+				param := next.AddParam(b, TypeI32)
+
+				b.SetCurrentBlock(entry)
+				insertJump(b, []Value{insertIconst32(b, 1)}, next)
+				b.Seal(entry)
+
+				b.SetCurrentBlock(next)
+				insertJump(b, []Value{param}, b.returnBlk)
+				b.Seal(next)
+			},
+		},
+		{
+			name: "unsealed",
+			setup: func(b *builder) {
+				entry, next := b.AllocateBasicBlock(), b.AllocateBasicBlock()
+
+				b.SetCurrentBlock(entry)
+				insertJump(b, nil, next)
+				b.Seal(entry)
+
+				b.SetCurrentBlock(next)
+				insertJump(b, []Value{insertIconst32(b, 1)}, b.returnBlk)
+			},
+			expErr: `invalid SSA:
+	blk1 is not sealed`,
+		},
+		{
+			name: "undefined",
+			setup: func(b *builder) {
+				entry := b.AllocateBasicBlock()
+				b.SetCurrentBlock(entry)
+				undefined := b.allocateValue(TypeI32)
+				insertJump(b, []Value{undefined}, b.returnBlk)
+				b.Seal(entry)
+			},
+			expErr: `invalid SSA:
+	Jump blk_ret, v0 in blk0: v0 is not defined`,
+		},
+		{
+			name: "used before definition",
+			setup: func(b *builder) {
+				entry := b.AllocateBasicBlock()
+				b.SetCurrentBlock(entry)
+				v1 := insertIconst32(b, 1)
+				add := b.AllocateInstruction()
+				add.AsIadd(v1, v1)
+				b.InsertInstruction(add)
+				v2 := add.Return()
+				// Make the add use its own result.
+				add.v2 = v2
+				insertJump(b, []Value{v2}, b.returnBlk)
+				b.Seal(entry)
+			},
+			expErr: `invalid SSA:
+	v1:i32 = Iadd v0, v1 in blk0: v1 is used before its definition`,
+		},
+		{
+			name: "not dominated",
+			setup: func(b *builder) {
+				entry, then, els, end := b.AllocateBasicBlock(), b.AllocateBasicBlock(), b.AllocateBasicBlock(), b.AllocateBasicBlock()
+
+				b.SetCurrentBlock(entry)
+				cond := insertIconst32(b, 0)
+				brz := b.AllocateInstruction()
+				brz.AsBrz(cond, nil, els)
+				b.InsertInstruction(brz)
+				insertJump(b, nil, then)
+				b.Seal(entry)
+
+				b.SetCurrentBlock(then)
+				definedInThen := insertIconst32(b, 1)
+				insertJump(b, nil, end)
+				b.Seal(then)
+
+				b.SetCurrentBlock(els)
+				insertJump(b, nil, end)
+				b.Seal(els)
+
+				b.SetCurrentBlock(end)
+				insertJump(b, []Value{definedInThen}, b.returnBlk)
+				b.Seal(end)
+
+				passCalculateImmediateDominators(b)
+			},
+			expErr: `invalid SSA:
+	Jump blk_ret, v1 in blk3: v1 is defined in blk1 which doesn't dominate blk3`,
+		},
+		{
+			name: "args count mismatch",
+			setup: func(b *builder) {
+				entry, next := b.AllocateBasicBlock(), b.AllocateBasicBlock()
+				param := next.AddParam(b, TypeI32)
+
+				b.SetCurrentBlock(entry)
+				insertJump(b, nil, next)
+				b.Seal(entry)
+
+				b.SetCurrentBlock(next)
+				insertJump(b, []Value{param}, b.returnBlk)
+				b.Seal(next)
+			},
+			expErr: `invalid SSA:
+	blk0 -> blk1: 0 args for 1 params`,
+		},
+		{
+			name: "args type mismatch",
+			setup: func(b *builder) {
+				entry, next := b.AllocateBasicBlock(), b.AllocateBasicBlock()
+				next.AddParam(b, TypeI64)
+
+				b.SetCurrentBlock(entry)
+				insertJump(b, []Value{insertIconst32(b, 1)}, next)
+				b.Seal(entry)
+
+				b.SetCurrentBlock(next)
+				insertJump(b, []Value{insertIconst32(b, 1)}, b.returnBlk)
+				b.Seal(next)
+			},
+			expErr: `invalid SSA:
+	blk0 -> blk1: arg #0 v1 is i32 but the param is i64`,
+		},
+		{
+			name: "multiple violations",
+			setup: func(b *builder) {
+				entry := b.AllocateBasicBlock()
+				b.SetCurrentBlock(entry)
+				insertJump(b, nil, b.returnBlk)
+			},
+			expErr: `invalid SSA:
+	blk0 is not sealed
+	blk0 -> blk_ret: 0 args for 1 params`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBuilder().(*builder)
+			b.Init(&Signature{Results: []Type{TypeI32}})
+			tc.setup(b)
+			err := b.Verify()
+			if tc.expErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expErr)
+			}
+		})
+	}
+}
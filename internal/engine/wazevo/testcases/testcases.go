@@ -32,6 +32,17 @@ var (
 		byte(math.Float64bits(64.0) >> 56),
 		wasm.OpcodeEnd,
 	}, nil)}
+	I64Constants = TestCase{Name: "i64_consts", Module: SingleFunctionModule(wasm.FunctionType{
+		Results: []wasm.ValueType{i64, i64, i64},
+	}, []byte{
+		// math.MinInt64 in signed LEB128.
+		wasm.OpcodeI64Const, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x7f,
+		// math.MaxInt64 in signed LEB128.
+		wasm.OpcodeI64Const, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00,
+		// -1 in signed LEB128.
+		wasm.OpcodeI64Const, 0x7f,
+		wasm.OpcodeEnd,
+	}, nil)}
 	Unreachable        = TestCase{Name: "unreachable", Module: SingleFunctionModule(vv, []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}, nil)}
 	OnlyReturn         = TestCase{Name: "only_return", Module: SingleFunctionModule(vv, []byte{wasm.OpcodeReturn, wasm.OpcodeEnd}, nil)}
 	Params             = TestCase{Name: "params", Module: SingleFunctionModule(i32f32f64_v, []byte{wasm.OpcodeReturn, wasm.OpcodeEnd}, nil)}
@@ -173,6 +184,47 @@ var (
 			wasm.OpcodeEnd,
 		}, []wasm.ValueType{}),
 	}
+	// BrIfSwitch is the switch-like code made of consecutive br_if, which returns the param plus 10, 20 or 30 if it's
+	// 0, 1 or 2 respectively, or otherwise the param plus 40.
+	BrIfSwitch = TestCase{
+		Name: "br_if_switch",
+		Module: SingleFunctionModule(i32_i32, []byte{
+			wasm.OpcodeBlock, blockSignature_vv,
+			wasm.OpcodeBlock, blockSignature_vv,
+			wasm.OpcodeBlock, blockSignature_vv,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 0,
+			wasm.OpcodeI32Eq,
+			wasm.OpcodeBrIf, 0,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeI32Eq,
+			wasm.OpcodeBrIf, 1,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 2,
+			wasm.OpcodeI32Eq,
+			wasm.OpcodeBrIf, 2,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 40,
+			wasm.OpcodeI32Add,
+			wasm.OpcodeReturn,
+			wasm.OpcodeEnd,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 10,
+			wasm.OpcodeI32Add,
+			wasm.OpcodeReturn,
+			wasm.OpcodeEnd,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 20,
+			wasm.OpcodeI32Add,
+			wasm.OpcodeReturn,
+			wasm.OpcodeEnd,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 30,
+			wasm.OpcodeI32Add,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
 	BlockBlockBr = TestCase{
 		Name: "block_block_br",
 		Module: SingleFunctionModule(vv, []byte{
@@ -193,6 +245,19 @@ var (
 			wasm.OpcodeEnd,
 		}, []wasm.ValueType{i32}),
 	}
+	IfWithoutElseParams = TestCase{
+		Name: "if_without_else_params",
+		Module: SingleFunctionModule(i32_i32, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 0,
+			// The block type is (param i32) (result i32), so the implicit else passes the param through as the result.
+			wasm.OpcodeIf, 0,
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeI32Add,
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
 	IfElse = TestCase{
 		Name: "if_else",
 		Module: SingleFunctionModule(vv, []byte{
@@ -204,6 +269,27 @@ var (
 			wasm.OpcodeEnd,
 		}, []wasm.ValueType{i32}),
 	}
+	EarlyReturnFromNestedBlocks = TestCase{
+		Name: "early_return_from_nested_blocks",
+		Module: SingleFunctionModule(i32_i32i32, []byte{
+			wasm.OpcodeI32Const, 10,
+			wasm.OpcodeBlock, blockSignature_vv,
+			wasm.OpcodeI32Const, 20,
+			wasm.OpcodeBlock, blockSignature_vv,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeIf, blockSignature_vv,
+			wasm.OpcodeI32Const, 30,
+			wasm.OpcodeI32Const, 40,
+			// Returns the top two values, not the ones pushed by the enclosing blocks.
+			wasm.OpcodeReturn,
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+			wasm.OpcodeDrop,
+			wasm.OpcodeEnd,
+			wasm.OpcodeI32Const, 50,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
 	SinglePredecessorLocalRefs = TestCase{
 		Name: "single_predecessor_local_refs",
 		Module: &wasm.Module{
@@ -683,6 +769,47 @@ var (
 			wasm.OpcodeEnd,
 		}, []wasm.ValueType{}),
 	}
+	IntegerExtensionChains = TestCase{
+		Name: "integer_extension_chains",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{i32, i64},
+			Results: []wasm.ValueType{i32, i32, i64, i64, i64, i64, i64, i64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Extend8S,
+			wasm.OpcodeI32Extend16S,
+
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Extend16S,
+			wasm.OpcodeI32Extend8S,
+
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Extend8S,
+			wasm.OpcodeI64ExtendI32S,
+
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Extend16S,
+			wasm.OpcodeI64ExtendI32U,
+
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI64ExtendI32S,
+			wasm.OpcodeI64Extend32S,
+
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI64ExtendI32U,
+			wasm.OpcodeI64Extend32S,
+
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI64ExtendI32U,
+			wasm.OpcodeI64Extend8S,
+
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI64Extend8S,
+			wasm.OpcodeI64Extend16S,
+
+			wasm.OpcodeEnd,
+		}, []wasm.ValueType{}),
+	}
 	FloatComparisons = TestCase{
 		Name: "float_comparisons",
 		Module: SingleFunctionModule(wasm.FunctionType{
@@ -730,6 +857,60 @@ var (
 			wasm.OpcodeEnd,
 		}, []wasm.ValueType{}),
 	}
+	FloatMulAdd = TestCase{
+		Name: "float_mul_add",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{f32, f32, f32, f64, f64, f64},
+			Results: []wasm.ValueType{f32, f64},
+		}, []byte{
+			// Each arithmetic must be rounded separately, in other words, this must not be contracted into FMA.
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF32Mul,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeF32Add,
+
+			wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeLocalGet, 4,
+			wasm.OpcodeF64Mul,
+			wasm.OpcodeLocalGet, 5,
+			wasm.OpcodeF64Add,
+
+			wasm.OpcodeEnd,
+		}, []wasm.ValueType{}),
+	}
+	Selects = TestCase{
+		Name: "selects",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{i32, i32, i32, i64, i64, f32, f32, f64, f64},
+			Results: []wasm.ValueType{i32, i64, f32, f64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeSelect,
+
+			wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeLocalGet, 4,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeSelect,
+
+			wasm.OpcodeLocalGet, 5,
+			wasm.OpcodeLocalGet, 6,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeSelect,
+
+			// The condition is a comparison, and typed select is used.
+			wasm.OpcodeLocalGet, 7,
+			wasm.OpcodeLocalGet, 8,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 0,
+			wasm.OpcodeI32Ne,
+			wasm.OpcodeTypedSelect, 1, f64,
+
+			wasm.OpcodeEnd,
+		}, []wasm.ValueType{}),
+	}
 	FibonacciRecursive = TestCase{
 		Name: "recursive_fibonacci",
 		Module: SingleFunctionModule(i32_i32, []byte{
@@ -752,6 +933,40 @@ var (
 			wasm.OpcodeEnd,
 		}, nil),
 	}
+	RecursiveSum = TestCase{
+		Name: "recursive_sum",
+		Module: SingleFunctionModule(i32_i32, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeI32LtS,
+			wasm.OpcodeIf, blockSignature_vv,
+			wasm.OpcodeI32Const, 0,
+			wasm.OpcodeReturn,
+			wasm.OpcodeEnd,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeI32Sub,
+			wasm.OpcodeCall, 0,
+			wasm.OpcodeI32Add,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// TailReturns returns the first param if it is non-zero, and otherwise the second one, both by the explicit return
+	// instructions including the one right before the end of the function.
+	TailReturns = TestCase{
+		Name: "tail_returns",
+		Module: SingleFunctionModule(i32i32_i32, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeIf, blockSignature_vv,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeReturn,
+			wasm.OpcodeEnd,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeReturn,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
 	ImportedFunctionCall = TestCase{
 		Name: "imported_function_call",
 		Imported: &wasm.Module{
@@ -779,6 +994,98 @@ var (
 			}}},
 		},
 	}
+	ImportedFunctionCallDistinctTypes = TestCase{
+		Name: "imported_function_call_distinct_types",
+		Imported: &wasm.Module{
+			ExportSection:   []wasm.Export{{Name: "i32i64_i64", Type: wasm.ExternTypeFunc}},
+			TypeSection:     []wasm.FunctionType{vv, i32i64_i64},
+			FunctionSection: []wasm.Index{1},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64ExtendI32U,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeI64Add,
+				wasm.OpcodeEnd,
+			}}},
+			NameSection: &wasm.NameSection{ModuleName: "env"},
+		},
+		Module: &wasm.Module{
+			ImportFunctionCount: 1,
+			// The same signature as the imported function is at the different index from the imported module.
+			TypeSection:     []wasm.FunctionType{i64_i64, i32i64_i64},
+			ImportSection:   []wasm.Import{{Type: wasm.ExternTypeFunc, Module: "env", Name: "i32i64_i64", DescFunc: 1}},
+			FunctionSection: []wasm.Index{0, 0},
+			ExportSection:   []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 1}},
+			CodeSection: []wasm.Code{
+				{Body: []byte{
+					wasm.OpcodeI32Const, 10,
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeCall, 0,
+					wasm.OpcodeCall, 2,
+					wasm.OpcodeEnd,
+				}},
+				{Body: []byte{
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeI64Add,
+					wasm.OpcodeEnd,
+				}},
+			},
+		},
+	}
+	ImportedGlobal = TestCase{
+		Name: "imported_global",
+		Imported: &wasm.Module{
+			ExportSection: []wasm.Export{
+				{Name: "g", Type: wasm.ExternTypeGlobal},
+				{Name: "get_g", Type: wasm.ExternTypeFunc},
+			},
+			GlobalSection: []wasm.Global{{
+				Type: wasm.GlobalType{ValType: i32, Mutable: true},
+				Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0xe4, 0x0}}, // 100
+			}},
+			TypeSection:     []wasm.FunctionType{v_i32},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeGlobalGet, 0,
+				wasm.OpcodeEnd,
+			}}},
+			NameSection: &wasm.NameSection{ModuleName: "env"},
+		},
+		Module: &wasm.Module{
+			ImportFunctionCount: 1,
+			ImportGlobalCount:   1,
+			TypeSection:         []wasm.FunctionType{v_i32, {Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32, i32, i32, i64}}},
+			ImportSection: []wasm.Import{
+				{Type: wasm.ExternTypeFunc, Module: "env", Name: "get_g", DescFunc: 0},
+				{Type: wasm.ExternTypeGlobal, Module: "env", Name: "g", DescGlobal: wasm.GlobalType{ValType: i32, Mutable: true}},
+			},
+			GlobalSection: []wasm.Global{{
+				Type: wasm.GlobalType{ValType: i64, Mutable: true},
+				Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: []byte{0x0}},
+			}},
+			FunctionSection: []wasm.Index{1},
+			ExportSection:   []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 1}},
+			CodeSection: []wasm.Code{{Body: []byte{
+				// Returns the imported global before and after adding the param to it.
+				wasm.OpcodeGlobalGet, 0,
+				wasm.OpcodeGlobalGet, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeGlobalSet, 0,
+				wasm.OpcodeGlobalGet, 0,
+				// The exporting module must see the update.
+				wasm.OpcodeCall, 0,
+				// Increments the local global counting the calls, and returns it.
+				wasm.OpcodeGlobalGet, 1,
+				wasm.OpcodeI64Const, 1,
+				wasm.OpcodeI64Add,
+				wasm.OpcodeGlobalSet, 1,
+				wasm.OpcodeGlobalGet, 1,
+				wasm.OpcodeEnd,
+			}}},
+		},
+	}
 	MemoryLoadBasic = TestCase{
 		Name: "memory_load_basic",
 		Module: &wasm.Module{
@@ -787,7 +1094,7 @@ var (
 				Results: []wasm.ValueType{i32},
 			}},
 			ExportSection:   []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
-			MemorySection:   &wasm.Memory{Min: 1},
+			MemorySection:   &wasm.Memory{Min: 1, Cap: 1},
 			FunctionSection: []wasm.Index{0},
 			CodeSection: []wasm.Code{{Body: []byte{
 				wasm.OpcodeLocalGet, 0,
@@ -909,6 +1216,779 @@ var (
 			DataSection: []wasm.DataSegment{{OffsetExpression: constOffsetExpr(0), Init: maskedBuf(int(wasm.MemoryPageSize))}},
 		},
 	}
+	MemoryStores = TestCase{
+		Name: "memory_stores",
+		Module: &wasm.Module{
+			TypeSection: []wasm.FunctionType{{
+				Params:  []wasm.ValueType{i32, i32, i64, f32, f64},
+				Results: []wasm.ValueType{i32, i64, f32, f64, i32, i32, i64, i64, i64},
+			}},
+			ExportSection:   []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+			MemorySection:   &wasm.Memory{Min: 1},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				// Stores the params at the base address given as the first param.
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeI32Store, 0x2, 0x0, // alignment=2 (natural alignment) staticOffset=0
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeI64Store, 0x3, 0x8, // alignment=3 (natural alignment) staticOffset=8
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 3,
+				wasm.OpcodeF32Store, 0x2, 0x10, // alignment=2 (natural alignment) staticOffset=16
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 4,
+				wasm.OpcodeF64Store, 0x3, 0x18, // alignment=3 (natural alignment) staticOffset=24
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeI32Store8, 0x0, 0x20, // alignment=0 (natural alignment) staticOffset=32
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeI32Store16, 0x1, 0x22, // alignment=1 (natural alignment) staticOffset=34
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeI64Store8, 0x0, 0x28, // alignment=0 (natural alignment) staticOffset=40
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeI64Store16, 0x1, 0x2a, // alignment=1 (natural alignment) staticOffset=42
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeI64Store32, 0x2, 0x2c, // alignment=2 (natural alignment) staticOffset=44
+
+				// Loads them back.
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Load, 0x2, 0x0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load, 0x3, 0x8,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeF32Load, 0x2, 0x10,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeF64Load, 0x3, 0x18,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Load8U, 0x0, 0x20,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Load16U, 0x1, 0x22,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load8U, 0x0, 0x28,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load16U, 0x1, 0x2a,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load32U, 0x2, 0x2c,
+				wasm.OpcodeEnd,
+			}}},
+		},
+	}
+	// ConstAddressMemoryAccesses updates the variables at the fixed addresses, like the globals lowered to memory by
+	// compilers, and returns their new values.
+	ConstAddressMemoryAccesses = TestCase{
+		Name: "const_address_memory_accesses",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32, i64}}},
+			ExportSection:   []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+			MemorySection:   &wasm.Memory{Min: 1, Cap: 1},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				// The i32 variable at 0x400 += param.
+				wasm.OpcodeI32Const, 0x80, 0x8, // 0x400
+				wasm.OpcodeI32Const, 0x80, 0x8, // 0x400
+				wasm.OpcodeI32Load, 0x2, 0x0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeI32Store, 0x2, 0x0,
+				// The i64 variable at 0x408 += 1, addressed by the static offset from zero.
+				wasm.OpcodeI32Const, 0,
+				wasm.OpcodeI32Const, 0,
+				wasm.OpcodeI64Load, 0x3, 0x88, 0x8, // staticOffset=0x408
+				wasm.OpcodeI64Const, 1,
+				wasm.OpcodeI64Add,
+				wasm.OpcodeI64Store, 0x3, 0x88, 0x8, // staticOffset=0x408
+				// Returns both variables.
+				wasm.OpcodeI32Const, 0x80, 0x8, // 0x400
+				wasm.OpcodeI32Load, 0x2, 0x0,
+				wasm.OpcodeI32Const, 0,
+				wasm.OpcodeI64Load, 0x3, 0x88, 0x8, // staticOffset=0x408
+				wasm.OpcodeEnd,
+			}}},
+		},
+	}
+	// MemoryStructRead reads the two adjacent i64 fields of the struct at the given address.
+	MemoryStructRead = TestCase{
+		Name: "memory_struct_read",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i64, i64}}},
+			ExportSection:   []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+			MemorySection:   &wasm.Memory{Min: 1, Cap: 1},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load, 0x3, 0x0, // alignment=3 (natural alignment) staticOffset=0
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load, 0x3, 0x8, // alignment=3 (natural alignment) staticOffset=8
+				wasm.OpcodeEnd,
+			}}},
+			DataSection: []wasm.DataSegment{{OffsetExpression: constOffsetExpr(0), Init: maskedBuf(int(wasm.MemoryPageSize))}},
+		},
+	}
+	// MemoryStructReadAcrossCall is the same as MemoryStructRead except that a function is called in between. Since a callee
+	// could grow the memory, the bounds check of the first load is not extended to the second one, and the memory base
+	// and length are loaded again after the call.
+	//
+	// TODO: grow the memory in the callee, and read the field only in bounds after that, once memory.grow is lowered.
+	// For now, the callee does nothing, so only the code around the call is covered, not the effect of the growth.
+	MemoryStructReadAcrossCall = TestCase{
+		Name: "memory_struct_read_across_call",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i64, i64}}, vv},
+			ExportSection:   []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+			MemorySection:   &wasm.Memory{Min: 1},
+			FunctionSection: []wasm.Index{0, 1},
+			CodeSection: []wasm.Code{
+				{Body: []byte{
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeI64Load, 0x3, 0x0, // alignment=3 (natural alignment) staticOffset=0
+					wasm.OpcodeCall, 1,
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeI64Load, 0x3, 0x8, // alignment=3 (natural alignment) staticOffset=8
+					wasm.OpcodeEnd,
+				}},
+				{Body: []byte{wasm.OpcodeEnd}},
+			},
+			DataSection: []wasm.DataSegment{{OffsetExpression: constOffsetExpr(0), Init: maskedBuf(int(wasm.MemoryPageSize))}},
+		},
+	}
+	// MemorySameBaseLoads reads the three adjacent i64 fields of the two structs at the given addresses in turn.
+	MemorySameBaseLoads = TestCase{
+		Name: "memory_same_base_loads",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i64, i64, i64, i64, i64, i64}}},
+			ExportSection:   []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+			MemorySection:   &wasm.Memory{Min: 1, Cap: 1},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load, 0x3, 0x0, // alignment=3 (natural alignment) staticOffset=0
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeI64Load, 0x3, 0x0, // alignment=3 (natural alignment) staticOffset=0
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load, 0x3, 0x8, // alignment=3 (natural alignment) staticOffset=8
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeI64Load, 0x3, 0x8, // alignment=3 (natural alignment) staticOffset=8
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load, 0x3, 0x10, // alignment=3 (natural alignment) staticOffset=16
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeI64Load, 0x3, 0x10, // alignment=3 (natural alignment) staticOffset=16
+				wasm.OpcodeEnd,
+			}}},
+			DataSection: []wasm.DataSegment{{OffsetExpression: constOffsetExpr(0), Init: maskedBuf(int(wasm.MemoryPageSize))}},
+		},
+	}
+	// MemorySameBaseLoadsBrIf reads the i64 at the given address, and then the one a page after it unless the second
+	// param is non-zero. The latter is always out of bounds, so the function traps only if it reaches the second load.
+	MemorySameBaseLoadsBrIf = TestCase{
+		Name: "memory_same_base_loads_br_if",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i64}}},
+			ExportSection:   []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+			MemorySection:   &wasm.Memory{Min: 1},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeBlock, wasm.ValueTypeI64,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load, 0x3, 0x0, // alignment=3 (natural alignment) staticOffset=0
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeBrIf, 0,
+				wasm.OpcodeDrop,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load, 0x3, 0x80, 0x80, 0x04, // alignment=3 (natural alignment) staticOffset=65536
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}}},
+			DataSection: []wasm.DataSegment{{OffsetExpression: constOffsetExpr(0), Init: maskedBuf(int(wasm.MemoryPageSize))}},
+		},
+	}
+	// TableGetSet stores the given references at the first index of the funcref and externref tables, and then returns the ones at the second index.
+	TableGetSet = TestCase{
+		Name: "table_get_set",
+		Module: &wasm.Module{
+			TypeSection: []wasm.FunctionType{{
+				Params:  []wasm.ValueType{i32, i32, wasm.ValueTypeFuncref, wasm.ValueTypeExternref},
+				Results: []wasm.ValueType{wasm.ValueTypeFuncref, wasm.ValueTypeExternref},
+			}},
+			ExportSection:   []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+			TableSection:    []wasm.Table{{Min: 10, Type: wasm.RefTypeFuncref}, {Min: 20, Type: wasm.RefTypeExternref}},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeTableSet, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 3,
+				wasm.OpcodeTableSet, 1,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeTableGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeTableGet, 1,
+				wasm.OpcodeEnd,
+			}}},
+		},
+	}
+	// IntegerDivision returns the signed and unsigned quotients of the first two i32 params, and then the ones of the
+	// last two i64 params.
+	IntegerDivision = TestCase{
+		Name: "integer_division",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{i32, i32, i64, i64},
+			Results: []wasm.ValueType{i32, i32, i64, i64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32DivS,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32DivU,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeI64DivS,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeI64DivU,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// IntegerRemainder returns the signed and unsigned remainders of the first two i32 params, and then the ones of the
+	// last two i64 params.
+	IntegerRemainder = TestCase{
+		Name: "integer_remainder",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{i32, i32, i64, i64},
+			Results: []wasm.ValueType{i32, i32, i64, i64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32RemS,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32RemU,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeI64RemS,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeI64RemU,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// IntegerBitwise returns the bitwise and, or and xor of the first two i32 params, and then the ones of the last
+	// two i64 params.
+	IntegerBitwise = TestCase{
+		Name: "integer_bitwise",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{i32, i32, i64, i64},
+			Results: []wasm.ValueType{i32, i32, i32, i64, i64, i64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32And,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32Or,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32Xor,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeI64And,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeI64Or,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeI64Xor,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// RefTypeLocals returns the uninitialized externref local, and the funcref param round-tripped through a funcref local.
+	RefTypeLocals = TestCase{
+		Name: "ref_type_locals",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{wasm.ValueTypeFuncref},
+			Results: []wasm.ValueType{wasm.ValueTypeExternref, wasm.ValueTypeFuncref},
+		}, []byte{
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalSet, 2,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeEnd,
+		}, []wasm.ValueType{wasm.ValueTypeExternref, wasm.ValueTypeFuncref}),
+	}
+	// IntegerRotate returns the first i32 param rotated left and right by the second one, and then the first i64
+	// param rotated left and right by the second one.
+	IntegerRotate = TestCase{
+		Name: "integer_rotate",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{i32, i32, i64, i64},
+			Results: []wasm.ValueType{i32, i32, i64, i64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32Rotl,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32Rotr,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeI64Rotl,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeI64Rotr,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// IntegerBitCounts returns the leading zeros, trailing zeros and one bits of the i32 param, and then the ones of
+	// the i64 param.
+	IntegerBitCounts = TestCase{
+		Name: "integer_bit_counts",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{i32, i64},
+			Results: []wasm.ValueType{i32, i32, i32, i64, i64, i64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Clz,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Ctz,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Popcnt,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI64Clz,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI64Ctz,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI64Popcnt,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// IntegerEqz returns whether the i32 param is zero, and then whether the i64 param is.
+	IntegerEqz = TestCase{
+		Name: "integer_eqz",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{i32, i64},
+			Results: []wasm.ValueType{i32, i32},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Eqz,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI64Eqz,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// TableConstIndices stores the externref param at the constant indexes below the minimum size of the table, and then
+	// returns the one at the index 3 if the i32 param is zero, or the one at the index 4 which is beyond the minimum
+	// size otherwise.
+	TableConstIndices = TestCase{
+		Name: "table_const_indices",
+		Module: &wasm.Module{
+			TypeSection: []wasm.FunctionType{{
+				Params:  []wasm.ValueType{i32, wasm.ValueTypeExternref},
+				Results: []wasm.ValueType{wasm.ValueTypeExternref},
+			}},
+			ExportSection:   []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+			TableSection:    []wasm.Table{{Min: 4, Type: wasm.RefTypeExternref}},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeI32Const, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeTableSet, 0,
+				wasm.OpcodeI32Const, 3,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeTableSet, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeIf, wasm.ValueTypeExternref,
+				wasm.OpcodeI32Const, 4,
+				wasm.OpcodeTableGet, 0,
+				wasm.OpcodeElse,
+				wasm.OpcodeI32Const, 3,
+				wasm.OpcodeTableGet, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}}},
+		},
+	}
+	// I32WrapI64 returns the lower 32 bits of the i64 param, and the one of the sum of the i64 param and itself.
+	I32WrapI64 = TestCase{
+		Name: "i32_wrap_i64",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{i64},
+			Results: []wasm.ValueType{i32, i32},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32WrapI64,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI64Add,
+			wasm.OpcodeI32WrapI64,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// FloatUnaryOps returns the negation, the absolute value and the square root of the f32 param, and then the ones
+	// of the f64 param.
+	FloatUnaryOps = TestCase{
+		Name: "float_unary_ops",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{f32, f64},
+			Results: []wasm.ValueType{f32, f32, f32, f64, f64, f64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF32Neg,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF32Abs,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF32Sqrt,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF64Neg,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF64Abs,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF64Sqrt,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// FloatRounding returns the ceiling, the floor, the truncation and the nearest integer of the f32 param, and then
+	// the ones of the f64 param.
+	FloatRounding = TestCase{
+		Name: "float_rounding",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{f32, f64},
+			Results: []wasm.ValueType{f32, f32, f32, f32, f64, f64, f64, f64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF32Ceil,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF32Floor,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF32Trunc,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF32Nearest,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF64Ceil,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF64Floor,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF64Trunc,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF64Nearest,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// FloatCopysign returns the copysign of the f32 params, and then the one of the f64 params.
+	FloatCopysign = TestCase{
+		Name: "float_copysign",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{f32, f32, f64, f64},
+			Results: []wasm.ValueType{f32, f64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF32Copysign,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeF64Copysign,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// BlockParamsBr passes the two params to the block of the type (param i32 i32) (result i32), which branches out
+	// with the result by br_if or br, and adds the result to the value pushed before the block.
+	BlockParamsBr = TestCase{
+		Name: "block_params_br",
+		Module: SingleFunctionModule(i32i32_i32, []byte{
+			wasm.OpcodeI32Const, 10,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeBlock, 0,
+			wasm.OpcodeI32Sub,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeBrIf, 0,
+			wasm.OpcodeI32Const, 50,
+			wasm.OpcodeI32Add,
+			wasm.OpcodeBr, 0,
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeEnd,
+			wasm.OpcodeI32Add,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// FloatPromoteDemote returns the f32 param promoted to f64, the f64 param demoted to f32, and the f32 param
+	// promoted and then demoted back to f32.
+	FloatPromoteDemote = TestCase{
+		Name: "float_promote_demote",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{f32, f64},
+			Results: []wasm.ValueType{f64, f32, f32},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF64PromoteF32,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF32DemoteF64,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF64PromoteF32,
+			wasm.OpcodeF32DemoteF64,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// FloatToI32S returns the f32 param and then the f64 param converted by i32.trunc_f32_s and i32.trunc_f64_s.
+	FloatToI32S = TestCase{
+		Name: "float_to_i32_s",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{f32, f64},
+			Results: []wasm.ValueType{i32, i32},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32TruncF32S,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32TruncF64S,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// FloatToI32U returns the f32 param and then the f64 param converted by i32.trunc_f32_u and i32.trunc_f64_u.
+	FloatToI32U = TestCase{
+		Name: "float_to_i32_u",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{f32, f64},
+			Results: []wasm.ValueType{i32, i32},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32TruncF32U,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32TruncF64U,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// FloatToI64S returns the f32 param and then the f64 param converted by i64.trunc_f32_s and i64.trunc_f64_s.
+	FloatToI64S = TestCase{
+		Name: "float_to_i64_s",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{f32, f64},
+			Results: []wasm.ValueType{i64, i64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI64TruncF32S,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI64TruncF64S,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// FloatToI64U returns the f32 param and then the f64 param converted by i64.trunc_f32_u and i64.trunc_f64_u.
+	FloatToI64U = TestCase{
+		Name: "float_to_i64_u",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{f32, f64},
+			Results: []wasm.ValueType{i64, i64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI64TruncF32U,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI64TruncF64U,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// FloatToIntSat returns the f32 param and the f64 param converted by i32.trunc_sat_f32_s, i32.trunc_sat_f32_u,
+	// i32.trunc_sat_f64_s and i32.trunc_sat_f64_u, and then the ones by the i64 variants in the same order.
+	FloatToIntSat = TestCase{
+		Name: "float_to_int_sat",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{f32, f64},
+			Results: []wasm.ValueType{i32, i32, i32, i32, i64, i64, i64, i64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI32TruncSatF32S,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI32TruncSatF32U,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI32TruncSatF64S,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI32TruncSatF64U,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64TruncSatF32S,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64TruncSatF32U,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64TruncSatF64S,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64TruncSatF64U,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// IfThenEmpty has the if-else whose then-branch is empty. The first if has no results, and the second one takes
+	// the param i32 which the empty then-branch passes through as the result, while the else-branch adds 50 to it.
+	IfThenEmpty = TestCase{
+		Name: "if_then_empty",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{i32i32_i32, i32_i32},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeIf, blockSignature_vv,
+				wasm.OpcodeElse,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeLocalSet, 1,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeIf, 1, // (param i32) (result i32)
+				wasm.OpcodeElse,
+				wasm.OpcodeI32Const, 50,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}}},
+			ExportSection: []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+		},
+	}
+	// IntToFloat returns the i32 param and the i64 param converted by f32.convert_i32_s, f32.convert_i32_u,
+	// f32.convert_i64_s and f32.convert_i64_u, and then the ones by the f64 variants in the same order.
+	IntToFloat = TestCase{
+		Name: "int_to_float",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{i32, i64},
+			Results: []wasm.ValueType{f32, f32, f32, f32, f64, f64, f64, f64},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF32ConvertI32S,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF32ConvertI32U,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF32ConvertI64S,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF32ConvertI64U,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF64ConvertI32S,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF64ConvertI32U,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF64ConvertI64S,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF64ConvertI64U,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
+	// NestedLoopsBrToBlock searches for the first multiple of 21 above the param, by the innermost loop incrementing
+	// the local 0 until it's a multiple of 3 and the middle one restarting it until it's a multiple of 7. Then br 2
+	// branches out of both loops to the block of the type (result i32 i32) with the value and the value times 10. The
+	// outermost loop restarts the search while the value is below 30, and otherwise returns the block results.
+	NestedLoopsBrToBlock = TestCase{
+		Name: "nested_loops_br_to_block",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{i32_i32i32, v_i32i32},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeLoop, 1, // (result i32 i32)
+				wasm.OpcodeBlock, 1, // (result i32 i32)
+				wasm.OpcodeLoop, blockSignature_vv,
+				wasm.OpcodeLoop, blockSignature_vv,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeLocalSet, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Const, 3,
+				wasm.OpcodeI32RemU,
+				wasm.OpcodeBrIf, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Const, 7,
+				wasm.OpcodeI32RemU,
+				wasm.OpcodeBrIf, 1,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Const, 10,
+				wasm.OpcodeI32Mul,
+				wasm.OpcodeBr, 2,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+				wasm.OpcodeUnreachable,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalSet, 1,
+				wasm.OpcodeLocalSet, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Const, 30,
+				wasm.OpcodeI32LtU,
+				wasm.OpcodeBrIf, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}, LocalTypes: []wasm.ValueType{i32}}},
+			ExportSection: []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+		},
+	}
+	// GlobalsMutatedByCall returns the local globals read before and after calling the function which updates them.
+	GlobalsMutatedByCall = TestCase{
+		Name: "globals_mutated_by_call",
+		Module: &wasm.Module{
+			TypeSection: []wasm.FunctionType{
+				{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i64, i64, f64}},
+				{Params: []wasm.ValueType{i64}},
+			},
+			GlobalSection: []wasm.Global{
+				{
+					Type: wasm.GlobalType{ValType: i64, Mutable: true},
+					Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: []byte{0xa}},
+				},
+				{
+					Type: wasm.GlobalType{ValType: f64, Mutable: true},
+					Init: wasm.ConstantExpression{Opcode: wasm.OpcodeF64Const, Data: []byte{0, 0, 0, 0, 0, 0, 0xf0, 0x3f}}, // 1.0
+				},
+			},
+			FunctionSection: []wasm.Index{0, 1},
+			CodeSection: []wasm.Code{
+				{Body: []byte{
+					wasm.OpcodeGlobalGet, 0,
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeCall, 1,
+					// The callee has updated both globals.
+					wasm.OpcodeGlobalGet, 0,
+					wasm.OpcodeGlobalGet, 1,
+					wasm.OpcodeEnd,
+				}},
+				{Body: []byte{
+					wasm.OpcodeGlobalGet, 0,
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeI64Add,
+					wasm.OpcodeGlobalSet, 0,
+					wasm.OpcodeGlobalGet, 1,
+					wasm.OpcodeF64Const, 0, 0, 0, 0, 0, 0, 0xe0, 0x3f, // 0.5
+					wasm.OpcodeF64Add,
+					wasm.OpcodeGlobalSet, 1,
+					wasm.OpcodeEnd,
+				}},
+			},
+			ExportSection: []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+		},
+	}
+	// FloatConstBitPatterns returns the float constants which are equal, or not, only by their bit patterns, as well
+	// as `x*1.5 + 1.5` of the param.
+	FloatConstBitPatterns = TestCase{
+		Name: "float_const_bit_patterns",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{f64}, Results: []wasm.ValueType{f32, f32, f32, f64, f64, f64}}},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeF32Const, 0, 0, 0xc0, 0x7f, // nan:0x400000
+				wasm.OpcodeF32Const, 1, 0, 0xc0, 0x7f, // nan:0x400001
+				wasm.OpcodeF32Const, 0, 0, 0xc0, 0x7f, // nan:0x400000
+				wasm.OpcodeF64Const, 0, 0, 0, 0, 0, 0, 0, 0, // 0.0
+				wasm.OpcodeF64Const, 0, 0, 0, 0, 0, 0, 0, 0x80, // -0.0
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeF64Const, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f, // 1.5
+				wasm.OpcodeF64Mul,
+				wasm.OpcodeF64Const, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f, // 1.5
+				wasm.OpcodeF64Add,
+				wasm.OpcodeEnd,
+			}}},
+			ExportSection: []wasm.Export{{Name: ExportName, Type: wasm.ExternTypeFunc, Index: 0}},
+		},
+	}
 )
 
 type TestCase struct {
@@ -937,6 +2017,8 @@ var (
 	i32i32_i32          = wasm.FunctionType{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}}
 	i32i32_i32i32       = wasm.FunctionType{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32, i32}}
 	i32_i32i32          = wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32, i32}}
+	i64_i64             = wasm.FunctionType{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i64}}
+	i32i64_i64          = wasm.FunctionType{Params: []wasm.ValueType{i32, i64}, Results: []wasm.ValueType{i64}}
 	i32f32f64_v         = wasm.FunctionType{Params: []wasm.ValueType{i32, f32, f64}, Results: nil}
 	i64f32f64_i64f32f64 = wasm.FunctionType{Params: []wasm.ValueType{i64, f32, f64}, Results: []wasm.ValueType{i64, f32, f64}}
 )
@@ -2,15 +2,27 @@ package wazevo
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
 	"os"
 	"runtime"
+	"sync"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/engine/interpreter"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/testcases"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
+	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 )
 
 var ctx = context.Background()
@@ -22,11 +34,92 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+// testFunction is the function at the index 0 of the module set up by newTestFunction, along with the engine which
+// compiled the module and the module engine of its instance.
+type testFunction struct {
+	api.Function
+	e  *engine
+	me *moduleEngine
+}
+
+// testSetup holds what the testOption of newTestFunction configures.
+type testSetup struct {
+	cfg         Config
+	inst        *wasm.ModuleInstance
+	memory      bool
+	compileHook func(index wasm.Index)
+}
+
+// testOption configures the engine or the module instance set up by newTestFunction.
+type testOption func(*testSetup)
+
+// withConfig compiles the module with the engine configured with cfg.
+func withConfig(cfg Config) testOption {
+	return func(s *testSetup) { s.cfg = cfg }
+}
+
+// withModuleInstance instantiates the module into inst, whose Source is set to the module.
+func withModuleInstance(inst *wasm.ModuleInstance) testOption {
+	return func(s *testSetup) { s.inst = inst }
+}
+
+// withMemory instantiates the module with the new memory instance of its memory section.
+func withMemory() testOption {
+	return func(s *testSetup) { s.memory = true }
+}
+
+// withCompileHook sets engine.compileHook before compiling the module.
+func withCompileHook(hook func(index wasm.Index)) testOption {
+	return func(s *testSetup) { s.compileHook = hook }
+}
+
+// newTestFunction compiles and instantiates the module m as configured by opts, and returns its function at the index 0.
+func newTestFunction(tb testing.TB, m *wasm.Module, opts ...testOption) *testFunction {
+	s := testSetup{inst: &wasm.ModuleInstance{}}
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	e := newEngine(ctx, api.CoreFeaturesV2, nil, s.cfg)
+	e.compileHook = s.compileHook
+	err := e.CompileModule(ctx, m, nil, false)
+	require.NoError(tb, err)
+
+	s.inst.Source = m
+	if s.memory {
+		s.inst.MemoryInstance = wasm.NewMemoryInstance(m.MemorySection)
+	}
+	me, err := e.NewModuleEngine(m, s.inst)
+	require.NoError(tb, err)
+	me.DoneInstantiation()
+	return &testFunction{Function: me.NewFunction(0), e: e, me: me.(*moduleEngine)}
+}
+
 func TestNewEngine(t *testing.T) {
 	e := NewEngine(ctx, api.CoreFeaturesV1, nil)
 	require.NotNil(t, e)
 }
 
+func TestNewEngineWithConfig(t *testing.T) {
+	e, ok := NewEngineWithConfig(Config{})(ctx, api.CoreFeaturesV1, nil).(*engine)
+	require.True(t, ok)
+	require.Equal(t, NewEngine(ctx, api.CoreFeaturesV1, nil), wasm.Engine(e))
+
+	e, ok = NewEngineWithConfig(Config{
+		LazyCompilation:  true,
+		SharedTrapBlocks: true,
+		RoundingMode:     backend.RoundingModeTowardZero,
+		RegionSize:       1000,
+		CrossCheck:       true,
+	})(ctx, api.CoreFeaturesV1, nil).(*engine)
+	require.True(t, ok)
+	require.True(t, e.lazyCompilation)
+	require.True(t, e.sharedTrapBlocks)
+	require.Equal(t, backend.RoundingModeTowardZero, e.roundingMode)
+	require.Equal(t, 1000, e.regionSize)
+	require.NotNil(t, e.crossCheck)
+}
+
 func TestEngine_CompiledModuleCount(t *testing.T) {
 	e, ok := NewEngine(ctx, api.CoreFeaturesV1, nil).(*engine)
 	require.True(t, ok)
@@ -59,4 +152,1256 @@ func Test_ExecutionContextOffsets(t *testing.T) {
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.stackGrowRequiredSize)), offsets.StackGrowRequiredSize)
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.savedRegisters)), offsets.SavedRegistersBegin)
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.savedRegisters))%16, wazevoapi.Offset(0))
+	require.Equal(t, uintptr(wazevoapi.SavedRegistersSlots*16), unsafe.Sizeof(execCtx.savedRegisters))
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.exitValue)), offsets.ExitValue)
+}
+
+func TestEngine_coverage(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeIf, byte(i32),
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeElse,
+			wasm.OpcodeI32Const, 2,
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "f", Index: 0, Type: wasm.ExternTypeFunc}},
+	}
+	f := newTestFunction(t, m, withConfig(Config{Coverage: true}))
+
+	results, err := f.Call(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, results)
+
+	counters, blocks := f.me.Coverage()
+	for blk, exp := range map[ssa.BasicBlockID]uint64{
+		0: 1, // Entry.
+		1: 1, // Then.
+		2: 0, // Else.
+		3: 1, // After if-else.
+	} {
+		index, ok := blocks[CoverageBlock{FunctionIndex: 0, BlockID: blk}]
+		require.True(t, ok)
+		require.Equal(t, exp, counters[index], blk.String())
+	}
+}
+
+func TestEngine_loopProfiling(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			// Decrements the param until it reaches zero.
+			wasm.OpcodeBlock, 0x40, // 0x40 is the v_v block type.
+			wasm.OpcodeLoop, 0x40,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 0,
+			wasm.OpcodeI32Eq,
+			wasm.OpcodeBrIf, 1,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeI32Sub,
+			wasm.OpcodeLocalSet, 0,
+			wasm.OpcodeBr, 0,
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "f", Index: 0, Type: wasm.ExternTypeFunc}},
+	}
+	f := newTestFunction(t, m, withConfig(Config{LoopProfiling: true}))
+
+	_, err := f.Call(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{10}, f.me.LoopCounters())
+
+	// The counters accumulate across calls.
+	_, err = f.Call(ctx, 5)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{15}, f.me.LoopCounters())
+}
+
+func TestEngine_flushDenormalsToZero(t *testing.T) {
+	f64 := wasm.ValueTypeF64
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{f64, f64}, Results: []wasm.ValueType{f64}},
+		[]byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeF64Add, wasm.OpcodeEnd}, nil)
+	// The smallest positive denormal.
+	denormal := math.Float64bits(math.SmallestNonzeroFloat64)
+	for _, ftz := range []bool{false, true} {
+		ftz := ftz
+		t.Run(fmt.Sprintf("ftz=%v", ftz), func(t *testing.T) {
+			f := newTestFunction(t, m, withConfig(Config{FlushDenormalsToZero: ftz}))
+
+			results, err := f.Call(ctx, denormal, 0)
+			require.NoError(t, err)
+			if ftz {
+				require.Equal(t, []uint64{0}, results)
+			} else {
+				require.Equal(t, []uint64{denormal}, results)
+			}
+
+			// Normal values are not affected.
+			results, err = f.Call(ctx, math.Float64bits(1.5), math.Float64bits(2.25))
+			require.NoError(t, err)
+			require.Equal(t, []uint64{math.Float64bits(3.75)}, results)
+		})
+	}
+}
+
+func TestEngine_roundingMode(t *testing.T) {
+	f32 := wasm.ValueTypeF32
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{f32, f32}, Results: []wasm.ValueType{f32}},
+		[]byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeF32Add, wasm.OpcodeEnd}, nil)
+	// The exact sum 1 + 0.75ulp is rounded up to 1 + 1ulp under the default rounding mode.
+	one, threeQuartersULP := math.Float32bits(1), math.Float32bits(0x1.8p-24)
+	for _, tc := range []struct {
+		mode backend.RoundingMode
+		exp  float32
+	}{
+		{mode: backend.RoundingModeNearest, exp: 1 + 0x1p-23},
+		{mode: backend.RoundingModeTowardZero, exp: 1},
+		{mode: backend.RoundingModeTowardPositive, exp: 1 + 0x1p-23},
+		{mode: backend.RoundingModeTowardNegative, exp: 1},
+	} {
+		tc := tc
+		t.Run(fmt.Sprintf("mode=%d", tc.mode), func(t *testing.T) {
+			f := newTestFunction(t, m, withConfig(Config{RoundingMode: tc.mode}))
+
+			results, err := f.Call(ctx, uint64(one), uint64(threeQuartersULP))
+			require.NoError(t, err)
+			require.Equal(t, []uint64{uint64(math.Float32bits(tc.exp))}, results)
+
+			// The exact results are not affected.
+			results, err = f.Call(ctx, uint64(math.Float32bits(1.5)), uint64(math.Float32bits(2.25)))
+			require.NoError(t, err)
+			require.Equal(t, []uint64{uint64(math.Float32bits(3.75))}, results)
+		})
+	}
+}
+
+func TestEngine_UnsupportedInstructions(t *testing.T) {
+	e, ok := NewEngine(ctx, api.CoreFeaturesV2, nil).(*engine)
+	require.True(t, ok)
+
+	supported := testcases.FibonacciRecursive.Module
+	require.Equal(t, []string{}, e.UnsupportedInstructions(supported))
+
+	v128Const := append([]byte{wasm.OpcodeVecPrefix, wasm.OpcodeVecV128Const}, make([]byte, 16)...)
+	unsupported := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0, 0},
+		CodeSection: []wasm.Code{
+			{Body: append(v128Const, wasm.OpcodeDrop, wasm.OpcodeEnd)},
+			{Body: []byte{wasm.OpcodeEnd}},
+			{Body: append(v128Const, wasm.OpcodeDrop, wasm.OpcodeEnd)},
+		},
+	}
+	require.Equal(t, []string{wasm.VectorInstructionName(wasm.OpcodeVecV128Const)}, e.UnsupportedInstructions(unsupported))
+
+	// The scan doesn't compile the module.
+	require.Equal(t, uint32(0), e.CompiledModuleCount())
+}
+
+func TestEngine_deterministicStack(t *testing.T) {
+	m := testcases.FibonacciRecursive.Module
+	for _, deterministic := range []bool{false, true} {
+		deterministic := deterministic
+		t.Run(fmt.Sprintf("deterministic=%v", deterministic), func(t *testing.T) {
+			e := newEngine(ctx, api.CoreFeaturesV1, nil, Config{DeterministicStack: deterministic})
+
+			err := e.CompileModule(ctx, m, nil, false)
+			require.NoError(t, err)
+
+			// Each run calls the function of a new module instance.
+			var stackBases [2]*byte
+			for i := range stackBases {
+				me, err := e.NewModuleEngine(m, &wasm.ModuleInstance{Source: m})
+				require.NoError(t, err)
+				me.DoneInstantiation()
+				ce := me.NewFunction(0).(*callEngine)
+
+				results, err := ce.Call(ctx, 20)
+				require.NoError(t, err)
+				require.Equal(t, []uint64{6765}, results)
+				stackBases[i] = &ce.stack[0]
+			}
+			if deterministic {
+				require.Equal(t, stackBases[0], stackBases[1])
+				require.Equal(t, 1, len(e.stackArena.free))
+			} else {
+				require.NotEqual(t, stackBases[0], stackBases[1])
+			}
+		})
+	}
+}
+
+func TestEngine_hostCallMetrics(t *testing.T) {
+	m := testcases.FibonacciRecursive.Module
+	for _, enabled := range []bool{false, true} {
+		enabled := enabled
+		t.Run(fmt.Sprintf("enabled=%v", enabled), func(t *testing.T) {
+			f := newTestFunction(t, m, withConfig(Config{HostCallMetrics: enabled}))
+			ce := f.Function.(*callEngine)
+
+			results, err := ce.Call(ctx, 20)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{6765}, results)
+
+			metrics := ce.LastCallMetrics()
+			if enabled {
+				require.True(t, metrics.Total > 0)
+			} else {
+				require.Equal(t, time.Duration(0), metrics.Total)
+			}
+		})
+	}
+}
+
+func TestEngine_strictAlignment(t *testing.T) {
+	// The i32.load in this module has the natural alignment hint, i.e. 4 bytes.
+	m := testcases.MemoryLoadBasic.Module
+	for _, strict := range []bool{false, true} {
+		strict := strict
+		t.Run(fmt.Sprintf("strict=%v", strict), func(t *testing.T) {
+			mem := wasm.NewMemoryInstance(m.MemorySection)
+			binary.LittleEndian.PutUint32(mem.Buffer[4:], 0xdeadbeef)
+			f := newTestFunction(t, m, withConfig(Config{StrictAlignment: strict}), withModuleInstance(&wasm.ModuleInstance{MemoryInstance: mem}))
+
+			results, err := f.Call(ctx, 4)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{0xdeadbeef}, results)
+
+			results, err = f.Call(ctx, 5)
+			if strict {
+				require.ErrorIs(t, err, wasmruntime.ErrRuntimeUnalignedMemoryAccess)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, []uint64{0xdeadbe}, results)
+			}
+		})
+	}
+}
+
+func TestEngine_sharedTrapBlocks(t *testing.T) {
+	// The check of the i32.load is extended by the i64.load, and the one of the i32.load16_u is separate, so the two
+	// checks pass the different exit codes to the shared trap block.
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32, i64, i32}}, []byte{
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI32Load, 0x2, 0x0,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI64Load, 0x3, 0x8,
+		wasm.OpcodeLocalGet, 1,
+		wasm.OpcodeI32Load16U, 0x1, 0x0,
+		wasm.OpcodeEnd,
+	}, nil)
+	m.MemorySection = &wasm.Memory{Min: 1}
+	for _, shared := range []bool{false, true} {
+		shared := shared
+		t.Run(fmt.Sprintf("shared=%v", shared), func(t *testing.T) {
+			mem := wasm.NewMemoryInstance(m.MemorySection)
+			binary.LittleEndian.PutUint32(mem.Buffer[0:], 0xdeadbeef)
+			binary.LittleEndian.PutUint64(mem.Buffer[8:], 0x0102030405060708)
+			binary.LittleEndian.PutUint16(mem.Buffer[16:], 0xcafe)
+			f := newTestFunction(t, m, withConfig(Config{SharedTrapBlocks: shared}), withModuleInstance(&wasm.ModuleInstance{MemoryInstance: mem}))
+
+			results, err := f.Call(ctx, 0, 16)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{0xdeadbeef, 0x0102030405060708, 0xcafe}, results)
+
+			for _, tc := range []struct {
+				params []uint64
+				expErr string
+			}{
+				{
+					// The i32.load is in bounds, but the i64.load is not.
+					params: []uint64{uint64(wasm.MemoryPageSize) - 8, 0},
+					expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32,i32) (i32,i64,i32)\n\t\t8-byte memory access at address 0x10000 or an earlier access checked together with it",
+				},
+				{
+					params: []uint64{0, uint64(wasm.MemoryPageSize) - 1},
+					expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32,i32) (i32,i64,i32)\n\t\t2-byte memory access at address 0xffff",
+				},
+			} {
+				_, err = f.Call(ctx, tc.params...)
+				require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+				require.EqualError(t, err, tc.expErr)
+			}
+		})
+	}
+}
+
+func TestEngine_preciseMemoryTraps(t *testing.T) {
+	// The check of the i32.load is extended by the i64.load unless the precise memory traps are enabled.
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32, i64}}, []byte{
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI32Load, 0x2, 0x0,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI64Load, 0x3, 0x8,
+		wasm.OpcodeEnd,
+	}, nil)
+	m.MemorySection = &wasm.Memory{Min: 1}
+	for _, tc := range []struct {
+		precise bool
+		expErr  string
+	}{
+		{
+			// The extended check reports the i64.load, though the i32.load is the first one out of bounds.
+			precise: false,
+			expErr:  "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32) (i32,i64)\n\t\t8-byte memory access at address 0x10006 or an earlier access checked together with it",
+		},
+		{
+			precise: true,
+			expErr:  "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0(i32) (i32,i64)\n\t\t4-byte memory access at address 0xfffe",
+		},
+	} {
+		tc := tc
+		t.Run(fmt.Sprintf("precise=%v", tc.precise), func(t *testing.T) {
+			f := newTestFunction(t, m, withConfig(Config{PreciseMemoryTraps: tc.precise}), withMemory())
+
+			_, err := f.Call(ctx, uint64(wasm.MemoryPageSize)-2)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+			require.EqualError(t, err, tc.expErr)
+		})
+	}
+}
+
+func TestEngine_UsedFeatures(t *testing.T) {
+	e, ok := NewEngine(ctx, api.CoreFeaturesV2, nil).(*engine)
+	require.True(t, ok)
+
+	plain := testcases.SingleFunctionModule(wasm.FunctionType{}, []byte{wasm.OpcodeEnd}, nil)
+	plain.ID = wasm.ModuleID{1}
+	signExt := testcases.SingleFunctionModule(wasm.FunctionType{}, []byte{
+		wasm.OpcodeI32Const, 1, wasm.OpcodeI32Extend8S, wasm.OpcodeDrop, wasm.OpcodeEnd,
+	}, nil)
+	signExt.ID = wasm.ModuleID{2}
+
+	_, ok = e.UsedFeatures(plain)
+	require.False(t, ok)
+
+	for _, tc := range []struct {
+		m   *wasm.Module
+		exp api.CoreFeatures
+	}{
+		{m: plain, exp: 0},
+		{m: signExt, exp: api.CoreFeatureSignExtensionOps},
+	} {
+		require.NoError(t, e.CompileModule(ctx, tc.m, nil, false))
+		features, ok := e.UsedFeatures(tc.m)
+		require.True(t, ok)
+		require.Equal(t, tc.exp, features)
+	}
+}
+
+func TestEngine_BoundsCheckAudit(t *testing.T) {
+	for _, tc := range []testcases.TestCase{
+		testcases.MemoryLoads, testcases.MemoryStores, testcases.MemoryStructReadAcrossCall, testcases.ConstAddressMemoryAccesses,
+		testcases.MemorySameBaseLoads, testcases.MemorySameBaseLoadsBrIf,
+	} {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			e := newEngine(ctx, api.CoreFeaturesV2, nil, Config{BoundsCheckAudit: true})
+
+			_, ok := e.BoundsCheckAudit(tc.Module)
+			require.False(t, ok)
+
+			require.NoError(t, e.CompileModule(ctx, tc.Module, nil, false))
+			audits, ok := e.BoundsCheckAudit(tc.Module)
+			require.True(t, ok)
+			require.NotEqual(t, 0, len(audits))
+
+			// Every access is checked under the default settings.
+			for fidx, accesses := range audits {
+				for _, a := range accesses {
+					require.True(t, a.Checked(), "function[%d] at offset %#x", fidx, a.Offset)
+				}
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		e, ok := NewEngine(ctx, api.CoreFeaturesV2, nil).(*engine)
+		require.True(t, ok)
+		m := testcases.MemoryLoads.Module
+		require.NoError(t, e.CompileModule(ctx, m, nil, false))
+		_, ok = e.BoundsCheckAudit(m)
+		require.False(t, ok)
+	})
+}
+
+func TestEngine_OpcodeStats(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		e := newEngine(ctx, api.CoreFeaturesV2, nil, Config{OpcodeStats: enabled})
+
+		m := testcases.MemoryLoads.Module
+		require.NoError(t, e.CompileModule(ctx, m, nil, false))
+		stats, ok := e.OpcodeStats(m)
+		require.Equal(t, enabled, ok)
+		if !enabled {
+			continue
+		}
+
+		var attributed int
+		for _, stat := range stats.Opcodes {
+			attributed += stat.Instructions
+		}
+		require.True(t, attributed > 0)
+		require.True(t, attributed <= stats.Instructions)
+		require.Equal(t, 1, stats.Opcodes[wasm.OpcodeEnd].Count)
+	}
+}
+
+func TestEngine_tableExternref(t *testing.T) {
+	m := testcases.TableGetSet.Module
+	funcTable := &wasm.TableInstance{References: make([]wasm.Reference, 10), Type: wasm.RefTypeFuncref}
+	externTable := &wasm.TableInstance{References: make([]wasm.Reference, 20), Type: wasm.RefTypeExternref}
+	f := newTestFunction(t, m, withModuleInstance(&wasm.ModuleInstance{Tables: []*wasm.TableInstance{funcTable, externTable}}))
+
+	// externref is a raw pointer, so the table doesn't keep the referent alive: the host is responsible for that.
+	type hostObject struct{ name string }
+	obj := &hostObject{name: "wazevo"}
+	ref := uint64(uintptr(unsafe.Pointer(obj)))
+
+	_, err := f.Call(ctx, 5, 0, 0, ref)
+	require.NoError(t, err)
+	require.Equal(t, wasm.Reference(ref), externTable.References[5])
+
+	runtime.GC()
+	results, err := f.Call(ctx, 0, 5, 0, 0)
+	require.NoError(t, err)
+	got := uintptr(results[1])
+	require.Equal(t, "wazevo", (*(**hostObject)(unsafe.Pointer(&got))).name)
+	runtime.KeepAlive(obj)
+
+	// Writes by the host are visible to the guest, and vice versa.
+	funcTable.References[3] = 0xfeed
+	results, err = f.Call(ctx, 0, 3, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0xfeed), results[0])
+
+	_, err = f.Call(ctx, 20, 0, 0, 0)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeInvalidTableAccess)
+}
+
+func TestEngine_lazyCompilation(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0, 0, 0},
+		CodeSection: []wasm.Code{
+			// Calls the function[1] with the param plus one.
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+			// Doubles the param.
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Add, wasm.OpcodeEnd}},
+			// Never called.
+			{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}},
+		},
+	}
+
+	run := func(t *testing.T, lazy bool) (results []uint64, compiled []wasm.Index) {
+		f0 := newTestFunction(t, m, withConfig(Config{LazyCompilation: lazy}),
+			withCompileHook(func(index wasm.Index) { compiled = append(compiled, index) }))
+		f1 := f0.me.NewFunction(1)
+		// Neither the compilation nor the instantiation of the module compiles the functions.
+		if lazy {
+			require.Equal(t, 0, len(compiled))
+		}
+		for _, c := range []struct {
+			f     api.Function
+			param uint64
+		}{{f0, 10}, {f0, 20}, {f1, 5}} {
+			res, err := c.f.Call(ctx, c.param)
+			require.NoError(t, err)
+			results = append(results, res...)
+		}
+		return
+	}
+
+	expResults, compiled := run(t, false)
+	require.Equal(t, []uint64{22, 42, 10}, expResults)
+	require.Equal(t, []wasm.Index{0, 1, 2}, compiled)
+
+	results, compiled := run(t, true)
+	require.Equal(t, expResults, results)
+	// The function[1] is compiled along with its caller, and then reused by the direct call.
+	require.Equal(t, []wasm.Index{0, 1}, compiled)
+}
+
+func TestEngine_closeDuringLazyCompilation(t *testing.T) {
+	// The lazy compilation reserves the executable while holding the lock of lazyCompiledFunctions, so Close must not
+	// wait for that lock while holding the one of the engine. Close is started while the first call compiles.
+	m := testcases.FibonacciRecursive.Module
+	var f *testFunction
+	closeErr := make(chan error, 1)
+	f = newTestFunction(t, m, withConfig(Config{LazyCompilation: true}), withCompileHook(func(wasm.Index) {
+		go func() { closeErr <- f.e.Close() }()
+		// Waits until Close holds the lock of the engine, or has already released it.
+		for f.e.mux.TryRLock() {
+			closed := f.e.compiledModules == nil
+			f.e.mux.RUnlock()
+			if closed {
+				break
+			}
+			runtime.Gosched()
+		}
+	}))
+
+	type result struct {
+		results []uint64
+		err     error
+	}
+	called := make(chan result, 1)
+	go func() {
+		results, err := f.Call(ctx, 20)
+		called <- result{results, err}
+	}()
+
+	select {
+	case r := <-called:
+		require.NoError(t, r.err)
+		require.Equal(t, []uint64{6765}, r.results)
+	case <-time.After(10 * time.Second):
+		t.Fatal("deadlock between Close and the lazy compilation")
+	}
+	require.NoError(t, <-closeErr)
+
+	// The functions not compiled before Close can't be compiled anymore.
+	_, err := f.me.NewFunction(0).Call(ctx, 20)
+	require.EqualError(t, err, "engine closed")
+}
+
+func TestEngine_identicalFunctionBodies(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	double := []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Add, wasm.OpcodeEnd}
+	callDouble := []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeCall, 0, wasm.OpcodeEnd}
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0, 0, 0, 0, 0},
+		CodeSection:     []wasm.Code{{Body: double}, {Body: double}, {Body: callDouble}, {Body: double}, {Body: callDouble}},
+	}
+
+	run := func(t *testing.T, lazy bool) (results []uint64, compiled []wasm.Index) {
+		f := newTestFunction(t, m, withConfig(Config{LazyCompilation: lazy}),
+			withCompileHook(func(index wasm.Index) { compiled = append(compiled, index) }))
+
+		for i := range m.CodeSection {
+			res, err := f.me.NewFunction(wasm.Index(i)).Call(ctx, 10)
+			require.NoError(t, err)
+			results = append(results, res...)
+		}
+		return
+	}
+
+	results, compiled := run(t, false)
+	require.Equal(t, []uint64{20, 20, 22, 20, 22}, results)
+	// Only the first ones of the identical functions are compiled, and the rest reuse their machine code.
+	require.Equal(t, []wasm.Index{0, 2}, compiled)
+
+	// With the lazy compilation, each function is freshly compiled on its own as the entry of its unit.
+	freshResults, compiled := run(t, true)
+	require.Equal(t, freshResults, results)
+	require.Equal(t, []wasm.Index{0, 1, 2, 0, 3, 4, 0}, compiled)
+}
+
+func TestEngine_ssaGraphHook(t *testing.T) {
+	graphs := map[wasm.Index]*ssa.Graph{}
+	e := newEngine(ctx, api.CoreFeaturesV1, nil, Config{SSAGraphHook: func(index wasm.Index, g *ssa.Graph) { graphs[index] = g }})
+
+	m := testcases.LoopBrIf.Module
+	err := e.CompileModule(ctx, m, nil, false)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(graphs))
+	// The critical back-edge of the loop is split by the block layout.
+	require.Equal(t, 4, len(graphs[0].Blocks))
+	require.Equal(t, 4, len(graphs[0].Edges))
+}
+
+func TestEngine_selfRecursiveStack(t *testing.T) {
+	m := testcases.RecursiveSum.Module
+	run := func(t *testing.T, lazy bool, recursiveStackSize uint64) (results []uint64, grows int) {
+		defer func(size uint64) { recursiveInitialStackSize = size }(recursiveInitialStackSize)
+		recursiveInitialStackSize = recursiveStackSize
+
+		ce := newTestFunction(t, m, withConfig(Config{LazyCompilation: lazy})).Function.(*callEngine)
+		for _, n := range []uint64{10, 1000, 10000} {
+			res, err := ce.Call(ctx, n)
+			require.NoError(t, err)
+			results = append(results, res...)
+		}
+		require.True(t, ce.selfRecursive)
+		require.True(t, uint64(len(ce.stack)) >= recursiveStackSize)
+		return results, ce.growStackCount
+	}
+
+	expResults, expGrows := run(t, false, initialStackSize)
+	require.Equal(t, []uint64{55, 500500, 50005000}, expResults)
+	for _, lazy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("lazy=%v", lazy), func(t *testing.T) {
+			results, grows := run(t, lazy, recursiveInitialStackSize)
+			require.Equal(t, expResults, results)
+			require.True(t, grows < expGrows, "%d >= %d", grows, expGrows)
+		})
+	}
+}
+
+func TestEngine_crossCheck(t *testing.T) {
+	m := testcases.AddSubParamsReturn.Module
+	// The interpreter requires the cached numbers of params and results, which are cached on validation otherwise.
+	m.TypeSection[0].CacheNumInUint64()
+	f := newTestFunction(t, m, withConfig(Config{CrossCheck: true}), withModuleInstance(&wasm.ModuleInstance{TypeIDs: []wasm.FunctionTypeID{0}}))
+
+	results, err := f.Call(ctx, 3, 5)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{5}, results)
+
+	// Injects a fault by checking against the interpreter running i32.mul in place of i32.add.
+	faulty := testcases.SingleFunctionModule(m.TypeSection[0], []byte{
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeLocalGet, 1,
+		wasm.OpcodeI32Mul,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI32Sub,
+		wasm.OpcodeEnd,
+	}, nil)
+	interp := interpreter.NewEngine(ctx, api.CoreFeaturesV1, nil)
+	err = interp.CompileModule(ctx, faulty, nil, false)
+	require.NoError(t, err)
+	faultyInst := &wasm.ModuleInstance{Source: faulty, TypeIDs: []wasm.FunctionTypeID{0}}
+	faultyInst.Engine, err = interp.NewModuleEngine(faulty, faultyInst)
+	require.NoError(t, err)
+	f.me.crossCheck = faultyInst.Engine
+
+	_, err = f.me.NewFunction(0).Call(ctx, 3, 5)
+	require.EqualError(t, err, "cross-check failed on function[0] with params [0x3 0x5]: "+
+		"compiled code returned the results [0x5], but the interpreter returned [0xc]")
+}
+
+func TestEngine_crossCheck_nearest(t *testing.T) {
+	m := testcases.FloatRounding.Module
+	m.TypeSection[0].CacheNumInUint64()
+	f := newTestFunction(t, m, withConfig(Config{CrossCheck: true}), withModuleInstance(&wasm.ModuleInstance{TypeIDs: []wasm.FunctionTypeID{0}}))
+
+	// The ties are rounded to even unlike C's round(), and the calls fail if the interpreter disagrees.
+	for _, tc := range []struct{ x, exp float64 }{{x: 0.5, exp: 0}, {x: 1.5, exp: 2}, {x: 2.5, exp: 2}} {
+		results, err := f.Call(ctx, uint64(math.Float32bits(float32(tc.x))), math.Float64bits(tc.x))
+		require.NoError(t, err)
+		require.Equal(t, uint64(math.Float32bits(float32(tc.exp))), results[3])
+		require.Equal(t, math.Float64bits(tc.exp), results[7])
+	}
+}
+
+func TestEngine_unoptimizedFunctions(t *testing.T) {
+	// The two functions are the same, and most of their extensions are eliminated by the optimization passes.
+	chains := testcases.IntegerExtensionChains.Module
+	m := &wasm.Module{
+		TypeSection:     chains.TypeSection,
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection:     []wasm.Code{chains.CodeSection[0], chains.CodeSection[0]},
+	}
+
+	run := func(t *testing.T, unoptimized map[wasm.Index]struct{}) (results []uint64, executableSize int) {
+		f := newTestFunction(t, m, withConfig(Config{UnoptimizedFunctions: unoptimized}))
+
+		for i := wasm.Index(0); i < 2; i++ {
+			res, err := f.me.NewFunction(i).Call(ctx, 0x80, 0x8080)
+			require.NoError(t, err)
+			results = append(results, res...)
+		}
+		return results, len(f.e.compiledModules[m.ID].executable)
+	}
+
+	expResults, optimizedSize := run(t, nil)
+	// Only the function[1] is compiled without the optimizations, and produces the same results.
+	results, size := run(t, map[wasm.Index]struct{}{1: {}})
+	require.Equal(t, expResults, results)
+	require.True(t, size > optimizedSize)
+}
+
+func TestEngine_regions(t *testing.T) {
+	// The huge function repeats the step local[1] = local[1]*3 + local[0], and returns local[1].
+	step := []byte{
+		wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Const, 3, wasm.OpcodeI32Mul,
+		wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Add, wasm.OpcodeLocalSet, 1,
+	}
+	var body []byte
+	for i := 0; i < 1000; i++ {
+		body = append(body, step...)
+	}
+	body = append(body, wasm.OpcodeLocalGet, 1, wasm.OpcodeEnd)
+	i32 := wasm.ValueTypeI32
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: body, LocalTypes: []wasm.ValueType{i32}}},
+	}
+
+	run := func(t *testing.T, regionSize int) (results []uint64, graphs int, maxInstructions int) {
+		hook := func(_ wasm.Index, g *ssa.Graph) {
+			graphs++
+			instructions := 0
+			for _, blk := range g.Blocks {
+				instructions += len(blk.Instructions)
+			}
+			if instructions > maxInstructions {
+				maxInstructions = instructions
+			}
+		}
+		f := newTestFunction(t, m, withConfig(Config{RegionSize: regionSize, SSAGraphHook: hook}))
+
+		for _, arg := range []uint64{0, 1, 12345} {
+			res, err := f.Call(ctx, arg)
+			require.NoError(t, err)
+			results = append(results, res...)
+		}
+		return
+	}
+
+	expResults, graphs, wholeInstructions := run(t, 0)
+	require.Equal(t, 1, graphs)
+	// The regions of 1000 bytes produce the same results, and each SSA function is a fraction of the whole one.
+	results, graphs, maxInstructions := run(t, 1000)
+	require.Equal(t, expResults, results)
+	require.Equal(t, 12, graphs) // The driver and the 11 regions.
+	require.True(t, maxInstructions*5 < wholeInstructions)
+}
+
+func TestEngine_executableBudget(t *testing.T) {
+	e, ok := NewEngine(ctx, api.CoreFeaturesV1, nil).(*engine)
+	require.True(t, ok)
+
+	newModule := func(id byte) *wasm.Module {
+		return &wasm.Module{
+			ID:              wasm.ModuleID{id},
+			TypeSection:     []wasm.FunctionType{{}},
+			FunctionSection: []wasm.Index{0},
+			CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+			ExportSection:   []wasm.Export{{Name: "f", Index: 0, Type: wasm.ExternTypeFunc}},
+		}
+	}
+
+	// Compile the first module without the budget to know the size of a module.
+	first := newModule(0)
+	err := e.CompileModule(ctx, first, nil, false)
+	require.NoError(t, err)
+	size := len(e.compiledModules[first.ID].executable)
+	require.Equal(t, size, e.executableSize)
+
+	// Compiling the same module again replaces the previous one, so it doesn't consume the budget twice.
+	err = e.CompileModule(ctx, first, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, size, e.executableSize)
+	require.Equal(t, uint32(1), e.CompiledModuleCount())
+
+	// Allows up to three modules in total.
+	e.executableBudget = size*3 + size/2
+	for i := byte(1); i < 3; i++ {
+		err = e.CompileModule(ctx, newModule(i), nil, false)
+		require.NoError(t, err)
+	}
+	err = e.CompileModule(ctx, newModule(3), nil, false)
+	require.EqualError(t, err, fmt.Sprintf("compiled code size exceeds the budget: %d (current) + %d (new) > %d (budget)",
+		size*3, size, e.executableBudget))
+	require.Equal(t, uint32(3), e.CompiledModuleCount())
+
+	// Deleting a compiled module frees up the budget.
+	e.DeleteCompiledModule(first)
+	require.Equal(t, size*2, e.executableSize)
+	err = e.CompileModule(ctx, newModule(3), nil, false)
+	require.NoError(t, err)
+	require.Equal(t, size*3, e.executableSize)
+
+	// Closing the engine frees up the whole budget.
+	require.NoError(t, e.Close())
+	require.Equal(t, 0, e.executableSize)
+}
+
+func TestCallEngine_Call_noParamsNoResults(t *testing.T) {
+	m := testcases.Empty.Module
+	f := newTestFunction(t, m)
+
+	for i := 0; i < 1000; i++ {
+		results, err := f.Call(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0, len(results))
+
+		// nil stack must be accepted as well.
+		err = f.CallWithStack(ctx, nil)
+		require.NoError(t, err)
+	}
+}
+
+func TestCallEngine_CallWithStack_invalidExitCode(t *testing.T) {
+	m := testcases.Empty.Module
+	f := newTestFunction(t, m)
+
+	// The normal return doesn't write the exit code, so the corrupted one is seen after the execution.
+	ce := f.Function.(*callEngine)
+	ce.execCtx.exitCode = 0xabcdef
+	err := ce.CallWithStack(ctx, nil)
+	require.EqualError(t, err, "invalid exit code: 0xabcdef")
+}
+
+func TestCallEngine_CallWithStack_integerTraps(t *testing.T) {
+	m := testcases.Empty.Module
+	f := newTestFunction(t, m)
+
+	// Same as TestCallEngine_CallWithStack_invalidExitCode, the exit code is seen after the normal return.
+	for _, tc := range []struct {
+		exitCode wazevoapi.ExitCode
+		expErr   error
+	}{
+		{exitCode: wazevoapi.ExitCodeIntegerDivisionByZero, expErr: wasmruntime.ErrRuntimeIntegerDivideByZero},
+		{exitCode: wazevoapi.ExitCodeIntegerOverflow, expErr: wasmruntime.ErrRuntimeIntegerOverflow},
+		{exitCode: wazevoapi.ExitCodeInvalidConversionToInteger, expErr: wasmruntime.ErrRuntimeInvalidConversionToInteger},
+	} {
+		ce := f.me.NewFunction(0).(*callEngine)
+		ce.execCtx.exitCode = tc.exitCode
+		err := ce.CallWithStack(ctx, nil)
+		require.ErrorIs(t, err, tc.expErr)
+		require.EqualError(t, err, "wasm error: "+tc.expErr.Error()+"\nwasm stack trace:\n\t.$0()")
+	}
+}
+
+func TestCallEngine_CallWithStack_memoryOutOfBounds(t *testing.T) {
+	m := testcases.Empty.Module
+	f := newTestFunction(t, m)
+
+	// Same as TestCallEngine_CallWithStack_invalidExitCode, the exit code is seen after the normal return.
+	for _, tc := range []struct {
+		exitCode  wazevoapi.ExitCode
+		exitValue uint64
+		expErr    string
+	}{
+		{
+			exitCode: wazevoapi.ExitCodeMemoryOutOfBoundsWithSize(2), exitValue: 0x10001,
+			expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0()\n\t\t2-byte memory access at address 0xffff",
+		},
+		{
+			exitCode: wazevoapi.ExitCodeMemoryOutOfBoundsWithSize(8), exitValue: 0x100000007,
+			expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0()\n\t\t8-byte memory access at address 0xffffffff",
+		},
+		{
+			// The check extended to the 8-byte access reports it along with the earlier ones.
+			exitCode: wazevoapi.ExitCodeMemoryOutOfBoundsExtended(8), exitValue: 0x10008,
+			expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0()\n\t\t8-byte memory access at address 0x10000 or an earlier access checked together with it",
+		},
+		{
+			// The size is unknown, so is the address.
+			exitCode: wazevoapi.ExitCodeMemoryOutOfBounds, exitValue: 0x10001,
+			expErr: "wasm error: out of bounds memory access\nwasm stack trace:\n\t.$0()",
+		},
+	} {
+		ce := f.me.NewFunction(0).(*callEngine)
+		ce.execCtx.exitCode = tc.exitCode
+		ce.execCtx.exitValue = tc.exitValue
+		err := ce.CallWithStack(ctx, nil)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+		require.EqualError(t, err, tc.expErr)
+	}
+}
+
+func TestModuleEngine_SetTrapHandler(t *testing.T) {
+	m := testcases.MemoryLoadBasic.Module
+	f := newTestFunction(t, m, withMemory())
+
+	errRecoverable := errors.New("recoverable out of bounds memory access")
+	var handled error
+	f.me.SetTrapHandler(wazevoapi.ExitCodeMemoryOutOfBounds, func(_ context.Context, code wazevoapi.ExitCode, err error) error {
+		require.Equal(t, wazevoapi.ExitCodeMemoryOutOfBounds, code)
+		handled = err
+		return errRecoverable
+	})
+
+	// The accesses in bounds are not affected.
+	results, err := f.Call(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(results))
+	require.Nil(t, handled)
+
+	// The handler gets the standard error, and its error is surfaced instead.
+	_, err = f.Call(ctx, uint64(wasm.MemoryPageSize))
+	require.Equal(t, errRecoverable, err)
+	require.ErrorIs(t, handled, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+
+	// The standard error is surfaced again once the handler is unregistered.
+	f.me.SetTrapHandler(wazevoapi.ExitCodeMemoryOutOfBounds, nil)
+	_, err = f.Call(ctx, uint64(wasm.MemoryPageSize))
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+}
+
+func TestCallEngine_reusableAfterTrap(t *testing.T) {
+	m := testcases.MemoryLoadBasic.Module
+	mem := wasm.NewMemoryInstance(m.MemorySection)
+	binary.LittleEndian.PutUint32(mem.Buffer, 0xdeadbeef)
+	f := newTestFunction(t, m, withModuleInstance(&wasm.ModuleInstance{MemoryInstance: mem}))
+	ce := f.Function.(*callEngine)
+
+	for i := 0; i < 2; i++ {
+		_, err := ce.Call(ctx, uint64(wasm.MemoryPageSize))
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+		require.Equal(t, executionContext{stackBottomPtr: &ce.stack[0]}, ce.execCtx)
+
+		results, err := ce.Call(ctx, 0)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{0xdeadbeef}, results)
+	}
+}
+
+func TestModuleEngine_NewFunctionPool(t *testing.T) {
+	m := testcases.FibonacciRecursive.Module
+	f := newTestFunction(t, m)
+
+	pool := f.me.NewFunctionPool(0)
+	fib := []uint64{0, 1, 1, 2, 3, 5, 8, 13, 21, 34, 55, 89, 144, 233, 377, 610, 987, 1597, 2584, 4181, 6765}
+
+	const goroutines, calls = 50, 200
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < calls; j++ {
+				// Each goroutine calls with different params to detect the results of others.
+				n := (i + j) % len(fib)
+				results, err := pool.Call(ctx, uint64(n))
+				if err != nil {
+					errs <- err
+					return
+				}
+				if results[0] != fib[n] {
+					errs <- fmt.Errorf("fib(%d) = %d, want %d", n, results[0], fib[n])
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func TestModuleEngine_NewFunctionByName(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Sub, wasm.OpcodeEnd}},
+		},
+		MemorySection: &wasm.Memory{Min: 1, Cap: 1},
+		ExportSection: []wasm.Export{
+			{Name: "add", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "sub", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+		},
+	}
+
+	f := newTestFunction(t, m, withModuleInstance(&wasm.ModuleInstance{ModuleName: "test", MemoryInstance: wasm.NewMemoryInstance(m.MemorySection)}))
+	mod := f.me
+
+	for _, tc := range []struct {
+		name      string
+		expResult uint64
+	}{
+		{name: "add", expResult: 15},
+		{name: "sub", expResult: 5},
+	} {
+		f, err := mod.NewFunctionByName(tc.name)
+		require.NoError(t, err)
+		results, err := f.Call(ctx, 10, 5)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{tc.expResult}, results)
+	}
+
+	_, err := mod.NewFunctionByName("mul")
+	require.EqualError(t, err, `"mul" is not exported in module "test"`)
+	_, err = mod.NewFunctionByName("memory")
+	require.EqualError(t, err, `export "memory" in module "test" is a memory, not a func`)
+}
+
+func TestCallEngine_Call_reusedParamResultSlice(t *testing.T) {
+	m := testcases.SwapParamAndReturn.Module
+	f := newTestFunction(t, m)
+
+	first, err := f.Call(ctx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2, 1}, first)
+
+	// Appending to the results must not write into the reused slice.
+	appended := append(first, 3)
+	require.Equal(t, []uint64{2, 1, 3}, appended)
+
+	second, err := f.Call(ctx, 3, 4)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{4, 3}, second)
+	// The results of the previous invocation are overwritten as documented on api.Function.
+	require.Equal(t, []uint64{4, 3}, first)
+
+	// CallWithStack uses the given slice instead of the reused one.
+	stack := []uint64{5, 6}
+	err = f.CallWithStack(ctx, stack)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{6, 5}, stack)
+	require.Equal(t, []uint64{4, 3}, second)
+}
+
+func TestCallEngine_Call_allocs(t *testing.T) {
+	m := testcases.SwapParamAndReturn.Module
+	f := newTestFunction(t, m)
+
+	// The params are passed as a slice since the variadic ones would be allocated by the caller.
+	params := []uint64{1, 2}
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := f.Call(ctx, params...); err != nil {
+			t.Fatal(err)
+		}
+	})
+	require.Equal(t, float64(0), allocs)
+}
+
+func TestCallEngine_Call_resultsTrimmed(t *testing.T) {
+	i64 := wasm.ValueTypeI64
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{i64, i64, i64}, Results: []wasm.ValueType{i64}}, []byte{
+		wasm.OpcodeLocalGet, 1,
+		wasm.OpcodeEnd,
+	}, nil)
+	f := newTestFunction(t, m)
+
+	results, err := f.Call(ctx, 1, 2, 3)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(results))
+	require.Equal(t, []uint64{2}, results)
+}
+
+func BenchmarkCallEngine_Call(b *testing.B) {
+	m := testcases.SwapParamAndReturn.Module
+	f := newTestFunction(b, m)
+
+	params := []uint64{1, 2}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Call(ctx, params...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEngine_structRead(b *testing.B) {
+	// The bounds checks of the two adjacent loads are combined into one.
+	m := testcases.MemoryStructRead.Module
+	f := newTestFunction(b, m, withMemory())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Call(ctx, 16); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEngine_sameBaseLoads(b *testing.B) {
+	// The bounds checks of the interleaved loads are hoisted into the first one of each base.
+	m := testcases.MemorySameBaseLoads.Module
+	f := newTestFunction(b, m, withMemory())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Call(ctx, 16, 256); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEngine_tailReturns(b *testing.B) {
+	// Both paths end with the explicit return, which is lowered into the single ret without the jump to the end.
+	m := testcases.TailReturns.Module
+	f := newTestFunction(b, m)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Call(ctx, uint64(i&1), 2); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEngine_integerExtensionChains(b *testing.B) {
+	// Most of the extensions are either eliminated or folded into the single one.
+	m := testcases.IntegerExtensionChains.Module
+	f := newTestFunction(b, m)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Call(ctx, 0x80, 0x8080); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEngine_constAddressMemoryAccesses(b *testing.B) {
+	// The constant addresses are folded into the immediate offsets of the loads and stores.
+	m := testcases.ConstAddressMemoryAccesses.Module
+	f := newTestFunction(b, m, withMemory())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Call(ctx, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEngine_tableConstIndices(b *testing.B) {
+	// The bounds checks of the table accesses are elided except the one beyond the minimum size of the table.
+	m := testcases.TableConstIndices.Module
+	f := newTestFunction(b, m, withModuleInstance(&wasm.ModuleInstance{Tables: []*wasm.TableInstance{{
+		References: make([]wasm.Reference, m.TableSection[0].Min), Min: m.TableSection[0].Min, Type: m.TableSection[0].Type,
+	}}}))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Call(ctx, 0, 0xdead); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEngine_selfRecursiveStack(b *testing.B) {
+	m := testcases.RecursiveSum.Module
+	for _, tc := range []struct {
+		name               string
+		recursiveStackSize uint64
+	}{
+		{name: "initial", recursiveStackSize: initialStackSize},
+		{name: "pre-grown", recursiveStackSize: recursiveInitialStackSize},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			defer func(size uint64) { recursiveInitialStackSize = size }(recursiveInitialStackSize)
+			recursiveInitialStackSize = tc.recursiveStackSize
+
+			f := newTestFunction(b, m)
+
+			// Each iteration starts with a fresh stack as the first call of a function does.
+			grows := 0
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ce := f.me.NewFunction(0).(*callEngine)
+				if _, err := ce.Call(ctx, 1000); err != nil {
+					b.Fatal(err)
+				}
+				grows += ce.growStackCount
+			}
+			b.ReportMetric(float64(grows)/float64(b.N), "grows/op")
+		})
+	}
+}
+
+func BenchmarkEngine_sharedTrapBlocks(b *testing.B) {
+	// Each load has its own bounds check since the addresses are the different values.
+	const loads = 64
+	i32 := wasm.ValueTypeI32
+	body := []byte{wasm.OpcodeI32Const, 0}
+	for i := 0; i < loads; i++ {
+		body = append(body, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, byte(i), wasm.OpcodeI32Add,
+			wasm.OpcodeI32Load, 0x2, 0x0, wasm.OpcodeI32Add)
+	}
+	body = append(body, wasm.OpcodeEnd)
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}, body, nil)
+	m.MemorySection = &wasm.Memory{Min: 1}
+
+	for _, shared := range []bool{false, true} {
+		b.Run(fmt.Sprintf("shared=%v", shared), func(b *testing.B) {
+			var size int
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				e := newEngine(ctx, api.CoreFeaturesV1, nil, Config{SharedTrapBlocks: shared})
+				if err := e.CompileModule(ctx, m, nil, false); err != nil {
+					b.Fatal(err)
+				}
+				size = e.executableSize
+			}
+			// The size of the executable is what the shared trap blocks reduce.
+			b.ReportMetric(float64(size), "bytes")
+		})
+	}
+}
+
+func BenchmarkEngine_repeatedFloatConsts(b *testing.B) {
+	// The same f64.const in the block is defined once in SSA.
+	const uses = 256
+	f64 := wasm.ValueTypeF64
+	body := []byte{wasm.OpcodeLocalGet, 0}
+	for i := 0; i < uses; i++ {
+		body = append(body, wasm.OpcodeF64Const, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f, wasm.OpcodeF64Mul) // 1.5
+	}
+	body = append(body, wasm.OpcodeEnd)
+	m := testcases.SingleFunctionModule(wasm.FunctionType{Params: []wasm.ValueType{f64}, Results: []wasm.ValueType{f64}}, body, nil)
+
+	var instructions int
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e := newEngine(ctx, api.CoreFeaturesV1, nil, Config{OpcodeStats: true})
+		if err := e.CompileModule(ctx, m, nil, false); err != nil {
+			b.Fatal(err)
+		}
+		stats, _ := e.OpcodeStats(m)
+		instructions = stats.Instructions
+	}
+	b.ReportMetric(float64(instructions), "ssa-instructions")
+}
+
+func BenchmarkEngine_compileIdenticalFunctions(b *testing.B) {
+	const functions = 1000
+	i32 := wasm.ValueTypeI32
+	for _, tc := range []struct {
+		name      string
+		identical bool
+	}{
+		{name: "distinct", identical: false},
+		{name: "identical", identical: true},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			m := &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+				FunctionSection: make([]wasm.Index, functions),
+				CodeSection:     make([]wasm.Code, functions),
+			}
+			for i := range m.CodeSection {
+				c := int32(1)
+				if !tc.identical {
+					c = int32(i)
+				}
+				body := []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const}
+				body = append(body, leb128.EncodeInt32(c)...)
+				body = append(body, wasm.OpcodeI32Mul, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Add, wasm.OpcodeEnd)
+				m.CodeSection[i] = wasm.Code{Body: body}
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				e := NewEngine(ctx, api.CoreFeaturesV1, nil)
+				if err := e.CompileModule(ctx, m, nil, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
 }
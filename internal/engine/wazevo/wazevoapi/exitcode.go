@@ -8,11 +8,79 @@ const (
 	ExitCodeGrowStack
 	ExitCodeUnreachable
 	ExitCodeMemoryOutOfBounds
+	ExitCodeUnalignedMemoryAccess
+	// ExitCodeCallGoModuleFunction and ExitCodeCallGoFunction are reserved for the trampolines calling the Go functions
+	// of host modules, which are not compiled yet.
+	ExitCodeCallGoModuleFunction
+	ExitCodeCallGoFunction
+	ExitCodeTableOutOfBounds
+	// ExitCodeIntegerDivisionByZero is raised by the integer division or remainder whose divisor is zero.
+	ExitCodeIntegerDivisionByZero
+	// ExitCodeIntegerOverflow is raised by the signed integer division of the minimum value by -1, whose result
+	// doesn't fit in the type. This is distinct from ExitCodeIntegerDivisionByZero as they are different traps
+	// in the spec, and the division must check the divisor against zero first.
+	ExitCodeIntegerOverflow
+	// ExitCodeInvalidConversionToInteger is raised by the trapping float-to-int conversion of NaN. The conversion of
+	// the value out of the range of the integer type raises ExitCodeIntegerOverflow instead as the spec defines.
+	ExitCodeInvalidConversionToInteger
+
+	// ExitCodeMask is the mask to extract the ExitCode from the value written by the machine code, whose upper bits
+	// might hold the operand of the exit, e.g. the size of the access for ExitCodeMemoryOutOfBounds.
+	ExitCodeMask ExitCode = 0xff
 )
 
+// exitCodeOperandLimit is the exclusive upper limit of the operand encoded in the upper bits of ExitCode.
+const exitCodeOperandLimit = 1 << 24
+
+// ExitCodeUnreachableWithOffset returns the ExitCode for ExitCodeUnreachable raised by the unreachable instruction
+// at the given byte offset in the function body. The offset is not encoded if it doesn't fit in the upper bits.
+func ExitCodeUnreachableWithOffset(offset int) ExitCode {
+	// The offset is encoded with 1 added so that zero means the offset is unknown.
+	if offset+1 >= exitCodeOperandLimit {
+		return ExitCodeUnreachable
+	}
+	return ExitCodeUnreachable | ExitCode((offset+1)<<8)
+}
+
+// UnreachableOffsetFromExitCode returns the offset encoded by ExitCodeUnreachableWithOffset, and false if it is
+// unknown.
+func UnreachableOffsetFromExitCode(exitCode ExitCode) (offset int, ok bool) {
+	operand := int(exitCode >> 8)
+	return operand - 1, operand > 0
+}
+
+// ExitCodeMemoryOutOfBoundsWithSize returns the ExitCode for ExitCodeMemoryOutOfBounds raised by the bounds check of
+// the memory access of `size` bytes. The machine code also writes the end of the accessed range, i.e. the effective
+// address plus size, into the execution context, so that the faulting address can be reported.
+func ExitCodeMemoryOutOfBoundsWithSize(size uint32) ExitCode {
+	return ExitCodeMemoryOutOfBounds | ExitCode(size<<8)
+}
+
+// ExitCodeMemoryOutOfBoundsExtended is the same as ExitCodeMemoryOutOfBoundsWithSize, but for the bounds check which
+// is extended to the access of `size` bytes from the earlier accesses. The reported access is the one reaching the
+// extended range, while the earlier accesses might be out of bounds as well.
+func ExitCodeMemoryOutOfBoundsExtended(size uint32) ExitCode {
+	return ExitCodeMemoryOutOfBoundsWithSize(size) | exitCodeBoundsCheckExtended
+}
+
+// exitCodeBoundsCheckExtended is the flag of ExitCodeMemoryOutOfBoundsExtended, which is the highest bit of the operand.
+const exitCodeBoundsCheckExtended ExitCode = exitCodeOperandLimit >> 1
+
+// MemoryAccessSizeFromExitCode returns the size encoded by ExitCodeMemoryOutOfBoundsWithSize or
+// ExitCodeMemoryOutOfBoundsExtended, and false if it is unknown.
+func MemoryAccessSizeFromExitCode(exitCode ExitCode) (size uint32, ok bool) {
+	size = uint32((exitCode &^ exitCodeBoundsCheckExtended) >> 8)
+	return size, size > 0
+}
+
+// BoundsCheckExtendedFromExitCode returns true if the exit code is the one of ExitCodeMemoryOutOfBoundsExtended.
+func BoundsCheckExtendedFromExitCode(exitCode ExitCode) bool {
+	return exitCode&ExitCodeMask == ExitCodeMemoryOutOfBounds && exitCode&exitCodeBoundsCheckExtended != 0
+}
+
 // String implements fmt.Stringer.
 func (e ExitCode) String() string {
-	switch e {
+	switch e & ExitCodeMask {
 	case ExitCodeOK:
 		return "ok"
 	case ExitCodeGrowStack:
@@ -21,6 +89,20 @@ func (e ExitCode) String() string {
 		return "unreachable"
 	case ExitCodeMemoryOutOfBounds:
 		return "memory_out_of_bounds"
+	case ExitCodeUnalignedMemoryAccess:
+		return "unaligned_memory_access"
+	case ExitCodeCallGoModuleFunction:
+		return "call_go_module_function"
+	case ExitCodeCallGoFunction:
+		return "call_go_function"
+	case ExitCodeTableOutOfBounds:
+		return "table_out_of_bounds"
+	case ExitCodeIntegerDivisionByZero:
+		return "integer_division_by_zero"
+	case ExitCodeIntegerOverflow:
+		return "integer_overflow"
+	case ExitCodeInvalidConversionToInteger:
+		return "invalid_conversion_to_integer"
 	}
 	panic("TODO")
 }
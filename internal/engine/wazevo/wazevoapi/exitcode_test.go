@@ -0,0 +1,53 @@
+package wazevoapi
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestExitCodeUnreachableWithOffset(t *testing.T) {
+	for _, offset := range []int{0, 1, 0xffff, exitCodeOperandLimit - 2} {
+		ec := ExitCodeUnreachableWithOffset(offset)
+		require.Equal(t, ExitCodeUnreachable, ec&ExitCodeMask)
+		actual, ok := UnreachableOffsetFromExitCode(ec)
+		require.True(t, ok)
+		require.Equal(t, offset, actual)
+	}
+
+	// Neither the plain exit code nor the one with too large an offset knows the offset.
+	for _, ec := range []ExitCode{ExitCodeUnreachable, ExitCodeUnreachableWithOffset(exitCodeOperandLimit - 1)} {
+		require.Equal(t, ExitCodeUnreachable, ec)
+		_, ok := UnreachableOffsetFromExitCode(ec)
+		require.False(t, ok)
+	}
+}
+
+func TestExitCodeMemoryOutOfBoundsWithSize(t *testing.T) {
+	for _, size := range []uint32{1, 2, 4, 8, 16} {
+		for _, extended := range []bool{false, true} {
+			ec := ExitCodeMemoryOutOfBoundsWithSize(size)
+			if extended {
+				ec = ExitCodeMemoryOutOfBoundsExtended(size)
+			}
+			require.Equal(t, ExitCodeMemoryOutOfBounds, ec&ExitCodeMask)
+			actual, ok := MemoryAccessSizeFromExitCode(ec)
+			require.True(t, ok)
+			require.Equal(t, size, actual)
+			require.Equal(t, extended, BoundsCheckExtendedFromExitCode(ec))
+		}
+	}
+
+	_, ok := MemoryAccessSizeFromExitCode(ExitCodeMemoryOutOfBounds)
+	require.False(t, ok)
+	require.False(t, BoundsCheckExtendedFromExitCode(ExitCodeMemoryOutOfBounds))
+}
+
+func TestExitCode_String(t *testing.T) {
+	// The integer traps are distinct from each other since the spec defines them as the different traps.
+	require.Equal(t, "integer_division_by_zero", ExitCodeIntegerDivisionByZero.String())
+	require.Equal(t, "integer_overflow", ExitCodeIntegerOverflow.String())
+	require.Equal(t, "invalid_conversion_to_integer", ExitCodeInvalidConversionToInteger.String())
+	require.NotEqual(t, ExitCodeIntegerDivisionByZero, ExitCodeIntegerOverflow)
+	require.NotEqual(t, ExitCodeInvalidConversionToInteger, ExitCodeIntegerOverflow)
+}
@@ -13,8 +13,13 @@ var ExecutionContextOffsets = ExecutionContextOffsetData{
 	StackPointerBeforeGrow: 56,
 	StackGrowRequiredSize:  64,
 	SavedRegistersBegin:    80,
+	ExitValue:              1104,
 }
 
+// SavedRegistersSlots is the number of the 16-byte slots of `savedRegisters` field in wazevo.executionContext.
+// Each saved register occupies one slot regardless of its type, so that the vector registers are saved in full.
+const SavedRegistersSlots = 64
+
 // ExecutionContextOffsetData allows the compilers to get the information about offsets to the fields of wazevo.executionContext,
 // which are necessary for compiling various instructions. This is globally unique.
 type ExecutionContextOffsetData struct {
@@ -38,6 +43,8 @@ type ExecutionContextOffsetData struct {
 	StackGrowRequiredSize Offset
 	// GoCallReturnAddress is an offset of the first element of `savedRegisters` field in wazevo.executionContext
 	SavedRegistersBegin Offset
+	// ExitValue is an offset of `exitValue` field in wazevo.executionContext
+	ExitValue Offset
 }
 
 // ModuleContextOffsetData allows the compilers to get the information about offsets to the fields of wazevo.moduleContextOpaque,
@@ -45,6 +52,18 @@ type ExecutionContextOffsetData struct {
 type ModuleContextOffsetData struct {
 	TotalSize                                                     int
 	LocalMemoryBegin, ImportedMemoryBegin, ImportedFunctionsBegin Offset
+	// TablesBegin is the offset of the pointers to the wasm.TableInstance of all tables including the imported ones,
+	// or -1 if the module has no table. See TableOffset.
+	TablesBegin Offset
+	// GlobalsBegin is the offset of the pointers to the wasm.GlobalInstance of all globals including the imported ones,
+	// or -1 if the module has no global. See GlobalOffset.
+	GlobalsBegin Offset
+	// CoverageBufferBegin is the offset of the pointer to the coverage counters, or -1 if the coverage is disabled.
+	// See AllocateCoverageBuffer.
+	CoverageBufferBegin Offset
+	// LoopCounterBufferBegin is the offset of the pointer to the loop back-edge counters, or -1 if the loop profiling is disabled.
+	// See AllocateLoopCounterBuffer.
+	LoopCounterBufferBegin Offset
 }
 
 func (m *ModuleContextOffsetData) ImportedFunctionOffset(i wasm.Index) (ptr, moduleCtx Offset) {
@@ -52,6 +71,30 @@ func (m *ModuleContextOffsetData) ImportedFunctionOffset(i wasm.Index) (ptr, mod
 	return base, base + 8
 }
 
+// TableOffset returns the offset of the pointer to the wasm.TableInstance of the given table index.
+func (m *ModuleContextOffsetData) TableOffset(tableIndex wasm.Index) Offset {
+	return m.TablesBegin + Offset(tableIndex)*8
+}
+
+// GlobalOffset returns the offset of the pointer to the wasm.GlobalInstance of the given global index.
+//
+// Note: the imported globals live in the module instances exporting them, so all globals are reached through the
+// pointers rather than embedded in moduleContextOpaque. This way, imported and local globals are accessed in the same way.
+func (m *ModuleContextOffsetData) GlobalOffset(globalIndex wasm.Index) Offset {
+	return m.GlobalsBegin + Offset(globalIndex)*8
+}
+
+// GlobalInstanceValueOffset is the offset of the value of wasm.GlobalInstance. 32-bit values are held in the lower bits.
+const GlobalInstanceValueOffset Offset = 8
+
+// TableInstanceBaseAddressOffset is the offset of the base address of wasm.TableInstance References, and
+// TableInstanceLenOffset is the offset of its length. These are the fields of the slice header at the beginning of
+// wasm.TableInstance.
+const (
+	TableInstanceBaseAddressOffset Offset = 0
+	TableInstanceLenOffset         Offset = 8
+)
+
 // Offset represents an offset of a field of a struct.
 type Offset int32
 
@@ -108,9 +151,50 @@ func NewModuleContextOffsetData(m *wasm.Module) ModuleContextOffsetData {
 	if m.ImportFunctionCount > 0 {
 		ret.ImportedFunctionsBegin = offset
 		// Each function consists of the pointer to the executable and the pointer to its moduleContextOpaque (16 bytes).
-		ret.TotalSize += int(m.ImportFunctionCount) * 16
+		size := int(m.ImportFunctionCount) * 16
+		offset += Offset(size)
+		ret.TotalSize += size
 	} else {
 		ret.ImportedFunctionsBegin = -1
 	}
+
+	if tables := int(m.ImportTableCount) + len(m.TableSection); tables > 0 {
+		ret.TablesBegin = offset
+		// Each table is the pointer to its *wasm.TableInstance.
+		size := tables * 8
+		offset += Offset(size)
+		ret.TotalSize += size
+	} else {
+		ret.TablesBegin = -1
+	}
+
+	if globals := int(m.ImportGlobalCount) + len(m.GlobalSection); globals > 0 {
+		ret.GlobalsBegin = offset
+		// Each global is the pointer to its *wasm.GlobalInstance.
+		size := globals * 8
+		offset += Offset(size)
+		ret.TotalSize += size
+	} else {
+		ret.GlobalsBegin = -1
+	}
+
+	// Coverage is opt-in via AllocateCoverageBuffer.
+	ret.CoverageBufferBegin = -1
+	// Loop profiling is opt-in via AllocateLoopCounterBuffer.
+	ret.LoopCounterBufferBegin = -1
 	return ret
 }
+
+// AllocateCoverageBuffer appends the pointer to the coverage counters at the end of moduleContextOpaque.
+// The counters are indexed by the coverage counter index assigned by the frontend for each basic block.
+func (m *ModuleContextOffsetData) AllocateCoverageBuffer() {
+	m.CoverageBufferBegin = Offset(m.TotalSize)
+	m.TotalSize += 8
+}
+
+// AllocateLoopCounterBuffer appends the pointer to the loop back-edge counters at the end of moduleContextOpaque.
+// The counters are indexed by the loop ID assigned by the frontend for each loop.
+func (m *ModuleContextOffsetData) AllocateLoopCounterBuffer() {
+	m.LoopCounterBufferBegin = Offset(m.TotalSize)
+	m.TotalSize += 8
+}
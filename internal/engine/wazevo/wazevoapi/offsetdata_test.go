@@ -2,6 +2,7 @@ package wazevoapi
 
 import (
 	"testing"
+	"unsafe"
 
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -20,6 +21,10 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				LocalMemoryBegin:       -1,
 				ImportedMemoryBegin:    -1,
 				ImportedFunctionsBegin: -1,
+				TablesBegin:            -1,
+				GlobalsBegin:           -1,
+				CoverageBufferBegin:    -1,
+				LoopCounterBufferBegin: -1,
 				TotalSize:              0,
 			},
 		},
@@ -30,6 +35,10 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				LocalMemoryBegin:       0,
 				ImportedMemoryBegin:    -1,
 				ImportedFunctionsBegin: -1,
+				TablesBegin:            -1,
+				GlobalsBegin:           -1,
+				CoverageBufferBegin:    -1,
+				LoopCounterBufferBegin: -1,
 				TotalSize:              16,
 			},
 		},
@@ -40,6 +49,10 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				LocalMemoryBegin:       -1,
 				ImportedMemoryBegin:    0,
 				ImportedFunctionsBegin: -1,
+				TablesBegin:            -1,
+				GlobalsBegin:           -1,
+				CoverageBufferBegin:    -1,
+				LoopCounterBufferBegin: -1,
 				TotalSize:              8,
 			},
 		},
@@ -50,6 +63,10 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				LocalMemoryBegin:       -1,
 				ImportedMemoryBegin:    -1,
 				ImportedFunctionsBegin: 0,
+				TablesBegin:            -1,
+				GlobalsBegin:           -1,
+				CoverageBufferBegin:    -1,
+				LoopCounterBufferBegin: -1,
 				TotalSize:              160,
 			},
 		},
@@ -60,6 +77,10 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				LocalMemoryBegin:       -1,
 				ImportedMemoryBegin:    0,
 				ImportedFunctionsBegin: 8,
+				TablesBegin:            -1,
+				GlobalsBegin:           -1,
+				CoverageBufferBegin:    -1,
+				LoopCounterBufferBegin: -1,
 				TotalSize:              168,
 			},
 		},
@@ -70,9 +91,47 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				LocalMemoryBegin:       0,
 				ImportedMemoryBegin:    -1,
 				ImportedFunctionsBegin: 16,
+				TablesBegin:            -1,
+				GlobalsBegin:           -1,
+				CoverageBufferBegin:    -1,
+				LoopCounterBufferBegin: -1,
 				TotalSize:              176,
 			},
 		},
+		{
+			name: "local mem / imported func / tables",
+			m: &wasm.Module{
+				MemorySection: &wasm.Memory{}, ImportFunctionCount: 10,
+				ImportTableCount: 1, TableSection: []wasm.Table{{}, {}},
+			},
+			exp: ModuleContextOffsetData{
+				LocalMemoryBegin:       0,
+				ImportedMemoryBegin:    -1,
+				ImportedFunctionsBegin: 16,
+				TablesBegin:            176,
+				GlobalsBegin:           -1,
+				CoverageBufferBegin:    -1,
+				LoopCounterBufferBegin: -1,
+				TotalSize:              200,
+			},
+		},
+		{
+			name: "imported mem / imported and local globals",
+			m: &wasm.Module{
+				ImportMemoryCount: 1,
+				ImportGlobalCount: 2, GlobalSection: []wasm.Global{{}},
+			},
+			exp: ModuleContextOffsetData{
+				LocalMemoryBegin:       -1,
+				ImportedMemoryBegin:    0,
+				ImportedFunctionsBegin: -1,
+				TablesBegin:            -1,
+				GlobalsBegin:           8,
+				CoverageBufferBegin:    -1,
+				LoopCounterBufferBegin: -1,
+				TotalSize:              32,
+			},
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			got := NewModuleContextOffsetData(tc.m)
@@ -80,3 +139,57 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 		})
 	}
 }
+
+func TestModuleContextOffsetData_AllocateCoverageBuffer(t *testing.T) {
+	got := NewModuleContextOffsetData(&wasm.Module{MemorySection: &wasm.Memory{}, ImportFunctionCount: 10})
+	got.AllocateCoverageBuffer()
+	require.Equal(t, ModuleContextOffsetData{
+		LocalMemoryBegin:       0,
+		ImportedMemoryBegin:    -1,
+		ImportedFunctionsBegin: 16,
+		TablesBegin:            -1,
+		GlobalsBegin:           -1,
+		CoverageBufferBegin:    176,
+		LoopCounterBufferBegin: -1,
+		TotalSize:              184,
+	}, got)
+}
+
+func TestModuleContextOffsetData_AllocateLoopCounterBuffer(t *testing.T) {
+	got := NewModuleContextOffsetData(&wasm.Module{MemorySection: &wasm.Memory{}})
+	got.AllocateCoverageBuffer()
+	got.AllocateLoopCounterBuffer()
+	require.Equal(t, ModuleContextOffsetData{
+		LocalMemoryBegin:       0,
+		ImportedMemoryBegin:    -1,
+		ImportedFunctionsBegin: -1,
+		TablesBegin:            -1,
+		GlobalsBegin:           -1,
+		CoverageBufferBegin:    16,
+		LoopCounterBufferBegin: 24,
+		TotalSize:              32,
+	}, got)
+}
+
+func TestModuleContextOffsetData_TableOffset(t *testing.T) {
+	m := ModuleContextOffsetData{TablesBegin: 16}
+	require.Equal(t, Offset(16), m.TableOffset(0))
+	require.Equal(t, Offset(32), m.TableOffset(2))
+}
+
+func TestModuleContextOffsetData_GlobalOffset(t *testing.T) {
+	m := ModuleContextOffsetData{GlobalsBegin: 16}
+	require.Equal(t, Offset(16), m.GlobalOffset(0))
+	require.Equal(t, Offset(32), m.GlobalOffset(2))
+}
+
+func TestGlobalInstanceValueOffset(t *testing.T) {
+	var globalInstance wasm.GlobalInstance
+	require.Equal(t, GlobalInstanceValueOffset, Offset(unsafe.Offsetof(globalInstance.Val)))
+}
+
+func TestTableInstanceOffsets(t *testing.T) {
+	var tableInstance wasm.TableInstance
+	// The length follows the base address in the slice header.
+	require.Equal(t, TableInstanceBaseAddressOffset, Offset(unsafe.Offsetof(tableInstance.References)))
+}
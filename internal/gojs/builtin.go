@@ -9,8 +9,10 @@ import (
 func newJsGlobal(config *config.Config) *jsVal {
 	var fetchProperty interface{} = goos.Undefined
 	proc := &processState{
-		cwd:   config.Workdir,
-		umask: config.Umask,
+		cwd:       absCwd(config.Workdir),
+		umask:     config.Umask,
+		ttys:      config.TTYs,
+		stdinMode: config.StdinMode,
 	}
 
 	return newJsVal(goos.RefValueGlobal, "global").
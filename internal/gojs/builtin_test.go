@@ -0,0 +1,34 @@
+package gojs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/gojs/config"
+	"github.com/tetratelabs/wazero/internal/gojs/custom"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func Test_processCwd(t *testing.T) {
+	tests := []struct {
+		name, workdir, expected string
+	}{
+		{name: "empty", workdir: "", expected: "/"},
+		{name: "root", workdir: "/", expected: "/"},
+		{name: "relative", workdir: "dir", expected: "/dir"},
+		{name: "unclean", workdir: "/dir/../sub/./dir/", expected: "/sub/dir"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			c := config.NewConfig()
+			c.Workdir = tc.workdir
+
+			process := newJsGlobal(c).Get("process").(*jsVal)
+			cwd, err := process.call(context.Background(), nil, process.ref, custom.NameProcessCwd)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, cwd)
+		})
+	}
+}
@@ -15,8 +15,31 @@ type Config struct {
 	// Workdir is the actual working directory value.
 	Workdir string
 	Umask   uint32
+
+	// TTYs are the file descriptors which report as a terminal to ioctl.
+	TTYs map[int32]struct{}
+
+	// StdinMode is how reading stdin behaves when no data is available.
+	StdinMode StdinMode
 }
 
+// StdinMode is how reading stdin behaves when no data is available.
+type StdinMode byte
+
+const (
+	// StdinModeDefault reads stdin as is, so whether it blocks depends on the
+	// underlying reader.
+	StdinModeDefault StdinMode = iota
+	// StdinModeBlocking waits until data is available, even if stdin is in
+	// the non-blocking mode.
+	StdinModeBlocking
+	// StdinModeNonblocking fails with EAGAIN instead of waiting.
+	StdinModeNonblocking
+	// StdinModeEOF reads nothing instead of waiting, which the reader sees as
+	// the end of the file.
+	StdinModeEOF
+)
+
 func NewConfig() *Config {
 	return &Config{
 		OsWorkdir: false,
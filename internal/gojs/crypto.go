@@ -2,6 +2,7 @@ package gojs
 
 import (
 	"context"
+	"io"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/gojs/custom"
@@ -20,12 +21,16 @@ var jsCrypto = newJsVal(goos.RefJsCrypto, custom.NameCrypto).
 	addFunction(custom.NameCryptoGetRandomValues, cryptoGetRandomValues{})
 
 // cryptoGetRandomValues implements jsFn
+//
+// The values are read from wazero.ModuleConfig WithRandSource. They are read
+// fully, as rand_js.go ignores the count and an io.Reader supplied by the
+// embedder may return fewer bytes than requested.
 type cryptoGetRandomValues struct{}
 
 func (cryptoGetRandomValues) invoke(_ context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
 	randSource := mod.(*wasm.ModuleInstance).Sys.RandSource()
 
 	r := args[0].(*goos.ByteArray)
-	n, err := randSource.Read(r.Unwrap())
+	n, err := io.ReadFull(randSource, r.Unwrap())
 	return uint32(n), err
 }
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"testing"
+	"testing/iotest"
 
+	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/experimental/logging"
+	"github.com/tetratelabs/wazero/internal/gojs/config"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
 
@@ -31,3 +34,25 @@ func Test_crypto(t *testing.T) {
 <== (n=5)
 `, logString(log))
 }
+
+func Test_crypto_randSource(t *testing.T) {
+	t.Parallel()
+
+	// The fixed source returns one byte per read, so the values delivered to
+	// the guest are correct only if the short reads are retried.
+	source := make([]byte, 256)
+	for i := range source {
+		source[i] = byte(i)
+	}
+
+	stdout, stderr, err := compileAndRun(testCtx, "crypto", func(moduleConfig wazero.ModuleConfig) (wazero.ModuleConfig, *config.Config) {
+		return moduleConfig.WithRandSource(iotest.OneByteReader(bytes.NewReader(source))), config.NewConfig()
+	})
+
+	require.Zero(t, stderr)
+	require.NoError(t, err)
+	// The runtime reads 40 bytes by getRandomData before crypto/rand reads 5
+	// bytes by crypto.getRandomValues, as seen in Test_crypto.
+	require.Equal(t, `28292a2b2c
+`, stdout)
+}
@@ -0,0 +1,34 @@
+package gojs
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/iotest"
+
+	"github.com/tetratelabs/wazero/internal/gojs/goos"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func Test_cryptoGetRandomValues(t *testing.T) {
+	// The source returns one byte per read, which must be retried until the
+	// array is filled.
+	source := iotest.OneByteReader(bytes.NewReader([]byte{1, 2, 3, 4, 5, 6}))
+	sysCtx, err := internalsys.NewContext(0, nil, nil, nil, nil, nil, source, nil, 0, nil, 0, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	mod := &wasm.ModuleInstance{Sys: sysCtx}
+
+	buf := make([]byte, 5)
+	n, err := cryptoGetRandomValues{}.invoke(context.Background(), mod, goos.WrapByteArray(buf))
+	require.NoError(t, err)
+	require.Equal(t, uint32(5), n)
+	require.Equal(t, []byte{1, 2, 3, 4, 5}, buf)
+
+	// The source has only one byte left.
+	n, err = cryptoGetRandomValues{}.invoke(context.Background(), mod, goos.WrapByteArray(buf))
+	require.Error(t, err)
+	require.Equal(t, uint32(1), n)
+	require.Equal(t, byte(6), buf[0])
+}
@@ -12,15 +12,21 @@ const (
 	NameFsWrite     = "write"
 	NameFsRead      = "read"
 	NameFsReaddir   = "readdir"
+	NameFsGetdents  = "getdents"
 	NameFsMkdir     = "mkdir"
+	NameFsMkdirat   = "mkdirat"
 	NameFsRmdir     = "rmdir"
 	NameFsRename    = "rename"
+	NameFsRenameat  = "renameat"
 	NameFsUnlink    = "unlink"
+	NameFsUnlinkat  = "unlinkat"
 	NameFsUtimes    = "utimes"
 	NameFsChmod     = "chmod"
 	NameFsFchmod    = "fchmod"
+	NameFsFchmodat  = "fchmodat"
 	NameFsChown     = "chown"
 	NameFsFchown    = "fchown"
+	NameFsFchownat  = "fchownat"
 	NameFsLchown    = "lchown"
 	NameFsTruncate  = "truncate"
 	NameFsFtruncate = "ftruncate"
@@ -28,6 +34,8 @@ const (
 	NameFsLink      = "link"
 	NameFsSymlink   = "symlink"
 	NameFsFsync     = "fsync"
+	NameFsPoll      = "poll"
+	NameFsIoctl     = "ioctl"
 )
 
 // FsNameSection are the functions defined in the object named NameFs. Results
@@ -74,11 +82,21 @@ var FsNameSection = map[string]*Names{
 		ParamNames:  []string{"path", NameCallback},
 		ResultNames: []string{"err", "dirents"},
 	},
+	NameFsGetdents: {
+		Name:        NameFsGetdents,
+		ParamNames:  []string{"fd", "buf", NameCallback},
+		ResultNames: []string{"err", "n"},
+	},
 	NameFsMkdir: {
 		Name:        NameFsMkdir,
 		ParamNames:  []string{"path", "perm", NameCallback},
 		ResultNames: []string{"err", "fd"},
 	},
+	NameFsMkdirat: {
+		Name:        NameFsMkdirat,
+		ParamNames:  []string{"dirfd", "path", "perm", NameCallback},
+		ResultNames: []string{"err", "fd"},
+	},
 	NameFsRmdir: {
 		Name:        NameFsRmdir,
 		ParamNames:  []string{"path", NameCallback},
@@ -89,11 +107,21 @@ var FsNameSection = map[string]*Names{
 		ParamNames:  []string{"from", "to", NameCallback},
 		ResultNames: []string{"err", "ok"},
 	},
+	NameFsRenameat: {
+		Name:        NameFsRenameat,
+		ParamNames:  []string{"fromDirfd", "from", "toDirfd", "to", NameCallback},
+		ResultNames: []string{"err", "ok"},
+	},
 	NameFsUnlink: {
 		Name:        NameFsUnlink,
 		ParamNames:  []string{"path", NameCallback},
 		ResultNames: []string{"err", "ok"},
 	},
+	NameFsUnlinkat: {
+		Name:        NameFsUnlinkat,
+		ParamNames:  []string{"dirfd", "path", "flags", NameCallback},
+		ResultNames: []string{"err", "ok"},
+	},
 	NameFsUtimes: {
 		Name:        NameFsUtimes,
 		ParamNames:  []string{"path", "atime", "mtime", NameCallback},
@@ -109,6 +137,11 @@ var FsNameSection = map[string]*Names{
 		ParamNames:  []string{"fd", "mode", NameCallback},
 		ResultNames: []string{"err", "ok"},
 	},
+	NameFsFchmodat: {
+		Name:        NameFsFchmodat,
+		ParamNames:  []string{"dirfd", "path", "mode", "flags", NameCallback},
+		ResultNames: []string{"err", "ok"},
+	},
 	NameFsChown: {
 		Name:        NameFsChown,
 		ParamNames:  []string{"path", "uid", "gid", NameCallback},
@@ -119,6 +152,11 @@ var FsNameSection = map[string]*Names{
 		ParamNames:  []string{"fd", "uid", "gid", NameCallback},
 		ResultNames: []string{"err", "ok"},
 	},
+	NameFsFchownat: {
+		Name:        NameFsFchownat,
+		ParamNames:  []string{"dirfd", "path", "uid", "gid", "flags", NameCallback},
+		ResultNames: []string{"err", "ok"},
+	},
 	NameFsLchown: {
 		Name:        NameFsLchown,
 		ParamNames:  []string{"path", "uid", "gid", NameCallback},
@@ -154,6 +192,16 @@ var FsNameSection = map[string]*Names{
 		ParamNames:  []string{"fd", NameCallback},
 		ResultNames: []string{"err", "ok"},
 	},
+	NameFsPoll: {
+		Name:        NameFsPoll,
+		ParamNames:  []string{"fds", "timeout", NameCallback},
+		ResultNames: []string{"err", "n"},
+	},
+	NameFsIoctl: {
+		Name:        NameFsIoctl,
+		ParamNames:  []string{"fd", "request", NameCallback},
+		ResultNames: []string{"err", "ok"},
+	},
 }
 
 // mode constants from syscall_js.go
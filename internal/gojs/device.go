@@ -0,0 +1,98 @@
+package gojs
+
+import (
+	"io"
+	"io/fs"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// modeCharDevice is the mode of the device files returned by devFS.
+const modeCharDevice = fs.ModeDevice | fs.ModeCharDevice | 0o666
+
+// devFS opens the device paths Go programs commonly expect on a POSIX host,
+// regardless of what, if anything, is mounted at "/dev".
+//
+// Notably, "/dev/urandom" reads from the module's random source instead of
+// the host, so that it is consistent with crypto/rand.
+type devFS struct {
+	experimentalsys.UnimplementedFS
+
+	randSource io.Reader
+}
+
+// isDevicePath returns true if the resolved path is handled by devFS.
+func isDevicePath(path string) bool {
+	switch path {
+	case "/dev/null", "/dev/zero", "/dev/urandom":
+		return true
+	}
+	return false
+}
+
+// OpenFile implements the same method as documented on sys.FS
+func (d *devFS) OpenFile(path string, _ experimentalsys.Oflag, _ fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	switch path {
+	case "/dev/null":
+		return &devFile{}, 0
+	case "/dev/zero":
+		return &devFile{r: zeroReader{}}, 0
+	case "/dev/urandom":
+		return &devFile{r: d.randSource}, 0
+	}
+	return nil, experimentalsys.ENOENT
+}
+
+// devFile is a character device which reads from r, or always returns EOF
+// when r is nil. Writes are always discarded.
+type devFile struct {
+	experimentalsys.UnimplementedFile
+
+	r io.Reader
+}
+
+// IsDir implements the same method as documented on sys.File
+func (*devFile) IsDir() (bool, experimentalsys.Errno) {
+	return false, 0
+}
+
+// Stat implements the same method as documented on sys.File
+func (*devFile) Stat() (sys.Stat_t, experimentalsys.Errno) {
+	return sys.Stat_t{Mode: modeCharDevice, Nlink: 1}, 0
+}
+
+// Read implements the same method as documented on sys.File
+func (f *devFile) Read(buf []byte) (int, experimentalsys.Errno) {
+	if f.r == nil {
+		return 0, 0 // Always EOF
+	}
+	n, err := f.r.Read(buf)
+	return n, experimentalsys.UnwrapOSError(err)
+}
+
+// Pread implements the same method as documented on sys.File
+func (f *devFile) Pread(buf []byte, _ int64) (int, experimentalsys.Errno) {
+	return f.Read(buf) // devices have no position
+}
+
+// Write implements the same method as documented on sys.File
+func (*devFile) Write(buf []byte) (int, experimentalsys.Errno) {
+	return len(buf), 0 // same as io.Discard
+}
+
+// Pwrite implements the same method as documented on sys.File
+func (f *devFile) Pwrite(buf []byte, _ int64) (int, experimentalsys.Errno) {
+	return f.Write(buf)
+}
+
+// zeroReader reads an infinite stream of zeros, like "/dev/zero".
+type zeroReader struct{}
+
+// Read implements io.Reader
+func (zeroReader) Read(buf []byte) (int, error) {
+	for i := range buf {
+		buf[i] = 0
+	}
+	return len(buf), nil
+}
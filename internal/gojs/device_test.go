@@ -0,0 +1,72 @@
+package gojs
+
+import (
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func Test_syscallOpen_device(t *testing.T) {
+	mod := &wasm.ModuleInstance{Sys: internalsys.DefaultContext(nil)}
+	defer mod.Sys.FS().Close()
+
+	open := func(t *testing.T, path string) int32 {
+		fd, errno := syscallOpen(mod, path, experimentalsys.O_RDWR, 0)
+		require.EqualErrno(t, 0, errno)
+		return fd
+	}
+
+	t.Run("/dev/null", func(t *testing.T) {
+		fd := open(t, "/dev/null")
+
+		n, errno := syscallWrite(mod, fd, nil, []byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 6, n)
+
+		n, errno = syscallRead(mod, fd, nil, make([]byte, 4))
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, n) // EOF
+	})
+
+	t.Run("/dev/zero", func(t *testing.T) {
+		fd := open(t, "/dev/zero")
+
+		buf := []byte{1, 2, 3, 4}
+		n, errno := syscallRead(mod, fd, nil, buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 4, n)
+		require.Equal(t, []byte{0, 0, 0, 0}, buf)
+	})
+
+	t.Run("/dev/urandom", func(t *testing.T) {
+		fd := open(t, "/dev/urandom")
+
+		buf := make([]byte, 8)
+		n, errno := syscallRead(mod, fd, nil, buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 8, n)
+
+		// The default random source is deterministic.
+		expected := make([]byte, 8)
+		_, err := internalsys.DefaultContext(nil).RandSource().Read(expected)
+		require.NoError(t, err)
+		require.Equal(t, expected, buf)
+	})
+
+	t.Run("fstat", func(t *testing.T) {
+		fd := open(t, "/dev/null")
+
+		st, err := syscallFstat(mod.Sys.FS(), fd)
+		require.NoError(t, err)
+		require.False(t, st.isDir)
+		require.Equal(t, uint32(1), st.nlink)
+	})
+
+	t.Run("not a device", func(t *testing.T) {
+		_, errno := syscallOpen(mod, "/dev/tty", experimentalsys.O_RDONLY, 0)
+		require.EqualErrno(t, experimentalsys.ENOSYS, errno) // UnimplementedFS
+	})
+}
@@ -1,7 +1,11 @@
 package gojs
 
 import (
+	"errors"
 	"io"
+	"io/fs"
+	"os"
+	"syscall"
 
 	"github.com/tetratelabs/wazero/experimental/sys"
 )
@@ -53,6 +57,10 @@ var (
 	ErrnoNotempty = &Errno{"ENOTEMPTY"}
 	// ErrnoNotsup Not supported, or operation not supported on socket.
 	ErrnoNotsup = &Errno{"ENOTSUP"}
+	// ErrnoNotty Inappropriate I/O control operation.
+	ErrnoNotty = &Errno{"ENOTTY"}
+	// ErrnoOverflow Value too large to be stored in data type.
+	ErrnoOverflow = &Errno{"EOVERFLOW"}
 	// ErrnoPerm Operation not permitted.
 	ErrnoPerm = &Errno{"EPERM"}
 	// ErrnoRofs read-only file system.
@@ -65,11 +73,10 @@ func ToErrno(err error) *Errno {
 	if err == nil || err == io.EOF {
 		return nil // io.EOF has no value in GOOS=js, and isn't an error.
 	}
-	errno, ok := err.(sys.Errno)
-	if !ok {
-		return ErrnoIo
+	if e, ok := err.(*Errno); ok {
+		return e // e.g. ErrnoNotty, which has no sys.Errno
 	}
-	switch errno {
+	switch errnoOf(err) {
 	case sys.EACCES:
 		return ErrnoAcces
 	case sys.EAGAIN:
@@ -102,6 +109,8 @@ func ToErrno(err error) *Errno {
 		return ErrnoNotempty
 	case sys.ENOTSUP:
 		return ErrnoNotsup
+	case sys.EOVERFLOW:
+		return ErrnoOverflow
 	case sys.EPERM:
 		return ErrnoPerm
 	case sys.EROFS:
@@ -110,3 +119,34 @@ func ToErrno(err error) *Errno {
 		return ErrnoIo
 	}
 }
+
+// errnoOf extracts the sys.Errno from err, searching wrapped errors at any
+// depth. This allows host errors such as an *os.PathError wrapping a
+// syscall.Errno to map to the correct code instead of EIO.
+func errnoOf(err error) sys.Errno {
+	var errno sys.Errno
+	if errors.As(err, &errno) {
+		return errno
+	}
+	var sysErrno syscall.Errno
+	if errors.As(err, &sysErrno) {
+		return sys.UnwrapOSError(sysErrno)
+	}
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return sys.UnwrapOSError(pathErr)
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return sys.ENOENT
+	case errors.Is(err, fs.ErrExist):
+		return sys.EEXIST
+	case errors.Is(err, fs.ErrPermission):
+		return sys.EPERM
+	case errors.Is(err, fs.ErrInvalid):
+		return sys.EINVAL
+	case errors.Is(err, fs.ErrClosed):
+		return sys.EBADF
+	}
+	return sys.EIO
+}
@@ -1,7 +1,12 @@
 package gojs
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"os"
+	"syscall"
 	"testing"
 
 	"github.com/tetratelabs/wazero/experimental/sys"
@@ -100,6 +105,21 @@ func TestToErrno(t *testing.T) {
 			input:    sys.ENOTSUP,
 			expected: ErrnoNotsup,
 		},
+		{
+			name:     "ErrnoNotty has no sys.Errno",
+			input:    ErrnoNotty,
+			expected: ErrnoNotty,
+		},
+		{
+			name:     "sys.EOVERFLOW",
+			input:    sys.EOVERFLOW,
+			expected: ErrnoOverflow,
+		},
+		{
+			name:     "syscall.EOVERFLOW",
+			input:    syscall.EOVERFLOW,
+			expected: ErrnoOverflow,
+		},
 		{
 			name:     "sys.EPERM",
 			input:    sys.EPERM,
@@ -115,6 +135,38 @@ func TestToErrno(t *testing.T) {
 			input:    sys.Errno(0xfe),
 			expected: ErrnoIo,
 		},
+		{
+			name:     "wrapped sys.Errno",
+			input:    fmt.Errorf("open: %w", fmt.Errorf("dir: %w", sys.ENOTDIR)),
+			expected: ErrnoNotdir,
+		},
+		{
+			name:     "wrapped *os.PathError of syscall.Errno",
+			input:    fmt.Errorf("open: %w", &os.PathError{Op: "open", Path: "/a", Err: syscall.ENOENT}),
+			expected: ErrnoNoent,
+		},
+		{
+			name: "*os.PathError of wrapped syscall.Errno",
+			input: fmt.Errorf("mkdir: %w", &os.PathError{
+				Op: "mkdir", Path: "/a", Err: fmt.Errorf("exists: %w", syscall.EEXIST),
+			}),
+			expected: ErrnoExist,
+		},
+		{
+			name:     "wrapped *os.PathError of fs.ErrPermission",
+			input:    fmt.Errorf("chmod: %w", &os.PathError{Op: "chmod", Path: "/a", Err: fs.ErrPermission}),
+			expected: ErrnoPerm,
+		},
+		{
+			name:     "wrapped fs.ErrNotExist",
+			input:    fmt.Errorf("stat: %w", fs.ErrNotExist),
+			expected: ErrnoNoent,
+		},
+		{
+			name:     "unknown error == ErrnoIo",
+			input:    errors.New("unknown"),
+			expected: ErrnoIo,
+		},
 	}
 
 	for _, tt := range tests {
@@ -3,9 +3,13 @@ package gojs
 import (
 	"context"
 	"fmt"
+	"io/fs"
+	"math"
 
 	"github.com/tetratelabs/wazero/api"
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	"github.com/tetratelabs/wazero/internal/gojs/config"
 	"github.com/tetratelabs/wazero/internal/gojs/custom"
 	"github.com/tetratelabs/wazero/internal/gojs/goos"
 	"github.com/tetratelabs/wazero/internal/gojs/util"
@@ -18,12 +22,14 @@ var (
 	// jsfsConstants = jsfs Get("constants") // fs_js.go init
 	jsfsConstants = newJsVal(goos.RefJsfsConstants, "constants").
 			addProperties(map[string]interface{}{
-			"O_WRONLY": oWRONLY,
-			"O_RDWR":   oRDWR,
-			"O_CREAT":  oCREAT,
-			"O_TRUNC":  oTRUNC,
-			"O_APPEND": oAPPEND,
-			"O_EXCL":   oEXCL,
+			"O_WRONLY":  oWRONLY,
+			"O_RDWR":    oRDWR,
+			"O_CREAT":   oCREAT,
+			"O_TRUNC":   oTRUNC,
+			"O_APPEND":  oAPPEND,
+			"O_EXCL":    oEXCL,
+			"O_SYNC":    oSYNC,
+			"O_CLOEXEC": oCLOEXEC,
 		})
 
 	// oWRONLY = jsfsConstants Get("O_WRONLY").Int() // fs_js.go init
@@ -43,8 +49,32 @@ var (
 
 	// oEXCL = jsfsConstants Get("O_EXCL").Int() // fs_js.go init
 	oEXCL = float64(experimentalsys.O_EXCL)
+
+	// oSYNC = jsfsConstants Get("O_SYNC").Int()
+	//
+	// Note: fs_js.go rejects O_SYNC before calling open, but other guests
+	// can pass it, in which case it is passed through to the file system.
+	oSYNC = float64(experimentalsys.O_SYNC)
+
+	// oCLOEXEC = jsfsConstants Get("O_CLOEXEC").Int()
+	oCLOEXEC = float64(oflagCloexec)
 )
 
+// oflagCloexec is the open flag which sets the close-on-exec flag of the
+// file descriptor. experimentalsys.Oflag has no such flag, so this uses a bit
+// none of them use. syscallOpen strips this before opening the file, so that
+// the file system doesn't misinterpret it.
+const oflagCloexec experimentalsys.Oflag = 1 << 31
+
+// atRemovedir is the unlinkat flag to remove a directory instead of a file.
+// This is the same value as AT_REMOVEDIR on Linux.
+const atRemovedir = 0x200
+
+// atSymlinkNofollow is the fchmodat and fchownat flag to operate on a symbolic
+// link itself instead of its target. This is the same value as
+// AT_SYMLINK_NOFOLLOW on Linux.
+const atSymlinkNofollow = 0x100
+
 // jsfs = js.Global().Get("fs") // fs_js.go init
 //
 // js.fsCall conventions:
@@ -59,26 +89,34 @@ func newJsFs(proc *processState) *jsVal {
 		addFunction(custom.NameFsStat, &jsfsStat{proc: proc}).
 		addFunction(custom.NameFsFstat, jsfsFstat{}).
 		addFunction(custom.NameFsLstat, &jsfsLstat{proc: proc}).
-		addFunction(custom.NameFsClose, jsfsClose{}).
-		addFunction(custom.NameFsRead, jsfsRead{}).
+		addFunction(custom.NameFsClose, &jsfsClose{proc: proc}).
+		addFunction(custom.NameFsRead, &jsfsRead{proc: proc}).
 		addFunction(custom.NameFsWrite, jsfsWrite{}).
 		addFunction(custom.NameFsReaddir, &jsfsReaddir{proc: proc}).
+		addFunction(custom.NameFsGetdents, &jsfsGetdents{proc: proc}).
 		addFunction(custom.NameFsMkdir, &jsfsMkdir{proc: proc}).
+		addFunction(custom.NameFsMkdirat, &jsfsMkdirat{proc: proc}).
 		addFunction(custom.NameFsRmdir, &jsfsRmdir{proc: proc}).
 		addFunction(custom.NameFsRename, &jsfsRename{proc: proc}).
+		addFunction(custom.NameFsRenameat, jsfsRenameat{}).
 		addFunction(custom.NameFsUnlink, &jsfsUnlink{proc: proc}).
+		addFunction(custom.NameFsUnlinkat, jsfsUnlinkat{}).
 		addFunction(custom.NameFsUtimes, &jsfsUtimes{proc: proc}).
 		addFunction(custom.NameFsChmod, &jsfsChmod{proc: proc}).
 		addFunction(custom.NameFsFchmod, jsfsFchmod{}).
+		addFunction(custom.NameFsFchmodat, jsfsFchmodat{}).
 		addFunction(custom.NameFsChown, &jsfsChown{proc: proc}).
 		addFunction(custom.NameFsFchown, jsfsFchown{}).
+		addFunction(custom.NameFsFchownat, jsfsFchownat{}).
 		addFunction(custom.NameFsLchown, &jsfsLchown{proc: proc}).
 		addFunction(custom.NameFsTruncate, &jsfsTruncate{proc: proc}).
 		addFunction(custom.NameFsFtruncate, jsfsFtruncate{}).
 		addFunction(custom.NameFsReadlink, &jsfsReadlink{proc: proc}).
 		addFunction(custom.NameFsLink, &jsfsLink{proc: proc}).
 		addFunction(custom.NameFsSymlink, &jsfsSymlink{proc: proc}).
-		addFunction(custom.NameFsFsync, jsfsFsync{})
+		addFunction(custom.NameFsFsync, jsfsFsync{}).
+		addFunction(custom.NameFsPoll, jsfsPoll{}).
+		addFunction(custom.NameFsIoctl, &jsfsIoctl{proc: proc})
 }
 
 // jsfsOpen implements implements jsFn for syscall.Open
@@ -96,13 +134,39 @@ func (o *jsfsOpen) invoke(ctx context.Context, mod api.Module, args ...interface
 	perm := custom.FromJsMode(goos.ValueToUint32(args[2]), o.proc.umask)
 	callback := args[3].(funcWrapper)
 
-	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
-
-	fd, errno := fsc.OpenFile(fsc.RootFS(), path, flags, perm)
+	fd, errno := syscallOpen(mod, path, flags, perm)
 
 	return callback.invoke(ctx, mod, goos.RefJsfs, maybeError(errno), fd) // note: error first
 }
 
+// syscallOpen is like syscall.Open, except common device paths such as
+// "/dev/null" are opened by devFS instead of the root file system.
+//
+// oflagCloexec is recorded on the file entry as internalsys.FileEntry
+// CloseOnExec, and experimentalsys.O_SYNC is passed through to the file
+// system, which makes writes synchronous if it supports that.
+func syscallOpen(mod api.Module, path string, flags experimentalsys.Oflag, perm fs.FileMode) (int32, experimentalsys.Errno) {
+	m := mod.(*wasm.ModuleInstance)
+	fsc := m.Sys.FS()
+
+	cloexec := flags&oflagCloexec != 0
+	flags &^= oflagCloexec
+
+	var fd int32
+	var errno experimentalsys.Errno
+	if isDevicePath(path) {
+		fd, errno = fsc.OpenFile(&devFS{randSource: m.Sys.RandSource()}, path, flags, perm)
+	} else {
+		fd, errno = fsc.OpenFile(fsc.RootFS(), path, flags, perm)
+	}
+	if errno == 0 && cloexec {
+		if f, ok := fsc.LookupFile(fd); ok {
+			f.CloseOnExec = true
+		}
+	}
+	return fd, errno
+}
+
 // jsfsStat implements jsFn for syscall.Stat
 //
 //	jsSt, err := fsCall("stat", path)
@@ -193,22 +257,50 @@ func newJsSt(st sys.Stat_t) *jsSt {
 	ret.mode = custom.ToJsMode(st.Mode)
 	ret.nlink = uint32(st.Nlink)
 	ret.size = st.Size
+	ret.blksize = jsStBlksize
+	ret.blocks = jsStBlocks(st.Size)
 	ret.atimeMs = st.Atim / 1e6
 	ret.mtimeMs = st.Mtim / 1e6
 	ret.ctimeMs = st.Ctim / 1e6
 	return ret
 }
 
+// jsStBlksize is the preferred I/O size reported as stat.blksize, which is
+// the page size on typical hosts.
+const jsStBlksize = 4096
+
+// jsStBlocks returns the count of 512-byte blocks reported as stat.blocks.
+//
+// Note: sys.Stat_t doesn't include the blocks allocated by the host, so this
+// is the size rounded up, which over-counts a sparse file. This keeps
+// blocks*512 >= size, which is what programs computing disk usage rely on.
+func jsStBlocks(size int64) int32 {
+	if size <= 0 {
+		return 0
+	}
+	blocks := size / 512
+	if size%512 != 0 {
+		blocks++
+	}
+	if blocks > math.MaxInt32 {
+		return math.MaxInt32 // GOOS=js defines stat.blocks as int32.
+	}
+	return int32(blocks)
+}
+
 // jsfsClose implements jsFn for syscall.Close
-type jsfsClose struct{}
+type jsfsClose struct {
+	proc *processState
+}
 
-func (jsfsClose) invoke(ctx context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
+func (c *jsfsClose) invoke(ctx context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 
 	fd := goos.ValueToInt32(args[0])
 	callback := args[1].(funcWrapper)
 
 	errno := fsc.CloseFile(fd)
+	delete(c.proc.direntPos, fd) // so that a reused fd starts from the beginning
 
 	return jsfsInvoke(ctx, mod, callback, errno)
 }
@@ -217,9 +309,11 @@ func (jsfsClose) invoke(ctx context.Context, mod api.Module, args ...interface{}
 // src/internal/poll/fd_unix.go poll.Read.
 //
 //	n, err := fsCall("read", fd, buf, 0, len(b), nil)
-type jsfsRead struct{}
+type jsfsRead struct {
+	proc *processState
+}
 
-func (jsfsRead) invoke(ctx context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
+func (r *jsfsRead) invoke(ctx context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
 	fd := goos.ValueToInt32(args[0])
 	buf, ok := args[1].(*goos.ByteArray)
 	if !ok {
@@ -231,7 +325,13 @@ func (jsfsRead) invoke(ctx context.Context, mod api.Module, args ...interface{})
 	callback := args[5].(funcWrapper)
 
 	var err error
-	n, errno := syscallRead(mod, fd, fOffset, buf.Unwrap()[offset:offset+byteCount])
+	var n int
+	var errno experimentalsys.Errno
+	if fd == 0 && fOffset == nil && r.proc.stdinMode != config.StdinModeDefault {
+		n, errno = syscallReadStdin(mod, r.proc.stdinMode, buf.Unwrap()[offset:offset+byteCount])
+	} else {
+		n, errno = syscallRead(mod, fd, fOffset, buf.Unwrap()[offset:offset+byteCount])
+	}
 	if errno != 0 {
 		err = errno
 	}
@@ -239,16 +339,79 @@ func (jsfsRead) invoke(ctx context.Context, mod api.Module, args ...interface{})
 	return callback.invoke(ctx, mod, goos.RefJsfs, err, uint32(n)) // note: error first
 }
 
+// lookupOpenFile is like internalsys.FSContext LookupFile, except an entry
+// without a file is not open either. Reads and writes use this so that a file
+// closed mid-operation consistently results in EBADF instead of a panic.
+func lookupOpenFile(fsc *internalsys.FSContext, fd int32) (*internalsys.FileEntry, bool) {
+	if f, ok := fsc.LookupFile(fd); ok && f.File != nil {
+		return f, true
+	}
+	return nil, false
+}
+
 // syscallRead is like syscall.Read
 func syscallRead(mod api.Module, fd int32, offset interface{}, buf []byte) (n int, errno experimentalsys.Errno) {
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
-
-	if f, ok := fsc.LookupFile(fd); !ok {
-		return 0, experimentalsys.EBADF
+	if f, ok := lookupOpenFile(fsc, fd); !ok {
+		errno = experimentalsys.EBADF
 	} else if offset != nil {
-		return f.File.Pread(buf, toInt64(offset))
+		var off int64
+		if off, errno = toFileOffset(offset); errno == 0 {
+			n, errno = f.File.Pread(buf, off)
+		}
 	} else {
-		return f.File.Read(buf)
+		n, errno = f.File.Read(buf)
+	}
+	if errno == experimentalsys.ENOSYS {
+		errno = experimentalsys.EBADF // e.g. unimplemented for read
+	}
+	return
+}
+
+// stdinWaitMillis is how long syscallReadStdin waits for data in each poll
+// in config.StdinModeBlocking. stdin is polled repeatedly instead of with a
+// negative timeout, as not every platform waits indefinitely on it.
+const stdinWaitMillis = 100
+
+// syscallReadStdin is like syscallRead for stdin, except what happens when
+// no data is available depends on the mode, which is not
+// config.StdinModeDefault.
+func syscallReadStdin(mod api.Module, mode config.StdinMode, buf []byte) (n int, errno experimentalsys.Errno) {
+	f, ok := lookupOpenFile(mod.(*wasm.ModuleInstance).Sys.FS(), 0)
+	if !ok {
+		return 0, experimentalsys.EBADF
+	}
+	for {
+		var ready bool
+		if mode == config.StdinModeBlocking {
+			ready, errno = f.File.Poll(fsapi.POLLIN, stdinWaitMillis)
+			if errno == experimentalsys.ENOSYS || errno == experimentalsys.ENOTSUP {
+				ready, errno = true, 0 // e.g. a regular file never blocks.
+			}
+		} else {
+			ready, errno = pollFile(f.File, fsapi.POLLIN)
+		}
+		if errno != 0 {
+			return
+		}
+
+		if !ready {
+			switch mode {
+			case config.StdinModeNonblocking:
+				return 0, experimentalsys.EAGAIN
+			case config.StdinModeEOF:
+				return 0, 0
+			}
+			continue
+		}
+
+		n, errno = f.File.Read(buf)
+		if errno == experimentalsys.EAGAIN && mode == config.StdinModeBlocking {
+			continue // e.g. another reader drained the data in the meantime.
+		} else if errno == experimentalsys.ENOSYS {
+			errno = experimentalsys.EBADF // e.g. unimplemented for read
+		}
+		return
 	}
 }
 
@@ -282,15 +445,21 @@ func (jsfsWrite) invoke(ctx context.Context, mod api.Module, args ...interface{}
 	return callback.invoke(ctx, mod, goos.RefJsfs, nil, goos.RefValueZero)
 }
 
-// syscallWrite is like syscall.Write
+// syscallWrite is like syscall.Write. n is the count of the bytes written even
+// if errno is non-zero, e.g. a write partially done until the disk is full.
 func syscallWrite(mod api.Module, fd int32, offset interface{}, buf []byte) (n int, errno experimentalsys.Errno) {
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
-	if f, ok := fsc.LookupFile(fd); !ok {
+	if f, ok := lookupOpenFile(fsc, fd); !ok {
 		errno = experimentalsys.EBADF
-	} else if offset != nil {
-		n, errno = f.File.Pwrite(buf, toInt64(offset))
-	} else {
+	} else if offset == nil || f.File.IsAppend() {
+		// fs_js.go passes the offset after a seek, but writes to a file opened
+		// with O_APPEND always land at its end, like pwrite on Linux.
 		n, errno = f.File.Write(buf)
+	} else {
+		var off int64
+		if off, errno = toFileOffset(offset); errno == 0 {
+			n, errno = f.File.Pwrite(buf, off)
+		}
 	}
 	if errno == experimentalsys.ENOSYS {
 		errno = experimentalsys.EBADF // e.g. unimplemented for write
@@ -298,6 +467,26 @@ func syscallWrite(mod api.Module, fd int32, offset interface{}, buf []byte) (n i
 	return
 }
 
+// maxFileOffset is the largest pread or pwrite offset, as GOOS=js passes it
+// as a JavaScript number, which only holds integers up to 2^53 exactly.
+const maxFileOffset = 1 << 53
+
+// toFileOffset converts the pread or pwrite offset, returning
+// experimentalsys.EOVERFLOW if it exceeds maxFileOffset.
+func toFileOffset(offset interface{}) (int64, experimentalsys.Errno) {
+	switch o := offset.(type) {
+	case float64:
+		if o > maxFileOffset {
+			return 0, experimentalsys.EOVERFLOW
+		}
+	case int64:
+		if o > maxFileOffset {
+			return 0, experimentalsys.EOVERFLOW
+		}
+	}
+	return toInt64(offset), 0
+}
+
 // jsfsReaddir implements jsFn for syscall.Open
 //
 //	dir, err := fsCall("readdir", path)
@@ -335,6 +524,112 @@ func syscallReaddir(_ context.Context, mod api.Module, name string) (*objectArra
 	}
 }
 
+// jsfsGetdents implements jsFn for the following
+//
+//	n, err := fsCall("getdents", fd, buf)
+//
+// Unlike readdir, this reads from an open directory, packing as many dirent
+// records as fit into buf. Subsequent calls resume after the last record
+// returned, and zero is returned when the directory is exhausted.
+type jsfsGetdents struct {
+	proc *processState
+}
+
+func (g *jsfsGetdents) invoke(ctx context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
+	fd := goos.ValueToInt32(args[0])
+	buf, ok := args[1].(*goos.ByteArray)
+	if !ok {
+		return nil, fmt.Errorf("arg[1] is %v not a []byte", args[1])
+	}
+	callback := args[2].(funcWrapper)
+
+	n, errno := syscallGetdents(mod, g.proc, fd, buf.Unwrap())
+
+	// It is safe to cast to uint32 because n <= len(buf).
+	return callback.invoke(ctx, mod, goos.RefJsfs, maybeError(errno), uint32(n)) // note: error first
+}
+
+// direntHeaderLen is the length of a dirent record before its name: ino (8),
+// off (8), reclen (2) and type (1).
+const direntHeaderLen = 19
+
+// syscallGetdents is like getdents64 on Linux: each record is a
+// little-endian linux_dirent64 (ino, off, reclen, type, then the NUL
+// terminated name) padded to 8 bytes.
+//
+// sys.EINVAL is returned if buf is too small for the next record.
+func syscallGetdents(mod api.Module, proc *processState, fd int32, buf []byte) (n int, errno experimentalsys.Errno) {
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+
+	f, errno := fsc.LookupDir(fd)
+	if errno != 0 {
+		return 0, errno
+	}
+	dir, errno := f.DirentCache()
+	if errno != 0 {
+		return 0, errno
+	}
+
+	// Read one more dirent than the smallest (24-byte) records could fill,
+	// so that we can tell EOF from a buf too small for the next record.
+	count := uint32(len(buf)/24) + 1
+	pos := proc.direntPos[fd]
+	dirents, errno := dir.Read(pos, count)
+	if errno != 0 {
+		return 0, errno
+	}
+
+	for i := range dirents {
+		d := &dirents[i]
+		reclen := (direntHeaderLen + len(d.Name) + 1 + 7) &^ 7
+		if n+reclen > len(buf) {
+			break
+		}
+		pos++
+
+		rec := buf[n : n+reclen]
+		le.PutUint64(rec, d.Ino)
+		le.PutUint64(rec[8:], pos)
+		le.PutUint16(rec[16:], uint16(reclen))
+		rec[18] = direntType(d.Type)
+		copy(rec[direntHeaderLen:], d.Name)
+		for j := direntHeaderLen + len(d.Name); j < reclen; j++ {
+			rec[j] = 0 // NUL terminator and padding
+		}
+		n += reclen
+	}
+
+	if n == 0 && len(dirents) > 0 {
+		return 0, experimentalsys.EINVAL // buf is too small for the next dirent.
+	}
+	if proc.direntPos == nil {
+		proc.direntPos = map[int32]uint64{}
+	}
+	proc.direntPos[fd] = pos
+	return n, 0
+}
+
+// direntType returns the d_type of a dirent, e.g. DT_DIR.
+func direntType(t fs.FileMode) byte {
+	switch {
+	case t.IsRegular():
+		return 8 // DT_REG
+	case t.IsDir():
+		return 4 // DT_DIR
+	case t&fs.ModeSymlink != 0:
+		return 10 // DT_LNK
+	case t&fs.ModeCharDevice != 0:
+		return 2 // DT_CHR
+	case t&fs.ModeDevice != 0:
+		return 6 // DT_BLK
+	case t&fs.ModeNamedPipe != 0:
+		return 1 // DT_FIFO
+	case t&fs.ModeSocket != 0:
+		return 12 // DT_SOCK
+	}
+	return 0 // DT_UNKNOWN
+}
+
 // jsfsMkdir implements implements jsFn for fs.Mkdir
 //
 //	jsFD /* Int */, err := fsCall("mkdir", path, perm)
@@ -347,11 +642,17 @@ func (m *jsfsMkdir) invoke(ctx context.Context, mod api.Module, args ...interfac
 	perm := custom.FromJsMode(goos.ValueToUint32(args[1]), m.proc.umask)
 	callback := args[2].(funcWrapper)
 
+	fd, errno := syscallMkdir(mod, path, perm)
+
+	return callback.invoke(ctx, mod, goos.RefJsfs, maybeError(errno), fd) // note: error first
+}
+
+// syscallMkdir is like syscall.Mkdir, except it also opens the directory.
+// The perm must already have the process umask applied.
+func syscallMkdir(mod api.Module, path string, perm fs.FileMode) (fd int32, errno experimentalsys.Errno) {
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 	root := fsc.RootFS()
 
-	var fd int32
-	var errno experimentalsys.Errno
 	// We need at least read access to open the file descriptor
 	if perm == 0 {
 		perm = 0o0500
@@ -359,10 +660,52 @@ func (m *jsfsMkdir) invoke(ctx context.Context, mod api.Module, args ...interfac
 	if errno = root.Mkdir(path, perm); errno == 0 {
 		fd, errno = fsc.OpenFile(root, path, experimentalsys.O_RDONLY, 0)
 	}
+	return
+}
+
+// jsfsMkdirat implements jsFn for the following
+//
+//	jsFD /* Int */, err := fsCall("mkdirat", dirfd, path, perm)
+type jsfsMkdirat struct {
+	proc *processState
+}
+
+func (m *jsfsMkdirat) invoke(ctx context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
+	dirFD := goos.ValueToInt32(args[0])
+	path := args[1].(string)
+	perm := custom.FromJsMode(goos.ValueToUint32(args[2]), m.proc.umask)
+	callback := args[3].(funcWrapper)
+
+	fd, errno := syscallMkdirat(mod, dirFD, path, perm)
 
 	return callback.invoke(ctx, mod, goos.RefJsfs, maybeError(errno), fd) // note: error first
 }
 
+// syscallMkdirat is like syscallMkdir, except a relative path is resolved
+// against the directory open as dirFD.
+func syscallMkdirat(mod api.Module, dirFD int32, path string, perm fs.FileMode) (int32, experimentalsys.Errno) {
+	if path, errno := resolvePathAt(mod, dirFD, path); errno != 0 {
+		return 0, errno
+	} else {
+		return syscallMkdir(mod, path, perm)
+	}
+}
+
+// resolvePathAt is like util.ResolvePath, except a relative path is resolved
+// against the directory open as dirFD instead of the current directory.
+func resolvePathAt(mod api.Module, dirFD int32, path string) (string, experimentalsys.Errno) {
+	if len(path) > 0 && path[0] == '/' {
+		return util.ResolvePath("/", path), 0 // dirFD is ignored
+	}
+
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	if dir, errno := fsc.LookupDir(dirFD); errno != 0 {
+		return "", errno
+	} else {
+		return util.ResolvePath(absCwd(dir.Name), path), 0
+	}
+}
+
 // jsfsRmdir implements jsFn for the following
 //
 //	_, err := fsCall("rmdir", path) // syscall.Rmdir
@@ -399,6 +742,39 @@ func (r *jsfsRename) invoke(ctx context.Context, mod api.Module, args ...interfa
 	return jsfsInvoke(ctx, mod, callback, errno)
 }
 
+// jsfsRenameat implements jsFn for the following
+//
+//	_, err := fsCall("renameat", fromDirfd, from, toDirfd, to)
+type jsfsRenameat struct{}
+
+func (jsfsRenameat) invoke(ctx context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
+	fromDirFD := goos.ValueToInt32(args[0])
+	from := args[1].(string)
+	toDirFD := goos.ValueToInt32(args[2])
+	to := args[3].(string)
+	callback := args[4].(funcWrapper)
+
+	errno := syscallRenameat(mod, fromDirFD, from, toDirFD, to)
+
+	return jsfsInvoke(ctx, mod, callback, errno)
+}
+
+// syscallRenameat is like syscall.Rename, except relative paths are resolved
+// against the directories open as fromDirFD and toDirFD.
+func syscallRenameat(mod api.Module, fromDirFD int32, from string, toDirFD int32, to string) experimentalsys.Errno {
+	from, errno := resolvePathAt(mod, fromDirFD, from)
+	if errno != 0 {
+		return errno
+	}
+	to, errno = resolvePathAt(mod, toDirFD, to)
+	if errno != 0 {
+		return errno
+	}
+
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	return fsc.RootFS().Rename(from, to)
+}
+
 // jsfsUnlink implements jsFn for the following
 //
 //	_, err := fsCall("unlink", path) // syscall.Unlink
@@ -416,6 +792,38 @@ func (u *jsfsUnlink) invoke(ctx context.Context, mod api.Module, args ...interfa
 	return jsfsInvoke(ctx, mod, callback, errno)
 }
 
+// jsfsUnlinkat implements jsFn for the following
+//
+//	_, err := fsCall("unlinkat", dirfd, path, flags)
+type jsfsUnlinkat struct{}
+
+func (jsfsUnlinkat) invoke(ctx context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
+	dirFD := goos.ValueToInt32(args[0])
+	path := args[1].(string)
+	flags := goos.ValueToUint32(args[2])
+	callback := args[3].(funcWrapper)
+
+	errno := syscallUnlinkat(mod, dirFD, path, flags)
+
+	return jsfsInvoke(ctx, mod, callback, errno)
+}
+
+// syscallUnlinkat is like syscall.Unlink, except a relative path is resolved
+// against the directory open as dirFD. When flags include atRemovedir, this
+// behaves like syscall.Rmdir instead.
+func syscallUnlinkat(mod api.Module, dirFD int32, path string, flags uint32) experimentalsys.Errno {
+	path, errno := resolvePathAt(mod, dirFD, path)
+	if errno != 0 {
+		return errno
+	}
+
+	root := mod.(*wasm.ModuleInstance).Sys.FS().RootFS()
+	if flags&atRemovedir != 0 {
+		return root.Rmdir(path)
+	}
+	return root.Unlink(path)
+}
+
 // jsfsUtimes implements jsFn for the following
 //
 //	_, err := fsCall("utimes", path, atime, mtime) // syscall.Utimens
@@ -475,6 +883,47 @@ func (jsfsFchmod) invoke(ctx context.Context, mod api.Module, args ...interface{
 	return jsfsInvoke(ctx, mod, callback, errno)
 }
 
+// jsfsFchmodat implements jsFn for the following
+//
+//	_, err := fsCall("fchmodat", dirfd, path, mode, flags)
+type jsfsFchmodat struct{}
+
+func (jsfsFchmodat) invoke(ctx context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
+	dirFD := goos.ValueToInt32(args[0])
+	path := args[1].(string)
+	mode := custom.FromJsMode(goos.ValueToUint32(args[2]), 0)
+	flags := goos.ValueToUint32(args[3])
+	callback := args[4].(funcWrapper)
+
+	errno := syscallFchmodat(mod, dirFD, path, mode, flags)
+
+	return jsfsInvoke(ctx, mod, callback, errno)
+}
+
+// syscallFchmodat is like syscall.Chmod, except a relative path is resolved
+// against the directory open as dirFD. When flags include atSymlinkNofollow,
+// a symbolic link is not followed, and results in ENOSYS as changing the mode
+// of a link itself isn't supported.
+func syscallFchmodat(mod api.Module, dirFD int32, path string, mode fs.FileMode, flags uint32) experimentalsys.Errno {
+	if flags&^atSymlinkNofollow != 0 {
+		return experimentalsys.EINVAL
+	}
+	path, errno := resolvePathAt(mod, dirFD, path)
+	if errno != 0 {
+		return errno
+	}
+
+	root := mod.(*wasm.ModuleInstance).Sys.FS().RootFS()
+	if flags&atSymlinkNofollow != 0 {
+		if st, errno := root.Lstat(path); errno != 0 {
+			return errno
+		} else if st.Mode&fs.ModeSymlink != 0 {
+			return experimentalsys.ENOSYS
+		}
+	}
+	return root.Chmod(path, mode)
+}
+
 // jsfsChown implements jsFn for the following
 //
 //	_, err := fsCall("chown", path, uint32(uid), uint32(gid)) // syscall.Chown
@@ -516,6 +965,38 @@ func (jsfsFchown) invoke(ctx context.Context, mod api.Module, args ...interface{
 	return jsfsInvoke(ctx, mod, callback, errno)
 }
 
+// jsfsFchownat implements jsFn for the following
+//
+//	_, err := fsCall("fchownat", dirfd, path, uint32(uid), uint32(gid), flags)
+type jsfsFchownat struct{}
+
+func (jsfsFchownat) invoke(ctx context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
+	dirFD := goos.ValueToInt32(args[0])
+	path := args[1].(string)
+	_ = args[2] // uid
+	_ = args[3] // gid
+	flags := goos.ValueToUint32(args[4])
+	callback := args[5].(funcWrapper)
+
+	errno := syscallFchownat(mod, dirFD, path, flags)
+
+	return jsfsInvoke(ctx, mod, callback, errno)
+}
+
+// syscallFchownat is like syscall.Chown, except a relative path is resolved
+// against the directory open as dirFD, and a symbolic link is not followed
+// when flags include atSymlinkNofollow. As the ownership isn't supported, this
+// results in ENOSYS unless resolving the path fails.
+func syscallFchownat(mod api.Module, dirFD int32, path string, flags uint32) experimentalsys.Errno {
+	if flags&^atSymlinkNofollow != 0 {
+		return experimentalsys.EINVAL
+	}
+	if _, errno := resolvePathAt(mod, dirFD, path); errno != 0 {
+		return errno
+	}
+	return experimentalsys.ENOSYS // We only support functions used in wasip1
+}
+
 // jsfsLchown implements jsFn for the following
 //
 //	_, err := fsCall("lchown", path, uint32(uid), uint32(gid)) // syscall.Lchown
@@ -561,16 +1042,20 @@ func (jsfsFtruncate) invoke(ctx context.Context, mod api.Module, args ...interfa
 	length := toInt64(args[1])
 	callback := args[2].(funcWrapper)
 
+	errno := syscallFtruncate(mod, fd, length)
+
+	return jsfsInvoke(ctx, mod, callback, errno)
+}
+
+// syscallFtruncate is like syscall.Ftruncate. When the file grows, the
+// extended part reads back as zeros, as guaranteed by sys.File Truncate.
+func syscallFtruncate(mod api.Module, fd int32, length int64) experimentalsys.Errno {
 	// Check to see if the file descriptor is available
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
-	var errno experimentalsys.Errno
-	if f, ok := fsc.LookupFile(fd); !ok {
-		errno = experimentalsys.EBADF
-	} else {
-		errno = f.File.Truncate(length)
+	if f, ok := fsc.LookupFile(fd); ok {
+		return f.File.Truncate(length)
 	}
-
-	return jsfsInvoke(ctx, mod, callback, errno)
+	return experimentalsys.EBADF
 }
 
 // jsfsReadlink implements jsFn for syscall.Readlink
@@ -648,6 +1133,165 @@ func (jsfsFsync) invoke(ctx context.Context, mod api.Module, args ...interface{}
 	return jsfsInvoke(ctx, mod, callback, errno)
 }
 
+// jsfsPoll implements jsFn for the following
+//
+//	n, err := fsCall("poll", fds, timeout)
+//
+// Like poll in POSIX, fds holds the pollfd records whose revents are written
+// back, and n is the number of records whose revents are not zero. timeout is
+// in milliseconds, and negative to wait indefinitely.
+type jsfsPoll struct{}
+
+func (jsfsPoll) invoke(ctx context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
+	fds, ok := args[0].(*goos.ByteArray)
+	if !ok {
+		return nil, fmt.Errorf("arg[0] is %v not a []byte", args[0])
+	}
+	timeout := goos.ValueToInt32(args[1])
+	callback := args[2].(funcWrapper)
+
+	n, errno := syscallPoll(mod, fds.Unwrap(), timeout)
+
+	// It is safe to cast to uint32 because n <= len(fds).
+	return callback.invoke(ctx, mod, goos.RefJsfs, maybeError(errno), uint32(n)) // note: error first
+}
+
+// pollfdLen is the length of a pollfd record: fd (4), events (2) and
+// revents (2).
+const pollfdLen = 8
+
+// pollIn, pollOut and pollNval are the poll events, which are the same values
+// as POLLIN, POLLOUT and POLLNVAL on Linux.
+const (
+	pollIn   = 0x1
+	pollOut  = 0x4
+	pollNval = 0x20
+)
+
+// syscallPoll is like poll on Linux: each record is a little-endian pollfd.
+// Negative fds are ignored, and the ones not open report pollNval.
+//
+// Files whose readiness can't be polled, such as regular files, are always
+// ready. As sys.File polls a single file, the timeout is observed by waiting
+// on the first file not ready to read, or by sleeping if there is none.
+func syscallPoll(mod api.Module, fds []byte, timeoutMillis int32) (n int, errno experimentalsys.Errno) {
+	if len(fds)%pollfdLen != 0 {
+		return 0, experimentalsys.EINVAL
+	}
+
+	var waitFile fsapi.File
+	if n, waitFile, errno = pollReady(mod, fds); errno != 0 || n > 0 || timeoutMillis == 0 {
+		return
+	}
+
+	if waitFile == nil {
+		if timeoutMillis > 0 {
+			mod.(*wasm.ModuleInstance).Sys.Nanosleep(int64(timeoutMillis) * 1e6)
+		}
+		return
+	}
+	if _, errno = waitFile.Poll(fsapi.POLLIN, timeoutMillis); errno != 0 {
+		return
+	}
+	n, _, errno = pollReady(mod, fds)
+	return
+}
+
+// pollReady writes the revents of each pollfd record in fds without waiting,
+// and returns the number of records whose revents are not zero. waitFile is
+// the first file which is not ready to read, if any.
+func pollReady(mod api.Module, fds []byte) (n int, waitFile fsapi.File, errno experimentalsys.Errno) {
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	for i := 0; i < len(fds); i += pollfdLen {
+		rec := fds[i : i+pollfdLen]
+		fd, events := int32(le.Uint32(rec)), le.Uint16(rec[4:])
+
+		var revents uint16
+		if f, ok := fsc.LookupFile(fd); !ok {
+			if fd >= 0 {
+				revents = pollNval
+			}
+		} else {
+			if events&pollIn != 0 {
+				ready, errno := pollFile(f.File, fsapi.POLLIN)
+				if errno != 0 {
+					return 0, nil, errno
+				} else if ready {
+					revents |= pollIn
+				} else if waitFile == nil {
+					waitFile = f.File
+				}
+			}
+			if events&pollOut != 0 {
+				ready, errno := pollFile(f.File, fsapi.POLLOUT)
+				if errno != 0 {
+					return 0, nil, errno
+				} else if ready {
+					revents |= pollOut
+				}
+			}
+		}
+
+		le.PutUint16(rec[6:], revents)
+		if revents != 0 {
+			n++
+		}
+	}
+	return
+}
+
+// pollFile returns if the file is ready for the event without waiting.
+func pollFile(f fsapi.File, flag fsapi.Pflag) (ready bool, errno experimentalsys.Errno) {
+	ready, errno = f.Poll(flag, 0)
+	if errno == experimentalsys.ENOSYS || errno == experimentalsys.ENOTSUP {
+		return true, 0 // e.g. a regular file never blocks.
+	}
+	return
+}
+
+// ioctlTcgets is the ioctl request to get the terminal attributes, used by
+// functions like golang.org/x/term.IsTerminal. This is the same value as
+// TCGETS on Linux.
+const ioctlTcgets = 0x5401
+
+// jsfsIoctl implements the ioctl requests needed to detect a terminal.
+//
+//	_, err := fsCall("ioctl", fd, request)
+//
+// err is nil when fd is a terminal, and ENOTTY when it is not.
+type jsfsIoctl struct {
+	proc *processState
+}
+
+func (i *jsfsIoctl) invoke(ctx context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
+	fd := goos.ValueToInt32(args[0])
+	request := goos.ValueToUint32(args[1])
+	callback := args[2].(funcWrapper)
+
+	err := syscallIoctl(mod, i.proc, fd, request)
+	return callback.invoke(ctx, mod, goos.RefJsfs, err, err == nil) // note: error first
+}
+
+// syscallIoctl returns nil if the ioctl request succeeds on the file
+// descriptor. Only the file descriptors configured as processState.ttys are
+// terminals.
+func syscallIoctl(mod api.Module, proc *processState, fd int32, request uint32) error {
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	if _, ok := fsc.LookupFile(fd); !ok {
+		return experimentalsys.EBADF
+	}
+
+	switch request {
+	case ioctlTcgets:
+		if _, ok := proc.ttys[fd]; ok {
+			return nil
+		}
+		return ErrnoNotty
+	default:
+		return experimentalsys.ENOSYS // We only support requests to detect a terminal.
+	}
+}
+
 // jsSt is pre-parsed from fs_js.go setStat to avoid thrashing
 type jsSt struct {
 	isDir   bool
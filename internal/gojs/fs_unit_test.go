@@ -0,0 +1,632 @@
+package gojs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/gojs/config"
+	"github.com/tetratelabs/wazero/internal/gojs/custom"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func Test_syscallMkdir_umask(t *testing.T) {
+	mod := &wasm.ModuleInstance{Sys: internalsys.DefaultContext(sysfs.DirFS(t.TempDir()))}
+	defer mod.Sys.FS().Close()
+
+	c := config.NewConfig()
+	require.Equal(t, uint32(0o022), c.Umask)
+
+	// This is the same conversion jsfsMkdir makes from the guest's perm.
+	perm := custom.FromJsMode(0o777, c.Umask)
+	require.Equal(t, fs.FileMode(0o755), perm)
+
+	fd, errno := syscallMkdir(mod, "/dir", perm)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, mod.Sys.FS().CloseFile(fd))
+
+	st, err := syscallStat(mod, "/dir")
+	require.NoError(t, err)
+	require.True(t, st.isDir)
+	require.Equal(t, fs.FileMode(0o755), custom.FromJsMode(st.mode, 0).Perm())
+}
+
+func Test_syscallOpen_flags(t *testing.T) {
+	mod := &wasm.ModuleInstance{Sys: internalsys.DefaultContext(sysfs.DirFS(t.TempDir()))}
+	fsc := mod.Sys.FS()
+	defer fsc.Close()
+
+	t.Run("O_CLOEXEC", func(t *testing.T) {
+		fd, errno := syscallOpen(mod, "/cloexec", experimentalsys.O_CREAT|experimentalsys.O_WRONLY|oflagCloexec, 0o644)
+		require.EqualErrno(t, 0, errno)
+		defer fsc.CloseFile(fd) //nolint
+
+		f, ok := fsc.LookupFile(fd)
+		require.True(t, ok)
+		require.True(t, f.CloseOnExec)
+
+		n, errno := f.File.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 6, n)
+	})
+
+	t.Run("O_SYNC", func(t *testing.T) {
+		fd, errno := syscallOpen(mod, "/sync", experimentalsys.O_CREAT|experimentalsys.O_RDWR|experimentalsys.O_SYNC, 0o644)
+		require.EqualErrno(t, 0, errno)
+		defer fsc.CloseFile(fd) //nolint
+
+		f, ok := fsc.LookupFile(fd)
+		require.True(t, ok)
+		require.False(t, f.CloseOnExec)
+
+		n, errno := f.File.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 6, n)
+	})
+}
+
+func Test_syscallReadWrite_offsetOverflow(t *testing.T) {
+	mod := &wasm.ModuleInstance{Sys: internalsys.DefaultContext(sysfs.DirFS(t.TempDir()))}
+	fsc := mod.Sys.FS()
+	defer fsc.Close()
+
+	fd, errno := syscallOpen(mod, "/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o644)
+	require.EqualErrno(t, 0, errno)
+	defer fsc.CloseFile(fd) //nolint
+
+	n, errno := syscallWrite(mod, fd, float64(1<<20), []byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 6, n)
+
+	buf := make([]byte, 6)
+	n, errno = syscallRead(mod, fd, float64(1<<20), buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 6, n)
+	require.Equal(t, "wazero", string(buf))
+
+	for _, offset := range []interface{}{float64(maxFileOffset + 2), float64(1 << 63), int64(maxFileOffset + 1)} {
+		_, errno = syscallRead(mod, fd, offset, buf)
+		require.EqualErrno(t, experimentalsys.EOVERFLOW, errno)
+		_, errno = syscallWrite(mod, fd, offset, buf)
+		require.EqualErrno(t, experimentalsys.EOVERFLOW, errno)
+	}
+}
+
+func Test_syscallWrite_append(t *testing.T) {
+	tmpDir := t.TempDir()
+	mod := &wasm.ModuleInstance{Sys: internalsys.DefaultContext(sysfs.DirFS(tmpDir))}
+	fsc := mod.Sys.FS()
+	defer fsc.Close()
+
+	fd, errno := syscallOpen(mod, "/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR|experimentalsys.O_APPEND, 0o644)
+	require.EqualErrno(t, 0, errno)
+	defer fsc.CloseFile(fd) //nolint
+
+	_, errno = syscallWrite(mod, fd, nil, []byte("wa"))
+	require.EqualErrno(t, 0, errno)
+
+	// fs_js.go passes the offset to write after seeking to the start, which
+	// must be ignored as the file is opened with O_APPEND.
+	n, errno := syscallWrite(mod, fd, float64(0), []byte("zero"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 4, n)
+
+	b, err := os.ReadFile(path.Join(tmpDir, "file"))
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+}
+
+func Test_syscallReadWrite_closed(t *testing.T) {
+	mod := &wasm.ModuleInstance{Sys: internalsys.DefaultContext(sysfs.DirFS(t.TempDir()))}
+	fsc := mod.Sys.FS()
+	defer fsc.Close()
+
+	buf := make([]byte, 6)
+	requireEBADF := func(t *testing.T, fd int32) {
+		for _, offset := range []interface{}{nil, float64(0)} {
+			_, errno := syscallWrite(mod, fd, offset, []byte("wazero"))
+			require.EqualErrno(t, experimentalsys.EBADF, errno)
+			_, errno = syscallRead(mod, fd, offset, buf)
+			require.EqualErrno(t, experimentalsys.EBADF, errno)
+		}
+	}
+
+	t.Run("closed fd", func(t *testing.T) {
+		fd, errno := syscallOpen(mod, "/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o644)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, fsc.CloseFile(fd))
+
+		requireEBADF(t, fd)
+	})
+
+	t.Run("file closed mid-operation", func(t *testing.T) {
+		fd, errno := syscallOpen(mod, "/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o644)
+		require.EqualErrno(t, 0, errno)
+		defer fsc.CloseFile(fd) //nolint
+
+		// The file is closed while the fd is still in the table.
+		f, ok := fsc.LookupFile(fd)
+		require.True(t, ok)
+		require.EqualErrno(t, 0, f.File.Close())
+
+		requireEBADF(t, fd)
+	})
+
+	t.Run("nil file", func(t *testing.T) {
+		fd, errno := syscallOpen(mod, "/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o644)
+		require.EqualErrno(t, 0, errno)
+		defer fsc.CloseFile(fd) //nolint
+
+		f, ok := fsc.LookupFile(fd)
+		require.True(t, ok)
+		file := f.File
+		f.File = nil
+		defer func() { f.File = file }()
+
+		requireEBADF(t, fd)
+	})
+}
+
+func Test_syscallFtruncate(t *testing.T) {
+	mod := &wasm.ModuleInstance{Sys: internalsys.DefaultContext(sysfs.DirFS(t.TempDir()))}
+	fsc := mod.Sys.FS()
+	defer fsc.Close()
+
+	fd, errno := syscallOpen(mod, "/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o644)
+	require.EqualErrno(t, 0, errno)
+	defer fsc.CloseFile(fd) //nolint
+
+	_, errno = syscallWrite(mod, fd, nil, []byte("wazero, wazero"))
+	require.EqualErrno(t, 0, errno)
+
+	// Shrinks the file, and then grows it larger than the original.
+	require.EqualErrno(t, 0, syscallFtruncate(mod, fd, 6))
+	require.EqualErrno(t, 0, syscallFtruncate(mod, fd, 32))
+
+	buf := make([]byte, 64)
+	n, errno := syscallRead(mod, fd, float64(0), buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 32, n)
+	require.Equal(t, "wazero", string(buf[:6]))
+	// The grown part is zeros, rather than the data before shrinking.
+	require.Equal(t, make([]byte, 26), buf[6:n])
+
+	t.Run("EBADF", func(t *testing.T) {
+		require.EqualErrno(t, experimentalsys.EBADF, syscallFtruncate(mod, 42, 0))
+	})
+
+	t.Run("EINVAL", func(t *testing.T) {
+		require.EqualErrno(t, experimentalsys.EINVAL, syscallFtruncate(mod, fd, -1))
+	})
+}
+
+func Test_syscallFstat_blocks(t *testing.T) {
+	mod := &wasm.ModuleInstance{Sys: internalsys.DefaultContext(sysfs.DirFS(t.TempDir()))}
+	fsc := mod.Sys.FS()
+	defer fsc.Close()
+
+	fd, errno := syscallOpen(mod, "/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o644)
+	require.EqualErrno(t, 0, errno)
+	defer fsc.CloseFile(fd) //nolint
+
+	st, err := syscallFstat(fsc, fd)
+	require.NoError(t, err)
+	require.Equal(t, int32(4096), st.blksize)
+	require.Equal(t, int32(0), st.blocks)
+
+	// Growing the file by truncation makes it sparse on most hosts.
+	for _, size := range []int64{1, 512, 513, 1 << 20} {
+		require.EqualErrno(t, 0, syscallFtruncate(mod, fd, size))
+
+		st, err = syscallFstat(fsc, fd)
+		require.NoError(t, err)
+		require.Equal(t, size, st.size)
+		require.True(t, int64(st.blocks)*512 >= st.size)
+		require.Equal(t, int32((size+511)/512), st.blocks)
+	}
+
+	require.Equal(t, int32(math.MaxInt32), jsStBlocks(math.MaxInt64))
+}
+
+func Test_syscallReadWrite_stdio(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	// DataErrReader returns io.EOF along with the last data, which must not be an error.
+	stdin := iotest.DataErrReader(strings.NewReader("wazero"))
+	sysCtx, err := internalsys.NewContext(0, nil, nil, stdin, &stdout, &stderr, nil, nil, 0, nil, 0, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	mod := &wasm.ModuleInstance{Sys: sysCtx}
+	defer mod.Sys.FS().Close()
+
+	buf := make([]byte, 16)
+	n, errno := syscallRead(mod, 0, nil, buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "wazero", string(buf[:n]))
+
+	// Round-trips what was read from stdin.
+	n, errno = syscallWrite(mod, 1, nil, buf[:n])
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 6, n)
+	require.Equal(t, "wazero", stdout.String())
+
+	n, errno = syscallWrite(mod, 2, nil, []byte("error"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 5, n)
+	require.Equal(t, "error", stderr.String())
+	require.Equal(t, "wazero", stdout.String())
+
+	// Subsequent reads are EOF, which is a zero-length read without an error.
+	n, errno = syscallRead(mod, 0, nil, buf)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, n)
+
+	t.Run("EBADF", func(t *testing.T) {
+		// stdin is not writable, and stdout and stderr are not readable.
+		_, errno := syscallWrite(mod, 0, nil, []byte("wazero"))
+		require.EqualErrno(t, experimentalsys.EBADF, errno)
+		for _, fd := range []int32{1, 2} {
+			_, errno = syscallRead(mod, fd, nil, buf)
+			require.EqualErrno(t, experimentalsys.EBADF, errno)
+		}
+	})
+}
+
+// partialWriter accepts up to limit bytes, and then fails like a full disk.
+type partialWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+func (w *partialWriter) Write(p []byte) (int, error) {
+	if n := w.limit - w.Len(); n < len(p) {
+		w.Buffer.Write(p[:n])
+		return n, errors.New("no space left on device")
+	}
+	return w.Buffer.Write(p)
+}
+
+func Test_syscallWrite_partial(t *testing.T) {
+	stdout := &partialWriter{limit: 5}
+	sysCtx, err := internalsys.NewContext(0, nil, nil, nil, stdout, nil, nil, nil, 0, nil, 0, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	mod := &wasm.ModuleInstance{Sys: sysCtx}
+	defer mod.Sys.FS().Close()
+
+	// The count of the bytes written before the error is returned along with it.
+	n, errno := syscallWrite(mod, 1, nil, []byte("wazero!"))
+	require.EqualErrno(t, experimentalsys.EIO, errno)
+	require.Equal(t, 5, n)
+	require.Equal(t, "wazer", stdout.String())
+
+	n, errno = syscallWrite(mod, 1, nil, []byte("o"))
+	require.EqualErrno(t, experimentalsys.EIO, errno)
+	require.Zero(t, n)
+}
+
+func Test_syscallPoll(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	sysCtx, err := internalsys.NewContext(0, nil, nil, r, nil, nil, nil, nil, 0, nil, 0, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	mod := &wasm.ModuleInstance{Sys: sysCtx}
+	defer mod.Sys.FS().Close()
+
+	pollfds := func(fds ...[2]int) []byte {
+		buf := make([]byte, len(fds)*pollfdLen)
+		for i, fd := range fds {
+			le.PutUint32(buf[i*pollfdLen:], uint32(int32(fd[0])))
+			le.PutUint16(buf[i*pollfdLen+4:], uint16(fd[1]))
+			le.PutUint16(buf[i*pollfdLen+6:], 0xffff) // overwritten
+		}
+		return buf
+	}
+	revents := func(buf []byte, i int) uint16 {
+		return le.Uint16(buf[i*pollfdLen+6:])
+	}
+
+	t.Run("pipe", func(t *testing.T) {
+		fds := pollfds([2]int{0, pollIn})
+		n, errno := syscallPoll(mod, fds, 0)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, n)
+		require.Zero(t, revents(fds, 0))
+
+		// Waits for the timeout as the pipe is still empty.
+		n, errno = syscallPoll(mod, fds, 10)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, n)
+
+		_, err = w.Write([]byte("wazero"))
+		require.NoError(t, err)
+
+		n, errno = syscallPoll(mod, fds, -1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 1, n)
+		require.Equal(t, uint16(pollIn), revents(fds, 0))
+
+		// Drains the pipe, so that it is not ready anymore.
+		buf := make([]byte, 16)
+		_, errno = syscallRead(mod, 0, nil, buf)
+		require.EqualErrno(t, 0, errno)
+		n, errno = syscallPoll(mod, fds, 0)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, n)
+	})
+
+	t.Run("mixed", func(t *testing.T) {
+		// stdout discards writes, so it never blocks. Negative fds are ignored.
+		fds := pollfds([2]int{0, pollIn}, [2]int{1, pollOut}, [2]int{-1, pollIn}, [2]int{42, pollIn})
+		n, errno := syscallPoll(mod, fds, -1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 2, n)
+		require.Zero(t, revents(fds, 0))
+		require.Equal(t, uint16(pollOut), revents(fds, 1))
+		require.Zero(t, revents(fds, 2))
+		require.Equal(t, uint16(pollNval), revents(fds, 3))
+	})
+
+	t.Run("EINVAL", func(t *testing.T) {
+		_, errno := syscallPoll(mod, make([]byte, pollfdLen-1), 0)
+		require.EqualErrno(t, experimentalsys.EINVAL, errno)
+	})
+}
+
+func Test_syscallAt(t *testing.T) {
+	mod := &wasm.ModuleInstance{Sys: internalsys.DefaultContext(sysfs.DirFS(t.TempDir()))}
+	fsc := mod.Sys.FS()
+	defer fsc.Close()
+
+	parentFD, errno := syscallMkdir(mod, "/parent", 0o755)
+	require.EqualErrno(t, 0, errno)
+
+	t.Run("mkdirat", func(t *testing.T) {
+		fd, errno := syscallMkdirat(mod, parentFD, "child", 0o755)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, fsc.CloseFile(fd))
+
+		st, err := syscallStat(mod, "/parent/child")
+		require.NoError(t, err)
+		require.True(t, st.isDir)
+	})
+
+	t.Run("renameat", func(t *testing.T) {
+		fd, errno := syscallMkdirat(mod, parentFD, "from", 0o755)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, fsc.CloseFile(fd))
+
+		require.EqualErrno(t, 0, syscallRenameat(mod, parentFD, "from", parentFD, "../to"))
+
+		_, err := syscallStat(mod, "/parent/from")
+		require.EqualErrno(t, experimentalsys.ENOENT, err.(experimentalsys.Errno))
+		_, err = syscallStat(mod, "/to")
+		require.NoError(t, err)
+	})
+
+	t.Run("unlinkat", func(t *testing.T) {
+		fd, errno := syscallOpen(mod, "/parent/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o644)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, fsc.CloseFile(fd))
+
+		// A directory requires atRemovedir.
+		require.EqualErrno(t, experimentalsys.EISDIR, syscallUnlinkat(mod, parentFD, "child", 0))
+		require.EqualErrno(t, 0, syscallUnlinkat(mod, parentFD, "child", atRemovedir))
+		require.EqualErrno(t, 0, syscallUnlinkat(mod, parentFD, "file", 0))
+
+		_, err := syscallStat(mod, "/parent/child")
+		require.EqualErrno(t, experimentalsys.ENOENT, err.(experimentalsys.Errno))
+		_, err = syscallStat(mod, "/parent/file")
+		require.EqualErrno(t, experimentalsys.ENOENT, err.(experimentalsys.Errno))
+	})
+
+	t.Run("fchmodat", func(t *testing.T) {
+		fd, errno := syscallOpen(mod, "/parent/mode", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o644)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, fsc.CloseFile(fd))
+		require.EqualErrno(t, 0, fsc.RootFS().Symlink("mode", "/parent/link"))
+
+		// Without atSymlinkNofollow, the link is followed.
+		require.EqualErrno(t, 0, syscallFchmodat(mod, parentFD, "link", 0o600, 0))
+		st, err := syscallStat(mod, "/parent/mode")
+		require.NoError(t, err)
+		require.Equal(t, fs.FileMode(0o600), custom.FromJsMode(st.mode, 0).Perm())
+
+		// The mode of a symlink itself can't be changed.
+		require.EqualErrno(t, experimentalsys.ENOSYS, syscallFchmodat(mod, parentFD, "link", 0o644, atSymlinkNofollow))
+		require.EqualErrno(t, 0, syscallFchmodat(mod, parentFD, "mode", 0o400, atSymlinkNofollow))
+		st, err = syscallStat(mod, "/parent/mode")
+		require.NoError(t, err)
+		require.Equal(t, fs.FileMode(0o400), custom.FromJsMode(st.mode, 0).Perm())
+
+		require.EqualErrno(t, experimentalsys.EINVAL, syscallFchmodat(mod, parentFD, "mode", 0o644, atRemovedir))
+	})
+
+	t.Run("fchownat", func(t *testing.T) {
+		require.EqualErrno(t, experimentalsys.ENOSYS, syscallFchownat(mod, parentFD, "mode", 0))
+		require.EqualErrno(t, experimentalsys.EINVAL, syscallFchownat(mod, parentFD, "mode", atRemovedir))
+		require.EqualErrno(t, experimentalsys.EBADF, syscallFchownat(mod, 42, "mode", 0))
+	})
+
+	t.Run("absolute path ignores dirfd", func(t *testing.T) {
+		fd, errno := syscallMkdirat(mod, -1, "/abs", 0o755)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, fsc.CloseFile(fd))
+		require.EqualErrno(t, 0, syscallUnlinkat(mod, -1, "/abs", atRemovedir))
+	})
+
+	t.Run("EBADF", func(t *testing.T) {
+		_, errno := syscallMkdirat(mod, 42, "dir", 0o755) // 42 is an arbitrary invalid FD
+		require.EqualErrno(t, experimentalsys.EBADF, errno)
+	})
+
+	t.Run("ENOTDIR", func(t *testing.T) {
+		fd, errno := syscallOpen(mod, "/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o644)
+		require.EqualErrno(t, 0, errno)
+		defer fsc.CloseFile(fd) //nolint
+
+		require.EqualErrno(t, experimentalsys.ENOTDIR, syscallUnlinkat(mod, fd, "dir", 0))
+	})
+}
+
+func Test_syscallGetdents(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a", "bb", "ccc"} {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, name), nil, 0o600))
+	}
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir"), 0o700))
+
+	mod := &wasm.ModuleInstance{Sys: internalsys.DefaultContext(sysfs.DirFS(tmpDir))}
+	fsc := mod.Sys.FS()
+	defer fsc.Close()
+
+	fd, errno := syscallOpen(mod, "/", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+
+	proc := &processState{}
+
+	// Read until getdents returns zero, with room for two records per call.
+	var names []string
+	types := map[string]byte{}
+	buf := make([]byte, 48)
+	for calls := 0; ; calls++ {
+		n, errno := syscallGetdents(mod, proc, fd, buf)
+		require.EqualErrno(t, 0, errno)
+		if n == 0 {
+			require.Equal(t, 3, calls) // 6 dirents, two per call
+			break
+		}
+		for rec := buf[:n]; len(rec) > 0; {
+			reclen := int(binary.LittleEndian.Uint16(rec[16:]))
+			require.Equal(t, 24, reclen)
+			name := rec[direntHeaderLen:reclen]
+			name = name[:bytes.IndexByte(name, 0)]
+			names = append(names, string(name))
+			types[string(name)] = rec[18]
+			rec = rec[reclen:]
+		}
+	}
+
+	sort.Strings(names)
+	require.Equal(t, []string{".", "..", "a", "bb", "ccc", "dir"}, names)
+	require.Equal(t, byte(4), types["dir"]) // DT_DIR
+	require.Equal(t, byte(8), types["a"])   // DT_REG
+
+	t.Run("EINVAL on a buffer too small", func(t *testing.T) {
+		fd, errno := syscallOpen(mod, "/", experimentalsys.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer fsc.CloseFile(fd) //nolint
+
+		_, errno = syscallGetdents(mod, proc, fd, make([]byte, 23))
+		require.EqualErrno(t, experimentalsys.EINVAL, errno)
+	})
+
+	t.Run("ENOTDIR", func(t *testing.T) {
+		fd, errno := syscallOpen(mod, "/a", experimentalsys.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer fsc.CloseFile(fd) //nolint
+
+		_, errno = syscallGetdents(mod, proc, fd, buf)
+		require.EqualErrno(t, experimentalsys.ENOTDIR, errno)
+	})
+}
+
+func Test_syscallReadStdin(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		mode          config.StdinMode
+		expEmptyErrno experimentalsys.Errno
+	}{
+		{name: "blocking", mode: config.StdinModeBlocking},
+		{name: "nonblocking", mode: config.StdinModeNonblocking, expEmptyErrno: experimentalsys.EAGAIN},
+		{name: "EOF", mode: config.StdinModeEOF},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			require.NoError(t, err)
+			defer r.Close()
+			defer w.Close()
+
+			sysCtx, err := internalsys.NewContext(0, nil, nil, r, nil, nil, nil, nil, 0, nil, 0, nil, nil, nil, nil, nil)
+			require.NoError(t, err)
+			mod := &wasm.ModuleInstance{Sys: sysCtx}
+			defer mod.Sys.FS().Close()
+
+			buf := make([]byte, 16)
+			if tc.mode == config.StdinModeBlocking {
+				// The read waits until the data is written.
+				go func() {
+					time.Sleep(10 * time.Millisecond)
+					_, _ = w.Write([]byte("wazero"))
+				}()
+				n, errno := syscallReadStdin(mod, tc.mode, buf)
+				require.EqualErrno(t, 0, errno)
+				require.Equal(t, "wazero", string(buf[:n]))
+			} else {
+				n, errno := syscallReadStdin(mod, tc.mode, buf)
+				require.EqualErrno(t, tc.expEmptyErrno, errno)
+				require.Zero(t, n)
+			}
+
+			_, err = w.Write([]byte("gojs"))
+			require.NoError(t, err)
+			n, errno := syscallReadStdin(mod, tc.mode, buf)
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, "gojs", string(buf[:n]))
+		})
+	}
+
+	t.Run("reader", func(t *testing.T) {
+		// Readers other than files are always ready, so they are read as is.
+		sysCtx, err := internalsys.NewContext(0, nil, nil, strings.NewReader("wazero"), nil, nil, nil, nil, 0, nil, 0, nil, nil, nil, nil, nil)
+		require.NoError(t, err)
+		mod := &wasm.ModuleInstance{Sys: sysCtx}
+		defer mod.Sys.FS().Close()
+
+		buf := make([]byte, 16)
+		n, errno := syscallReadStdin(mod, config.StdinModeNonblocking, buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "wazero", string(buf[:n]))
+	})
+}
+
+func Test_syscallIoctl(t *testing.T) {
+	mod := &wasm.ModuleInstance{Sys: internalsys.DefaultContext(sysfs.DirFS(t.TempDir()))}
+	fsc := mod.Sys.FS()
+	defer fsc.Close()
+
+	fd, errno := syscallOpen(mod, "/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o644)
+	require.EqualErrno(t, 0, errno)
+	defer fsc.CloseFile(fd) //nolint
+
+	// Only stdout is configured as a terminal.
+	c := config.NewConfig()
+	c.TTYs = map[int32]struct{}{1: {}}
+	proc := &processState{ttys: c.TTYs}
+
+	require.NoError(t, syscallIoctl(mod, proc, 1, ioctlTcgets))
+	require.Equal(t, ErrnoNotty, syscallIoctl(mod, proc, 2, ioctlTcgets))
+	require.Equal(t, ErrnoNotty, syscallIoctl(mod, proc, fd, ioctlTcgets))
+
+	t.Run("EBADF", func(t *testing.T) {
+		require.Equal(t, experimentalsys.EBADF, syscallIoctl(mod, proc, 42, ioctlTcgets))
+	})
+
+	t.Run("ENOSYS", func(t *testing.T) {
+		require.Equal(t, experimentalsys.ENOSYS, syscallIoctl(mod, proc, 1, 0x5413 /* TIOCGWINSZ */))
+	})
+}
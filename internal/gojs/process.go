@@ -6,6 +6,7 @@ import (
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/gojs/config"
 	"github.com/tetratelabs/wazero/internal/gojs/custom"
 	"github.com/tetratelabs/wazero/internal/gojs/goos"
 	"github.com/tetratelabs/wazero/internal/gojs/util"
@@ -15,6 +16,17 @@ import (
 type processState struct {
 	cwd   string
 	umask uint32
+
+	// direntPos is the position of the next dirent to return from getdents,
+	// keyed by directory file descriptor. Entries are removed on close.
+	direntPos map[int32]uint64
+
+	// ttys are the file descriptors which are terminals. See config.Config.
+	ttys map[int32]struct{}
+
+	// stdinMode is how reading stdin behaves when no data is available. See
+	// config.Config.
+	stdinMode config.StdinMode
 }
 
 func newJsProcess(proc *processState) *jsVal {
@@ -45,7 +57,17 @@ type processCwd struct {
 }
 
 func (p *processCwd) invoke(_ context.Context, _ api.Module, _ ...interface{}) (interface{}, error) {
-	return p.proc.cwd, nil
+	return absCwd(p.proc.cwd), nil
+}
+
+// absCwd returns the working directory as an absolute and cleaned path, as
+// Go expects from syscall.Getwd. An empty path, such as an unset workdir,
+// means the root directory.
+func absCwd(cwd string) string {
+	if cwd == "" || cwd[0] != '/' {
+		cwd = "/" + cwd
+	}
+	return path.Clean(cwd)
 }
 
 // processChdir implements jsFn for fs.Open syscall.Chdir in fs_js.go
@@ -54,7 +76,7 @@ type processChdir struct {
 }
 
 func (p *processChdir) invoke(_ context.Context, mod api.Module, args ...interface{}) (interface{}, error) {
-	oldWd := p.proc.cwd
+	oldWd := absCwd(p.proc.cwd)
 	newWd := util.ResolvePath(oldWd, args[0].(string))
 
 	newWd = path.Clean(newWd)
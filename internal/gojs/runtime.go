@@ -3,6 +3,7 @@ package gojs
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
@@ -42,7 +43,7 @@ func wasmWrite(_ context.Context, mod api.Module, stack goarch.Stack) {
 	p := stack.ParamBytes(mod.Memory(), 1 /*, 2 */)
 
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
-	if f, ok := fsc.LookupFile(fd); ok {
+	if f, ok := lookupOpenFile(fsc, fd); ok {
 		_, errno := f.File.Write(p)
 		switch errno {
 		case 0:
@@ -156,10 +157,9 @@ func getRandomData(_ context.Context, mod api.Module, stack goarch.Stack) {
 
 	randSource := mod.(*wasm.ModuleInstance).Sys.RandSource()
 
-	bufLen := len(r)
-	if n, err := randSource.Read(r); err != nil {
-		panic(fmt.Errorf("RandSource.Read(r /* len=%d */) failed: %w", bufLen, err))
-	} else if n != bufLen {
-		panic(fmt.Errorf("RandSource.Read(r /* len=%d */) read %d bytes", bufLen, n))
+	// Read fully, as the source supplied by the embedder may return fewer
+	// bytes than requested.
+	if n, err := io.ReadFull(randSource, r); err != nil {
+		panic(fmt.Errorf("RandSource.Read(r /* len=%d */) read %d bytes: %w", len(r), n, err))
 	}
 }
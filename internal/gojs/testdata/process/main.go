@@ -44,6 +44,8 @@ func Main() {
 		{"..", "/"},
 		{".", "/"},
 		{"..", "/"},
+		{"dir/../dir/./", "/dir"},
+		{"/dir/..//", "/"},
 	}
 
 	for _, dir := range dirs {
@@ -53,6 +53,11 @@ type FileEntry struct {
 	// File is always non-nil.
 	File fsapi.File
 
+	// CloseOnExec is true when the file was opened with the close-on-exec
+	// flag, e.g. O_CLOEXEC in GOOS=js. There's no exec in Wasm, so this is
+	// only recorded for the guest to read back.
+	CloseOnExec bool
+
 	// direntCache is nil until DirentCache was called.
 	direntCache *DirentCache
 }
@@ -282,6 +287,26 @@ func (c *FSContext) LookupFile(fd int32) (*FileEntry, bool) {
 	return c.openedFiles.Lookup(fd)
 }
 
+// LookupDir returns the directory open as dirFD, for resolving paths relative
+// to it, e.g. in mkdirat.
+//
+// # Errors
+//
+// A zero sys.Errno is success. The below are expected otherwise:
+//   - sys.EBADF: dirFD is not open.
+//   - sys.ENOTDIR: dirFD is not a directory.
+func (c *FSContext) LookupDir(dirFD int32) (*FileEntry, sys.Errno) {
+	if f, ok := c.openedFiles.Lookup(dirFD); !ok {
+		return nil, sys.EBADF
+	} else if isDir, errno := f.File.IsDir(); errno != 0 {
+		return nil, errno
+	} else if !isDir {
+		return nil, sys.ENOTDIR
+	} else {
+		return f, 0
+	}
+}
+
 // OpenFile opens the file into the table and returns its file descriptor.
 // The result must be closed by CloseFile or Close.
 func (c *FSContext) OpenFile(fs sys.FS, path string, flag sys.Oflag, perm fs.FileMode) (int32, sys.Errno) {
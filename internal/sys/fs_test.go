@@ -129,6 +129,43 @@ func TestFSContext_CloseFile(t *testing.T) {
 	})
 }
 
+func TestFSContext_LookupDir(t *testing.T) {
+	embedFS, err := fs.Sub(testdata, "testdata")
+	require.NoError(t, err)
+	testFS := &sysfs.AdaptFS{FS: embedFS}
+
+	c := Context{}
+	err = c.InitFSContext(nil, nil, nil, []sys.FS{testFS}, []string{"/"}, nil)
+	require.NoError(t, err)
+	fsc := c.fsc
+	defer fsc.Close()
+
+	dirFD, errno := fsc.OpenFile(testFS, "sub", sys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+
+	fileFD, errno := fsc.OpenFile(testFS, "empty.txt", sys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+
+	t.Run("pre-open", func(t *testing.T) {
+		f, errno := fsc.LookupDir(FdPreopen)
+		require.EqualErrno(t, 0, errno)
+		require.True(t, f.IsPreopen)
+	})
+	t.Run("dir", func(t *testing.T) {
+		f, errno := fsc.LookupDir(dirFD)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "sub", f.Name)
+	})
+	t.Run("ENOTDIR for a file", func(t *testing.T) {
+		_, errno := fsc.LookupDir(fileFD)
+		require.EqualErrno(t, sys.ENOTDIR, errno)
+	})
+	t.Run("EBADF for an invalid FD", func(t *testing.T) {
+		_, errno := fsc.LookupDir(42) // 42 is an arbitrary invalid FD
+		require.EqualErrno(t, sys.EBADF, errno)
+	})
+}
+
 func TestFSContext_noPreopens(t *testing.T) {
 	c := Context{}
 	err := c.InitFSContext(nil, nil, nil, nil, nil, nil)
@@ -302,6 +302,8 @@ func ToErrno(errno sys.Errno) Errno {
 		return ErrnoNotsock
 	case sys.ENOTSUP:
 		return ErrnoNotsup
+	case sys.EOVERFLOW:
+		return ErrnoOverflow
 	case sys.EPERM:
 		return ErrnoPerm
 	case sys.EROFS:
@@ -101,6 +101,11 @@ func TestToErrno(t *testing.T) {
 			input:    sys.ENOTSUP,
 			expected: ErrnoNotsup,
 		},
+		{
+			name:     "sys.EOVERFLOW",
+			input:    sys.EOVERFLOW,
+			expected: ErrnoOverflow,
+		},
 		{
 			name:     "sys.EPERM",
 			input:    sys.EPERM,
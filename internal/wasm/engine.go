@@ -36,9 +36,14 @@ type Engine interface {
 
 // ModuleEngine implements function calls for a given module.
 type ModuleEngine interface {
-	// DoneInstantiation is called at the end of the instantiation of the module.
+	// DoneInstantiation is called at the end of the instantiation of the module, but before the start function,
+	// if any, is invoked so that the start function runs on the fully initialized module.
 	DoneInstantiation()
 
+	// MemoryGrown is called after the memory defined by this module is grown, so that the engine can update the
+	// information on the memory buffer cached for the compiled code.
+	MemoryGrown()
+
 	// NewFunction returns an api.Function for the given function pointed by the given Index.
 	NewFunction(index Index) api.Function
 
@@ -41,6 +41,9 @@ type MemoryInstance struct {
 	mux sync.RWMutex
 	// definition is known at compile time.
 	definition api.MemoryDefinition
+	// ownerModuleEngine is the ModuleEngine of the module defining this memory, notified via
+	// ModuleEngine.MemoryGrown when Grow changes the buffer. This is nil if the memory is not attached to any module.
+	ownerModuleEngine ModuleEngine
 }
 
 // NewMemoryInstance creates a new instance based on the parameters in the SectionIDMemory.
@@ -196,12 +199,14 @@ func (m *MemoryInstance) Grow(delta uint32) (result uint32, ok bool) {
 	} else if newPages > m.Cap { // grow the memory.
 		m.Buffer = append(m.Buffer, make([]byte, MemoryPagesToBytesNum(delta))...)
 		m.Cap = newPages
-		return currentPages, true
 	} else { // We already have the capacity we need.
 		sp := (*reflect.SliceHeader)(unsafe.Pointer(&m.Buffer))
 		sp.Len = int(MemoryPagesToBytesNum(newPages))
-		return currentPages, true
 	}
+	if m.ownerModuleEngine != nil {
+		m.ownerModuleEngine.MemoryGrown()
+	}
+	return currentPages, true
 }
 
 // PageSize returns the current memory buffer size in pages.
@@ -90,6 +90,29 @@ func TestMemoryInstance_Grow_Size(t *testing.T) {
 	}
 }
 
+func TestMemoryInstance_Grow_ownerModuleEngine(t *testing.T) {
+	me := &mockModuleEngine{}
+	m := &MemoryInstance{Max: 10, Buffer: make([]byte, 0), ownerModuleEngine: me}
+
+	_, ok := m.Grow(1)
+	require.True(t, ok)
+	require.Equal(t, 1, me.memoryGrownCount)
+
+	// The buffer doesn't change on zero page grow or failure.
+	_, ok = m.Grow(0)
+	require.True(t, ok)
+	_, ok = m.Grow(10)
+	require.False(t, ok)
+	require.Equal(t, 1, me.memoryGrownCount)
+
+	// Growing within the capacity is also notified.
+	m.Cap = 10
+	m.Buffer = make([]byte, MemoryPageSize, MemoryPagesToBytesNum(10))
+	_, ok = m.Grow(1)
+	require.True(t, ok)
+	require.Equal(t, 2, me.memoryGrownCount)
+}
+
 func TestMemoryInstance_ReadByte(t *testing.T) {
 	mem := &MemoryInstance{Buffer: []byte{0, 0, 0, 0, 0, 0, 0, 16}, Min: 1}
 	v, ok := mem.ReadByte(7)
@@ -216,8 +216,8 @@ func boolToByte(b bool) (ret byte) {
 	return
 }
 
-// typeOfFunction returns the wasm.FunctionType for the given function space index or nil.
-func (m *Module) typeOfFunction(funcIdx Index) *FunctionType {
+// TypeOfFunction returns the wasm.FunctionType for the given function space index or nil.
+func (m *Module) TypeOfFunction(funcIdx Index) *FunctionType {
 	typeSectionLength, importedFunctionCount := uint32(len(m.TypeSection)), m.ImportFunctionCount
 	if funcIdx < importedFunctionCount {
 		// Imports are not exclusively functions. This is the current function index in the loop.
@@ -300,7 +300,7 @@ func (m *Module) validateStartSection() error {
 	// TODO: this should be verified during decode so that errors have the correct source positions
 	if m.StartSection != nil {
 		startIndex := *m.StartSection
-		ft := m.typeOfFunction(startIndex)
+		ft := m.TypeOfFunction(startIndex)
 		if ft == nil { // TODO: move this check to decoder so that a module can never be decoded invalidly
 			return fmt.Errorf("invalid start function: func[%d] has an invalid type", startIndex)
 		}
@@ -636,6 +636,7 @@ func (m *ModuleInstance) buildMemory(module *Module) {
 	if memSec != nil {
 		m.MemoryInstance = NewMemoryInstance(memSec)
 		m.MemoryInstance.definition = &module.MemoryDefinitionSection[0]
+		m.MemoryInstance.ownerModuleEngine = m.Engine
 	}
 }
 
@@ -843,7 +843,8 @@ func TestModule_buildMemoryInstance(t *testing.T) {
 		min := uint32(1)
 		max := uint32(10)
 		mDef := MemoryDefinition{moduleName: "foo"}
-		m := ModuleInstance{}
+		me := &mockModuleEngine{}
+		m := ModuleInstance{Engine: me}
 		m.buildMemory(&Module{
 			MemorySection:           &Memory{Min: min, Cap: min, Max: max},
 			MemoryDefinitionSection: []MemoryDefinition{mDef},
@@ -852,6 +853,7 @@ func TestModule_buildMemoryInstance(t *testing.T) {
 		require.Equal(t, min, mem.Min)
 		require.Equal(t, max, mem.Max)
 		require.Equal(t, &mDef, mem.definition)
+		require.Equal(t, ModuleEngine(me), mem.ownerModuleEngine)
 	})
 }
 
@@ -370,6 +370,8 @@ func (s *Store) instantiate(
 
 	m.applyElements(module.ElementSection)
 
+	m.Engine.DoneInstantiation()
+
 	// Execute the start function.
 	if module.StartSection != nil {
 		funcIdx := *module.StartSection
@@ -381,8 +383,6 @@ func (s *Store) instantiate(
 			return nil, fmt.Errorf("start %s failed: %w", module.funcDesc(SectionIDFunction, funcIdx), err)
 		}
 	}
-
-	m.Engine.DoneInstantiation()
 	return
 }
 
@@ -405,7 +405,7 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 			case ExternTypeFunc:
 				expectedType := &module.TypeSection[i.DescFunc]
 				src := importedModule.Source
-				actual := src.typeOfFunction(imported.Index)
+				actual := src.TypeOfFunction(imported.Index)
 				if !actual.EqualsSignature(expectedType.Params, expectedType.Results) {
 					err = errorInvalidImport(i, fmt.Errorf("signature mismatch: %s != %s", expectedType, actual))
 					return
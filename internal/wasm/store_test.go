@@ -412,6 +412,7 @@ type mockModuleEngine struct {
 	callFailIndex        int
 	functionRefs         map[Index]Reference
 	resolveImportsCalled map[Index]Index
+	memoryGrownCount     int
 }
 
 type mockCallEngine struct {
@@ -456,6 +457,9 @@ func (e *mockEngine) NewModuleEngine(_ *Module, _ *ModuleInstance) (ModuleEngine
 // mockModuleEngine implements the same method as documented on wasm.ModuleEngine.
 func (e *mockModuleEngine) DoneInstantiation() {}
 
+// MemoryGrown implements the same method as documented on wasm.ModuleEngine.
+func (e *mockModuleEngine) MemoryGrown() { e.memoryGrownCount++ }
+
 // FunctionInstanceReference implements the same method as documented on wasm.ModuleEngine.
 func (e *mockModuleEngine) FunctionInstanceReference(i Index) Reference {
 	return e.functionRefs[i]
@@ -27,6 +27,9 @@ var (
 	ErrRuntimeInvalidTableAccess = New("invalid table access")
 	// ErrRuntimeIndirectCallTypeMismatch indicates that the type check failed during call_indirect.
 	ErrRuntimeIndirectCallTypeMismatch = New("indirect call type mismatch")
+	// ErrRuntimeUnalignedMemoryAccess indicates that the program tried to access the memory at an address
+	// which is not aligned to the alignment hint of the instruction. This is only raised when strict alignment is enabled.
+	ErrRuntimeUnalignedMemoryAccess = New("unaligned memory access")
 )
 
 // Error is returned by a wasm.Engine during the execution of Wasm functions, and they indicate that the Wasm runtime